@@ -21,12 +21,17 @@ import (
 	"github.com/yuhaohwang/bililive-go/src/live"
 	"github.com/yuhaohwang/bililive-go/src/log"
 	"github.com/yuhaohwang/bililive-go/src/metrics"
+	"github.com/yuhaohwang/bililive-go/src/notify"
 	"github.com/yuhaohwang/bililive-go/src/pkg/events"
 	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
 	"github.com/yuhaohwang/bililive-go/src/pushers"
 	"github.com/yuhaohwang/bililive-go/src/recorders"
 	"github.com/yuhaohwang/bililive-go/src/rtmp"
 	"github.com/yuhaohwang/bililive-go/src/servers"
+	"github.com/yuhaohwang/bililive-go/src/shutdown"
+	"github.com/yuhaohwang/bililive-go/src/sources"
+	"github.com/yuhaohwang/bililive-go/src/thumbnails"
 )
 
 // getConfig 函数用于获取程序的配置信息。
@@ -79,6 +84,20 @@ func getConfigBesidesExecutable() (*configs.Config, error) {
 }
 
 func main() {
+	// 导入和导出直播间列表只修改或读取配置文件，不启动录制
+	switch flag.Command {
+	case flag.CommandImport, flag.CommandExport:
+		run := runImport
+		if flag.Command == flag.CommandExport {
+			run = runExport
+		}
+		if err := run(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 获取配置信息
 	config, err := getConfig()
 	if err != nil {
@@ -135,20 +154,26 @@ func main() {
 		if v, ok := inst.Config.Cookies[u.Host]; ok {
 			opts = append(opts, live.WithKVStringCookies(u, v))
 		}
-		opts = append(opts, live.WithQuality(room.Quality))
+		opts = append(opts, live.WithQuality(room.Quality), live.WithHostName(room.HostName), live.WithRoomName(room.RoomName), live.WithLowLatency(room.LowLatency), live.WithFfmpegPath(inst.Config.FfmpegPath))
+		if name, tool, ok := inst.Config.GetExternalTool(u.String()); ok {
+			opts = append(opts, live.WithExternalTool(name, tool.Path))
+		}
 		l, err := live.New(u, inst.Cache, opts...)
 		if err != nil {
 			logger.WithField("url", room).Error(err.Error())
 			continue
 		}
-		if _, ok := inst.Lives[l.GetLiveId()]; ok {
-			logger.Errorf("%s 已存在!", room)
+		if _, ok := inst.GetLive(l.GetLiveId()); ok {
+			logger.Errorf("%s 已存在!", room.Url)
 			continue
 		}
-		inst.Lives[l.GetLiveId()] = l
+		inst.SetLive(l)
 		room.LiveId = l.GetLiveId()
 	}
 
+	// 创建退出管理器，收到退出信号或通过接口请求退出时使用。
+	sm := shutdown.NewManager(ctx)
+
 	// 如果配置中启用了RPC服务器，启动RPC服务器。
 	if inst.Config.RPC.Enable {
 		if err := servers.NewServer(ctx).Start(ctx); err != nil {
@@ -169,6 +194,15 @@ func main() {
 	if err := pm.Start(ctx); err != nil {
 		logger.Fatalf("初始化推送器管理器失败，错误: %s", err)
 	}
+	if err := thumbnails.NewManager(ctx).Start(ctx); err != nil {
+		logger.Fatalf("初始化缩略图管理器失败，错误: %s", err)
+	}
+	if err := notify.NewManager(ctx).Start(ctx); err != nil {
+		logger.Fatalf("初始化通知管理器失败，错误: %s", err)
+	}
+	if err := postprocess.NewManager(ctx).Start(ctx); err != nil {
+		logger.Fatalf("初始化后处理管理器失败，错误: %s", err)
+	}
 
 	// 创建rtmp自动配置器
 	rtmpAutoConfig := rtmp.NewRtmp(ctx)
@@ -182,8 +216,11 @@ func main() {
 		logger.Fatalf("初始化指标收集器失败，错误: %s", err)
 	}
 
+	// 修复上次异常退出时未完成的录制文件，需要在开始录制前执行。
+	recorders.RecoverFiles(ctx)
+
 	// 遍历所有直播房间，如果房间配置为正在监听，则添加到监听器管理器。
-	for _, _live := range inst.Lives {
+	for _, _live := range inst.GetLives() {
 		room, err := inst.Config.GetLiveRoomByUrl(_live.GetRawUrl())
 		if err != nil {
 			logger.WithFields(map[string]interface{}{"room": _live.GetRawUrl()}).Error(err)
@@ -198,21 +235,24 @@ func main() {
 		time.Sleep(time.Second * 5)
 	}
 
-	// 创建一个用于捕获信号的通道。
+	// 启动自动发现，需要在已有的直播间开始监听后执行，避免与上面的循环同时修改直播间列表。
+	if err := sources.NewManager(ctx, servers.NewRoomApplier()).Start(ctx); err != nil {
+		logger.Fatalf("初始化自动发现管理器失败，错误: %s", err)
+	}
+
+	// 捕获退出信号，第一次收到时在宽限时间内完成录制和后处理后退出，再次收到时立即退出。
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
+		sig := <-c
+		sm.Shutdown(sig.String())
 		<-c
-		// 如果配置中启用了RPC服务器，关闭RPC服务器。
-		if inst.Config.RPC.Enable {
-			inst.Server.Close(ctx)
-		}
-		// 关闭监听器管理器和录制器管理器。
-		inst.ListenerManager.Close(ctx)
-		inst.RecorderManager.Close(ctx)
+		logger.Warn("再次收到退出信号，立即退出")
+		os.Exit(1)
 	}()
 
-	// 等待程序实例的WaitGroup计数为0，即等待所有协程结束。
+	// 等待程序实例的WaitGroup计数为0，即等待所有协程结束，开始退出后会等到退出流程完成。
 	inst.WaitGroup.Wait()
 	logger.Info("再见~")
+	log.Close(logger)
 }
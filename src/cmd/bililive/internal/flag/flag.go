@@ -47,11 +47,40 @@ var (
 
 	// 视频分割策略
 	SplitStrategies = app.Flag("split-strategies", "视频分割策略，支持\"on_room_name_changed\", \"max_duration:(duration)\"").Strings()
+
+	// 运行录制，不指定子命令时使用
+	runCmd = app.Command("run", "监听和录制直播（默认）。").Default()
+
+	// 导入直播间列表到配置文件
+	importCmd = app.Command("import", "从文件导入直播间列表到配置文件，按直播ID跳过重复的直播间。")
+	// 导入的文件，-表示标准输入
+	ImportFile = importCmd.Arg("file", "导入的文件，-表示标准输入。").Required().String()
+	// 导入的格式
+	ImportFormat = importCmd.Flag("format", "格式：auto、csv、json、urls、bililiverecorder、blrec。").Default("auto").String()
+	// 只检查，不修改配置文件
+	ImportDryRun = importCmd.Flag("dry-run", "只输出导入计划，不修改配置文件。").Default("false").Bool()
+
+	// 导出配置文件中的直播间列表
+	exportCmd = app.Command("export", "导出配置文件中的直播间列表。")
+	// 导出的格式
+	ExportFormat = exportCmd.Flag("format", "格式：csv、json、urls、bililiverecorder、blrec。").Default("csv").String()
+	// 导出的文件，为空时输出到标准输出
+	ExportFile = exportCmd.Flag("file", "导出的文件，为空时输出到标准输出。").Short('f').Default("").String()
+
+	// Command 是命令行指定的子命令
+	Command string
+)
+
+// 子命令
+const (
+	CommandRun    = "run"
+	CommandImport = "import"
+	CommandExport = "export"
 )
 
 func init() {
 	// 解析命令行参数
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	Command = kingpin.MustParse(app.Parse(os.Args[1:]))
 }
 
 // GenConfigFromFlags 通过解析命令行参数生成配置信息。
@@ -7,6 +7,8 @@ import (
 	_ "github.com/yuhaohwang/bililive-go/src/live/cc"
 	_ "github.com/yuhaohwang/bililive-go/src/live/douyin"
 	_ "github.com/yuhaohwang/bililive-go/src/live/douyu"
+	_ "github.com/yuhaohwang/bililive-go/src/live/external"
+	_ "github.com/yuhaohwang/bililive-go/src/live/generic"
 	_ "github.com/yuhaohwang/bililive-go/src/live/hongdoufm"
 	_ "github.com/yuhaohwang/bililive-go/src/live/huajiao"
 	_ "github.com/yuhaohwang/bililive-go/src/live/huya"
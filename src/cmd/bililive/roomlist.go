@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yuhaohwang/bililive-go/src/cmd/bililive/internal/flag"
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/pkg/roomlist"
+)
+
+// getConfigFile 获取导入和导出使用的配置文件，没有指定时使用可执行文件旁边的 config.yml。
+func getConfigFile() (*configs.Config, error) {
+	if *flag.Conf != "" {
+		return configs.NewConfigWithFile(*flag.Conf)
+	}
+	return getConfigBesidesExecutable()
+}
+
+// runImport 将文件中的直播间导入到配置文件，输出导入计划。
+// 离线时无法向平台查询直播ID，只按地址生成的直播ID去重。
+func runImport() error {
+	config, err := getConfigFile()
+	if err != nil {
+		return err
+	}
+	var b []byte
+	if *flag.ImportFile == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(*flag.ImportFile)
+	}
+	if err != nil {
+		return err
+	}
+	rooms, invalid, format, err := roomlist.Parse(*flag.ImportFormat, b)
+	if err != nil {
+		return err
+	}
+	plan := roomlist.NewPlan(rooms, invalid, roomlist.ExistingRooms(config.LiveRooms))
+	for _, room := range plan.Add {
+		fmt.Printf("添加：%s\n", room.Url)
+	}
+	for _, d := range plan.Duplicates {
+		fmt.Printf("重复：%s（已有 %s）\n", d.Url, d.Existing)
+	}
+	for _, i := range plan.Invalid {
+		fmt.Printf("无效：第 %d 行 %s：%s\n", i.Line, i.Value, i.Error)
+	}
+	fmt.Fprintf(os.Stderr, "格式：%s，添加 %d 个，重复 %d 个，无效 %d 个\n", format, len(plan.Add), len(plan.Duplicates), len(plan.Invalid))
+	if *flag.ImportDryRun || len(plan.Add) == 0 {
+		return nil
+	}
+	config.LiveRooms = append(config.LiveRooms, plan.Add...)
+	return config.Marshal()
+}
+
+// runExport 将配置文件中的直播间导出为指定格式。
+func runExport() error {
+	config, err := getConfigFile()
+	if err != nil {
+		return err
+	}
+	b, skipped, err := roomlist.Format(*flag.ExportFormat, config.LiveRooms)
+	if err != nil {
+		return err
+	}
+	for _, room := range skipped {
+		fmt.Fprintf(os.Stderr, "跳过不支持的直播间：%s\n", room.Url)
+	}
+	if *flag.ExportFile == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(*flag.ExportFile, b, 0644)
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/yuhaohwang/bililive-go/src/live"
@@ -41,6 +44,152 @@ func (r *RPC) verify() error {
 type Feature struct {
 	UseNativeFlvParser         bool `yaml:"use_native_flv_parser"`         // 是否使用本地FLV解析器
 	RemoveSymbolOtherCharacter bool `yaml:"remove_symbol_other_character"` // 是否删除特殊符号
+	SaveCover                  bool `yaml:"save_cover"`                    // 是否在录制文件旁保存直播封面和主播头像
+}
+
+// 解析器名称。
+const (
+	ParserFfmpeg     = "ffmpeg"     // 使用 FFmpeg 录制
+	ParserNative     = "native"     // 内置的 FLV 解析器
+	ParserStreamlink = "streamlink" // 使用 streamlink 下载
+	ParserYtDlp      = "yt-dlp"     // 使用 yt-dlp 下载
+	ParserHls        = "hls"        // 内置的 HLS 下载，会跳过插入的广告分片
+)
+
+// defaultPlatformParsers 是各域名默认使用的解析器，只录制音频或录制时转码时不使用。
+var defaultPlatformParsers = map[string]string{
+	"www.twitch.tv": ParserHls,
+}
+
+// ExternalTool包含外部下载工具的配置。
+type ExternalTool struct {
+	Path string   `yaml:"path"` // 可执行文件路径，为空时在 PATH 中查找
+	Args []string `yaml:"args"` // 追加在直播间地址前的命令行参数
+}
+
+// Parsers包含按平台选择解析器的配置。
+type Parsers struct {
+	Platforms  map[string]string `yaml:"platforms"`  // 直播间域名使用的解析器：ffmpeg、native、hls、streamlink、yt-dlp
+	Streamlink ExternalTool      `yaml:"streamlink"` // streamlink 配置
+	YtDlp      ExternalTool      `yaml:"yt_dlp"`     // yt-dlp 配置
+}
+
+// verify 验证解析器配置的有效性。
+func (p *Parsers) verify() error {
+	for host, name := range p.Platforms {
+		switch name {
+		case ParserFfmpeg, ParserNative, ParserStreamlink, ParserYtDlp, ParserHls:
+		default:
+			return fmt.Errorf("域名 %s 使用的解析器不支持：%s", host, name)
+		}
+	}
+	return nil
+}
+
+// 自动发现直播间的平台。
+const (
+	SourcePlatformBilibili = "bilibili"
+	SourcePlatformDouyu    = "douyu"
+	SourcePlatformTwitch   = "twitch"
+)
+
+// 自动发现直播间的类型。
+const (
+	SourceTypeFollowing = "following" // cookies 中登录的账号关注的主播
+	SourceTypeCategory  = "category"  // 分区中正在直播的直播间
+)
+
+// 自动发现的直播间的添加策略。
+const (
+	SourceAddAll    = "all"    // 添加列表中的全部直播间
+	SourceAddLiving = "living" // 只添加正在直播的直播间，已添加的直播间下播后保留
+)
+
+// 不再出现在列表中的自动管理的直播间的移除策略。
+const (
+	SourceRemoveKeep   = "keep"   // 保留
+	SourceRemoveStop   = "stop"   // 停止监听，保留在直播间列表中
+	SourceRemoveRemove = "remove" // 从直播间列表中移除，正在录制时等到录制结束
+)
+
+// DefaultSourceInterval 是自动发现的默认间隔。
+const DefaultSourceInterval = 10 * time.Minute
+
+// Source 是自动发现直播间的来源，定期通过平台接口获取关注列表或分区中的直播间，并同步到直播间列表。
+type Source struct {
+	Name         string        `yaml:"name"`                    // 来源名称，不能重复，自动添加的直播间的 source 为该名称
+	Platform     string        `yaml:"platform"`                // 平台：bilibili、douyu、twitch
+	Type         string        `yaml:"type"`                    // 类型：following、category
+	Category     string        `yaml:"category,omitempty"`      // 分区，bilibili 为“父分区ID/分区ID”，douyu 为分区ID，twitch 为游戏名称
+	Interval     time.Duration `yaml:"interval,omitempty"`      // 获取列表的间隔，为0时为10分钟
+	AddPolicy    string        `yaml:"add_policy,omitempty"`    // 添加策略：all、living，为空时为 all
+	RemovePolicy string        `yaml:"remove_policy,omitempty"` // 移除策略：keep、stop、remove，为空时为 keep
+	Limit        int           `yaml:"limit,omitempty"`         // 最多获取的直播间数量，0表示不限制
+	ListenOnly   bool          `yaml:"listen_only,omitempty"`   // 添加的直播间只监听，不录制
+	Group        string        `yaml:"group,omitempty"`         // 添加的直播间的分组
+	Tags         []string      `yaml:"tags,omitempty"`          // 添加的直播间的标签
+}
+
+// GetInterval 返回获取列表的间隔。
+func (s Source) GetInterval() time.Duration {
+	if s.Interval <= 0 {
+		return DefaultSourceInterval
+	}
+	return s.Interval
+}
+
+// GetAddPolicy 返回添加策略。
+func (s Source) GetAddPolicy() string {
+	if s.AddPolicy == "" {
+		return SourceAddAll
+	}
+	return s.AddPolicy
+}
+
+// GetRemovePolicy 返回移除策略。
+func (s Source) GetRemovePolicy() string {
+	if s.RemovePolicy == "" {
+		return SourceRemoveKeep
+	}
+	return s.RemovePolicy
+}
+
+// verify 验证自动发现配置的有效性。
+func (s *Source) verify() error {
+	if s.Name == "" {
+		return fmt.Errorf("sources的name不能为空")
+	}
+	switch s.Platform {
+	case SourcePlatformBilibili, SourcePlatformDouyu, SourcePlatformTwitch:
+	default:
+		return fmt.Errorf("source %s 的平台不支持：%s", s.Name, s.Platform)
+	}
+	switch s.Type {
+	case SourceTypeFollowing:
+	case SourceTypeCategory:
+		if s.Category == "" {
+			return fmt.Errorf("source %s 的category不能为空", s.Name)
+		}
+	default:
+		return fmt.Errorf("source %s 的类型不支持：%s", s.Name, s.Type)
+	}
+	if s.Interval != 0 && s.Interval < time.Minute {
+		return fmt.Errorf("source %s 的interval的最小值为一分钟", s.Name)
+	}
+	switch s.GetAddPolicy() {
+	case SourceAddAll, SourceAddLiving:
+	default:
+		return fmt.Errorf("source %s 的add_policy不支持：%s", s.Name, s.AddPolicy)
+	}
+	switch s.GetRemovePolicy() {
+	case SourceRemoveKeep, SourceRemoveStop, SourceRemoveRemove:
+	default:
+		return fmt.Errorf("source %s 的remove_policy不支持：%s", s.Name, s.RemovePolicy)
+	}
+	if s.Limit < 0 {
+		return fmt.Errorf("source %s 的limit不能小于0", s.Name)
+	}
+	return nil
 }
 
 // VideoSplitStrategies包含视频分割策略信息。
@@ -57,29 +206,490 @@ type OnRecordFinished struct {
 	CustomCommandline     string `yaml:"custom_commandline"`       // 自定义命令行操作
 }
 
+// 后处理步骤类型。
+const (
+	StepRemux     = "remux"     // 使用 FFmpeg 无损转封装
+	StepTranscode = "transcode" // 使用 FFmpeg 转码
+	StepDanmaku   = "danmaku"   // 渲染录制文件旁的弹幕文件
+	StepUpload    = "upload"    // 上传文件
+	StepMove      = "move"      // 移动文件及其附属文件
+	StepCommand   = "command"   // 执行自定义命令
+	StepWebhook   = "webhook"   // 发送 Webhook 请求
+	StepMerge     = "merge"     // 合并同一场直播的分段文件，由 merge 配置自动添加，不能在 steps 中使用
+)
+
+// 分段合并方式。
+const (
+	MergeAuto   = "auto"   // 全部为 FLV 文件时使用内置合并，否则使用 FFmpeg
+	MergeNative = "native" // 内置的 FLV 标签拼接
+	MergeFfmpeg = "ffmpeg" // FFmpeg concat demuxer
+)
+
+// PostProcessCondition包含后处理步骤的执行条件，所有条件都满足时才执行。
+type PostProcessCondition struct {
+	Platforms  []string `yaml:"platforms"`  // 平台名称，如 哔哩哔哩，为空时不限制
+	Extensions []string `yaml:"extensions"` // 当前文件的扩展名，如 .flv，为空时不限制
+	MinSize    int64    `yaml:"min_size"`   // 当前文件的最小大小（MB）
+	When       string   `yaml:"when"`       // 模板表达式，渲染结果为 true 时执行
+}
+
+// PostProcessStep包含一个后处理步骤的配置。
+type PostProcessStep struct {
+	Name            string               `yaml:"name"`              // 步骤名称，为空时使用类型
+	Type            string               `yaml:"type"`              // 步骤类型
+	If              PostProcessCondition `yaml:"if"`                // 执行条件
+	Timeout         time.Duration        `yaml:"timeout"`           // 单次执行的超时时间，为0时不限制
+	Retries         int                  `yaml:"retries"`           // 失败后的重试次数
+	RetryDelay      time.Duration        `yaml:"retry_delay"`       // 重试间隔
+	Concurrency     int                  `yaml:"concurrency"`       // 该步骤同时执行的最大数量，为0时不限制
+	ContinueOnError bool                 `yaml:"continue_on_error"` // 失败后是否继续执行后续步骤
+	DeleteSource    bool                 `yaml:"delete_source"`     // 成功后是否删除该步骤的输入文件（remux、transcode、command）
+
+	Format    string            `yaml:"format"`     // remux、transcode 的输出格式，默认为 mp4
+	AppendExt bool              `yaml:"append_ext"` // remux、transcode 在输入文件名后追加扩展名（如 a.flv.mp4），而不是替换原有扩展名
+	Args      []string          `yaml:"args"`       // transcode 的 FFmpeg 输出参数
+	Profile   string            `yaml:"profile"`    // transcode 使用的转码配置名称，优先于 args，直播间设置的 post_process_profile 优先于此
+	Command   string            `yaml:"command"`    // command、danmaku、upload 执行的命令模板
+	Target    string            `yaml:"target"`     // move 的目标目录模板
+	Url       string            `yaml:"url"`        // webhook 的请求地址；upload 未设置 command 时以 PUT 上传到该地址模板
+	Headers   map[string]string `yaml:"headers"`    // webhook、upload 的请求头
+}
+
+// StepName 返回步骤名称。
+func (s *PostProcessStep) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+// verify 验证后处理步骤的有效性。
+func (s *PostProcessStep) verify() error {
+	switch s.Type {
+	case StepRemux:
+	case StepTranscode:
+		if len(s.Args) == 0 && s.Profile == "" {
+			return fmt.Errorf("后处理步骤 %s 需要设置 args 或 profile", s.StepName())
+		}
+	case StepDanmaku, StepCommand:
+		if s.Command == "" {
+			return fmt.Errorf("后处理步骤 %s 需要设置 command", s.StepName())
+		}
+	case StepUpload:
+		if s.Command == "" && s.Url == "" {
+			return fmt.Errorf("后处理步骤 %s 需要设置 command 或 url", s.StepName())
+		}
+	case StepMove:
+		if s.Target == "" {
+			return fmt.Errorf("后处理步骤 %s 需要设置 target", s.StepName())
+		}
+	case StepWebhook:
+		if s.Url == "" {
+			return fmt.Errorf("后处理步骤 %s 需要设置 url", s.StepName())
+		}
+	default:
+		return fmt.Errorf("不支持的后处理步骤类型：%s", s.Type)
+	}
+	if s.Timeout < 0 || s.Retries < 0 || s.RetryDelay < 0 || s.Concurrency < 0 {
+		return fmt.Errorf("后处理步骤 %s 的 timeout、retries、retry_delay 和 concurrency 不能小于0", s.StepName())
+	}
+	return nil
+}
+
+// 转码配置的视频编码。
+const (
+	CodecCopy = "copy" // 不转码
+	CodecH264 = "h264"
+	CodecHEVC = "hevc"
+	CodecAV1  = "av1"
+)
+
+// 转码配置的输出格式。
+const (
+	FormatFlv = "flv"
+	FormatMkv = "mkv"
+	FormatMp4 = "mp4"
+	FormatTs  = "ts"
+)
+
+var (
+	// bitrateRegexp 匹配 FFmpeg 的码率，如 800k、5M。
+	bitrateRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmM]?$`)
+	// resolutionRegexp 匹配分辨率，如 1920x1080，宽或高为 -2 时按比例缩放。
+	resolutionRegexp = regexp.MustCompile(`^(-[12]|[0-9]+)x(-[12]|[0-9]+)$`)
+)
+
+// TranscodeProfile包含一个命名的转码配置，可用于录制时转码或后处理的 transcode 步骤。
+type TranscodeProfile struct {
+	Codec        string   `yaml:"codec"`         // 视频编码：copy、h264、hevc、av1，为空时使用 copy
+	Encoder      string   `yaml:"encoder"`       // FFmpeg 编码器，如 libx264、libx265、hevc_vaapi、h264_nvenc，为空时使用对应编码的软件编码器
+	Device       string   `yaml:"device"`        // VAAPI 编码器使用的设备，为空时使用 /dev/dri/renderD128
+	Bitrate      string   `yaml:"bitrate"`       // 视频码率，如 5M，为空时使用编码器的默认值
+	Crf          int      `yaml:"crf"`           // 软件编码器的质量参数，为0时使用编码器的默认值
+	Preset       string   `yaml:"preset"`        // 编码器的预设，如 veryfast
+	Resolution   string   `yaml:"resolution"`    // 输出分辨率，如 1280x720、-2x720，为空时不缩放
+	AudioCodec   string   `yaml:"audio_codec"`   // 音频编码器：copy、aac、libopus、none，为空时使用 copy
+	AudioBitrate string   `yaml:"audio_bitrate"` // 音频码率，如 128k
+	Format       string   `yaml:"format"`        // 输出格式：flv、mkv、mp4、ts，为空时 h264 使用 flv，其他编码使用 mkv
+	ExtraArgs    []string `yaml:"extra_args"`    // 追加在输出文件前的 FFmpeg 参数
+}
+
+// GetCodec 返回视频编码。
+func (p *TranscodeProfile) GetCodec() string {
+	if p.Codec == "" {
+		return CodecCopy
+	}
+	return p.Codec
+}
+
+// GetEncoder 返回视频编码器。
+func (p *TranscodeProfile) GetEncoder() string {
+	if p.Encoder != "" {
+		return p.Encoder
+	}
+	switch p.GetCodec() {
+	case CodecH264:
+		return "libx264"
+	case CodecHEVC:
+		return "libx265"
+	case CodecAV1:
+		return "libsvtav1"
+	}
+	return CodecCopy
+}
+
+// IsVaapi 判断是否使用 VAAPI 硬件编码。
+func (p *TranscodeProfile) IsVaapi() bool {
+	return strings.HasSuffix(p.GetEncoder(), "_vaapi")
+}
+
+// GetDevice 返回 VAAPI 编码器使用的设备。
+func (p *TranscodeProfile) GetDevice() string {
+	if p.Device == "" {
+		return "/dev/dri/renderD128"
+	}
+	return p.Device
+}
+
+// GetFormat 返回输出格式，FLV 只能可靠地保存 H.264，其他编码默认使用录制中断时仍可播放的 MKV。
+func (p *TranscodeProfile) GetFormat() string {
+	if p.Format != "" {
+		return p.Format
+	}
+	switch p.GetCodec() {
+	case CodecCopy, CodecH264:
+		return FormatFlv
+	}
+	return FormatMkv
+}
+
+// verify 验证转码配置的有效性。
+func (p *TranscodeProfile) verify(name string) error {
+	switch p.Codec {
+	case "", CodecCopy, CodecH264, CodecHEVC, CodecAV1:
+	default:
+		return fmt.Errorf("转码配置 %s 的 codec 不支持：%s", name, p.Codec)
+	}
+	if p.GetCodec() == CodecCopy && p.Encoder != "" && p.Encoder != CodecCopy {
+		return fmt.Errorf("转码配置 %s 设置了 encoder，需要同时设置 codec", name)
+	}
+	if p.GetCodec() == CodecCopy && (p.Bitrate != "" || p.Crf != 0 || p.Preset != "" || p.Resolution != "") {
+		return fmt.Errorf("转码配置 %s 不转码视频时不能设置 bitrate、crf、preset 和 resolution", name)
+	}
+	if p.Bitrate != "" && !bitrateRegexp.MatchString(p.Bitrate) {
+		return fmt.Errorf("转码配置 %s 的 bitrate 无效：%s", name, p.Bitrate)
+	}
+	if p.AudioBitrate != "" && !bitrateRegexp.MatchString(p.AudioBitrate) {
+		return fmt.Errorf("转码配置 %s 的 audio_bitrate 无效：%s", name, p.AudioBitrate)
+	}
+	if p.Crf < 0 {
+		return fmt.Errorf("转码配置 %s 的 crf 不能小于0", name)
+	}
+	if p.Resolution != "" && !resolutionRegexp.MatchString(p.Resolution) {
+		return fmt.Errorf("转码配置 %s 的 resolution 无效：%s", name, p.Resolution)
+	}
+	switch p.Format {
+	case "", FormatFlv, FormatMkv, FormatMp4, FormatTs:
+	default:
+		return fmt.Errorf("转码配置 %s 的 format 不支持：%s", name, p.Format)
+	}
+	if p.GetFormat() == FormatFlv && p.GetCodec() != CodecCopy && p.GetCodec() != CodecH264 {
+		return fmt.Errorf("转码配置 %s 的 %s 编码不能使用 flv 格式", name, p.GetCodec())
+	}
+	return nil
+}
+
+// SessionMerge包含直播结束后合并同一场直播分段文件的配置。
+type SessionMerge struct {
+	Enable         bool          `yaml:"enable"`          // 是否合并分段，开启后分段会在直播结束后合并为一个文件再执行后处理步骤
+	Method         string        `yaml:"method"`          // 合并方式：auto、native、ffmpeg，为空时使用 auto
+	Wait           time.Duration `yaml:"wait"`            // 直播结束后等待最后一个分段写完的时间，为0时使用默认值
+	DeleteSegments bool          `yaml:"delete_segments"` // 合并成功后是否删除分段文件
+}
+
+// verify 验证分段合并配置的有效性。
+func (m *SessionMerge) verify() error {
+	switch m.Method {
+	case "", MergeAuto, MergeNative, MergeFfmpeg:
+	default:
+		return fmt.Errorf("不支持的分段合并方式：%s", m.Method)
+	}
+	if m.Wait < 0 {
+		return fmt.Errorf("merge的wait不能小于0")
+	}
+	return nil
+}
+
+// PostProcess包含录制完成后的处理流水线配置。
+type PostProcess struct {
+	Workers   int               `yaml:"workers"`    // 同时处理的任务数，为0时使用默认值
+	StateFile string            `yaml:"state_file"` // 任务状态的保存路径，为空时保存在输出路径下的 .bililive-go/jobs.json
+	Merge     SessionMerge      `yaml:"merge"`      // 分段合并配置
+	Steps     []PostProcessStep `yaml:"steps"`      // 按顺序执行的步骤，为空时根据 on_record_finished 生成
+}
+
+// GetSteps 返回后处理步骤，未设置 steps 时将 on_record_finished 转换为对应的步骤。
+func (c *Config) GetSteps() []PostProcessStep {
+	if len(c.PostProcess.Steps) > 0 {
+		return c.PostProcess.Steps
+	}
+	o := c.OnRecordFinished
+	if cmd := strings.TrimSpace(o.CustomCommandline); cmd != "" {
+		return []PostProcessStep{{Type: StepCommand, Command: cmd, DeleteSource: o.DeleteFlvAfterConvert}}
+	}
+	if o.ConvertToMp4 {
+		// 与旧版本相同，输出文件名为 a.flv.mp4
+		return []PostProcessStep{{Type: StepRemux, Format: "mp4", AppendExt: true, DeleteSource: o.DeleteFlvAfterConvert}}
+	}
+	return nil
+}
+
+// Shutdown包含程序退出时的排空配置。
+type Shutdown struct {
+	GracePeriod time.Duration `yaml:"grace_period"` // 等待录制文件写完和后处理任务完成的最长时间，为0时使用默认值，超时后未完成的任务会在下次启动时继续
+}
+
+// Thumbnails包含录制文件缩略图和联系表的生成配置。
+type Thumbnails struct {
+	Enable  bool `yaml:"enable"`  // 是否在录制完成后生成缩略图，需要 FFmpeg
+	Width   int  `yaml:"width"`   // 单帧宽度（像素）
+	Count   int  `yaml:"count"`   // 联系表帧数
+	Columns int  `yaml:"columns"` // 联系表列数
+}
+
+// 支持通知的事件。
+const (
+	NotifyLiveStart   = "live_start"   // 开播
+	NotifyRecordError = "record_error" // 录制失败
+	NotifyDiskLow     = "disk_low"     // 磁盘空间不足
+	NotifyAll         = "all"          // 用于房间的 mute_notify，表示屏蔽全部通知
+)
+
+// NotifyEvents 是所有支持通知的事件。
+var NotifyEvents = []string{NotifyLiveStart, NotifyRecordError, NotifyDiskLow}
+
+// NotifyTemplate包含通知消息的标题和正文模板。
+type NotifyTemplate struct {
+	Title string `yaml:"title"` // 标题模板
+	Body  string `yaml:"body"`  // 正文模板
+}
+
+// TelegramNotify包含Telegram机器人通知配置。
+type TelegramNotify struct {
+	Enable bool   `yaml:"enable"`  // 是否启用
+	Token  string `yaml:"token"`   // 机器人Token
+	ChatID string `yaml:"chat_id"` // 接收消息的会话ID
+	ApiUrl string `yaml:"api_url"` // API地址，为空时使用官方地址
+}
+
+// DiscordNotify包含Discord Webhook通知配置。
+type DiscordNotify struct {
+	Enable     bool   `yaml:"enable"`      // 是否启用
+	WebhookUrl string `yaml:"webhook_url"` // Webhook地址
+}
+
+// EmailNotify包含SMTP邮件通知配置。
+type EmailNotify struct {
+	Enable   bool     `yaml:"enable"`   // 是否启用
+	Host     string   `yaml:"host"`     // SMTP服务器地址
+	Port     int      `yaml:"port"`     // SMTP服务器端口
+	SSL      bool     `yaml:"ssl"`      // 是否使用SSL直接连接（通常为465端口），否则在支持时使用STARTTLS
+	Username string   `yaml:"username"` // 用户名，为空时不进行认证
+	Password string   `yaml:"password"` // 密码
+	From     string   `yaml:"from"`     // 发件人
+	To       []string `yaml:"to"`       // 收件人列表
+}
+
+// ServerChanNotify包含Server酱通知配置。
+type ServerChanNotify struct {
+	Enable  bool   `yaml:"enable"`   // 是否启用
+	SendKey string `yaml:"send_key"` // SendKey
+	ApiUrl  string `yaml:"api_url"`  // API地址，为空时使用官方地址
+}
+
+// BarkNotify包含Bark通知配置。
+type BarkNotify struct {
+	Enable    bool   `yaml:"enable"`     // 是否启用
+	ServerUrl string `yaml:"server_url"` // Bark服务器地址，为空时使用官方地址
+	DeviceKey string `yaml:"device_key"` // 设备Key
+	Group     string `yaml:"group"`      // 消息分组
+	Sound     string `yaml:"sound"`      // 提示音
+}
+
+// Notify包含消息通知配置。
+type Notify struct {
+	Events            []string                  `yaml:"events"`              // 需要通知的事件，为空时通知全部事件
+	Templates         map[string]NotifyTemplate `yaml:"templates"`           // 按事件自定义的消息模板，未设置时使用默认模板
+	ErrorCooldown     time.Duration             `yaml:"error_cooldown"`      // 同一房间录制失败通知的最小间隔
+	DiskLowThreshold  int                       `yaml:"disk_low_threshold"`  // 输出路径剩余空间低于该值（MB）时通知，为0时不检查
+	DiskCheckInterval time.Duration             `yaml:"disk_check_interval"` // 磁盘空间检查间隔
+	Telegram          TelegramNotify            `yaml:"telegram"`            // Telegram机器人
+	Discord           DiscordNotify             `yaml:"discord"`             // Discord Webhook
+	Email             EmailNotify               `yaml:"email"`               // SMTP邮件
+	ServerChan        ServerChanNotify          `yaml:"server_chan"`         // Server酱
+	Bark              BarkNotify                `yaml:"bark"`                // Bark
+}
+
+// verify 验证通知设置的有效性。
+func (n *Notify) verify() error {
+	for _, event := range n.Events {
+		if !isNotifyEvent(event) {
+			return fmt.Errorf("不支持的通知事件：%s", event)
+		}
+	}
+	for event := range n.Templates {
+		if !isNotifyEvent(event) {
+			return fmt.Errorf("不支持的通知事件：%s", event)
+		}
+	}
+	if n.DiskLowThreshold < 0 {
+		return fmt.Errorf("disk_low_threshold不能小于0")
+	}
+	if n.DiskLowThreshold > 0 && n.DiskCheckInterval <= 0 {
+		return fmt.Errorf("disk_check_interval必须大于0")
+	}
+	if t := n.Telegram; t.Enable && (t.Token == "" || t.ChatID == "") {
+		return fmt.Errorf("telegram通知需要设置token和chat_id")
+	}
+	if d := n.Discord; d.Enable && d.WebhookUrl == "" {
+		return fmt.Errorf("discord通知需要设置webhook_url")
+	}
+	if e := n.Email; e.Enable && (e.Host == "" || e.Port <= 0 || e.From == "" || len(e.To) == 0) {
+		return fmt.Errorf("email通知需要设置host、port、from和to")
+	}
+	if s := n.ServerChan; s.Enable && s.SendKey == "" {
+		return fmt.Errorf("server_chan通知需要设置send_key")
+	}
+	if b := n.Bark; b.Enable && b.DeviceKey == "" {
+		return fmt.Errorf("bark通知需要设置device_key")
+	}
+	return nil
+}
+
+// isNotifyEvent 判断是否是支持通知的事件。
+func isNotifyEvent(event string) bool {
+	for _, e := range NotifyEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// 日志格式
+const (
+	LogFormatText = "text" // 文本格式
+	LogFormatJson = "json" // 每行一个JSON对象
+)
+
+// 按时间切分日志的周期
+const (
+	LogRotateHourly = "hourly" // 每小时
+	LogRotateDaily  = "daily"  // 每天
+)
+
+// logLevels 是可以设置的日志级别
+var logLevels = []string{"trace", "debug", "info", "warn", "warning", "error", "fatal", "panic"}
+
+// LogRotate包含日志文件的切分配置。
+type LogRotate struct {
+	MaxSize    int    `yaml:"max_size"`    // 单个日志文件的最大大小（MB），为0时不按大小切分
+	Interval   string `yaml:"interval"`    // 按时间切分的周期，可以是 hourly 或 daily，为空时不按时间切分
+	Compress   bool   `yaml:"compress"`    // 是否使用gzip压缩切分出的日志文件
+	MaxBackups int    `yaml:"max_backups"` // 保留切分出的日志文件的数量，为0时不限制
+	MaxAge     int    `yaml:"max_age"`     // 保留切分出的日志文件的天数，为0时不限制
+}
+
+// Enabled 判断是否需要切分日志文件。
+func (r *LogRotate) Enabled() bool {
+	return r.MaxSize > 0 || r.Interval != ""
+}
+
 // Log包含日志相关信息。
 type Log struct {
-	OutPutFolder string `yaml:"out_put_folder"` // 输出日志文件夹
-	SaveLastLog  bool   `yaml:"save_last_log"`  // 是否保存最后一条日志
-	SaveEveryLog bool   `yaml:"save_every_log"` // 是否保存每一条日志
+	OutPutFolder string    `yaml:"out_put_folder"` // 输出日志文件夹
+	SaveLastLog  bool      `yaml:"save_last_log"`  // 是否保存最后一条日志
+	SaveEveryLog bool      `yaml:"save_every_log"` // 是否保存每一条日志
+	Format       string    `yaml:"format"`         // 日志格式，可以是 text 或 json，默认为 text
+	Level        string    `yaml:"level"`          // 日志级别，为空时根据 debug 选择 debug 或 info，运行时可以通过接口修改
+	Rotate       LogRotate `yaml:"rotate"`         // 日志文件的切分配置
+	PerRoom      bool      `yaml:"per_room"`       // 是否将每个直播间的日志另外保存到日志文件夹下 rooms 目录中的单独文件
+}
+
+// verify 验证日志设置的有效性。
+func (l *Log) verify() error {
+	switch l.Format {
+	case "", LogFormatText, LogFormatJson:
+	default:
+		return fmt.Errorf("不支持的日志格式：%s", l.Format)
+	}
+	if l.Level != "" && !IsLogLevel(l.Level) {
+		return fmt.Errorf("不支持的日志级别：%s", l.Level)
+	}
+	switch l.Rotate.Interval {
+	case "", LogRotateHourly, LogRotateDaily:
+	default:
+		return fmt.Errorf("不支持的日志切分周期：%s", l.Rotate.Interval)
+	}
+	if l.Rotate.MaxSize < 0 || l.Rotate.MaxBackups < 0 || l.Rotate.MaxAge < 0 {
+		return fmt.Errorf("日志切分的max_size、max_backups和max_age不能小于0")
+	}
+	return nil
+}
+
+// IsLogLevel 判断是否是支持的日志级别。
+func IsLogLevel(level string) bool {
+	for _, l := range logLevels {
+		if l == strings.ToLower(level) {
+			return true
+		}
+	}
+	return false
 }
 
 // Config包含所有配置信息。
 type Config struct {
-	File                 string               `yaml:"-"`                      // 配置文件路径
-	RPC                  RPC                  `yaml:"rpc"`                    // RPC配置
-	Debug                bool                 `yaml:"debug"`                  // 是否启用调试模式
-	Interval             int                  `yaml:"interval"`               // 采集间隔
-	OutPutPath           string               `yaml:"out_put_path"`           // 输出路径
-	FfmpegPath           string               `yaml:"ffmpeg_path"`            // FFmpeg路径
-	Log                  Log                  `yaml:"log"`                    // 日志配置
-	Feature              Feature              `yaml:"feature"`                // 特性配置
-	LiveRooms            []LiveRoom           `yaml:"live_rooms"`             // 直播房间配置
-	OutputTmpl           string               `yaml:"out_put_tmpl"`           // 输出模板
-	VideoSplitStrategies VideoSplitStrategies `yaml:"video_split_strategies"` // 视频分割策略
-	Cookies              map[string]string    `yaml:"cookies"`                // Cookies配置
-	OnRecordFinished     OnRecordFinished     `yaml:"on_record_finished"`     // 录制完成后的操作配置
-	TimeoutInUs          int                  `yaml:"timeout_in_us"`          // 超时时间（微秒）
+	File                 string                      `yaml:"-"`                      // 配置文件路径
+	RPC                  RPC                         `yaml:"rpc"`                    // RPC配置
+	Debug                bool                        `yaml:"debug"`                  // 是否启用调试模式
+	Interval             int                         `yaml:"interval"`               // 采集间隔
+	OutPutPath           string                      `yaml:"out_put_path"`           // 输出路径
+	FfmpegPath           string                      `yaml:"ffmpeg_path"`            // FFmpeg路径
+	Log                  Log                         `yaml:"log"`                    // 日志配置
+	Feature              Feature                     `yaml:"feature"`                // 特性配置
+	LiveRooms            []LiveRoom                  `yaml:"live_rooms"`             // 直播房间配置
+	OutputTmpl           string                      `yaml:"out_put_tmpl"`           // 输出模板
+	VideoSplitStrategies VideoSplitStrategies        `yaml:"video_split_strategies"` // 视频分割策略
+	Cookies              map[string]string           `yaml:"cookies"`                // Cookies配置
+	OnRecordFinished     OnRecordFinished            `yaml:"on_record_finished"`     // 录制完成后的操作配置
+	Thumbnails           Thumbnails                  `yaml:"thumbnails"`             // 缩略图配置
+	Notify               Notify                      `yaml:"notify"`                 // 消息通知配置
+	PostProcess          PostProcess                 `yaml:"post_process"`           // 录制完成后的处理流水线配置
+	Shutdown             Shutdown                    `yaml:"shutdown"`               // 程序退出配置
+	Parsers              Parsers                     `yaml:"parsers"`                // 按平台选择解析器的配置
+	TranscodeProfiles    map[string]TranscodeProfile `yaml:"transcode_profiles"`     // 命名的转码配置
+	Sources              []Source                    `yaml:"sources,omitempty"`      // 自动发现直播间的来源
+	TimeoutInUs          int                         `yaml:"timeout_in_us"`          // 超时时间（微秒）
 
 	liveRoomIndexCache map[string]int
 }
@@ -96,6 +706,63 @@ type LiveRoom struct {
 	Rtmp      string  `yaml:"rtmp"`         // 转推地址
 	Push      bool    `yaml:"push"`         // 转推
 	Pushing   bool    `yaml:"is_pushing"`   // 转推状态
+
+	HostName   string `yaml:"host_name,omitempty"`   // 直接的直播流地址使用的主播名，为空时使用域名
+	RoomName   string `yaml:"room_name,omitempty"`   // 直接的直播流地址使用的房间名，为空时使用文件名
+	LowLatency bool   `yaml:"low_latency,omitempty"` // 使用低延迟的直播流，目前只支持 twitch
+
+	MuteNotify []string `yaml:"mute_notify,omitempty"` // 屏蔽的通知事件，all表示屏蔽全部
+
+	AudioOnly   bool   `yaml:"audio_only,omitempty"`   // 只录制音频
+	AudioFormat string `yaml:"audio_format,omitempty"` // 只录制音频时的格式：aac、m4a、opus、flv，为空时使用 aac
+
+	TranscodeProfile   string `yaml:"transcode_profile,omitempty"`    // 录制时使用的转码配置，为空时不转码
+	PostProcessProfile string `yaml:"post_process_profile,omitempty"` // 后处理 transcode 步骤使用的转码配置，优先于步骤的设置
+
+	Group string   `yaml:"group,omitempty"` // 直播间所属的分组，如部门
+	Tags  []string `yaml:"tags,omitempty"`  // 直播间的标签，用于筛选和批量操作
+
+	Source        string `yaml:"source,omitempty"`         // 自动添加该直播间的来源名称，为空时为手动添加，只有自动添加的直播间会被来源移除
+	SourceStopped bool   `yaml:"source_stopped,omitempty"` // 是否被来源停止监听，只有来源停止的直播间会在重新出现在列表中时恢复监听，手动修改监听状态时清除
+}
+
+// 只录制音频时的格式。
+const (
+	AudioFormatAac  = "aac"  // ADTS 格式的 AAC，不转码
+	AudioFormatM4a  = "m4a"  // 分片 MP4 封装的 AAC，不转码
+	AudioFormatOpus = "opus" // 转码为 Opus
+	AudioFormatFlv  = "flv"  // 只包含音频的 FLV，可使用内置 FLV 解析器
+)
+
+// GetAudioFormat 返回只录制音频时的格式。
+func (l *LiveRoom) GetAudioFormat() string {
+	if l.AudioFormat == "" {
+		return AudioFormatAac
+	}
+	return l.AudioFormat
+}
+
+// verify 验证直播房间配置的有效性。
+func (l *LiveRoom) verify() error {
+	switch l.AudioFormat {
+	case "", AudioFormatAac, AudioFormatM4a, AudioFormatOpus, AudioFormatFlv:
+	default:
+		return fmt.Errorf("直播间 %s 的 audio_format 不支持：%s", l.Url, l.AudioFormat)
+	}
+	if l.AudioOnly && l.TranscodeProfile != "" {
+		return fmt.Errorf("直播间 %s 只录制音频时不能设置 transcode_profile", l.Url)
+	}
+	return nil
+}
+
+// IsNotifyMuted 判断房间是否屏蔽了指定事件的通知。
+func (l *LiveRoom) IsNotifyMuted(event string) bool {
+	for _, e := range l.MuteNotify {
+		if e == event || e == NotifyAll {
+			return true
+		}
+	}
+	return false
 }
 
 // liveRoomAlias用于在配置中同时支持字符串和LiveRoom格式。
@@ -142,10 +809,12 @@ var defaultConfig = Config{
 		OutPutFolder: "./",
 		SaveLastLog:  true,
 		SaveEveryLog: false,
+		Format:       LogFormatText,
 	},
 	Feature: Feature{
 		UseNativeFlvParser:         false,
 		RemoveSymbolOtherCharacter: false,
+		SaveCover:                  false,
 	},
 	LiveRooms:          []LiveRoom{},
 	File:               "",
@@ -157,6 +826,28 @@ var defaultConfig = Config{
 		ConvertToMp4:          false,
 		DeleteFlvAfterConvert: false,
 	},
+	Thumbnails: Thumbnails{
+		Enable:  false,
+		Width:   320,
+		Count:   12,
+		Columns: 4,
+	},
+	PostProcess: PostProcess{
+		Workers: 2,
+		Merge: SessionMerge{
+			Enable: false,
+			Method: MergeAuto,
+			Wait:   30 * time.Second,
+		},
+	},
+	Shutdown: Shutdown{
+		GracePeriod: time.Minute,
+	},
+	Notify: Notify{
+		ErrorCooldown:     10 * time.Minute,
+		DiskLowThreshold:  0,
+		DiskCheckInterval: 5 * time.Minute,
+	},
 	TimeoutInUs: 60000000,
 }
 
@@ -175,6 +866,9 @@ func (c *Config) Verify() error {
 	if err := c.RPC.verify(); err != nil {
 		return err
 	}
+	if err := c.Log.verify(); err != nil {
+		return err
+	}
 	if c.Interval <= 0 {
 		return fmt.Errorf("采集间隔不能小于等于0")
 	}
@@ -184,12 +878,116 @@ func (c *Config) Verify() error {
 	if maxDur := c.VideoSplitStrategies.MaxDuration; maxDur > 0 && maxDur < time.Minute {
 		return fmt.Errorf("max_duration的最小值为一分钟")
 	}
+	if t := c.Thumbnails; t.Enable && (t.Width <= 0 || t.Count <= 0 || t.Columns <= 0) {
+		return fmt.Errorf("thumbnails的width、count和columns必须大于0")
+	}
+	if c.PostProcess.Workers < 0 {
+		return fmt.Errorf("post_process的workers不能小于0")
+	}
+	if c.Shutdown.GracePeriod < 0 {
+		return fmt.Errorf("shutdown的grace_period不能小于0")
+	}
+	if err := c.PostProcess.Merge.verify(); err != nil {
+		return err
+	}
+	for name, profile := range c.TranscodeProfiles {
+		if err := profile.verify(name); err != nil {
+			return err
+		}
+	}
+	for i := range c.PostProcess.Steps {
+		step := &c.PostProcess.Steps[i]
+		if err := step.verify(); err != nil {
+			return err
+		}
+		if err := c.verifyProfile(step.Profile, "后处理步骤 "+step.StepName()); err != nil {
+			return err
+		}
+	}
+	if err := c.Notify.verify(); err != nil {
+		return err
+	}
+	if err := c.Parsers.verify(); err != nil {
+		return err
+	}
+	sourceNames := make(map[string]struct{}, len(c.Sources))
+	for i := range c.Sources {
+		source := &c.Sources[i]
+		if err := source.verify(); err != nil {
+			return err
+		}
+		if _, ok := sourceNames[source.Name]; ok {
+			return fmt.Errorf("source的name重复：%s", source.Name)
+		}
+		sourceNames[source.Name] = struct{}{}
+	}
+	for i := range c.LiveRooms {
+		room := &c.LiveRooms[i]
+		if err := room.verify(); err != nil {
+			return err
+		}
+		if err := c.verifyProfile(room.TranscodeProfile, "直播间 "+room.Url); err != nil {
+			return err
+		}
+		if err := c.verifyProfile(room.PostProcessProfile, "直播间 "+room.Url); err != nil {
+			return err
+		}
+	}
 	if !c.RPC.Enable && len(c.LiveRooms) == 0 {
 		return fmt.Errorf("RPC未启用，且未设置直播房间，程序没有可执行操作")
 	}
 	return nil
 }
 
+// GetParser 返回直播间地址的域名配置的解析器，未配置时返回空字符串。
+func (c *Config) GetParser(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	return c.Parsers.Platforms[u.Host]
+}
+
+// DefaultParser 返回直播间地址的域名默认使用的解析器，没有默认值时返回空字符串。
+func DefaultParser(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	return defaultPlatformParsers[u.Host]
+}
+
+// GetExternalTool 返回直播间地址的域名配置的外部工具（streamlink 或 yt-dlp），未配置时 ok 为 false。
+func (c *Config) GetExternalTool(rawUrl string) (name string, tool ExternalTool, ok bool) {
+	switch name = c.GetParser(rawUrl); name {
+	case ParserStreamlink:
+		return name, c.Parsers.Streamlink, true
+	case ParserYtDlp:
+		return name, c.Parsers.YtDlp, true
+	}
+	return "", ExternalTool{}, false
+}
+
+// GetTranscodeProfile 返回指定名称的转码配置。
+func (c *Config) GetTranscodeProfile(name string) (*TranscodeProfile, bool) {
+	profile, ok := c.TranscodeProfiles[name]
+	if !ok {
+		return nil, false
+	}
+	return &profile, true
+}
+
+// verifyProfile 验证引用的转码配置是否存在，名称为空时不检查。
+func (c *Config) verifyProfile(name, owner string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := c.TranscodeProfiles[name]; !ok {
+		return fmt.Errorf("%s 使用的转码配置 %s 不存在", owner, name)
+	}
+	return nil
+}
+
 // RefreshLiveRoomIndexCache 刷新直播房间索引缓存。
 func (c *Config) RefreshLiveRoomIndexCache() {
 	for index, room := range c.LiveRooms {
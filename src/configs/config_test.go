@@ -3,6 +3,7 @@ package configs
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -72,4 +73,161 @@ func TestConfig_Verify(t *testing.T) {
 	cfg.OutPutPath = os.TempDir()
 	cfg.RPC.Enable = false
 	assert.Error(t, cfg.Verify())
+
+	// 恢复RPC的值，设置无效的音频格式，预期会出错
+	cfg.RPC.Enable = true
+	cfg.LiveRooms = []LiveRoom{{Url: "https://live.bilibili.com/1", AudioOnly: true, AudioFormat: "mp3"}}
+	assert.Error(t, cfg.Verify())
+	cfg.LiveRooms[0].AudioFormat = AudioFormatOpus
+	assert.NoError(t, cfg.Verify())
+	cfg.LiveRooms[0].AudioFormat = ""
+	assert.Equal(t, AudioFormatAac, cfg.LiveRooms[0].GetAudioFormat())
+}
+
+// TestConfig_GetParser 测试按域名选择解析器。
+func TestConfig_GetParser(t *testing.T) {
+	cfg := NewConfig()
+	cfg.OutPutPath = os.TempDir()
+	cfg.Parsers.Platforms = map[string]string{"www.twitch.tv": ParserStreamlink}
+	assert.NoError(t, cfg.Verify())
+	assert.Equal(t, ParserStreamlink, cfg.GetParser("https://www.twitch.tv/foo"))
+	assert.Equal(t, "", cfg.GetParser("https://live.bilibili.com/1"))
+
+	name, _, ok := cfg.GetExternalTool("https://www.twitch.tv/foo")
+	assert.True(t, ok)
+	assert.Equal(t, ParserStreamlink, name)
+	_, _, ok = cfg.GetExternalTool("https://live.bilibili.com/1")
+	assert.False(t, ok)
+
+	cfg.Parsers.Platforms["live.bilibili.com"] = "unknown"
+	assert.Error(t, cfg.Verify())
+}
+
+// TestConfig_VerifyLog 测试日志配置的验证。
+func TestConfig_VerifyLog(t *testing.T) {
+	cfg := NewConfig()
+	cfg.OutPutPath = os.TempDir()
+	cfg.RPC.Enable = true
+	cfg.Log.Format = LogFormatJson
+	cfg.Log.Level = "Warn"
+	cfg.Log.Rotate = LogRotate{MaxSize: 10, Interval: LogRotateDaily, MaxBackups: 3}
+	assert.NoError(t, cfg.Verify())
+	assert.True(t, cfg.Log.Rotate.Enabled())
+
+	cfg.Log.Format = "xml"
+	assert.Error(t, cfg.Verify())
+	cfg.Log.Format = LogFormatText
+	cfg.Log.Level = "verbose"
+	assert.Error(t, cfg.Verify())
+	cfg.Log.Level = ""
+	cfg.Log.Rotate.Interval = "weekly"
+	assert.Error(t, cfg.Verify())
+	cfg.Log.Rotate = LogRotate{MaxAge: -1}
+	assert.Error(t, cfg.Verify())
+	assert.False(t, cfg.Log.Rotate.Enabled())
+}
+
+// TestConfig_VerifyTranscodeProfiles 测试转码配置的验证。
+func TestConfig_VerifyTranscodeProfiles(t *testing.T) {
+	cfg := NewConfig()
+	cfg.OutPutPath = os.TempDir()
+	cfg.TranscodeProfiles = map[string]TranscodeProfile{
+		"hevc": {Codec: CodecHEVC, Encoder: "hevc_vaapi", Bitrate: "5M", Resolution: "1920x1080"},
+	}
+	cfg.LiveRooms = []LiveRoom{{Url: "https://live.bilibili.com/1", TranscodeProfile: "hevc", PostProcessProfile: "hevc"}}
+	cfg.PostProcess.Steps = []PostProcessStep{{Type: StepTranscode, Profile: "hevc"}}
+	assert.NoError(t, cfg.Verify())
+	profile, ok := cfg.GetTranscodeProfile("hevc")
+	assert.True(t, ok)
+	assert.Equal(t, FormatMkv, profile.GetFormat())
+
+	// 引用不存在的转码配置
+	cfg.PostProcess.Steps[0].Profile = "h264"
+	assert.Error(t, cfg.Verify())
+	cfg.PostProcess.Steps[0].Profile = "hevc"
+	cfg.LiveRooms[0].TranscodeProfile = "h264"
+	assert.Error(t, cfg.Verify())
+	cfg.LiveRooms[0].TranscodeProfile = "hevc"
+
+	// 只录制音频时不能转码
+	cfg.LiveRooms[0].AudioOnly = true
+	assert.Error(t, cfg.Verify())
+	cfg.LiveRooms[0].AudioOnly = false
+
+	invalid := []TranscodeProfile{
+		{Codec: "vp8"},
+		{Encoder: "libx264"},
+		{Bitrate: "5M"},
+		{Codec: CodecH264, Bitrate: "fast"},
+		{Codec: CodecH264, Resolution: "720p"},
+		{Codec: CodecH264, Crf: -1},
+		{Codec: CodecHEVC, Format: FormatFlv},
+		{Format: "avi"},
+	}
+	for _, profile := range invalid {
+		cfg.TranscodeProfiles["invalid"] = profile
+		assert.Error(t, cfg.Verify(), "%+v", profile)
+	}
+}
+
+// TestConfig_GetSteps 测试后处理步骤的获取，未设置steps时由on_record_finished转换。
+func TestConfig_GetSteps(t *testing.T) {
+	cfg := NewConfig()
+	assert.Empty(t, cfg.GetSteps())
+
+	// 转换为MP4
+	cfg.OnRecordFinished = OnRecordFinished{ConvertToMp4: true, DeleteFlvAfterConvert: true}
+	assert.Equal(t, []PostProcessStep{{Type: StepRemux, Format: "mp4", AppendExt: true, DeleteSource: true}}, cfg.GetSteps())
+
+	// 自定义命令优先于转换
+	cfg.OnRecordFinished.CustomCommandline = "echo {{ .FileName }}"
+	assert.Equal(t, []PostProcessStep{{Type: StepCommand, Command: "echo {{ .FileName }}", DeleteSource: true}}, cfg.GetSteps())
+
+	// 设置steps后忽略on_record_finished
+	cfg.PostProcess.Steps = []PostProcessStep{{Type: StepMove, Target: "done"}}
+	assert.Equal(t, cfg.PostProcess.Steps, cfg.GetSteps())
+
+	// 无效的步骤
+	cfg.OutPutPath = os.TempDir()
+	assert.NoError(t, cfg.Verify())
+	cfg.PostProcess.Steps = []PostProcessStep{{Type: StepTranscode}}
+	assert.Error(t, cfg.Verify())
+	cfg.PostProcess.Steps = []PostProcessStep{{Type: "unknown"}}
+	assert.Error(t, cfg.Verify())
+	cfg.PostProcess.Steps = []PostProcessStep{{Type: StepMerge}}
+	assert.Error(t, cfg.Verify())
+	cfg.PostProcess.Steps = nil
+	cfg.PostProcess.Merge.Method = "unknown"
+	assert.Error(t, cfg.Verify())
+}
+
+// TestConfig_VerifySources 测试自动发现配置的验证。
+func TestConfig_VerifySources(t *testing.T) {
+	cfg := NewConfig()
+	cfg.OutPutPath = os.TempDir()
+	cfg.Sources = []Source{
+		{Name: "follow", Platform: SourcePlatformBilibili, Type: SourceTypeFollowing},
+		{Name: "lol", Platform: SourcePlatformTwitch, Type: SourceTypeCategory, Category: "League of Legends",
+			Interval: time.Hour, AddPolicy: SourceAddLiving, RemovePolicy: SourceRemoveRemove},
+	}
+	assert.NoError(t, cfg.Verify())
+	assert.Equal(t, DefaultSourceInterval, cfg.Sources[0].GetInterval())
+	assert.Equal(t, SourceAddAll, cfg.Sources[0].GetAddPolicy())
+	assert.Equal(t, SourceRemoveKeep, cfg.Sources[0].GetRemovePolicy())
+
+	invalid := []Source{
+		{Platform: SourcePlatformBilibili, Type: SourceTypeFollowing},
+		{Name: "a", Platform: "huya", Type: SourceTypeFollowing},
+		{Name: "a", Platform: SourcePlatformDouyu, Type: "rank"},
+		{Name: "a", Platform: SourcePlatformDouyu, Type: SourceTypeCategory},
+		{Name: "a", Platform: SourcePlatformDouyu, Type: SourceTypeFollowing, Interval: time.Second},
+		{Name: "a", Platform: SourcePlatformDouyu, Type: SourceTypeFollowing, AddPolicy: "offline"},
+		{Name: "a", Platform: SourcePlatformDouyu, Type: SourceTypeFollowing, RemovePolicy: "delete"},
+		{Name: "a", Platform: SourcePlatformDouyu, Type: SourceTypeFollowing, Limit: -1},
+		{Name: "follow", Platform: SourcePlatformDouyu, Type: SourceTypeFollowing},
+	}
+	for _, source := range invalid {
+		cfg.Sources = []Source{{Name: "follow", Platform: SourcePlatformBilibili, Type: SourceTypeFollowing}, source}
+		assert.Error(t, cfg.Verify(), "%+v", source)
+	}
 }
@@ -12,15 +12,56 @@ import (
 
 // Instance 结构体包含了应用程序中的各种组件和配置信息。
 type Instance struct {
-	WaitGroup        sync.WaitGroup              // WaitGroup 用于等待各个 goroutine 的完成。
-	Config           *configs.Config             // Config 包含应用程序的配置信息。
-	Logger           *interfaces.Logger          // Logger 是日志记录器接口，用于记录日志。
-	Lives            map[live.ID]live.Live       // Lives 包含所有 live.Live 接口的实例。
-	Cache            gcache.Cache                // Cache 是一个缓存实例，用于存储临时数据。
-	Server           interfaces.Module           // Server 是应用程序的服务器模块。
-	EventDispatcher  interfaces.Module           // EventDispatcher 是事件分发器模块。
-	ListenerManager  interfaces.Module           // ListenerManager 是监听器管理器模块。
-	RecorderManager  interfaces.Module           // RecorderManager 是录制器管理器模块。
-	PusherManager    interfaces.Module           // PusherManager 是推送器管理器模块。
-	WebsocketManager interfaces.WebsocketManager // WebsocketManager 是websocket管理器模块。
+	WaitGroup          sync.WaitGroup              // WaitGroup 用于等待各个 goroutine 的完成。
+	Config             *configs.Config             // Config 包含应用程序的配置信息。
+	Logger             *interfaces.Logger          // Logger 是日志记录器接口，用于记录日志。
+	Lives              map[live.ID]live.Live       // Lives 包含所有 live.Live 接口的实例，启动后通过 GetLive、GetLives、SetLive 和 DeleteLive 访问。
+	RoomLock           sync.RWMutex                // RoomLock 保护 Config.LiveRooms，接口和自动发现修改直播间时持有写锁，接口读取时持有读锁。
+	Cache              gcache.Cache                // Cache 是一个缓存实例，用于存储临时数据。
+	Server             interfaces.Module           // Server 是应用程序的服务器模块。
+	EventDispatcher    interfaces.Module           // EventDispatcher 是事件分发器模块。
+	ListenerManager    interfaces.Module           // ListenerManager 是监听器管理器模块。
+	RecorderManager    interfaces.Module           // RecorderManager 是录制器管理器模块。
+	PusherManager      interfaces.Module           // PusherManager 是推送器管理器模块。
+	ThumbnailManager   interfaces.Module           // ThumbnailManager 是缩略图管理器模块。
+	NotifyManager      interfaces.Module           // NotifyManager 是消息通知管理器模块。
+	PostProcessManager interfaces.Module           // PostProcessManager 是录制后处理管理器模块。
+	ShutdownManager    interfaces.Module           // ShutdownManager 是程序退出管理器模块。
+	SourceManager      interfaces.Module           // SourceManager 是自动发现直播间的管理器模块。
+	WebsocketManager   interfaces.WebsocketManager // WebsocketManager 是websocket管理器模块。
+
+	livesLock sync.RWMutex // livesLock 保护 Lives，只在读写 Lives 时持有，因此持有 RoomLock 或在事件监听器中也可以访问
+}
+
+// GetLives 返回 Lives 的副本。
+func (inst *Instance) GetLives() map[live.ID]live.Live {
+	inst.livesLock.RLock()
+	defer inst.livesLock.RUnlock()
+	lives := make(map[live.ID]live.Live, len(inst.Lives))
+	for id, l := range inst.Lives {
+		lives[id] = l
+	}
+	return lives
+}
+
+// GetLive 返回指定的直播。
+func (inst *Instance) GetLive(id live.ID) (live.Live, bool) {
+	inst.livesLock.RLock()
+	defer inst.livesLock.RUnlock()
+	l, ok := inst.Lives[id]
+	return l, ok
+}
+
+// SetLive 添加或替换直播。
+func (inst *Instance) SetLive(l live.Live) {
+	inst.livesLock.Lock()
+	defer inst.livesLock.Unlock()
+	inst.Lives[l.GetLiveId()] = l
+}
+
+// DeleteLive 移除指定的直播。
+func (inst *Instance) DeleteLive(id live.ID) {
+	inst.livesLock.Lock()
+	defer inst.livesLock.Unlock()
+	delete(inst.Lives, id)
 }
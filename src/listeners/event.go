@@ -27,3 +27,6 @@ const RoomNameChanged events.EventType = "RoomNameChanged"
 
 // RoomInitializingFinished 表示房间初始化完成的事件类型。
 const RoomInitializingFinished events.EventType = "RoomInitializingFinished"
+
+// ListenError 表示监听过程中发生错误的事件类型，事件对象为 *live.ErrorParam。
+const ListenError events.EventType = "ListenError"
@@ -105,7 +105,13 @@ func (l *listener) refresh() {
 		l.logger.
 			WithError(err).
 			WithField("url", l.Live.GetRawUrl()).
+			WithField("live_id", l.Live.GetLiveId()).
 			Error("failed to load room info")
+		l.ed.DispatchEvent(events.NewEvent(ListenError, &live.ErrorParam{
+			Live:      l.Live,
+			Component: "listener",
+			Err:       err,
+		}))
 		return
 	}
 
@@ -115,8 +121,9 @@ func (l *listener) refresh() {
 		evtTyp       events.EventType
 		logInfo      string
 		fields       = map[string]interface{}{
-			"room": info.RoomName,
-			"host": info.HostName,
+			"room":    info.RoomName,
+			"host":    info.HostName,
+			"live_id": l.Live.GetLiveId(),
 		}
 	)
 
@@ -129,7 +136,12 @@ func (l *listener) refresh() {
 	case 0:
 		isStatusChanged = false
 	case statusToTrueEvt:
-		l.Live.SetLastStartTime(time.Now())
+		// 优先使用平台记录的开播时间
+		startTime := info.LiveStartTime
+		if startTime.IsZero() {
+			startTime = time.Now()
+		}
+		l.Live.SetLastStartTime(startTime)
 		evtTyp = LiveStart
 		logInfo = "Live Start"
 	case statusToFalseEvt:
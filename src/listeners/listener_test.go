@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/bluele/gcache"
 	"github.com/golang/mock/gomock"
@@ -32,6 +33,7 @@ func TestRefresh(t *testing.T) {
 	})
 	log.New(ctx)
 	live := livemock.NewMockLive(ctrl)
+	live.EXPECT().GetLiveId().Return(livepkg.ID("test")).AnyTimes()
 	l := NewListener(ctx, live).(*listener)
 
 	// false -> false
@@ -46,6 +48,16 @@ func TestRefresh(t *testing.T) {
 	l.refresh()
 	assert.True(t, l.status.roomStatus)
 
+	// true -> false -> true，使用平台记录的开播时间
+	live.EXPECT().GetInfo().Return(&livepkg.Info{Status: false}, nil)
+	ed.EXPECT().DispatchEvent(events.NewEvent(LiveEnd, live))
+	l.refresh()
+	startTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	live.EXPECT().GetInfo().Return(&livepkg.Info{Status: true, LiveStartTime: startTime}, nil)
+	live.EXPECT().SetLastStartTime(startTime)
+	ed.EXPECT().DispatchEvent(events.NewEvent(LiveStart, live))
+	l.refresh()
+
 	// true -> true, roomName change
 	live.EXPECT().GetInfo().Return(&livepkg.Info{Status: true, RoomName: "a"}, nil)
 	l.refresh()
@@ -75,10 +87,14 @@ func TestRefreshWithError(t *testing.T) {
 	})
 	log.New(ctx)
 	live := livemock.NewMockLive(ctrl)
+	live.EXPECT().GetLiveId().Return(livepkg.ID("test")).AnyTimes()
 	l := NewListener(ctx, live).(*listener)
 
 	live.EXPECT().GetInfo().Return(nil, errors.New("this is error"))
 	live.EXPECT().GetRawUrl().Return("")
+	ed.EXPECT().DispatchEvent(gomock.Any()).Do(func(event *events.Event) {
+		assert.Equal(t, ListenError, event.Type)
+	})
 	l.refresh()
 	assert.False(t, l.status.roomStatus)
 }
@@ -97,6 +113,7 @@ func TestListenerStartAndClose(t *testing.T) {
 	})
 	log.New(ctx)
 	live := livemock.NewMockLive(ctrl)
+	live.EXPECT().GetLiveId().Return(livepkg.ID("test")).AnyTimes()
 	live.EXPECT().GetInfo().Return(&livepkg.Info{Status: false}, nil)
 	ed.EXPECT().DispatchEvent(gomock.Any()).Times(2)
 	l := NewListener(ctx, live)
@@ -63,7 +63,7 @@ func (m *manager) registryListener(ctx context.Context, ed events.Dispatcher) {
 		logger := inst.Logger
 
 		// 5. 将 live 添加到应用程序实例的 Lives 列表中。
-		inst.Lives[live.GetLiveId()] = live
+		inst.SetLive(live)
 
 		// 6. 通过直播的原始URL获取房间信息。
 		room, err := inst.Config.GetLiveRoomByUrl(live.GetRawUrl())
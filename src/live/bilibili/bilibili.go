@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/tidwall/gjson"
 	"github.com/yuhaohwang/requests"
@@ -25,6 +26,9 @@ const (
 	liveApiUrlv2 = "https://api.live.bilibili.com/xlive/web-room/v2/index/getRoomPlayInfo"
 )
 
+// cstZone 是接口返回的时间使用的时区
+var cstZone = time.FixedZone("CST", 8*60*60)
+
 // 初始化函数，注册 Bilibili 直播源
 func init() {
 	live.Register(domain, new(builder))
@@ -109,6 +113,13 @@ func (l *Live) GetInfo() (info *live.Info, err error) {
 		Live:     l,
 		RoomName: gjson.GetBytes(body, "data.title").String(),
 		Status:   gjson.GetBytes(body, "data.live_status").Int() == 1,
+		CoverUrl: gjson.GetBytes(body, "data.user_cover").String(),
+		Category: gjson.GetBytes(body, "data.area_name").String(),
+		Online:   gjson.GetBytes(body, "data.online").Int(),
+	}
+	// 未开播时 live_time 为 0000-00-00 00:00:00，解析失败时保持零值
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", gjson.GetBytes(body, "data.live_time").String(), cstZone); err == nil && info.Status {
+		info.LiveStartTime = t
 	}
 
 	resp, err = requests.Get(userApiUrl, live.CommonUserAgent, requests.Query("roomid", l.realID))
@@ -127,6 +138,7 @@ func (l *Live) GetInfo() (info *live.Info, err error) {
 	}
 
 	info.HostName = gjson.GetBytes(body, "data.info.uname").String()
+	info.AvatarUrl = gjson.GetBytes(body, "data.info.face").String()
 	return info, nil
 }
 
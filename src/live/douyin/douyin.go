@@ -116,10 +116,14 @@ func (l *Live) getRoomInfoFromBody(body string) (info *live.Info, streamUrlInfos
 
 	isStreaming := mainJson.Get("state.roomStore.roomInfo.room.status_str").String() == "2"
 	info = &live.Info{
-		Live:     l,
-		HostName: mainJson.Get("state.roomStore.roomInfo.anchor.nickname").String(),
-		RoomName: mainJson.Get("state.roomStore.roomInfo.room.title").String(),
-		Status:   isStreaming,
+		Live:      l,
+		HostName:  mainJson.Get("state.roomStore.roomInfo.anchor.nickname").String(),
+		RoomName:  mainJson.Get("state.roomStore.roomInfo.room.title").String(),
+		Status:    isStreaming,
+		CoverUrl:  mainJson.Get("state.roomStore.roomInfo.room.cover.url_list.0").String(),
+		AvatarUrl: mainJson.Get("state.roomStore.roomInfo.anchor.avatar_thumb.url_list.0").String(),
+		Category:  mainJson.Get("state.roomStore.roomInfo.partition_road_map.partition.title").String(),
+		Online:    utils.ParseCount(mainJson.Get("state.roomStore.roomInfo.room.room_view_stats.display_value").String()),
 	}
 	if !isStreaming {
 		return
@@ -343,10 +347,14 @@ func (l *Live) legacy_GetInfo(body string) (info *live.Info, err error) {
 		return nil, err
 	}
 	info = &live.Info{
-		Live:     l,
-		HostName: data.Get("user.nickname").String(),
-		RoomName: data.Get("data.0.title").String(),
-		Status:   data.Get("data.0.status").Int() == 2,
+		Live:      l,
+		HostName:  data.Get("user.nickname").String(),
+		RoomName:  data.Get("data.0.title").String(),
+		Status:    data.Get("data.0.status").Int() == 2,
+		CoverUrl:  data.Get("data.0.cover.url_list.0").String(),
+		AvatarUrl: data.Get("user.avatar_thumb.url_list.0").String(),
+		Category:  data.Get("partition_road_map.partition.title").String(),
+		Online:    utils.ParseCount(data.Get("data.0.user_count_str").String()),
 	}
 	return
 }
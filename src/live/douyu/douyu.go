@@ -247,6 +247,13 @@ func (l *Live) GetInfo() (info *live.Info, err error) {
 		RoomName:     gjson.GetBytes(body, "room.room_name").String(),
 		Status:       gjson.GetBytes(body, "room.show_status").Int() == 1 && gjson.GetBytes(body, "room.videoLoop").Int() == 0,
 		CustomLiveId: "douyu/" + l.roomID,
+		CoverUrl:     gjson.GetBytes(body, "room.room_pic").String(),
+		AvatarUrl:    gjson.GetBytes(body, "room.avatar.big").String(),
+		Category:     gjson.GetBytes(body, "room.second_lvl_name").String(),
+		Online:       utils.ParseCount(gjson.GetBytes(body, "room.room_biz_all.hot").String()),
+	}
+	if showTime := gjson.GetBytes(body, "room.show_time").Int(); showTime > 0 && info.Status {
+		info.LiveStartTime = time.Unix(showTime, 0)
 	}
 	return info, nil
 }
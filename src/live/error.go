@@ -12,3 +12,6 @@ var ErrRoomUrlIncorrect = errors.New("room url incorrect")
 
 // ErrInternalError 表示内部错误的错误。
 var ErrInternalError = errors.New("internal error")
+
+// ErrNotSupported 表示不支持该 URL 的错误。
+var ErrNotSupported = errors.New("not support this url")
@@ -0,0 +1,85 @@
+// Package external 实现没有对应平台插件、由外部工具（streamlink、yt-dlp）处理的直播间，
+// 在 parsers.platforms 中为直播间的域名配置 streamlink 或 yt-dlp 后使用。
+package external
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/live/internal"
+	tools "github.com/yuhaohwang/bililive-go/src/pkg/parser/external"
+)
+
+// probeTimeout 是使用外部工具检测直播间的超时时间。
+const probeTimeout = 30 * time.Second
+
+func init() {
+	live.ExternalLiveBuilderInstance = new(builder)
+}
+
+type builder struct{}
+
+// Build 方法只接受设置了外部工具的地址，其他地址返回 live.ErrNotSupported。
+func (b *builder) Build(url *url.URL, opt ...live.Option) (live.Live, error) {
+	l := &Live{
+		BaseLive: internal.NewBaseLive(url, opt...),
+	}
+	if l.Options.ExternalTool == "" {
+		return nil, live.ErrNotSupported
+	}
+	return l, nil
+}
+
+// Live 表示一个由外部工具处理的直播间。
+type Live struct {
+	internal.BaseLive
+}
+
+// GetInfo 方法使用外部工具检测直播间是否正在直播，工具没有返回主播名和标题时使用配置中的设置，未设置时使用域名和路径。
+func (l *Live) GetInfo() (*live.Info, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	result, err := tools.Probe(ctx, l.Options.ExternalTool, l.Options.ExternalToolPath, l.Url.String(), l.cookie())
+	if err != nil {
+		return nil, err
+	}
+	return &live.Info{
+		Live:     l,
+		HostName: firstNonEmpty(result.HostName, l.Options.HostName, l.Url.Hostname()),
+		RoomName: firstNonEmpty(result.RoomName, l.Options.RoomName, strings.Trim(l.Url.Path, "/")),
+		Status:   result.Living,
+	}, nil
+}
+
+// GetStreamUrls 方法返回直播间地址本身，外部工具直接处理直播间地址。
+func (l *Live) GetStreamUrls() ([]*url.URL, error) {
+	u := *l.Url
+	return []*url.URL{&u}, nil
+}
+
+// GetPlatformCNName 方法返回直播间的域名。
+func (l *Live) GetPlatformCNName() string {
+	return l.Url.Hostname()
+}
+
+// cookie 返回直播间地址的 cookie，格式与配置中的 cookies 相同。
+func (l *Live) cookie() string {
+	kvs := make([]string, 0)
+	for _, c := range l.Options.Cookies.Cookies(l.Url) {
+		kvs = append(kvs, c.Name+"="+c.Value)
+	}
+	return strings.Join(kvs, "; ")
+}
+
+// firstNonEmpty 返回第一个不为空的字符串。
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,208 @@
+// Package generic 实现直接的直播流地址（HTTP-FLV、HLS 和 RTMP）的通用直播间，
+// 用于录制没有对应平台插件的内部或自建直播流。
+package generic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/live/internal"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+const (
+	cnName = "直播流"
+
+	userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/59.0.3071.115 Safari/537.36"
+
+	// probeTimeout 是检测直播流是否在线的超时时间。
+	probeTimeout = 10 * time.Second
+)
+
+// errNoFfprobe 表示找不到 ffprobe，无法判断 RTMP 直播流是否在线。
+var errNoFfprobe = errors.New("找不到 ffprobe，无法检测 RTMP 直播流")
+
+func init() {
+	live.FallbackLiveBuilderInstance = new(builder)
+}
+
+type builder struct{}
+
+// Build 方法只接受直接的直播流地址，其他地址返回 live.ErrNotSupported。
+func (b *builder) Build(url *url.URL, opt ...live.Option) (live.Live, error) {
+	if streamType(url) == "" {
+		return nil, live.ErrNotSupported
+	}
+	l := &Live{
+		BaseLive: internal.NewBaseLive(url, opt...),
+	}
+	l.client = &http.Client{Jar: l.Options.Cookies, Timeout: probeTimeout}
+	return l, nil
+}
+
+// 直播流类型。
+const (
+	typeFlv  = "flv"
+	typeHls  = "hls"
+	typeRtmp = "rtmp"
+)
+
+// streamType 根据地址判断直播流类型，不是直接的直播流地址时返回空字符串。
+func streamType(u *url.URL) string {
+	switch strings.ToLower(u.Scheme) {
+	case "rtmp", "rtmps":
+		return typeRtmp
+	case "http", "https":
+		switch strings.ToLower(path.Ext(u.Path)) {
+		case ".flv":
+			return typeFlv
+		case ".m3u8":
+			return typeHls
+		}
+	}
+	return ""
+}
+
+// Live 表示一个直接的直播流地址。
+type Live struct {
+	internal.BaseLive
+	client *http.Client
+}
+
+// GetInfo 方法通过请求直播流检测是否在线，主播名和房间名使用配置中的设置，未设置时使用域名和文件名。
+func (l *Live) GetInfo() (*live.Info, error) {
+	online, err := l.probe()
+	if err != nil {
+		return nil, err
+	}
+	hostName := l.Options.HostName
+	if hostName == "" {
+		hostName = l.Url.Hostname()
+	}
+	roomName := l.Options.RoomName
+	if roomName == "" {
+		roomName = strings.TrimSuffix(path.Base(l.Url.Path), path.Ext(l.Url.Path))
+	}
+	return &live.Info{
+		Live:     l,
+		HostName: hostName,
+		RoomName: roomName,
+		Status:   online,
+	}, nil
+}
+
+// GetStreamUrls 方法返回直播流地址本身。
+func (l *Live) GetStreamUrls() ([]*url.URL, error) {
+	u := *l.Url
+	return []*url.URL{&u}, nil
+}
+
+// GetPlatformCNName 方法返回平台的中文名称。
+func (l *Live) GetPlatformCNName() string {
+	return cnName
+}
+
+// probe 检测直播流是否在线，只有无法判断时才返回错误。
+func (l *Live) probe() (bool, error) {
+	switch streamType(l.Url) {
+	case typeFlv:
+		return l.probeFlv()
+	case typeHls:
+		return l.probeHls()
+	case typeRtmp:
+		return l.probeRtmp()
+	}
+	return false, live.ErrNotSupported
+}
+
+// get 请求直播流地址，服务器返回 404 时视为不在线并返回 nil，连接失败和其他状态码返回错误。
+func (l *Live) get() (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, l.Url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		// 流媒体服务器在直播流未开始时通常返回 404
+		resp.Body.Close()
+		return nil, nil
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("请求直播流失败：%s", resp.Status)
+}
+
+// probeFlv 读取 FLV 文件头，能读取到时视为在线。
+func (l *Live) probeFlv() (bool, error) {
+	resp, err := l.get()
+	if err != nil || resp == nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	b := make([]byte, 3)
+	if _, err := io.ReadFull(resp.Body, b); err != nil {
+		return false, nil
+	}
+	return string(b) == "FLV", nil
+}
+
+// probeHls 读取播放列表，是有效的直播播放列表且包含分片时视为在线，已结束的播放列表视为不在线。
+func (l *Live) probeHls() (bool, error) {
+	resp, err := l.get()
+	if err != nil || resp == nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return false, nil
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("#EXTM3U")) {
+		return false, nil
+	}
+	if bytes.Contains(body, []byte("#EXT-X-ENDLIST")) {
+		return false, nil
+	}
+	s := bufio.NewScanner(bytes.NewReader(body))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// probeRtmp 使用 ffprobe 检测直播流，找不到 ffprobe 时无法判断是否在线，返回错误。
+func (l *Live) probeRtmp() (bool, error) {
+	ffprobe, err := utils.FFprobePath(l.Options.FfmpegPath)
+	if err != nil {
+		return false, errNoFfprobe
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, ffprobe,
+		"-v", "error",
+		"-show_entries", "stream=codec_type",
+		"-of", "csv=p=0",
+		l.Url.String(),
+	).Output()
+	return err == nil && len(bytes.TrimSpace(out)) > 0, nil
+}
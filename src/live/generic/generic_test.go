@@ -0,0 +1,96 @@
+package generic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+func TestBuild(t *testing.T) {
+	for _, rawUrl := range []string{
+		"https://example.com/live/a.flv?token=1",
+		"http://example.com/hls/a.m3u8",
+		"rtmp://example.com/live/a",
+	} {
+		u, _ := url.Parse(rawUrl)
+		_, err := new(builder).Build(u)
+		assert.NoError(t, err, rawUrl)
+	}
+	u, _ := url.Parse("https://example.com/room/1")
+	_, err := new(builder).Build(u)
+	assert.Equal(t, live.ErrNotSupported, err)
+}
+
+func TestGetInfo(t *testing.T) {
+	online := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error.flv" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !online {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Path {
+		case "/live/a.flv":
+			w.Write([]byte("FLV\x01\x05"))
+		case "/hls/a.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXTINF:2.0,\n1.ts\n"))
+		case "/hls/ended.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXTINF:2.0,\n1.ts\n#EXT-X-ENDLIST\n"))
+		}
+	}))
+	defer server.Close()
+
+	build := func(path string, opts ...live.Option) live.Live {
+		u, _ := url.Parse(server.URL + path)
+		l, err := new(builder).Build(u, opts...)
+		assert.NoError(t, err)
+		return l
+	}
+
+	info, err := build("/live/a.flv").GetInfo()
+	assert.NoError(t, err)
+	assert.True(t, info.Status)
+	assert.Equal(t, "127.0.0.1", info.HostName)
+	assert.Equal(t, "a", info.RoomName)
+
+	info, err = build("/hls/a.m3u8", live.WithHostName("主播"), live.WithRoomName("房间")).GetInfo()
+	assert.NoError(t, err)
+	assert.True(t, info.Status)
+	assert.Equal(t, "主播", info.HostName)
+	assert.Equal(t, "房间", info.RoomName)
+
+	// 已结束的播放列表视为不在线
+	info, err = build("/hls/ended.m3u8").GetInfo()
+	assert.NoError(t, err)
+	assert.False(t, info.Status)
+
+	online = false
+	info, err = build("/live/a.flv").GetInfo()
+	assert.NoError(t, err)
+	assert.False(t, info.Status)
+
+	// 其他状态码和连接失败无法判断是否在线
+	_, err = build("/error.flv").GetInfo()
+	assert.Error(t, err)
+	l := build("/live/a.flv")
+	server.Close()
+	_, err = l.GetInfo()
+	assert.Error(t, err)
+}
+
+func TestGetInfoRtmpWithoutFfprobe(t *testing.T) {
+	u, _ := url.Parse("rtmp://127.0.0.1/live/a")
+	l, err := new(builder).Build(u, live.WithFfmpegPath(filepath.Join(t.TempDir(), "ffmpeg")))
+	assert.NoError(t, err)
+	_, err = l.GetInfo()
+	assert.Equal(t, errNoFfprobe, err)
+}
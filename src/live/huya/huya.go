@@ -77,6 +77,10 @@ func (l *Live) GetInfo() (info *live.Info, err error) {
 		hostName  = strFilter.Do(utils.Match1(`"nick":"([^"]*)"`, body))
 		roomName  = strFilter.Do(utils.Match1(`"introduction":"([^"]*)"`, body))
 		status    = strFilter.Do(utils.Match1(`"isOn":([^,]*),`, body))
+		// 页面中的地址会转义斜杠
+		urlFilter = utils.NewStringFilterChain(strFilter, utils.StringFilterFunc(func(s string) string {
+			return strings.ReplaceAll(s, `\/`, "/")
+		}))
 	)
 
 	if hostName == "" || roomName == "" || status == "" {
@@ -84,10 +88,17 @@ func (l *Live) GetInfo() (info *live.Info, err error) {
 	}
 
 	info = &live.Info{
-		Live:     l,
-		HostName: hostName,
-		RoomName: roomName,
-		Status:   status == "true",
+		Live:      l,
+		HostName:  hostName,
+		RoomName:  roomName,
+		Status:    status == "true",
+		CoverUrl:  urlFilter.Do(utils.Match1(`"screenshot":"([^"]*)"`, body)),
+		AvatarUrl: urlFilter.Do(utils.Match1(`"avatar180":"([^"]*)"`, body)),
+		Category:  strFilter.Do(utils.Match1(`"gameFullName":"([^"]*)"`, body)),
+		Online:    utils.ParseCount(utils.Match1(`"totalCount":(\d+)`, body)),
+	}
+	if startTime, _ := strconv.ParseInt(utils.Match1(`"startTime":(\d+)`, body), 10, 64); startTime > 0 && info.Status {
+		info.LiveStartTime = time.Unix(startTime, 0)
 	}
 	return info, nil
 }
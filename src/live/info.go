@@ -2,6 +2,7 @@ package live
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // Info 结构体用于存储直播信息，包括主播名、房间名、状态等。
@@ -15,28 +16,49 @@ type Info struct {
 	Initializing                  bool
 	CustomLiveId                  string
 	AudioOnly                     bool
+
+	// 以下字段由平台提供，不支持的平台为零值
+	CoverUrl      string    // 直播封面地址
+	AvatarUrl     string    // 主播头像地址
+	Category      string    // 直播分区
+	Online        int64     // 在线人数或人气值
+	LiveStartTime time.Time // 平台记录的开播时间
+
+	// 以下字段来自直播间配置
+	Group  string   // 直播间所属的分组
+	Tags   []string // 直播间的标签
+	Source string   // 自动添加该直播间的来源名称，为空时为手动添加
 }
 
 // MarshalJSON 方法用于将 Info 结构体序列化为 JSON 格式。
 func (i *Info) MarshalJSON() ([]byte, error) {
 	t := struct {
-		Id                ID     `json:"id"`                             // 直播唯一标识
-		LiveUrl           string `json:"live_url"`                       // 直播原始 URL
-		PlatformCNName    string `json:"platform_cn_name"`               // 平台中文名称
-		HostName          string `json:"host_name"`                      // 主播名
-		RoomName          string `json:"room_name"`                      // 房间名
-		Status            bool   `json:"status"`                         // 是否正在直播
-		Listening         bool   `json:"listening"`                      // 是否正在监听
-		Recording         bool   `json:"recording"`                      // 是否正在录制
-		Pushing           bool   `json:"pushing"`                        // 是否正在转推
-		Initializing      bool   `json:"initializing"`                   // 是否正在初始化
-		LastStartTime     string `json:"last_start_time,omitempty"`      // 上次开始时间的字符串表示形式
-		LastStartTimeUnix int64  `json:"last_start_time_unix,omitempty"` // 上次开始时间的 UNIX 时间戳
-		AudioOnly         bool   `json:"audio_only"`                     // 是否仅音频直播
-		RtmpUrl           string `json:"rtmp_url"`                       // 直播转推 URL
-		Listen            bool   `json:"listen"`                         // 是否开启直播监听
-		Record            bool   `json:"record"`                         // 是否开启直播录制
-		Push              bool   `json:"push"`                           // 是否开启直播转推
+		Id                ID       `json:"id"`                             // 直播唯一标识
+		LiveUrl           string   `json:"live_url"`                       // 直播原始 URL
+		PlatformCNName    string   `json:"platform_cn_name"`               // 平台中文名称
+		HostName          string   `json:"host_name"`                      // 主播名
+		RoomName          string   `json:"room_name"`                      // 房间名
+		Status            bool     `json:"status"`                         // 是否正在直播
+		Listening         bool     `json:"listening"`                      // 是否正在监听
+		Recording         bool     `json:"recording"`                      // 是否正在录制
+		Pushing           bool     `json:"pushing"`                        // 是否正在转推
+		Initializing      bool     `json:"initializing"`                   // 是否正在初始化
+		LastStartTime     string   `json:"last_start_time,omitempty"`      // 上次开始时间的字符串表示形式
+		LastStartTimeUnix int64    `json:"last_start_time_unix,omitempty"` // 上次开始时间的 UNIX 时间戳
+		AudioOnly         bool     `json:"audio_only"`                     // 是否仅音频直播
+		RtmpUrl           string   `json:"rtmp_url"`                       // 直播转推 URL
+		Listen            bool     `json:"listen"`                         // 是否开启直播监听
+		Record            bool     `json:"record"`                         // 是否开启直播录制
+		Push              bool     `json:"push"`                           // 是否开启直播转推
+		CoverUrl          string   `json:"cover_url,omitempty"`            // 直播封面地址
+		AvatarUrl         string   `json:"avatar_url,omitempty"`           // 主播头像地址
+		Category          string   `json:"category,omitempty"`             // 直播分区
+		Online            int64    `json:"online,omitempty"`               // 在线人数或人气值
+		LiveStartTime     string   `json:"live_start_time,omitempty"`      // 平台记录的开播时间
+		LiveStartTimeUnix int64    `json:"live_start_time_unix,omitempty"` // 平台记录的开播时间的 UNIX 时间戳
+		Group             string   `json:"group,omitempty"`                // 直播间所属的分组
+		Tags              []string `json:"tags,omitempty"`                 // 直播间的标签
+		Source            string   `json:"source,omitempty"`               // 自动添加该直播间的来源名称
 	}{
 		Id:             i.Live.GetLiveId(),
 		LiveUrl:        i.Live.GetRawUrl(),
@@ -53,10 +75,21 @@ func (i *Info) MarshalJSON() ([]byte, error) {
 		Listen:         i.Listen,
 		Record:         i.Record,
 		Push:           i.Push,
+		CoverUrl:       i.CoverUrl,
+		AvatarUrl:      i.AvatarUrl,
+		Category:       i.Category,
+		Online:         i.Online,
+		Group:          i.Group,
+		Tags:           i.Tags,
+		Source:         i.Source,
 	}
 	if !i.Live.GetLastStartTime().IsZero() {
 		t.LastStartTime = i.Live.GetLastStartTime().Format("2006-01-02 15:04:05")
 		t.LastStartTimeUnix = i.Live.GetLastStartTime().Unix()
 	}
+	if !i.LiveStartTime.IsZero() {
+		t.LiveStartTime = i.LiveStartTime.Format("2006-01-02 15:04:05")
+		t.LiveStartTimeUnix = i.LiveStartTime.Unix()
+	}
 	return json.Marshal(t)
 }
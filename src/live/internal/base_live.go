@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"fmt"
 	"net/url"
 	"time"
 
@@ -19,7 +18,7 @@ type BaseLive struct {
 
 // genLiveId 根据 URL 生成直播唯一标识符
 func genLiveId(url *url.URL) live.ID {
-	return genLiveIdByString(fmt.Sprintf("%s%s", url.Host, url.Path))
+	return live.GenLiveId(url)
 }
 
 // genLiveIdByString 根据字符串生成直播唯一标识符
@@ -4,7 +4,8 @@
 package live
 
 import (
-	"errors"
+	"crypto/md5"
+	"encoding/hex"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -18,6 +19,10 @@ import (
 var (
 	m                               = make(map[string]Builder)
 	InitializingLiveBuilderInstance InitializingLiveBuilder
+	// FallbackLiveBuilderInstance 用于构建没有对应平台构建器的地址，如直接的直播流地址
+	FallbackLiveBuilderInstance Builder
+	// ExternalLiveBuilderInstance 用于构建没有对应平台构建器、由外部工具（streamlink、yt-dlp）处理的直播间地址
+	ExternalLiveBuilderInstance Builder
 )
 
 // Register 函数用于注册直播平台的构建器。
@@ -48,10 +53,25 @@ type InitializingFinishedParam struct {
 	Info             *Info
 }
 
+// ErrorParam 结构体包含了错误事件的参数。
+type ErrorParam struct {
+	Live      Live
+	Component string   // 产生错误的组件，如 listener、recorder、pusher
+	Err       error    // 错误内容
+	StreamUrl *url.URL // 相关的直播流地址，可能为空
+}
+
 // Options 结构体包含了直播平台的选项，如 cookies 和视频质量等。
 type Options struct {
-	Cookies *cookiejar.Jar
-	Quality int
+	Cookies    *cookiejar.Jar
+	Quality    int
+	HostName   string // 自定义的主播名，只用于无法获取直播信息的平台
+	RoomName   string // 自定义的房间名，只用于无法获取直播信息的平台
+	LowLatency bool   // 是否使用低延迟的直播流，只用于支持的平台
+
+	ExternalTool     string // 处理没有对应平台插件的直播间地址的外部工具（streamlink、yt-dlp）
+	ExternalToolPath string // 外部工具的可执行文件路径，为空时在 PATH 中查找
+	FfmpegPath       string // 配置的 FFmpeg 路径，用于查找同一目录下的 ffprobe，为空时在 PATH 中查找
 }
 
 // NewOptions 函数用于创建新的选项。
@@ -104,9 +124,52 @@ func WithQuality(quality int) Option {
 	}
 }
 
+// WithHostName 函数用于设置自定义的主播名。
+func WithHostName(name string) Option {
+	return func(opts *Options) {
+		opts.HostName = name
+	}
+}
+
+// WithRoomName 函数用于设置自定义的房间名。
+func WithRoomName(name string) Option {
+	return func(opts *Options) {
+		opts.RoomName = name
+	}
+}
+
+// WithLowLatency 函数用于设置是否使用低延迟的直播流。
+func WithLowLatency(lowLatency bool) Option {
+	return func(opts *Options) {
+		opts.LowLatency = lowLatency
+	}
+}
+
+// WithExternalTool 函数用于设置处理没有对应平台插件的直播间地址的外部工具。
+func WithExternalTool(name, path string) Option {
+	return func(opts *Options) {
+		opts.ExternalTool = name
+		opts.ExternalToolPath = path
+	}
+}
+
+// WithFfmpegPath 函数用于设置配置的 FFmpeg 路径。
+func WithFfmpegPath(path string) Option {
+	return func(opts *Options) {
+		opts.FfmpegPath = path
+	}
+}
+
 // ID 类型用于表示直播的唯一标识。
 type ID string
 
+// GenLiveId 根据直播间地址生成直播的唯一标识，与平台构建器默认使用的标识相同。
+// 平台返回了自定义标识的直播间（如只能通过短号访问的房间）实际的标识会不同。
+func GenLiveId(u *url.URL) ID {
+	sum := md5.Sum([]byte(u.Host + u.Path))
+	return ID(hex.EncodeToString(sum[:]))
+}
+
 // StreamUrlInfo 结构体包含了直播流的相关信息。
 type StreamUrlInfo struct {
 	Url         *url.URL
@@ -127,6 +190,10 @@ type Live interface {
 	SetLastStartTime(time.Time)
 }
 
+// RequestObserver 在每次请求平台接口后调用，用于统计各域名接口的耗时和错误，为空时不统计。
+// 需要在开始监听前设置。
+var RequestObserver func(domain, method string, duration time.Duration, err error)
+
 // WrappedLive 结构体用于包装实现了 Live 接口的对象，添加了缓存功能。
 type WrappedLive struct {
 	Live
@@ -141,9 +208,23 @@ func newWrappedLive(live Live, cache gcache.Cache) Live {
 	}
 }
 
+// observe 调用 RequestObserver 记录一次接口请求。
+func (w *WrappedLive) observe(method string, start time.Time, err error) {
+	if RequestObserver == nil {
+		return
+	}
+	domain := ""
+	if u, e := url.Parse(w.GetRawUrl()); e == nil {
+		domain = u.Host
+	}
+	RequestObserver(domain, method, time.Since(start), err)
+}
+
 // GetInfo 方法用于获取直播信息，同时支持缓存功能。
 func (w *WrappedLive) GetInfo() (*Info, error) {
+	start := time.Now()
 	i, err := w.Live.GetInfo()
+	w.observe("GetInfo", start, err)
 	if err != nil {
 		if info, err2 := w.cache.Get(w); err2 == nil {
 			info.(*Info).RoomName = err.Error()
@@ -156,11 +237,26 @@ func (w *WrappedLive) GetInfo() (*Info, error) {
 	return i, nil
 }
 
+// GetStreamUrls 方法用于获取直播流地址，同时记录接口请求。
+func (w *WrappedLive) GetStreamUrls() ([]*url.URL, error) {
+	start := time.Now()
+	us, err := w.Live.GetStreamUrls()
+	w.observe("GetStreamUrls", start, err)
+	return us, err
+}
+
 // New 函数用于创建一个直播平台实例。
 func New(url *url.URL, cache gcache.Cache, opts ...Option) (live Live, err error) {
 	builder, ok := getBuilder(url.Host)
 	if !ok {
-		return nil, errors.New("not support this url")
+		switch {
+		case ExternalLiveBuilderInstance != nil && MustNewOptions(opts...).ExternalTool != "":
+			builder = ExternalLiveBuilderInstance
+		case FallbackLiveBuilderInstance != nil:
+			builder = FallbackLiveBuilderInstance
+		default:
+			return nil, ErrNotSupported
+		}
 	}
 	live, err = builder.Build(url, opts...)
 	if err != nil {
@@ -18,16 +18,30 @@ const (
 	domain = "www.twitch.tv"
 	cnName = "twitch"
 
-	clientId      = "jzkbprff40iqj646a697cyrvl0zt2m6"
-	channelApiUrl = "https://api.twitch.tv/kraken/channels/%s"
-	liveBaseUrl   = "https://usher.ttvnw.net/api/channel/hls/%s.m3u8"
-	streamApiUrl  = "https://api.twitch.tv/kraken/streams/%s"
-	tokenApiUrl   = "https://api.twitch.tv/api/channels/%s/access_token"
-
-	v5Header   = "application/vnd.twitchtv.v5+json"
-	userApiUrl = "https://api.twitch.tv/kraken/users?login=%s"
+	// clientId 是 Twitch 网页播放器使用的 Client-ID
+	clientId    = "kimne78kx3ncx6brgo4mv6wki5h1ko"
+	liveBaseUrl = "https://usher.ttvnw.net/api/channel/hls/%s.m3u8"
+
+	// authCookie 是登录后保存 OAuth 令牌的 cookie，设置后以登录用户的身份获取直播流（如订阅者专属的直播）
+	authCookie = "auth-token"
+
+	// playbackAccessTokenHash 是 PlaybackAccessToken 持久化查询的哈希
+	playbackAccessTokenHash = "0828119ded1c13477966434e15800ff57ddacf13ba1911c129dc2200705b0712"
+
+	channelQuery = `query ChannelInfo($login: String!) {
+  user(login: $login) {
+    id
+    login
+    displayName
+    broadcastSettings { title }
+    stream { id title type createdAt viewersCount game { name } }
+  }
+}`
 )
 
+// gqlUrl 是 Twitch GQL 接口的地址，测试时替换为本地服务。
+var gqlUrl = "https://gql.twitch.tv/gql"
+
 func init() {
 	live.Register(domain, new(builder))
 }
@@ -40,118 +54,137 @@ func (b *builder) Build(url *url.URL, opt ...live.Option) (live.Live, error) {
 	}, nil
 }
 
-var headers = map[string]string{"client-id": clientId}
-
 type Live struct {
 	internal.BaseLive
-	userId, hostName, roomName string
 }
 
-// 在hostName, roomName为空执行，在live有效时再从steam api解析
-func (l *Live) parseInfo() error {
+// channel 返回直播间地址中的频道名。
+func (l *Live) channel() (string, error) {
 	paths := strings.Split(l.Url.Path, "/")
-	if len(paths) < 2 {
-		return live.ErrRoomUrlIncorrect
+	if len(paths) < 2 || paths[1] == "" {
+		return "", live.ErrRoomUrlIncorrect
 	}
-	chanId := paths[1]
-	resp, err := requests.Get(fmt.Sprintf(userApiUrl, chanId), live.CommonUserAgent,
-		requests.Header("client-id", clientId), requests.Header("Accept", v5Header))
-	if err != nil {
-		return err
+	return strings.ToLower(paths[1]), nil
+}
+
+// oauthToken 返回 cookies 中设置的 OAuth 令牌，未设置时返回空字符串。
+func (l *Live) oauthToken() string {
+	for _, cookie := range l.Options.Cookies.Cookies(l.Url) {
+		if cookie.Name == authCookie {
+			return cookie.Value
+		}
 	}
-	body, err := resp.Bytes()
-	if err != nil {
-		return err
+	return ""
+}
+
+// gql 发送 GQL 请求，返回响应内容。
+func (l *Live) gql(body interface{}) ([]byte, error) {
+	opts := []requests.RequestOption{
+		live.CommonUserAgent,
+		requests.Header("Client-ID", clientId),
+		requests.JSON(body),
 	}
-	if resp.StatusCode != http.StatusOK {
-		return live.ErrRoomNotExist
+	if token := l.oauthToken(); token != "" {
+		opts = append(opts, requests.Header("Authorization", "OAuth "+token))
 	}
-	if gjson.GetBytes(body, "_total").Int() < 1 {
-		return live.ErrRoomNotExist
+	resp, err := requests.Post(gqlUrl, opts...)
+	if err != nil {
+		return nil, err
 	}
-	l.userId = gjson.GetBytes(body, "users").Array()[0].Get("_id").String()
-
-	resp, err = requests.Get(fmt.Sprintf(channelApiUrl, l.userId), live.CommonUserAgent,
-		requests.Header("client-id", clientId), requests.Header("Accept", v5Header))
+	data, err := resp.Bytes()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return live.ErrRoomNotExist
+		return nil, fmt.Errorf("twitch gql 请求失败：%d %s", resp.StatusCode, gjson.GetBytes(data, "message").String())
 	}
-	body, err = resp.Bytes()
-	if err != nil {
-		return err
+	if errs := gjson.GetBytes(data, "errors"); errs.Exists() {
+		return nil, fmt.Errorf("twitch gql 请求失败：%s", errs.Get("0.message").String())
 	}
-	l.hostName = gjson.GetBytes(body, "name").String()
-	l.roomName = gjson.GetBytes(body, "status").String()
-	return nil
+	return data, nil
 }
 
 func (l *Live) GetInfo() (info *live.Info, err error) {
-	if l.hostName == "" || l.roomName == "" || l.userId == "" {
-		if err := l.parseInfo(); err != nil {
-			return nil, err
-		}
-	}
-	resp, err := requests.Get(fmt.Sprintf(streamApiUrl, l.userId), live.CommonUserAgent,
-		requests.Header("client-id", clientId), requests.Header("Accept", v5Header))
+	channel, err := l.channel()
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, live.ErrRoomNotExist
-	}
-	body, err := resp.Bytes()
+	data, err := l.gql(map[string]interface{}{
+		"operationName": "ChannelInfo",
+		"query":         channelQuery,
+		"variables":     map[string]interface{}{"login": channel},
+	})
 	if err != nil {
 		return nil, err
 	}
-	status := gjson.GetBytes(body, "stream").String() != ""
-	if status {
-		l.roomName = gjson.GetBytes(body, "stream.channel.status").String()
+	user := gjson.GetBytes(data, "data.user")
+	if !user.IsObject() {
+		return nil, live.ErrRoomNotExist
+	}
+	stream := user.Get("stream")
+	// 重播（rerun）等类型不视为正在直播
+	status := stream.IsObject() && stream.Get("type").String() == "live"
+	roomName := user.Get("broadcastSettings.title").String()
+	if title := stream.Get("title").String(); status && title != "" {
+		roomName = title
 	}
 	info = &live.Info{
 		Live:     l,
-		HostName: l.hostName,
-		RoomName: l.roomName,
+		HostName: user.Get("displayName").String(),
+		RoomName: roomName,
 		Status:   status,
 	}
 	return info, nil
 }
 
-func (l *Live) GetStreamUrls() (us []*url.URL, err error) {
-	if l.hostName == "" || l.roomName == "" {
-		if err := l.parseInfo(); err != nil {
-			return nil, err
-		}
+// playbackAccessToken 获取观看直播的访问令牌和签名。
+func (l *Live) playbackAccessToken(channel string) (token, sig string, err error) {
+	data, err := l.gql(map[string]interface{}{
+		"operationName": "PlaybackAccessToken",
+		"extensions": map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": playbackAccessTokenHash,
+			},
+		},
+		"variables": map[string]interface{}{
+			"isLive":     true,
+			"login":      channel,
+			"isVod":      false,
+			"vodID":      "",
+			"playerType": "embed",
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	accessToken := gjson.GetBytes(data, "data.streamPlaybackAccessToken")
+	if !accessToken.IsObject() {
+		return "", "", live.ErrRoomNotExist
 	}
-	resp, err := requests.Get(fmt.Sprintf(tokenApiUrl, l.hostName), live.CommonUserAgent, requests.Header("client-id", clientId))
+	return accessToken.Get("value").String(), accessToken.Get("signature").String(), nil
+}
+
+func (l *Live) GetStreamUrls() (us []*url.URL, err error) {
+	channel, err := l.channel()
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, live.ErrRoomNotExist
-	}
-	body, err := resp.Bytes()
+	token, sig, err := l.playbackAccessToken(channel)
 	if err != nil {
 		return nil, err
 	}
-	var (
-		token = gjson.GetBytes(body, "token").String()
-		sig   = gjson.GetBytes(body, "sig").String()
-		p     = fmt.Sprintf("%d", rand.Intn(9000000)+1000000)
-	)
-	u, err := url.Parse(fmt.Sprintf(liveBaseUrl, l.hostName))
+	u, err := url.Parse(fmt.Sprintf(liveBaseUrl, channel))
 	if err != nil {
 		return nil, err
 	}
 	v := url.Values{}
 	v.Add("allow_source", "true")
 	v.Add("allow_audio_only", "true")
-	v.Add("allow_spectre", "true")
-	v.Add("p", p)
+	v.Add("fast_bread", fmt.Sprint(l.Options.LowLatency)) // 低延迟播放列表，包含预取分片
+	v.Add("p", fmt.Sprintf("%d", rand.Intn(9000000)+1000000))
 	v.Add("player", "twitchweb")
-	v.Add("segment_preference", "4")
+	v.Add("playlist_include_framerate", "true")
 	v.Add("sig", sig)
 	v.Add("token", token)
 	u.RawQuery = v.Encode()
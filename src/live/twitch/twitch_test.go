@@ -0,0 +1,99 @@
+package twitch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+// newGqlServer 启动一个按频道名返回 testdata 中记录的 GQL 响应的服务，返回收到的请求头。
+func newGqlServer(t *testing.T) *[]http.Header {
+	headers := make([]http.Header, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		body, _ := io.ReadAll(r.Body)
+		login := gjson.GetBytes(body, "variables.login").String()
+		fixture := "channel_" + login + ".json"
+		if gjson.GetBytes(body, "operationName").String() == "PlaybackAccessToken" {
+			fixture = "playback_access_token.json"
+			if login == "integrity" {
+				fixture = "integrity_error.json"
+			}
+		}
+		b, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			b, _ = os.ReadFile(filepath.Join("testdata", "channel_not_found.json"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}))
+	t.Cleanup(server.Close)
+	old := gqlUrl
+	gqlUrl = server.URL
+	t.Cleanup(func() { gqlUrl = old })
+	return &headers
+}
+
+func newTestLive(t *testing.T, rawUrl string, opts ...live.Option) *Live {
+	u, err := url.Parse(rawUrl)
+	assert.NoError(t, err)
+	l, err := new(builder).Build(u, opts...)
+	assert.NoError(t, err)
+	return l.(*Live)
+}
+
+func TestGetInfo(t *testing.T) {
+	newGqlServer(t)
+
+	info, err := newTestLive(t, "https://www.twitch.tv/live").GetInfo()
+	assert.NoError(t, err)
+	assert.True(t, info.Status)
+	assert.Equal(t, "Twitch", info.HostName)
+	assert.Equal(t, "Twitch Weekly", info.RoomName)
+
+	info, err = newTestLive(t, "https://www.twitch.tv/offline").GetInfo()
+	assert.NoError(t, err)
+	assert.False(t, info.Status)
+	assert.Equal(t, "Twitch Weekly", info.RoomName)
+
+	_, err = newTestLive(t, "https://www.twitch.tv/not_found").GetInfo()
+	assert.Equal(t, live.ErrRoomNotExist, err)
+
+	_, err = newTestLive(t, "https://www.twitch.tv/").GetInfo()
+	assert.Equal(t, live.ErrRoomUrlIncorrect, err)
+}
+
+func TestGetStreamUrls(t *testing.T) {
+	headers := newGqlServer(t)
+
+	u, _ := url.Parse("https://www.twitch.tv/")
+	l := newTestLive(t, "https://www.twitch.tv/Live",
+		live.WithKVStringCookies(u, "auth-token=abcdef"), live.WithLowLatency(true))
+	us, err := l.GetStreamUrls()
+	assert.NoError(t, err)
+	if assert.Len(t, us, 1) {
+		assert.Equal(t, "usher.ttvnw.net", us[0].Host)
+		assert.Equal(t, "/api/channel/hls/live.m3u8", us[0].Path)
+		query := us[0].Query()
+		assert.Equal(t, "3f2b9c0d6e7a1b4c5d8e9f0a1b2c3d4e5f6a7b8c", query.Get("sig"))
+		assert.Equal(t, "twitch", gjson.Get(query.Get("token"), "channel").String())
+		assert.Equal(t, "true", query.Get("fast_bread"))
+	}
+	if assert.NotEmpty(t, *headers) {
+		h := (*headers)[len(*headers)-1]
+		assert.Equal(t, clientId, h.Get("Client-ID"))
+		assert.Equal(t, "OAuth abcdef", h.Get("Authorization"))
+	}
+
+	_, err = newTestLive(t, "https://www.twitch.tv/integrity").GetStreamUrls()
+	assert.ErrorContains(t, err, "failed integrity check")
+}
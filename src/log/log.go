@@ -10,6 +10,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/yuhaohwang/bililive-go/src/configs"
 	"github.com/yuhaohwang/bililive-go/src/instance"
 	"github.com/yuhaohwang/bililive-go/src/interfaces"
 )
@@ -19,12 +20,6 @@ func New(ctx context.Context) *interfaces.Logger {
 	// 获取应用程序实例。
 	inst := instance.GetInstance(ctx)
 
-	// 将默认日志级别设置为 Info，或者如果启用 Debug 模式则设置为 Debug。
-	logLevel := logrus.InfoLevel
-	if inst.Config.Debug {
-		logLevel = logrus.DebugLevel
-	}
-
 	// 获取应用程序配置。
 	config := inst.Config
 
@@ -42,7 +37,7 @@ func New(ctx context.Context) *interfaces.Logger {
 		if config.Log.SaveEveryLog {
 			runID := time.Now().Format("run-2006-01-02-15-04-05")
 			logLocation := filepath.Join(outputFolder, runID+".log")
-			logFile, err := os.OpenFile(logLocation, os.O_CREATE|os.O_WRONLY, 0644)
+			logFile, err := openLogFile(logLocation, config.Log.Rotate, false)
 			if err != nil {
 				log.Fatalf("无法打开日志文件 %s 以进行输出: %s", logLocation, err)
 			} else {
@@ -50,10 +45,10 @@ func New(ctx context.Context) *interfaces.Logger {
 			}
 		}
 
-		// 如果启用了 SaveLastLog，创建或截断默认的日志文件。
+		// 如果启用了 SaveLastLog，创建或截断默认的日志文件，需要切分时改为追加写入。
 		if config.Log.SaveLastLog {
 			logLocation := filepath.Join(outputFolder, "bililive-go.log")
-			logFile, err := os.OpenFile(logLocation, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			logFile, err := openLogFile(logLocation, config.Log.Rotate, true)
 			if err != nil {
 				log.Fatalf("无法打开默认日志文件 %s 以进行输出: %s", logLocation, err)
 			} else {
@@ -63,19 +58,60 @@ func New(ctx context.Context) *interfaces.Logger {
 	}
 
 	// 使用指定配置创建日志记录器实例。
+	formatter := NewFormatter(config.Log.Format)
 	logger := &interfaces.Logger{Logger: &logrus.Logger{
-		Out: io.MultiWriter(writers...),
-		Formatter: &logrus.TextFormatter{
-			DisableColors:   true,
-			FullTimestamp:   true,
-			TimestampFormat: "2006-01-02 15:04:05",
-		},
-		Hooks: make(logrus.LevelHooks),
-		Level: logLevel,
+		Out:       io.MultiWriter(writers...),
+		Formatter: formatter,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     Level(config),
 	}}
 
+	// 如果启用了 PerRoom，将带有直播间 ID 的日志另外写入直播间的日志文件。
+	if config.Log.PerRoom {
+		logger.AddHook(newRoomHook(filepath.Join(outputFolder, RoomLogFolder), config.Log.Rotate, formatter))
+	}
+
 	// 设置应用程序日志记录器为创建的日志记录器实例。
 	inst.Logger = logger
 
 	return logger
 }
+
+// Level 返回配置的日志级别，未配置时在 Debug 模式下为 Debug，否则为 Info。
+func Level(config *configs.Config) logrus.Level {
+	if config.Log.Level != "" {
+		if level, err := logrus.ParseLevel(config.Log.Level); err == nil {
+			return level
+		}
+	}
+	if config.Debug {
+		return logrus.DebugLevel
+	}
+	return logrus.InfoLevel
+}
+
+// NewFormatter 创建指定格式的日志格式化器，格式为空或未知时使用文本格式。
+func NewFormatter(format string) logrus.Formatter {
+	if format == configs.LogFormatJson {
+		return &logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+		}
+	}
+	return &logrus.TextFormatter{
+		DisableColors:   true,
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02 15:04:05",
+	}
+}
+
+// openLogFile 打开日志文件，需要切分时使用 RotateWriter 追加写入，否则根据 truncate 决定是否清空原有内容。
+func openLogFile(name string, rotate configs.LogRotate, truncate bool) (io.Writer, error) {
+	if rotate.Enabled() {
+		return NewRotateWriter(name, rotate)
+	}
+	flag := os.O_CREATE | os.O_WRONLY
+	if truncate {
+		flag |= os.O_TRUNC
+	}
+	return os.OpenFile(name, flag, 0644)
+}
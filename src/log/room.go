@@ -0,0 +1,141 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+const (
+	// LiveIDField 是日志中直播间 ID 字段的名称，带有该字段的日志会另外写入直播间的日志文件
+	LiveIDField = "live_id"
+	// RoomLogFolder 是日志文件夹下保存直播间日志文件的目录
+	RoomLogFolder = "rooms"
+)
+
+// roomHook 将带有直播间 ID 的日志写入 <目录>/<直播间 ID>.log。
+type roomHook struct {
+	folder    string
+	rotate    configs.LogRotate
+	formatter logrus.Formatter
+
+	lock    sync.Mutex
+	writers map[string]*RotateWriter
+}
+
+// newRoomHook 创建写入 folder 目录的直播间日志钩子。
+func newRoomHook(folder string, rotate configs.LogRotate, formatter logrus.Formatter) *roomHook {
+	return &roomHook{
+		folder:    folder,
+		rotate:    rotate,
+		formatter: formatter,
+		writers:   make(map[string]*RotateWriter),
+	}
+}
+
+// Levels 返回钩子处理的日志级别，实际写入的级别由日志记录器的级别决定。
+func (h *roomHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 将日志写入所属直播间的日志文件。
+func (h *roomHook) Fire(entry *logrus.Entry) error {
+	value, ok := entry.Data[LiveIDField]
+	if !ok {
+		return nil
+	}
+	id := sanitizeFileName(fmt.Sprint(value))
+	if id == "" {
+		return nil
+	}
+	w, err := h.writer(id)
+	if err != nil {
+		return err
+	}
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// writer 返回直播间的日志文件写入器，不存在时创建。
+func (h *roomHook) writer(id string) (*RotateWriter, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if w, ok := h.writers[id]; ok {
+		return w, nil
+	}
+	w, err := NewRotateWriter(filepath.Join(h.folder, id+".log"), h.rotate)
+	if err != nil {
+		return nil, err
+	}
+	h.writers[id] = w
+	return w, nil
+}
+
+// remove 关闭并删除直播间的日志文件写入器。
+func (h *roomHook) remove(id string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if w, ok := h.writers[id]; ok {
+		w.Close()
+		delete(h.writers, id)
+	}
+}
+
+// close 关闭所有直播间的日志文件写入器。
+func (h *roomHook) close() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for id, w := range h.writers {
+		w.Close()
+		delete(h.writers, id)
+	}
+}
+
+// roomHookOf 返回日志记录器的直播间日志钩子，未开启 per_room 时返回 nil。
+func roomHookOf(logger *interfaces.Logger) *roomHook {
+	if logger == nil {
+		return nil
+	}
+	for _, hook := range logger.Hooks[logrus.InfoLevel] {
+		if h, ok := hook.(*roomHook); ok {
+			return h
+		}
+	}
+	return nil
+}
+
+// RemoveRoom 关闭直播间的日志文件，用于直播间被删除时，之后再有该直播间的日志时会重新打开。
+func RemoveRoom(logger *interfaces.Logger, id live.ID) {
+	if h := roomHookOf(logger); h != nil {
+		h.remove(sanitizeFileName(string(id)))
+	}
+}
+
+// Close 关闭所有直播间的日志文件，用于程序退出时。
+func Close(logger *interfaces.Logger) {
+	if h := roomHookOf(logger); h != nil {
+		h.close()
+	}
+}
+
+// sanitizeFileName 替换直播间 ID 中不能用于文件名的字符，自定义的直播间 ID 可能包含这些字符。
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, strings.Trim(name, ". "))
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+)
+
+func TestRoomHook(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(NewFormatter(configs.LogFormatJson))
+	logger.AddHook(newRoomHook(dir, configs.LogRotate{}, logger.Formatter))
+
+	logger.WithField(LiveIDField, "abc").Info("room message")
+	logger.WithField(LiveIDField, "a/b").Warn("custom id")
+	logger.Info("global message")
+	logger.WithField(LiveIDField, "abc").Debug("filtered by level")
+
+	b, err := os.ReadFile(filepath.Join(dir, "abc.log"))
+	assert.NoError(t, err)
+	entry := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, "room message", entry["msg"])
+	assert.Equal(t, "abc", entry[LiveIDField])
+
+	assert.FileExists(t, filepath.Join(dir, "a_b.log"))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestRemoveRoom(t *testing.T) {
+	dir := t.TempDir()
+	logger := &interfaces.Logger{Logger: logrus.New()}
+	logger.SetOutput(io.Discard)
+	hook := newRoomHook(dir, configs.LogRotate{}, logger.Formatter)
+	logger.AddHook(hook)
+
+	logger.WithField(LiveIDField, "a/b").Info("message")
+	logger.WithField(LiveIDField, "c").Info("message")
+	assert.Len(t, hook.writers, 2)
+
+	RemoveRoom(logger, "a/b")
+	assert.Len(t, hook.writers, 1)
+	// 删除后仍有日志时重新打开
+	logger.WithField(LiveIDField, "a/b").Info("late message")
+	assert.Len(t, hook.writers, 2)
+
+	Close(logger)
+	assert.Empty(t, hook.writers)
+	// 未开启 per_room 时不做处理
+	RemoveRoom(&interfaces.Logger{Logger: logrus.New()}, "c")
+}
+
+func TestLevel(t *testing.T) {
+	cfg := configs.NewConfig()
+	assert.Equal(t, logrus.InfoLevel, Level(cfg))
+	cfg.Debug = true
+	assert.Equal(t, logrus.DebugLevel, Level(cfg))
+	cfg.Log.Level = "warn"
+	assert.Equal(t, logrus.WarnLevel, Level(cfg))
+}
@@ -0,0 +1,232 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// backupTimeFormat 是切分出的日志文件名中的时间格式，按字符串排序即按时间排序。
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateWriter 将日志追加写入文件，并按大小或时间切分。
+// 切分出的文件名为 <名称>-<时间><扩展名>，可以选择压缩，并按数量和天数清理。
+type RotateWriter struct {
+	filename string
+	rotate   configs.LogRotate
+
+	lock     sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+
+	// millLock 保证压缩和清理不会同时进行
+	millLock sync.Mutex
+	milling  sync.WaitGroup
+}
+
+// NewRotateWriter 创建写入 filename 的日志文件写入器，文件不存在时创建。
+func NewRotateWriter(filename string, rotate configs.LogRotate) (*RotateWriter, error) {
+	w := &RotateWriter{
+		filename: filename,
+		rotate:   rotate,
+		now:      time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open 以追加方式打开日志文件。
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = w.now()
+	if w.size > 0 {
+		// 已有内容的文件从最后修改的时间开始计算周期
+		w.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// period 返回时间所在的切分周期，周期不同时需要切分。
+func (w *RotateWriter) period(t time.Time) string {
+	switch w.rotate.Interval {
+	case configs.LogRotateHourly:
+		return t.Format("2006-01-02T15")
+	case configs.LogRotateDaily:
+		return t.Format("2006-01-02")
+	}
+	return ""
+}
+
+// shouldRotate 判断写入 n 字节前是否需要切分。
+func (w *RotateWriter) shouldRotate(n int) bool {
+	if w.size == 0 {
+		return false
+	}
+	if maxSize := int64(w.rotate.MaxSize) * 1024 * 1024; maxSize > 0 && w.size+int64(n) > maxSize {
+		return true
+	}
+	return w.period(w.openedAt) != w.period(w.now())
+}
+
+// Write 写入日志，需要时先切分文件。
+func (w *RotateWriter) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(len(b)) {
+		if err := w.doRotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即切分日志文件。
+func (w *RotateWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.doRotate()
+}
+
+// doRotate 将当前文件重命名为带时间的文件并打开新文件，之后在后台压缩和清理。
+func (w *RotateWriter) doRotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	ext := filepath.Ext(w.filename)
+	backup := strings.TrimSuffix(w.filename, ext) + "-" + w.now().Format(backupTimeFormat) + ext
+	if err := os.Rename(w.filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.milling.Add(1)
+	go w.mill(backup)
+	return nil
+}
+
+// mill 压缩刚切分出的文件，并删除超出数量或天数的文件。
+func (w *RotateWriter) mill(backup string) {
+	defer w.milling.Done()
+	w.millLock.Lock()
+	defer w.millLock.Unlock()
+	if w.rotate.Compress {
+		if err := compressFile(backup); err == nil {
+			os.Remove(backup)
+		}
+	}
+	w.cleanup()
+}
+
+// backups 返回切分出的文件，从新到旧排列。
+func (w *RotateWriter) backups() []string {
+	ext := filepath.Ext(w.filename)
+	prefix := filepath.Base(strings.TrimSuffix(w.filename, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(w.filename))
+	if err != nil {
+		return nil
+	}
+	files := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(filepath.Dir(w.filename), name))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files
+}
+
+// cleanup 删除超出保留数量或保留天数的切分文件。
+func (w *RotateWriter) cleanup() {
+	deadline := time.Time{}
+	if w.rotate.MaxAge > 0 {
+		deadline = w.now().Add(-time.Duration(w.rotate.MaxAge) * 24 * time.Hour)
+	}
+	for i, file := range w.backups() {
+		if w.rotate.MaxBackups > 0 && i >= w.rotate.MaxBackups {
+			os.Remove(file)
+			continue
+		}
+		if info, err := os.Stat(file); err == nil && !deadline.IsZero() && info.ModTime().Before(deadline) {
+			os.Remove(file)
+		}
+	}
+}
+
+// compressFile 将文件压缩为同名的 .gz 文件。
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	return dst.Close()
+}
+
+// Close 关闭日志文件，之后写入时会重新打开。
+func (w *RotateWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
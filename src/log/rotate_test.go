@@ -0,0 +1,110 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// newTestRotateWriter 创建使用指定时间的写入器。
+func newTestRotateWriter(t *testing.T, name string, rotate configs.LogRotate, now *time.Time) *RotateWriter {
+	w := &RotateWriter{filename: name, rotate: rotate, now: func() time.Time { return *now }}
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// waitMill 等待后台的压缩和清理完成。
+func waitMill(w *RotateWriter) {
+	w.milling.Wait()
+}
+
+func TestRotateWriterBySize(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "bililive-go.log")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	w := newTestRotateWriter(t, name, configs.LogRotate{MaxSize: 1, MaxBackups: 2}, &now)
+	defer w.Close()
+
+	line := []byte(strings.Repeat("a", 700*1024))
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Second)
+		_, err := w.Write(line)
+		assert.NoError(t, err)
+	}
+	waitMill(w)
+
+	// 每次写入都超过了1MB，切分出3个文件，只保留最新的2个
+	backups := w.backups()
+	if assert.Len(t, backups, 2) {
+		assert.Equal(t, filepath.Join(dir, "bililive-go-2024-01-01T00-00-04.000.log"), backups[0])
+		assert.Equal(t, filepath.Join(dir, "bililive-go-2024-01-01T00-00-03.000.log"), backups[1])
+	}
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(line)), info.Size())
+}
+
+func TestRotateWriterByTime(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "room.log")
+	now := time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local)
+	w := newTestRotateWriter(t, name, configs.LogRotate{Interval: configs.LogRotateDaily, Compress: true}, &now)
+	defer w.Close()
+
+	_, err := w.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	now = now.Add(30 * time.Second)
+	_, err = w.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = w.Write([]byte("third\n"))
+	assert.NoError(t, err)
+	waitMill(w)
+
+	backups := w.backups()
+	if assert.Len(t, backups, 1) {
+		assert.Equal(t, filepath.Join(dir, "room-2024-01-02T00-00-30.000.log.gz"), backups[0])
+		f, err := os.Open(backups[0])
+		assert.NoError(t, err)
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		assert.NoError(t, err)
+		b, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", string(b))
+	}
+	b, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "third\n", string(b))
+}
+
+func TestRotateWriterMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "bililive-go.log")
+	old := filepath.Join(dir, "bililive-go-2023-01-01T00-00-00.000.log")
+	assert.NoError(t, os.WriteFile(old, []byte("old"), 0644))
+	oldTime := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(old, oldTime, oldTime))
+	// 名称不符合格式的文件不会被清理
+	other := filepath.Join(dir, "bililive-go-other.log")
+	assert.NoError(t, os.WriteFile(other, []byte("other"), 0644))
+
+	now := time.Now()
+	w := newTestRotateWriter(t, name, configs.LogRotate{MaxSize: 1, MaxAge: 1}, &now)
+	defer w.Close()
+	assert.NoError(t, w.Rotate())
+	waitMill(w)
+
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, other)
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
+	"github.com/yuhaohwang/bililive-go/src/pushers"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+// eventMetrics 是通过事件和回调累计的指标，与抓取时计算的指标一起由收集器输出。
+type eventMetrics struct {
+	platformRequestSeconds *prometheus.HistogramVec
+	platformRequestErrors  *prometheus.CounterVec
+	recorderSplits         *prometheus.CounterVec
+	pusherErrors           *prometheus.CounterVec
+	jobSeconds             *prometheus.HistogramVec
+	stepSeconds            *prometheus.HistogramVec
+}
+
+// newEventMetrics 创建累计指标。
+func newEventMetrics() *eventMetrics {
+	return &eventMetrics{
+		platformRequestSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "bgo",
+			Subsystem: "platform",
+			Name:      "request_duration_seconds",
+			Help:      "platform api request duration",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"domain", "method"}),
+		platformRequestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "bgo",
+			Subsystem: "platform",
+			Name:      "request_errors_total",
+			Help:      "platform api request errors",
+		}, []string{"domain", "method"}),
+		recorderSplits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "bgo",
+			Subsystem: "recorder",
+			Name:      "splits_total",
+			Help:      "recorder file splits",
+		}, []string{"live_id"}),
+		pusherErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "bgo",
+			Subsystem: "pusher",
+			Name:      "errors_total",
+			Help:      "pusher errors",
+		}, []string{"live_id"}),
+		jobSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "bgo",
+			Subsystem: "postprocess",
+			Name:      "job_duration_seconds",
+			Help:      "post-processing job duration",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}, []string{"status"}),
+		stepSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "bgo",
+			Subsystem: "postprocess",
+			Name:      "step_duration_seconds",
+			Help:      "post-processing step duration",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}, []string{"type", "status"}),
+	}
+}
+
+// collectors 返回所有累计指标。
+func (m *eventMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.platformRequestSeconds,
+		m.platformRequestErrors,
+		m.recorderSplits,
+		m.pusherErrors,
+		m.jobSeconds,
+		m.stepSeconds,
+	}
+}
+
+// observeRequest 记录一次平台接口请求，作为 live.RequestObserver 使用。
+func (m *eventMetrics) observeRequest(domain, method string, duration time.Duration, err error) {
+	m.platformRequestSeconds.WithLabelValues(domain, method).Observe(duration.Seconds())
+	if err != nil {
+		m.platformRequestErrors.WithLabelValues(domain, method).Inc()
+	}
+}
+
+// observeJob 记录结束的后处理任务及其步骤的耗时。
+func (m *eventMetrics) observeJob(job *postprocess.Job) {
+	m.jobSeconds.WithLabelValues(string(job.Status)).Observe(float64(job.Duration) / 1000)
+	for _, step := range job.Steps {
+		if step.Status == postprocess.StatusSucceeded || step.Status == postprocess.StatusFailed {
+			m.stepSeconds.WithLabelValues(step.Type, string(step.Status)).Observe(float64(step.Duration) / 1000)
+		}
+	}
+}
+
+// register 注册事件监听器。
+func (m *eventMetrics) register(ed events.Dispatcher) {
+	// 达到最大时长时重启录制器，达到文件大小或出现新的视频序列头时由解析器开始新的文件
+	splitListener := events.NewEventListener(func(event *events.Event) {
+		m.recorderSplits.WithLabelValues(string(event.Object.(live.Live).GetLiveId())).Inc()
+	})
+	ed.AddEventListener(recorders.RecorderRestart, splitListener)
+	ed.AddEventListener(recorders.RecorderSplit, splitListener)
+	ed.AddEventListener(pushers.PusherError, events.NewEventListener(func(event *events.Event) {
+		m.pusherErrors.WithLabelValues(string(event.Object.(*live.ErrorParam).Live.GetLiveId())).Inc()
+	}))
+	ed.AddEventListener(postprocess.JobFinished, events.NewEventListener(func(event *events.Event) {
+		m.observeJob(event.Object.(*postprocess.Job))
+	}))
+}
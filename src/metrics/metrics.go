@@ -3,7 +3,9 @@ package metrics
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +15,9 @@ import (
 	"github.com/yuhaohwang/bililive-go/src/interfaces"
 	"github.com/yuhaohwang/bililive-go/src/listeners"
 	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+	"github.com/yuhaohwang/bililive-go/src/pushers"
 	"github.com/yuhaohwang/bililive-go/src/recorders"
 )
 
@@ -32,6 +37,13 @@ var (
 		[]string{"live_id", "live_url", "live_host_name", "live_room_name", "start_time"},
 		nil,
 	)
+	liveOnline = prometheus.NewDesc(
+		// 定义 liveOnline 指标的描述符
+		prometheus.BuildFQName("bgo", "live", "online"),
+		"live online count reported by platform",
+		[]string{"live_id", "live_url", "live_host_name", "live_room_name", "category"},
+		nil,
+	)
 	recorderTotalBytes = prometheus.NewDesc(
 		// 定义 recorderTotalBytes 指标的描述符
 		prometheus.BuildFQName("bgo", "recorder", "total_bytes"),
@@ -39,18 +51,87 @@ var (
 		[]string{"live_id", "live_url", "live_host_name", "live_room_name"},
 		nil,
 	)
+	recorderBitrate = prometheus.NewDesc(
+		// 定义 recorderBitrate 指标的描述符
+		prometheus.BuildFQName("bgo", "recorder", "bitrate_kbps"),
+		"recorder bitrate in kbit/s",
+		[]string{"live_id", "live_url", "live_host_name", "live_room_name"},
+		nil,
+	)
+	recorderFps = prometheus.NewDesc(
+		// 定义 recorderFps 指标的描述符
+		prometheus.BuildFQName("bgo", "recorder", "fps"),
+		"recorder video frames per second",
+		[]string{"live_id", "live_url", "live_host_name", "live_room_name"},
+		nil,
+	)
+	recorderReconnects = prometheus.NewDesc(
+		// 定义 recorderReconnects 指标的描述符
+		prometheus.BuildFQName("bgo", "recorder", "reconnects_total"),
+		"recorder reconnects since the live started, excluding file splits",
+		[]string{"live_id", "live_url", "live_host_name", "live_room_name"},
+		nil,
+	)
+	recorderStallSeconds = prometheus.NewDesc(
+		// 定义 recorderStallSeconds 指标的描述符
+		prometheus.BuildFQName("bgo", "recorder", "stall_seconds_total"),
+		"seconds without an active stream between reconnects",
+		[]string{"live_id", "live_url", "live_host_name", "live_room_name"},
+		nil,
+	)
+	pusherUp = prometheus.NewDesc(
+		// 定义 pusherUp 指标的描述符
+		prometheus.BuildFQName("bgo", "pusher", "up"),
+		"whether the pusher of a living room with push enabled is running",
+		[]string{"live_id", "live_url", "rtmp_host"},
+		nil,
+	)
+	diskFreeBytes = prometheus.NewDesc(
+		// 定义 diskFreeBytes 指标的描述符
+		prometheus.BuildFQName("bgo", "disk", "free_bytes"),
+		"free space of the output path",
+		[]string{"path"},
+		nil,
+	)
 )
 
+// recorderGauges 是从录制状态中读取的数值指标，状态值带单位时只解析开头的数字。
+var recorderGauges = []struct {
+	desc      *prometheus.Desc
+	key       string
+	valueType prometheus.ValueType
+}{
+	{recorderTotalBytes, "total_size", prometheus.CounterValue},
+	{recorderBitrate, "bitrate", prometheus.GaugeValue},
+	{recorderFps, "fps", prometheus.GaugeValue},
+	{recorderReconnects, "reconnects", prometheus.CounterValue},
+	{recorderStallSeconds, "stall_seconds", prometheus.CounterValue},
+}
+
 // collector 结构表示 Prometheus 指标收集器
 type collector struct {
-	inst *instance.Instance
+	inst   *instance.Instance
+	events *eventMetrics
 }
 
 // NewCollector 创建一个新的收集器实例
 func NewCollector(ctx context.Context) interfaces.Module {
 	return &collector{
-		inst: instance.GetInstance(ctx),
+		inst:   instance.GetInstance(ctx),
+		events: newEventMetrics(),
+	}
+}
+
+// parseNumber 解析状态值开头的数字，如 FFmpeg 输出的 "1234.5kbits/s"。
+func parseNumber(s string) (float64, bool) {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e'
+	})
+	if end >= 0 {
+		s = s[:end]
 	}
+	value, err := strconv.ParseFloat(s, 64)
+	return value, err == nil
 }
 
 // bool2float64 将布尔值转换为浮点数（0 或 1）
@@ -64,7 +145,7 @@ func bool2float64(b bool) float64 {
 // Collect 收集 Prometheus 指标
 func (c collector) Collect(ch chan<- prometheus.Metric) {
 	wg := sync.WaitGroup{}
-	for id, l := range c.inst.Lives {
+	for id, l := range c.inst.GetLives() {
 		wg.Add(1)
 		go func(id live.ID, l live.Live) {
 			defer wg.Done()
@@ -80,6 +161,11 @@ func (c collector) Collect(ch chan<- prometheus.Metric) {
 			)
 
 			if info.Status && listening {
+				ch <- prometheus.MustNewConstMetric(
+					liveOnline, prometheus.GaugeValue, float64(info.Online),
+					string(id), l.GetRawUrl(), info.HostName, info.RoomName, info.Category,
+				)
+
 				startTime := info.Live.GetLastStartTime()
 				duration := time.Since(startTime).Seconds()
 
@@ -90,28 +176,62 @@ func (c collector) Collect(ch chan<- prometheus.Metric) {
 
 				if r, err := c.inst.RecorderManager.(recorders.Manager).GetRecorder(context.Background(), id); err == nil {
 					if status, err := r.GetStatus(); err == nil {
-						if value, err := strconv.ParseFloat(status["total_size"], 64); err == nil {
-							ch <- prometheus.MustNewConstMetric(recorderTotalBytes, prometheus.CounterValue, value,
-								string(id), l.GetRawUrl(), info.HostName, info.RoomName)
+						for _, g := range recorderGauges {
+							if value, ok := parseNumber(status[g.key]); ok {
+								ch <- prometheus.MustNewConstMetric(g.desc, g.valueType, value,
+									string(id), l.GetRawUrl(), info.HostName, info.RoomName)
+							}
 						}
 					}
 				}
+
+				if room, err := c.inst.Config.GetLiveRoomByUrl(l.GetRawUrl()); err == nil && room.Push && room.Rtmp != "" {
+					rtmpHost := ""
+					if u, err := url.Parse(room.Rtmp); err == nil {
+						rtmpHost = u.Host
+					}
+					running := c.inst.PusherManager.(pushers.Manager).HasPusher(context.Background(), id)
+					ch <- prometheus.MustNewConstMetric(pusherUp, prometheus.GaugeValue, bool2float64(running),
+						string(id), l.GetRawUrl(), rtmpHost)
+				}
 			}
 		}(id, l)
 	}
 	wg.Wait()
+
+	if free, err := utils.DiskFree(c.inst.Config.OutPutPath); err == nil {
+		ch <- prometheus.MustNewConstMetric(diskFreeBytes, prometheus.GaugeValue, float64(free), c.inst.Config.OutPutPath)
+	}
+	for _, m := range c.events.collectors() {
+		m.Collect(ch)
+	}
 }
 
 // Describe 描述 Prometheus 指标
-func (collector) Describe(ch chan<- *prometheus.Desc) {
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- liveStatus
 	ch <- liveDurationSeconds
-	ch <- recorderTotalBytes
+	ch <- liveOnline
+	for _, g := range recorderGauges {
+		ch <- g.desc
+	}
+	ch <- pusherUp
+	ch <- diskFreeBytes
+	for _, m := range c.events.collectors() {
+		m.Describe(ch)
+	}
 }
 
 // Start 启动收集器
 func (c *collector) Start(_ context.Context) error {
-	return prometheus.Register(c)
+	if err := prometheus.Register(c); err != nil {
+		return err
+	}
+	live.RequestObserver = c.events.observeRequest
+	if ed, ok := c.inst.EventDispatcher.(events.Dispatcher); ok {
+		c.events.register(ed)
+	}
+	return nil
 }
 
 // Close 关闭收集器
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
+)
+
+func TestParseNumber(t *testing.T) {
+	for s, want := range map[string]float64{
+		"1234.5kbits/s": 1234.5,
+		"30.00":         30,
+		"1048576":       1048576,
+		"2.500":         2.5,
+	} {
+		value, ok := parseNumber(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, want, value, s)
+	}
+	for _, s := range []string{"", "N/A", "kbits/s"} {
+		_, ok := parseNumber(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestEventMetrics(t *testing.T) {
+	m := newEventMetrics()
+	registry := prometheus.NewPedanticRegistry()
+	for _, c := range m.collectors() {
+		assert.NoError(t, registry.Register(c))
+	}
+
+	m.observeRequest("live.bilibili.com", "GetInfo", 200*time.Millisecond, nil)
+	m.observeRequest("live.bilibili.com", "GetInfo", time.Second, errors.New("timeout"))
+	m.observeJob(&postprocess.Job{
+		Status:   postprocess.StatusFailed,
+		Duration: 90000,
+		Steps: []*postprocess.StepState{
+			{Type: "remux", Status: postprocess.StatusSucceeded, Duration: 60000},
+			{Type: "upload", Status: postprocess.StatusFailed, Duration: 30000},
+			{Type: "move", Status: postprocess.StatusPending},
+		},
+	})
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	got := make(map[string][]float64)
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			if h := metric.GetHistogram(); h != nil {
+				got[f.GetName()] = append(got[f.GetName()], float64(h.GetSampleCount()), h.GetSampleSum())
+			} else {
+				got[f.GetName()] = append(got[f.GetName()], metric.GetCounter().GetValue())
+			}
+		}
+	}
+	assert.Equal(t, []float64{2, 1.2}, got["bgo_platform_request_duration_seconds"])
+	assert.Equal(t, []float64{1}, got["bgo_platform_request_errors_total"])
+	assert.Equal(t, []float64{1, 90}, got["bgo_postprocess_job_duration_seconds"])
+	// 未执行的步骤不计入
+	assert.Len(t, got["bgo_postprocess_step_duration_seconds"], 4)
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+const barkServerUrl = "https://api.day.app"
+
+// barkNotifier 通过 Bark 发送通知。
+type barkNotifier struct {
+	cfg configs.BarkNotify
+}
+
+// Name 返回通知渠道的名称。
+func (n *barkNotifier) Name() string {
+	return "bark"
+}
+
+// Send 调用 /push 接口发送通知。
+func (n *barkNotifier) Send(ctx context.Context, msg *Message) error {
+	serverUrl := n.cfg.ServerUrl
+	if serverUrl == "" {
+		serverUrl = barkServerUrl
+	}
+	body := map[string]interface{}{
+		"device_key": n.cfg.DeviceKey,
+		"title":      msg.Title,
+		"body":       msg.Body,
+	}
+	if n.cfg.Group != "" {
+		body["group"] = n.cfg.Group
+	}
+	if n.cfg.Sound != "" {
+		body["sound"] = n.cfg.Sound
+	}
+	data, err := postJSON(ctx, strings.TrimRight(serverUrl, "/")+"/push", body)
+	if err != nil {
+		return err
+	}
+	if result := gjson.ParseBytes(data); result.Get("code").Int() != 200 {
+		return fmt.Errorf("发送失败：%s", result.Get("message").String())
+	}
+	return nil
+}
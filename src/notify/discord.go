@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// discordNotifier 通过 Discord Webhook 发送通知。
+type discordNotifier struct {
+	cfg configs.DiscordNotify
+}
+
+// Name 返回通知渠道的名称。
+func (n *discordNotifier) Name() string {
+	return "discord"
+}
+
+// Send 向 Webhook 地址发送通知，标题以粗体显示。
+func (n *discordNotifier) Send(ctx context.Context, msg *Message) error {
+	content := msg.Body
+	if msg.Title != "" {
+		content = "**" + msg.Title + "**\n" + msg.Body
+	}
+	_, err := postJSON(ctx, n.cfg.WebhookUrl, map[string]interface{}{
+		"content": content,
+	})
+	return err
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// smtpSession 记录 SMTP 桩收到的一次会话。
+type smtpSession struct {
+	Auth string
+	From string
+	To   []string
+	Data string
+}
+
+// newSMTPStub 启动一个只接受一次会话的最简 SMTP 服务器。
+func newSMTPStub(t *testing.T) (host string, port int, sessions chan smtpSession) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	sessions = make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		session := smtpSession{}
+		reply("220 stub ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO":
+				reply("250-stub")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				session.Auth = line
+				reply("235 ok")
+			case "MAIL":
+				session.From = line
+				reply("250 ok")
+			case "RCPT":
+				session.To = append(session.To, line)
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				data := new(strings.Builder)
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				session.Data = data.String()
+				reply("250 ok")
+			case "QUIT":
+				reply("221 bye")
+				sessions <- session
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, sessions
+}
+
+func TestEmailNotifier(t *testing.T) {
+	host, port, sessions := newSMTPStub(t)
+	n := &emailNotifier{cfg: configs.EmailNotify{
+		Host:     host,
+		Port:     port,
+		Username: "user",
+		Password: "pass",
+		From:     "bililive <bot@example.com>",
+		To:       []string{"a@example.com", "b@example.com"},
+	}}
+	assert.NoError(t, n.Send(context.Background(), testMessage))
+
+	session := <-sessions
+	assert.Equal(t, "AUTH PLAIN "+base64.StdEncoding.EncodeToString([]byte("\x00user\x00pass")), session.Auth)
+	assert.Equal(t, "MAIL FROM:<bot@example.com>", session.From)
+	assert.Equal(t, []string{"RCPT TO:<a@example.com>", "RCPT TO:<b@example.com>"}, session.To)
+	assert.Contains(t, session.Data, "Subject: =?UTF-8?b?"+base64.StdEncoding.EncodeToString([]byte("主播 开播了"))+"?=")
+	assert.Contains(t, session.Data, base64.StdEncoding.EncodeToString([]byte("房间名")))
+	assert.Contains(t, session.Data, "To: <a@example.com>, <b@example.com>")
+
+	n.cfg.Port = 1
+	assert.Error(t, n.Send(context.Background(), testMessage))
+}
@@ -0,0 +1,233 @@
+// Package notify 负责在开播、录制失败和磁盘空间不足时向各通知渠道发送消息。
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/listeners"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+// sendTimeout 是单个通知渠道发送消息的超时时间。
+const sendTimeout = 30 * time.Second
+
+// Manager 定义通知管理器的接口。
+type Manager interface {
+	interfaces.Module
+	// Notify 渲染并向所有启用的渠道发送通知，事件未启用或没有启用的渠道时直接返回。
+	Notify(ctx context.Context, data *TemplateData) error
+}
+
+// manager 是 Manager 的实现。
+type manager struct {
+	inst *instance.Instance
+
+	lock      sync.Mutex
+	lastError map[live.ID]time.Time // 各房间上次发送录制失败通知的时间
+	diskLow   bool                  // 是否已发送过磁盘空间不足的通知
+
+	stop chan struct{}
+}
+
+// NewManager 创建一个新的通知管理器实例。
+func NewManager(ctx context.Context) Manager {
+	inst := instance.GetInstance(ctx)
+	m := &manager{
+		inst:      inst,
+		lastError: make(map[live.ID]time.Time),
+		stop:      make(chan struct{}),
+	}
+	inst.NotifyManager = m
+	return m
+}
+
+// Start 检查通知模板，监听开播和录制失败事件，并启动磁盘空间检查。
+func (m *manager) Start(ctx context.Context) error {
+	if err := parseTemplates(m.inst.Config); err != nil {
+		return fmt.Errorf("通知模板无效：%v", err)
+	}
+	ed := m.inst.EventDispatcher.(events.Dispatcher)
+	ed.AddEventListener(listeners.LiveStart, events.NewEventListener(func(event *events.Event) {
+		m.onLiveStart(ctx, event.Object.(live.Live))
+	}))
+	ed.AddEventListener(recorders.RecorderError, events.NewEventListener(func(event *events.Event) {
+		m.onRecordError(ctx, event.Object.(*live.ErrorParam))
+	}))
+	go m.runDiskCheck(ctx)
+	return nil
+}
+
+// Close 停止磁盘空间检查。
+func (m *manager) Close(ctx context.Context) {
+	close(m.stop)
+}
+
+// Notify 渲染并向所有启用的渠道发送通知。
+func (m *manager) Notify(ctx context.Context, data *TemplateData) error {
+	cfg := m.inst.Config
+	if !isEventEnabled(cfg, data.Event) {
+		return nil
+	}
+	notifiers := newNotifiers(cfg.Notify)
+	if len(notifiers) == 0 {
+		return nil
+	}
+	if data.Time.IsZero() {
+		data.Time = time.Now()
+	}
+	msg, err := render(cfg, data)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(notifiers))
+	wg := sync.WaitGroup{}
+	for i, n := range notifiers {
+		wg.Add(1)
+		go func(i int, n Notifier) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+			defer cancel()
+			if err := n.Send(ctx, msg); err != nil {
+				errs[i] = fmt.Errorf("%s 通知发送失败：%v", n.Name(), err)
+			}
+		}(i, n)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// isEventEnabled 判断事件是否需要通知。
+func isEventEnabled(cfg *configs.Config, event string) bool {
+	if len(cfg.Notify.Events) == 0 {
+		return true
+	}
+	for _, e := range cfg.Notify.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// isMuted 判断直播间是否屏蔽了事件的通知。
+func (m *manager) isMuted(l live.Live, event string) bool {
+	room, err := m.inst.Config.GetLiveRoomByUrl(l.GetRawUrl())
+	if err != nil {
+		return false
+	}
+	return room.IsNotifyMuted(event)
+}
+
+// liveData 根据直播间生成模板数据。
+func (m *manager) liveData(l live.Live, event string) *TemplateData {
+	data := &TemplateData{
+		Event:    event,
+		Time:     time.Now(),
+		LiveID:   l.GetLiveId(),
+		Platform: l.GetPlatformCNName(),
+		Url:      l.GetRawUrl(),
+	}
+	if m.inst.Cache != nil {
+		if obj, err := m.inst.Cache.Get(l); err == nil {
+			info := obj.(*live.Info)
+			data.HostName = info.HostName
+			data.RoomName = info.RoomName
+		}
+	}
+	return data
+}
+
+// send 发送通知并记录失败日志。
+func (m *manager) send(ctx context.Context, data *TemplateData) {
+	if err := m.Notify(ctx, data); err != nil {
+		m.inst.Logger.WithError(err).Warnf("发送 %s 通知失败", data.Event)
+	}
+}
+
+// onLiveStart 处理开播事件。
+func (m *manager) onLiveStart(ctx context.Context, l live.Live) {
+	if m.isMuted(l, configs.NotifyLiveStart) {
+		return
+	}
+	m.send(ctx, m.liveData(l, configs.NotifyLiveStart))
+}
+
+// onRecordError 处理录制失败事件，同一房间在 error_cooldown 内只通知一次。
+func (m *manager) onRecordError(ctx context.Context, param *live.ErrorParam) {
+	if m.isMuted(param.Live, configs.NotifyRecordError) {
+		return
+	}
+	id := param.Live.GetLiveId()
+	m.lock.Lock()
+	if last, ok := m.lastError[id]; ok && time.Since(last) < m.inst.Config.Notify.ErrorCooldown {
+		m.lock.Unlock()
+		return
+	}
+	m.lastError[id] = time.Now()
+	m.lock.Unlock()
+
+	data := m.liveData(param.Live, configs.NotifyRecordError)
+	data.Component = param.Component
+	if param.Err != nil {
+		data.Error = param.Err.Error()
+	}
+	m.send(ctx, data)
+}
+
+// runDiskCheck 周期性地检查输出路径的剩余空间。
+func (m *manager) runDiskCheck(ctx context.Context) {
+	interval := m.inst.Config.Notify.DiskCheckInterval
+	if interval <= 0 {
+		interval = configs.NewConfig().Notify.DiskCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.checkDisk(ctx)
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDisk 检查一次输出路径的剩余空间，低于阈值时通知，恢复后才会再次通知。
+func (m *manager) checkDisk(ctx context.Context) {
+	cfg := m.inst.Config
+	threshold := cfg.Notify.DiskLowThreshold
+	if threshold <= 0 {
+		return
+	}
+	free, err := utils.DiskFree(cfg.OutPutPath)
+	if err != nil {
+		m.inst.Logger.WithError(err).Debugf("获取磁盘剩余空间失败：%s", cfg.OutPutPath)
+		return
+	}
+	freeMB := free / 1024 / 1024
+
+	m.lock.Lock()
+	low := freeMB < uint64(threshold)
+	notify := low && !m.diskLow
+	m.diskLow = low
+	m.lock.Unlock()
+	if notify {
+		m.send(ctx, &TemplateData{
+			Event:       configs.NotifyDiskLow,
+			Path:        cfg.OutPutPath,
+			FreeMB:      freeMB,
+			ThresholdMB: threshold,
+		})
+	}
+}
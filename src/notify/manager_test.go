@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/bluele/gcache"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+)
+
+// newTestManager 创建一个向 Discord 桩发送通知的管理器。
+func newTestManager(t *testing.T, cfg *configs.Config) (*manager, chan stubRequest) {
+	server, requests := newHTTPStub(t, http.StatusNoContent, "")
+	cfg.Notify.Discord = configs.DiscordNotify{Enable: true, WebhookUrl: server.URL}
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Config: cfg,
+		Logger: &interfaces.Logger{Logger: logrus.New()},
+	})
+	return NewManager(ctx).(*manager), requests
+}
+
+// discordContent 读取 Discord 桩收到的消息内容，没有收到时返回空字符串。
+func discordContent(requests chan stubRequest) string {
+	select {
+	case req := <-requests:
+		body := make(map[string]string)
+		json.Unmarshal(req.Body, &body)
+		return body["content"]
+	default:
+		return ""
+	}
+}
+
+func TestManagerLiveStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetLiveId().Return(live.ID("a")).AnyTimes()
+	l.EXPECT().GetRawUrl().Return("https://live.bilibili.com/1").AnyTimes()
+	l.EXPECT().GetPlatformCNName().Return("哔哩哔哩").AnyTimes()
+
+	cfg := configs.NewConfig()
+	cfg.LiveRooms = []configs.LiveRoom{{Url: "https://live.bilibili.com/1"}}
+	cfg.Notify.Templates = map[string]configs.NotifyTemplate{
+		configs.NotifyLiveStart: {Body: `{{ .Platform }} {{ .Url | trimPrefix "https://" }}`},
+	}
+	m, requests := newTestManager(t, cfg)
+	m.inst.Cache = gcache.New(4).LRU().Build()
+	m.inst.Cache.Set(l, &live.Info{Live: l, HostName: "主播", RoomName: "房间"})
+	assert.NoError(t, parseTemplates(cfg))
+
+	m.onLiveStart(context.Background(), l)
+	assert.Equal(t, "**主播 开播了**\n哔哩哔哩 live.bilibili.com/1", discordContent(requests))
+
+	// 房间屏蔽通知
+	cfg.LiveRooms[0].MuteNotify = []string{configs.NotifyLiveStart}
+	m.onLiveStart(context.Background(), l)
+	assert.Equal(t, "", discordContent(requests))
+
+	// 事件未启用
+	cfg.LiveRooms[0].MuteNotify = nil
+	cfg.Notify.Events = []string{configs.NotifyDiskLow}
+	m.onLiveStart(context.Background(), l)
+	assert.Equal(t, "", discordContent(requests))
+
+	cfg.Notify.Templates[configs.NotifyLiveStart] = configs.NotifyTemplate{Title: "{{ .HostName "}
+	assert.Error(t, parseTemplates(cfg))
+}
+
+func TestManagerRecordErrorCooldown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetLiveId().Return(live.ID("a")).AnyTimes()
+	l.EXPECT().GetRawUrl().Return("https://live.bilibili.com/1").AnyTimes()
+	l.EXPECT().GetPlatformCNName().Return("哔哩哔哩").AnyTimes()
+
+	cfg := configs.NewConfig()
+	m, requests := newTestManager(t, cfg)
+	param := &live.ErrorParam{Live: l, Component: "recorder", Err: errors.New("boom")}
+
+	m.onRecordError(context.Background(), param)
+	assert.Equal(t, "**录制失败**\n[哔哩哔哩] ：\nboom", discordContent(requests))
+	// 冷却时间内不再通知
+	m.onRecordError(context.Background(), param)
+	assert.Equal(t, "", discordContent(requests))
+
+	cfg.Notify.ErrorCooldown = 0
+	m.onRecordError(context.Background(), param)
+	assert.Contains(t, discordContent(requests), "boom")
+}
+
+func TestManagerDiskLow(t *testing.T) {
+	cfg := configs.NewConfig()
+	cfg.OutPutPath = t.TempDir()
+	m, requests := newTestManager(t, cfg)
+
+	// 未设置阈值时不检查
+	m.checkDisk(context.Background())
+	assert.Equal(t, "", discordContent(requests))
+
+	cfg.Notify.DiskLowThreshold = 1 << 30
+	m.checkDisk(context.Background())
+	assert.Contains(t, discordContent(requests), "磁盘空间不足")
+	// 恢复前只通知一次
+	m.checkDisk(context.Background())
+	assert.Equal(t, "", discordContent(requests))
+
+	cfg.Notify.DiskLowThreshold = 1
+	m.checkDisk(context.Background())
+	cfg.Notify.DiskLowThreshold = 1 << 30
+	m.checkDisk(context.Background())
+	assert.Contains(t, discordContent(requests), "磁盘空间不足")
+}
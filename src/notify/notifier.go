@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// Message 是渲染后的通知消息。
+type Message struct {
+	Event string // 事件名
+	Title string // 标题
+	Body  string // 正文
+}
+
+// Notifier 定义通知渠道的接口。
+type Notifier interface {
+	// Name 返回通知渠道的名称。
+	Name() string
+	// Send 发送一条通知消息。
+	Send(ctx context.Context, msg *Message) error
+}
+
+// newNotifiers 根据配置创建所有启用的通知渠道。
+func newNotifiers(cfg configs.Notify) []Notifier {
+	notifiers := make([]Notifier, 0)
+	if cfg.Telegram.Enable {
+		notifiers = append(notifiers, &telegramNotifier{cfg: cfg.Telegram})
+	}
+	if cfg.Discord.Enable {
+		notifiers = append(notifiers, &discordNotifier{cfg: cfg.Discord})
+	}
+	if cfg.Email.Enable {
+		notifiers = append(notifiers, &emailNotifier{cfg: cfg.Email})
+	}
+	if cfg.ServerChan.Enable {
+		notifiers = append(notifiers, &serverChanNotifier{cfg: cfg.ServerChan})
+	}
+	if cfg.Bark.Enable {
+		notifiers = append(notifiers, &barkNotifier{cfg: cfg.Bark})
+	}
+	return notifiers
+}
+
+// postJSON 以 JSON 格式发送 POST 请求并返回响应内容。
+func postJSON(ctx context.Context, rawUrl string, body interface{}) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return post(ctx, rawUrl, "application/json", bytes.NewReader(b))
+}
+
+// post 发送 POST 请求并返回响应内容，响应状态码不是 2xx 时返回错误。
+func post(ctx context.Context, rawUrl, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("状态码 %d：%s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// joinMessage 将标题和正文合并为一段文本，用于不区分标题的通知渠道。
+func joinMessage(msg *Message) string {
+	if msg.Body == "" {
+		return msg.Title
+	}
+	if msg.Title == "" {
+		return msg.Body
+	}
+	return msg.Title + "\n" + msg.Body
+}
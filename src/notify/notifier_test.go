@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// stubRequest 记录 HTTP 桩收到的请求。
+type stubRequest struct {
+	Path        string
+	ContentType string
+	Body        []byte
+}
+
+// newHTTPStub 创建一个记录请求并返回固定响应的 HTTP 桩。
+func newHTTPStub(t *testing.T, status int, resp string) (*httptest.Server, chan stubRequest) {
+	requests := make(chan stubRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests <- stubRequest{Path: r.URL.Path, ContentType: r.Header.Get("Content-Type"), Body: b}
+		w.WriteHeader(status)
+		io.WriteString(w, resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var testMessage = &Message{Event: configs.NotifyLiveStart, Title: "主播 开播了", Body: "房间名"}
+
+func TestTelegramNotifier(t *testing.T) {
+	server, requests := newHTTPStub(t, http.StatusOK, `{"ok":true}`)
+	n := &telegramNotifier{cfg: configs.TelegramNotify{Token: "123:abc", ChatID: "42", ApiUrl: server.URL}}
+	assert.NoError(t, n.Send(context.Background(), testMessage))
+	req := <-requests
+	assert.Equal(t, "/bot123:abc/sendMessage", req.Path)
+	body := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(req.Body, &body))
+	assert.Equal(t, "42", body["chat_id"])
+	assert.Equal(t, "主播 开播了\n房间名", body["text"])
+
+	server, _ = newHTTPStub(t, http.StatusBadRequest, `{"ok":false,"description":"chat not found"}`)
+	n.cfg.ApiUrl = server.URL
+	assert.Error(t, n.Send(context.Background(), testMessage))
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	server, requests := newHTTPStub(t, http.StatusNoContent, "")
+	n := &discordNotifier{cfg: configs.DiscordNotify{WebhookUrl: server.URL + "/api/webhooks/1/token"}}
+	assert.NoError(t, n.Send(context.Background(), testMessage))
+	req := <-requests
+	assert.Equal(t, "/api/webhooks/1/token", req.Path)
+	assert.JSONEq(t, `{"content":"**主播 开播了**\n房间名"}`, string(req.Body))
+}
+
+func TestServerChanNotifier(t *testing.T) {
+	server, requests := newHTTPStub(t, http.StatusOK, `{"code":0,"message":""}`)
+	n := &serverChanNotifier{cfg: configs.ServerChanNotify{SendKey: "SCT1", ApiUrl: server.URL}}
+	assert.NoError(t, n.Send(context.Background(), testMessage))
+	req := <-requests
+	assert.Equal(t, "/SCT1.send", req.Path)
+	assert.Equal(t, "application/x-www-form-urlencoded", req.ContentType)
+	form, _ := url.ParseQuery(string(req.Body))
+	assert.Equal(t, "主播 开播了", form.Get("title"))
+	assert.Equal(t, "房间名", form.Get("desp"))
+
+	server, _ = newHTTPStub(t, http.StatusOK, `{"code":40001,"message":"bad key"}`)
+	n.cfg.ApiUrl = server.URL
+	assert.EqualError(t, n.Send(context.Background(), testMessage), "发送失败：bad key")
+}
+
+func TestBarkNotifier(t *testing.T) {
+	server, requests := newHTTPStub(t, http.StatusOK, `{"code":200,"message":"success"}`)
+	n := &barkNotifier{cfg: configs.BarkNotify{ServerUrl: server.URL, DeviceKey: "key", Group: "bililive"}}
+	assert.NoError(t, n.Send(context.Background(), testMessage))
+	req := <-requests
+	assert.Equal(t, "/push", req.Path)
+	assert.JSONEq(t, `{"device_key":"key","title":"主播 开播了","body":"房间名","group":"bililive"}`, string(req.Body))
+}
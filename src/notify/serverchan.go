@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+const serverChanApiUrl = "https://sctapi.ftqq.com"
+
+// serverChanNotifier 通过 Server酱 发送通知。
+type serverChanNotifier struct {
+	cfg configs.ServerChanNotify
+}
+
+// Name 返回通知渠道的名称。
+func (n *serverChanNotifier) Name() string {
+	return "server_chan"
+}
+
+// Send 调用 {send_key}.send 接口发送通知。
+func (n *serverChanNotifier) Send(ctx context.Context, msg *Message) error {
+	apiUrl := n.cfg.ApiUrl
+	if apiUrl == "" {
+		apiUrl = serverChanApiUrl
+	}
+	form := url.Values{}
+	form.Set("title", msg.Title)
+	form.Set("desp", msg.Body)
+	data, err := post(ctx, fmt.Sprintf("%s/%s.send", strings.TrimRight(apiUrl, "/"), n.cfg.SendKey),
+		"application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	if result := gjson.ParseBytes(data); result.Get("code").Int() != 0 {
+		return fmt.Errorf("发送失败：%s", result.Get("message").String())
+	}
+	return nil
+}
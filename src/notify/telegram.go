@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+const telegramApiUrl = "https://api.telegram.org"
+
+// telegramNotifier 通过 Telegram 机器人发送通知。
+type telegramNotifier struct {
+	cfg configs.TelegramNotify
+}
+
+// Name 返回通知渠道的名称。
+func (n *telegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Send 调用 sendMessage 接口发送通知。
+func (n *telegramNotifier) Send(ctx context.Context, msg *Message) error {
+	apiUrl := n.cfg.ApiUrl
+	if apiUrl == "" {
+		apiUrl = telegramApiUrl
+	}
+	data, err := postJSON(ctx, fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(apiUrl, "/"), n.cfg.Token), map[string]interface{}{
+		"chat_id":                  n.cfg.ChatID,
+		"text":                     joinMessage(msg),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	if result := gjson.ParseBytes(data); !result.Get("ok").Bool() {
+		return fmt.Errorf("发送失败：%s", result.Get("description").String())
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+// TemplateData 是渲染通知模板时可用的数据。
+type TemplateData struct {
+	Event       string    // 事件名
+	Time        time.Time // 事件发生时间
+	LiveID      live.ID   // 直播 ID
+	Platform    string    // 平台名称
+	HostName    string    // 主播名
+	RoomName    string    // 房间名
+	Url         string    // 直播间地址
+	Component   string    // 出错的组件，仅 record_error
+	Error       string    // 错误信息，仅 record_error
+	Path        string    // 检查的路径，仅 disk_low
+	FreeMB      uint64    // 剩余空间（MB），仅 disk_low
+	ThresholdMB int       // 通知阈值（MB），仅 disk_low
+}
+
+// defaultTemplates 是各事件的默认通知模板。
+var defaultTemplates = map[string]configs.NotifyTemplate{
+	configs.NotifyLiveStart: {
+		Title: "{{ .HostName }} 开播了",
+		Body:  "[{{ .Platform }}] {{ .HostName }}：{{ .RoomName }}\n{{ .Url }}",
+	},
+	configs.NotifyRecordError: {
+		Title: "{{ .HostName }} 录制失败",
+		Body:  "[{{ .Platform }}] {{ .HostName }}：{{ .RoomName }}\n{{ .Error }}",
+	},
+	configs.NotifyDiskLow: {
+		Title: "磁盘空间不足",
+		Body:  "{{ .Path }} 剩余 {{ .FreeMB }} MB，低于 {{ .ThresholdMB }} MB",
+	},
+}
+
+// getTemplate 返回事件的通知模板，未配置的部分使用默认模板。
+func getTemplate(cfg *configs.Config, event string) configs.NotifyTemplate {
+	tmpl := defaultTemplates[event]
+	if custom, ok := cfg.Notify.Templates[event]; ok {
+		if custom.Title != "" {
+			tmpl.Title = custom.Title
+		}
+		if custom.Body != "" {
+			tmpl.Body = custom.Body
+		}
+	}
+	return tmpl
+}
+
+// parseTemplates 解析所有事件的通知模板，用于在启动时检查模板是否有效。
+func parseTemplates(cfg *configs.Config) error {
+	for _, event := range configs.NotifyEvents {
+		tmpl := getTemplate(cfg, event)
+		for _, text := range []string{tmpl.Title, tmpl.Body} {
+			if _, err := template.New(event).Funcs(utils.GetFuncMap(cfg)).Parse(text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// render 使用事件的通知模板渲染消息。
+func render(cfg *configs.Config, data *TemplateData) (*Message, error) {
+	tmpl := getTemplate(cfg, data.Event)
+	title, err := execute(cfg, data.Event, tmpl.Title, data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := execute(cfg, data.Event, tmpl.Body, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Event: data.Event, Title: title, Body: body}, nil
+}
+
+// execute 渲染一段模板文本。
+func execute(cfg *configs.Config, name, text string, data *TemplateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(utils.GetFuncMap(cfg)).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
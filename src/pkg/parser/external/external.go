@@ -0,0 +1,248 @@
+// Package external 实现调用 streamlink 或 yt-dlp 下载直播流的解析器。
+// 外部工具直接处理直播间地址，标准输出写入录制文件，标准错误中的进度信息用于 Status。
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+)
+
+const (
+	StreamlinkName = configs.ParserStreamlink
+	YtDlpName      = configs.ParserYtDlp
+
+	// stopTimeout 是发送中断信号后等待外部工具退出的时间，超时后强制结束。
+	stopTimeout = 10 * time.Second
+)
+
+func init() {
+	parser.Register(StreamlinkName, &builder{tool: streamlink})
+	parser.Register(YtDlpName, &builder{tool: ytDlp})
+}
+
+// tool 描述一个外部下载工具。
+type tool struct {
+	name string
+	// args 返回将直播间地址输出到标准输出的命令行参数，cookieFile 为 cookie 文件的路径，没有 cookie 时为空
+	args func(ctx context.Context, cfg configs.ExternalTool, rawUrl, cookieFile string) []string
+	// cookieFile 返回写入 cookie 文件的内容
+	cookieFile func(rawUrl, cookie string) string
+	// parseProgress 解析标准错误中的一行输出，更新状态
+	parseProgress func(line string, status map[string]string)
+	// probe 检测直播间是否正在直播，cookieFile 与 args 相同
+	probe func(ctx context.Context, path, rawUrl, cookieFile string) (*ProbeResult, error)
+}
+
+type builder struct {
+	tool *tool
+}
+
+func (b *builder) Build(cfg map[string]string) (parser.Parser, error) {
+	return &Parser{
+		tool:   b.tool,
+		debug:  cfg["debug"] == "true",
+		status: make(map[string]string),
+		stopCh: make(chan struct{}),
+		exited: make(chan struct{}),
+	}, nil
+}
+
+// Parser 调用外部工具下载直播流。
+type Parser struct {
+	tool  *tool
+	debug bool
+
+	lock      sync.Mutex
+	cmd       *exec.Cmd
+	status    map[string]string // 外部工具输出的进度
+	written   int64             // 已写入录制文件的字节数
+	startTime time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	exited   chan struct{} // 外部工具退出后关闭
+}
+
+// toolConfig 返回外部工具的配置。
+func (p *Parser) toolConfig(cfg *configs.Config) configs.ExternalTool {
+	if p.tool.name == StreamlinkName {
+		return cfg.Parsers.Streamlink
+	}
+	return cfg.Parsers.YtDlp
+}
+
+// ParseLiveStream 使用外部工具下载直播间的直播流，外部工具自行解析直播间地址，streamUrl 不会被使用。
+func (p *Parser) ParseLiveStream(ctx context.Context, streamUrl *url.URL, l live.Live, file string) error {
+	inst := instance.GetInstance(ctx)
+	cfg := p.toolConfig(inst.Config)
+	path := cfg.Path
+	if path == "" {
+		path = p.tool.name
+	}
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return fmt.Errorf("找不到 %s：%w", p.tool.name, err)
+	}
+
+	rawUrl := l.GetRawUrl()
+	cookie := ""
+	if u, err := url.Parse(rawUrl); err == nil {
+		cookie = inst.Config.Cookies[u.Host]
+	}
+
+	cookieFile, removeCookieFile, err := p.tool.writeCookieFile(rawUrl, cookie)
+	if err != nil {
+		return err
+	}
+	defer removeCookieFile()
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cmd := exec.Command(path, p.tool.args(ctx, cfg, rawUrl, cookieFile)...)
+	cmd.Stdout = &countWriter{w: f, p: p}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	select {
+	case <-p.stopCh:
+		p.lock.Unlock()
+		return nil
+	default:
+	}
+	if inst.Config.Debug {
+		inst.Logger.Debugf("执行命令：%s", cmd.String())
+	}
+	if err := cmd.Start(); err != nil {
+		p.lock.Unlock()
+		return err
+	}
+	p.cmd = cmd
+	p.startTime = time.Now()
+	p.lock.Unlock()
+
+	p.scanProgress(stderr)
+	err = cmd.Wait()
+	close(p.exited)
+	select {
+	case <-p.stopCh:
+		// 停止时外部工具以中断信号退出，不视为错误
+		return nil
+	default:
+	}
+	return err
+}
+
+// scanProgress 读取外部工具的标准错误，解析进度直到管道关闭。
+func (p *Parser) scanProgress(r io.Reader) {
+	s := bufio.NewScanner(r)
+	s.Split(scanLines)
+	for s.Scan() {
+		line := string(bytes.TrimSpace(s.Bytes()))
+		if line == "" {
+			continue
+		}
+		if p.debug {
+			fmt.Fprintln(os.Stderr, line)
+		}
+		p.lock.Lock()
+		p.tool.parseProgress(line, p.status)
+		p.lock.Unlock()
+	}
+}
+
+// scanLines 按换行符或回车符分割输出，外部工具使用回车符刷新同一行的进度。
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// countWriter 统计写入录制文件的字节数。
+type countWriter struct {
+	w io.Writer
+	p *Parser
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.p.lock.Lock()
+	c.p.written += int64(n)
+	c.p.lock.Unlock()
+	return n, err
+}
+
+// Status 返回下载状态，total_size 和 bitrate 根据写入的字节数计算，其他字段来自外部工具的进度输出。
+func (p *Parser) Status() (map[string]string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	status := make(map[string]string, len(p.status)+4)
+	for k, v := range p.status {
+		status[k] = v
+	}
+	status["parser"] = p.tool.name
+	status["total_size"] = strconv.FormatInt(p.written, 10)
+	if !p.startTime.IsZero() {
+		elapsed := time.Since(p.startTime)
+		status["out_time"] = parser.FormatOutTime(elapsed)
+		if seconds := elapsed.Seconds(); seconds > 0 {
+			status["bitrate"] = fmt.Sprintf("%.1fkbits/s", float64(p.written)*8/1000/seconds)
+		}
+	}
+	return status, nil
+}
+
+// Stop 停止外部工具，先发送中断信号使其写完缓冲的数据，超时后强制结束。
+func (p *Parser) Stop() error {
+	p.stopOnce.Do(func() {
+		p.lock.Lock()
+		close(p.stopCh)
+		cmd := p.cmd
+		p.lock.Unlock()
+		if cmd == nil || cmd.Process == nil {
+			return
+		}
+		if runtime.GOOS == "windows" || cmd.Process.Signal(os.Interrupt) != nil {
+			cmd.Process.Kill()
+			return
+		}
+		go func() {
+			timer := time.NewTimer(stopTimeout)
+			defer timer.Stop()
+			select {
+			case <-p.exited:
+			case <-timer.C:
+				cmd.Process.Kill()
+			}
+		}()
+	})
+	return nil
+}
@@ -0,0 +1,156 @@
+package external
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+)
+
+func TestParseProgress(t *testing.T) {
+	status := make(map[string]string)
+	parseStreamlinkProgress("[cli][info] Opening stream: 1080p60 (hls)", status)
+	parseStreamlinkProgress("[download] Written 12.34 MiB to - (10s @ 1.23 MiB/s)", status)
+	assert.Equal(t, map[string]string{
+		"message":    "[cli][info] Opening stream: 1080p60 (hls)",
+		"downloaded": "12.34 MiB",
+		"elapsed":    "10s",
+		"speed":      "1.23 MiB/s",
+	}, status)
+
+	status = make(map[string]string)
+	parseYtDlpProgress("[youtube] abc: Downloading m3u8 information", status)
+	parseYtDlpProgress(ytDlpProgressPrefix+"downloaded_bytes=1024;speed=  1.00MiB/s;elapsed=2.5;fragment_index=NA", status)
+	assert.Equal(t, map[string]string{
+		"message":          "[youtube] abc: Downloading m3u8 information",
+		"downloaded_bytes": "1024",
+		"speed":            "1.00MiB/s",
+		"elapsed":          "2.5",
+	}, status)
+}
+
+func TestParseLiveStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	dir := t.TempDir()
+	// 模拟 streamlink：检查参数后向标准输出写入数据，向标准错误输出进度
+	script := filepath.Join(dir, "streamlink")
+	assert.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+prev=
+for arg in "$@"; do
+	echo "$arg" >> "$0.args"
+	if [ "$prev" = "--config" ]; then cat "$arg" > "$0.cookies"; fi
+	prev="$arg"
+done
+printf 'stream data'
+printf '[download] Written 11 B to - (1s @ 11 B/s)\r' >&2
+`), 0755))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetRawUrl().Return("https://www.twitch.tv/foo").AnyTimes()
+
+	cfg := configs.NewConfig()
+	cfg.Cookies = map[string]string{"www.twitch.tv": "a=1; b=2"}
+	cfg.Parsers.Streamlink = configs.ExternalTool{Path: script, Args: []string{"--twitch-low-latency"}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Config: cfg,
+		Logger: &interfaces.Logger{Logger: logger},
+	})
+
+	p, err := parser.New(StreamlinkName, map[string]string{})
+	assert.NoError(t, err)
+	file := filepath.Join(dir, "out.ts")
+	assert.NoError(t, p.ParseLiveStream(ctx, nil, l, file))
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "stream data", string(b))
+	b, err = os.ReadFile(script + ".args")
+	assert.NoError(t, err)
+	args := strings.Fields(string(b))
+	// cookie 通过临时的配置文件传递，不出现在命令行中，外部工具退出后删除
+	assert.Len(t, args, 10)
+	cookieFile := args[6]
+	assert.Equal(t, []string{
+		"--stdout", "--progress", "force", "--loglevel", "info",
+		"--config", cookieFile,
+		"--twitch-low-latency", "https://www.twitch.tv/foo", "best",
+	}, args)
+	assert.NoFileExists(t, cookieFile)
+	b, err = os.ReadFile(script + ".cookies")
+	assert.NoError(t, err)
+	assert.Equal(t, "http-cookie=a=1\nhttp-cookie=b=2\n", string(b))
+
+	status, err := p.(parser.StatusParser).Status()
+	assert.NoError(t, err)
+	assert.Equal(t, StreamlinkName, status["parser"])
+	assert.Equal(t, "11", status["total_size"])
+	assert.Equal(t, "11 B/s", status["speed"])
+	assert.NoError(t, p.Stop())
+}
+
+func TestYtDlpCookieFile(t *testing.T) {
+	assert.Equal(t, "# Netscape HTTP Cookie File\n"+
+		".youtube.com\tTRUE\t/\tFALSE\t0\tSID\ta=b\n"+
+		".youtube.com\tTRUE\t/\tFALSE\t0\tHSID\t1\n",
+		ytDlpCookieFile("https://www.youtube.com/@bar/live", "SID=a=b; HSID=1; invalid"))
+}
+
+func TestProbe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	dir := t.TempDir()
+	writeScript := func(name, body string) string {
+		script := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"+body), 0755))
+		return script
+	}
+	ctx := context.Background()
+
+	script := writeScript("streamlink", `echo '{"plugin":"twitch","metadata":{"author":"Foo","title":"Hello"},"streams":{"best":{}}}'`)
+	result, err := Probe(ctx, StreamlinkName, script, "https://www.twitch.tv/foo", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &ProbeResult{Living: true, HostName: "Foo", RoomName: "Hello"}, result)
+
+	script = writeScript("streamlink-offline", `echo '{"error":"No playable streams found on this URL: https://www.twitch.tv/foo"}'; exit 1`)
+	result, err = Probe(ctx, StreamlinkName, script, "https://www.twitch.tv/foo", "")
+	assert.NoError(t, err)
+	assert.False(t, result.Living)
+
+	script = writeScript("streamlink-error", `echo '{"error":"No plugin can handle URL: https://example.com/"}'; exit 1`)
+	_, err = Probe(ctx, StreamlinkName, script, "https://example.com/", "")
+	assert.EqualError(t, err, "streamlink：No plugin can handle URL: https://example.com/")
+
+	script = writeScript("yt-dlp", `echo '{"is_live":true,"live_status":"is_live","uploader":"Bar","title":"Live"}'`)
+	result, err = Probe(ctx, YtDlpName, script, "https://www.youtube.com/@bar/live", "a=1")
+	assert.NoError(t, err)
+	assert.Equal(t, &ProbeResult{Living: true, HostName: "Bar", RoomName: "Live"}, result)
+
+	script = writeScript("yt-dlp-offline", `echo 'ERROR: [youtube:tab] @bar: The channel is not currently live' >&2; exit 1`)
+	result, err = Probe(ctx, YtDlpName, script, "https://www.youtube.com/@bar/live", "")
+	assert.NoError(t, err)
+	assert.False(t, result.Living)
+
+	script = writeScript("yt-dlp-error", `echo 'ERROR: Unsupported URL: https://example.com/' >&2; exit 1`)
+	_, err = Probe(ctx, YtDlpName, script, "https://example.com/", "")
+	assert.EqualError(t, err, "yt-dlp：ERROR: Unsupported URL: https://example.com/")
+}
@@ -0,0 +1,106 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ytDlpOfflineRegexp 匹配 yt-dlp 在直播间未开播时的错误信息。
+var ytDlpOfflineRegexp = regexp.MustCompile(`(?i)not currently live|is offline|is not live|will begin|premieres in|live event has ended|no video formats found`)
+
+// ProbeResult 是外部工具检测直播间的结果。
+type ProbeResult struct {
+	Living   bool   // 是否正在直播
+	HostName string // 主播名，工具没有返回时为空
+	RoomName string // 直播标题，工具没有返回时为空
+}
+
+// Probe 使用外部工具检测直播间是否正在直播，path 为空时在 PATH 中查找工具。
+// 只有工具返回的信息能够表示未开播时才视为不在线，其他失败返回错误。
+func Probe(ctx context.Context, name, path, rawUrl, cookie string) (*ProbeResult, error) {
+	var t *tool
+	switch name {
+	case StreamlinkName:
+		t = streamlink
+	case YtDlpName:
+		t = ytDlp
+	default:
+		return nil, fmt.Errorf("不支持的外部工具：%s", name)
+	}
+	if path == "" {
+		path = t.name
+	}
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("找不到 %s：%w", t.name, err)
+	}
+	cookieFile, removeCookieFile, err := t.writeCookieFile(rawUrl, cookie)
+	if err != nil {
+		return nil, err
+	}
+	defer removeCookieFile()
+	return t.probe(ctx, path, rawUrl, cookieFile)
+}
+
+// probeStreamlink 使用 streamlink --json 获取直播间的直播流和元数据，没有可用的直播流时视为不在线。
+func probeStreamlink(ctx context.Context, path, rawUrl, cookieFile string) (*ProbeResult, error) {
+	args := []string{"--json"}
+	if cookieFile != "" {
+		args = append(args, "--config", cookieFile)
+	}
+	// 失败时 streamlink 也会在标准输出中输出包含 error 的 JSON
+	out, err := exec.CommandContext(ctx, path, append(args, rawUrl)...).Output()
+	result := gjson.ParseBytes(out)
+	if e := result.Get("error"); e.Exists() {
+		if strings.Contains(e.String(), "No playable streams") {
+			return &ProbeResult{}, nil
+		}
+		return nil, fmt.Errorf("streamlink：%s", e.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("streamlink：%w", err)
+	}
+	return &ProbeResult{
+		Living:   len(result.Get("streams").Map()) > 0,
+		HostName: result.Get("metadata.author").String(),
+		RoomName: result.Get("metadata.title").String(),
+	}, nil
+}
+
+// probeYtDlp 使用 yt-dlp 获取直播间的信息，错误信息表示未开播或 live_status 不是 is_live 时视为不在线。
+func probeYtDlp(ctx context.Context, path, rawUrl, cookieFile string) (*ProbeResult, error) {
+	args := []string{"--dump-single-json", "--no-playlist", "--no-warnings"}
+	if cookieFile != "" {
+		args = append(args, "--cookies", cookieFile)
+	}
+	out, err := exec.CommandContext(ctx, path, append(args, rawUrl)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			msg := strings.TrimSpace(string(exitErr.Stderr))
+			if ytDlpOfflineRegexp.MatchString(msg) {
+				return &ProbeResult{}, nil
+			}
+			if msg != "" {
+				return nil, fmt.Errorf("yt-dlp：%s", msg)
+			}
+		}
+		return nil, fmt.Errorf("yt-dlp：%w", err)
+	}
+	result := gjson.ParseBytes(out)
+	hostName := result.Get("uploader").String()
+	if hostName == "" {
+		hostName = result.Get("channel").String()
+	}
+	return &ProbeResult{
+		Living:   result.Get("is_live").Bool() || result.Get("live_status").String() == "is_live",
+		HostName: hostName,
+		RoomName: result.Get("title").String(),
+	}, nil
+}
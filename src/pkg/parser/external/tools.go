@@ -0,0 +1,148 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+var (
+	streamlink = &tool{
+		name:          StreamlinkName,
+		args:          streamlinkArgs,
+		cookieFile:    streamlinkCookieFile,
+		parseProgress: parseStreamlinkProgress,
+		probe:         probeStreamlink,
+	}
+	ytDlp = &tool{
+		name:          YtDlpName,
+		args:          ytDlpArgs,
+		cookieFile:    ytDlpCookieFile,
+		parseProgress: parseYtDlpProgress,
+		probe:         probeYtDlp,
+	}
+
+	// streamlinkProgressRegexp 匹配 streamlink 的进度，如 [download] Written 12.34 MiB to - (10s @ 1.23 MiB/s)
+	streamlinkProgressRegexp = regexp.MustCompile(`Written (.+?) to .*\((.+?) @ (.+?)\)`)
+)
+
+// ytDlpProgressPrefix 是 yt-dlp 进度模板输出的前缀。
+const ytDlpProgressPrefix = "bililive-progress:"
+
+// streamlinkArgs 返回 streamlink 的命令行参数，以最高画质输出到标准输出。
+func streamlinkArgs(ctx context.Context, cfg configs.ExternalTool, rawUrl, cookieFile string) []string {
+	args := []string{"--stdout", "--progress", "force", "--loglevel", "info"}
+	if cookieFile != "" {
+		args = append(args, "--config", cookieFile)
+	}
+	args = append(args, cfg.Args...)
+	return append(args, rawUrl, "best")
+}
+
+// streamlinkCookieFile 返回 streamlink 配置文件格式的 cookie，每个 cookie 一行 http-cookie 选项。
+func streamlinkCookieFile(rawUrl, cookie string) string {
+	b := new(strings.Builder)
+	for _, kv := range strings.Split(cookie, ";") {
+		if kv = strings.TrimSpace(kv); kv != "" {
+			fmt.Fprintf(b, "http-cookie=%s\n", kv)
+		}
+	}
+	return b.String()
+}
+
+// ytDlpArgs 返回 yt-dlp 的命令行参数，从直播开始的位置输出到标准输出，进度按固定格式输出到标准错误。
+func ytDlpArgs(ctx context.Context, cfg configs.ExternalTool, rawUrl, cookieFile string) []string {
+	args := []string{
+		"--output", "-",
+		"--no-part", "--no-playlist",
+		"--newline", "--progress",
+		"--progress-template", "download:" + ytDlpProgressPrefix +
+			"downloaded_bytes=%(progress.downloaded_bytes)s;speed=%(progress._speed_str)s;elapsed=%(progress.elapsed)s;fragment_index=%(progress.fragment_index)s",
+	}
+	if ffmpeg, err := utils.GetFFmpegPath(ctx); err == nil {
+		args = append(args, "--ffmpeg-location", ffmpeg)
+	}
+	if cookieFile != "" {
+		args = append(args, "--cookies", cookieFile)
+	}
+	args = append(args, cfg.Args...)
+	return append(args, rawUrl)
+}
+
+// ytDlpCookieFile 返回 Netscape 格式的 cookie 文件，cookie 对直播间域名及其子域名有效。
+func ytDlpCookieFile(rawUrl, cookie string) string {
+	domain := ""
+	if u, err := url.Parse(rawUrl); err == nil {
+		domain = "." + strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	b := new(strings.Builder)
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, kv := range strings.Split(cookie, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok || k == "" {
+			continue
+		}
+		fmt.Fprintf(b, "%s\tTRUE\t/\tFALSE\t0\t%s\t%s\n", domain, k, v)
+	}
+	return b.String()
+}
+
+// writeCookieFile 将 cookie 写入只有当前用户可读的临时文件，通过文件传递的 cookie 不会出现在命令行和日志中。
+// cookie 为空时返回空路径，调用方在外部工具退出后调用 remove 删除文件。
+func (t *tool) writeCookieFile(rawUrl, cookie string) (file string, remove func(), err error) {
+	if strings.TrimSpace(cookie) == "" {
+		return "", func() {}, nil
+	}
+	f, err := os.CreateTemp("", t.name+"-cookies-*.txt")
+	if err != nil {
+		return "", nil, err
+	}
+	remove = func() { os.Remove(f.Name()) }
+	if _, err = f.WriteString(t.cookieFile(rawUrl, cookie)); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
+	return f.Name(), remove, nil
+}
+
+// parseStreamlinkProgress 解析 streamlink 的输出，进度行更新已下载大小、时长和速度，其他行作为最后的消息。
+func parseStreamlinkProgress(line string, status map[string]string) {
+	if m := streamlinkProgressRegexp.FindStringSubmatch(line); m != nil {
+		status["downloaded"] = m[1]
+		status["elapsed"] = m[2]
+		status["speed"] = m[3]
+		return
+	}
+	status["message"] = line
+}
+
+// parseYtDlpProgress 解析 yt-dlp 的输出，进度模板中的字段直接写入状态，NA 表示不可用，其他行作为最后的消息。
+func parseYtDlpProgress(line string, status map[string]string) {
+	if !strings.HasPrefix(line, ytDlpProgressPrefix) {
+		status["message"] = line
+		return
+	}
+	for _, kv := range strings.Split(strings.TrimPrefix(line, ytDlpProgressPrefix), ";") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if v == "" || v == "NA" {
+			delete(status, k)
+			continue
+		}
+		status[k] = v
+	}
+}
@@ -4,14 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +26,12 @@ const (
 	userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/59.0.3071.115 Safari/537.36"
 )
 
+// statusTimeout 是获取 FFmpeg 状态的最长等待时间，需要大于调度程序等待状态输出的 3 秒，测试中会修改
+var statusTimeout = 5 * time.Second
+
+// ErrStatusUnavailable 表示 FFmpeg 未启动、已经退出或没有及时输出状态
+var ErrStatusUnavailable = errors.New("FFmpeg 状态不可用")
+
 func init() {
 	parser.Register(Name, new(builder))
 }
@@ -44,6 +49,10 @@ func (b *builder) Build(cfg map[string]string) (parser.Parser, error) {
 		statusReq:   make(chan struct{}, 1),
 		statusResp:  make(chan map[string]string, 1),
 		timeoutInUs: cfg["timeout_in_us"],
+		audioOnly:   cfg["audio_only"] == "true",
+		audioFormat: cfg["audio_format"],
+
+		transcodeProfile: cfg["transcode_profile"],
 	}, nil
 }
 
@@ -54,6 +63,10 @@ type Parser struct {
 	closeOnce   *sync.Once
 	debug       bool
 	timeoutInUs string
+	audioOnly   bool   // 只录制音频
+	audioFormat string // 只录制音频时的格式
+
+	transcodeProfile string // 录制时使用的转码配置名称，为空时不转码
 
 	statusReq  chan struct{}
 	statusResp chan map[string]string
@@ -112,9 +125,9 @@ func (p *Parser) scheduler() {
 				if !ok {
 					return
 				}
-				p.statusResp <- p.decodeFFmpegStatus(b)
+				p.respondStatus(p.decodeFFmpegStatus(b))
 			case <-time.After(time.Second * 3):
-				p.statusResp <- nil
+				p.respondStatus(nil)
 			}
 		default:
 			if _, ok := <-statusCh; !ok {
@@ -124,11 +137,58 @@ func (p *Parser) scheduler() {
 	}
 }
 
-// Status 获取FFmpeg的状态信息
+// respondStatus 返回状态，请求方已经超时而没有取走上一次的状态时丢弃，避免阻塞读取 FFmpeg 的输出。
+func (p *Parser) respondStatus(status map[string]string) {
+	select {
+	case p.statusResp <- status:
+	default:
+	}
+}
+
+// Status 获取FFmpeg的状态信息，不会阻塞超过 statusTimeout。
+// 调度程序未启动（如 FFmpeg 启动失败）、已经退出或没有及时响应时返回 ErrStatusUnavailable。
 func (p *Parser) Status() (map[string]string, error) {
-	// TODO: 检查解析器是否正在运行
-	p.statusReq <- struct{}{}
-	return <-p.statusResp, nil
+	select {
+	case p.statusReq <- struct{}{}:
+	default:
+		// 上一次请求还没有被处理
+		return nil, ErrStatusUnavailable
+	}
+	select {
+	case status, ok := <-p.statusResp:
+		if !ok {
+			return nil, ErrStatusUnavailable
+		}
+		return status, nil
+	case <-time.After(statusTimeout):
+		return nil, ErrStatusUnavailable
+	}
+}
+
+// outputArgs 返回不转码时的输出参数，只录制音频时丢弃视频并按格式输出。
+func (p *Parser) outputArgs() []string {
+	if !p.audioOnly {
+		return []string{
+			"-c", "copy", // 不转码
+			"-bsf:a", "aac_adtstoasc", // 音频比特流过滤器
+			"-f", "flv",
+		}
+	}
+	switch p.audioFormat {
+	case "m4a":
+		// 使用分片 MP4，录制中断时已写入的部分仍可播放
+		return []string{
+			"-vn", "-c:a", "copy",
+			"-bsf:a", "aac_adtstoasc",
+			"-f", "mp4", "-movflags", "empty_moov+default_base_moof", "-frag_duration", "5000000",
+		}
+	case "opus":
+		return []string{"-vn", "-c:a", "libopus", "-b:a", "64k", "-f", "ogg"}
+	case "flv":
+		return []string{"-vn", "-c:a", "copy", "-bsf:a", "aac_adtstoasc", "-f", "flv"}
+	default:
+		return []string{"-vn", "-c:a", "copy", "-f", "adts"}
+	}
 }
 
 // ParseLiveStream 解析直播流
@@ -138,25 +198,21 @@ func (p *Parser) ParseLiveStream(ctx context.Context, url *url.URL, live live.Li
 		return err
 	}
 
-	encoder := "no"
-
-	// 不编码
-	args := []string{
-		"-nostats",       // 禁止显示统计信息
-		"-progress", "-", // 将进度信息输出到标准输出
-		"-y", "-re", // 覆盖输出
-		"-user_agent", userAgent, // UA
-		"-referer", live.GetRawUrl(), // 直播间地址
-		"-rw_timeout", p.timeoutInUs, // 读写超时
-		"-i", url.String(), // 直播流
-		"-c", "copy", // 不转码
-		"-bsf:a", "aac_adtstoasc", // 音频比特流过滤器
-		"-f", "flv",
+	inst := instance.GetInstance(ctx)
+	outputArgs := p.outputArgs()
+	args := make([]string, 0, 32)
+	if p.transcodeProfile != "" {
+		profile, ok := inst.Config.GetTranscodeProfile(p.transcodeProfile)
+		if !ok {
+			return fmt.Errorf("转码配置 %s 不存在", p.transcodeProfile)
+		}
+		// 直播流的音频编码未知，复制到 MP4 时由 FFmpeg 按需插入 aac_adtstoasc
+		globalArgs, transcodeArgs := TranscodeArgs(profile, profile.GetFormat(), "")
+		args = append(args, globalArgs...)
+		outputArgs = append(transcodeArgs, FormatArgs(profile.GetFormat())...)
 	}
 
-	// hevc_vaapi
-	hevcArgs := []string{
-		"-vaapi_device", "/dev/dri/renderD129", // 指定加速卡
+	args = append(args,
 		"-nostats",       // 禁止显示统计信息
 		"-progress", "-", // 将进度信息输出到标准输出
 		"-y", "-re", // 覆盖输出
@@ -164,59 +220,14 @@ func (p *Parser) ParseLiveStream(ctx context.Context, url *url.URL, live live.Li
 		"-referer", live.GetRawUrl(), // 直播间地址
 		"-rw_timeout", p.timeoutInUs, // 读写超时
 		"-i", url.String(), // 直播流
-		"-vf", "format=nv12,hwupload", // 启用硬件上传
-		"-c:v", "hevc_vaapi", // 视频编码器
-		"-b:v", "5M", // 码率
-		"-c:a", "aac", // 音频编码器
-		"-bsf:a", "aac_adtstoasc", // 音频比特流过滤器
-	}
+	)
+	args = append(args, outputArgs...)
 
-	// h264_vaapi
-	h264Args := []string{
-		"-vaapi_device", "/dev/dri/renderD129", // 指定加速卡
-		"-nostats",       // 禁止显示统计信息
-		"-progress", "-", // 将进度信息输出到标准输出
-		"-y", "-re", // 覆盖输出
-		"-user_agent", userAgent, // UA
-		"-referer", live.GetRawUrl(), // 直播间地址
-		"-rw_timeout", p.timeoutInUs, // 读写超时
-		"-i", url.String(), // 直播流
-		"-vf", "format=nv12,hwupload", // 启用硬件上传
-		"-c:v", "h264_vaapi", // 视频编码器
-		"-b:v", "8M", // 码率
-		"-c:a", "aac", // 音频编码器
-		"-bsf:a", "aac_adtstoasc", // 音频比特流过滤器
-	}
-
-	fileName := strings.TrimSuffix(file, filepath.Ext(file))
-	fileName = strings.TrimSuffix(fileName, "_%03d")
-
-	if encoder == "hevc" {
-		args = hevcArgs
-		file = fileName + ".mp4"
-	} else if encoder == "h264" {
-		args = h264Args
-	} else if encoder == "cache" {
-		m3u8 := fileName + ".m3u8"
-		args = append(args, "-f", "segment")       // 切片
-		args = append(args, "-segment_time", "60") // 切片时长
-		args = append(args, "-segment_wrap", "10") // 切片循环
-		args = append(args, "-segment_list", m3u8) // 切片列表
-		// args = append(args, "-fs", "50M")          // 切片大小
-	}
-
-	if fileName == filepath.Ext(file) {
-		args = append(args, "-buffer_size", "250M") // 缓存
-	}
-
-	if encoder != "cache" {
-		inst := instance.GetInstance(ctx)
-		MaxFileSize := inst.Config.VideoSplitStrategies.MaxFileSize
-		if MaxFileSize < 0 {
-			inst.Logger.Infof("无效的MaxFileSize：%d", MaxFileSize)
-		} else if MaxFileSize > 0 {
-			args = append(args, "-fs", strconv.Itoa(MaxFileSize))
-		}
+	MaxFileSize := inst.Config.VideoSplitStrategies.MaxFileSize
+	if MaxFileSize < 0 {
+		inst.Logger.Infof("无效的MaxFileSize：%d", MaxFileSize)
+	} else if MaxFileSize > 0 {
+		args = append(args, "-fs", strconv.Itoa(MaxFileSize))
 	}
 
 	args = append(args, file)
@@ -239,13 +250,23 @@ func (p *Parser) ParseLiveStream(ctx context.Context, url *url.URL, live live.Li
 		return err
 	}
 	go p.scheduler()
-	return p.cmd.Wait()
+	if err = p.cmd.Wait(); err != nil {
+		return err
+	}
+	// FFmpeg 写满 -fs 指定的大小后正常退出
+	if MaxFileSize > 0 {
+		if fi, err := os.Stat(file); err == nil && fi.Size() >= int64(MaxFileSize) {
+			return parser.ErrSplit
+		}
+	}
+	return nil
 }
 
 // Stop 停止解析器
 func (p *Parser) Stop() error {
 	p.closeOnce.Do(func() {
-		if p.cmd.ProcessState == nil {
+		// FFmpeg 启动前失败时没有可以停止的进程
+		if p.cmd != nil && p.cmdStdIn != nil && p.cmd.ProcessState == nil {
 			p.cmdStdIn.Write([]byte("q"))
 		}
 	})
@@ -0,0 +1,32 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusNotRunning(t *testing.T) {
+	timeout := statusTimeout
+	statusTimeout = 10 * time.Millisecond
+	defer func() { statusTimeout = timeout }()
+
+	// FFmpeg 启动失败时调度程序没有启动，请求超时后不再阻塞
+	b, _ := new(builder).Build(nil)
+	p := b.(*Parser)
+	_, err := p.Status()
+	assert.ErrorIs(t, err, ErrStatusUnavailable)
+	_, err = p.Status()
+	assert.ErrorIs(t, err, ErrStatusUnavailable)
+	assert.NoError(t, p.Stop())
+
+	// FFmpeg 退出后调度程序关闭了响应通道
+	b, _ = new(builder).Build(nil)
+	p = b.(*Parser)
+	close(p.statusResp)
+	for i := 0; i < 2; i++ {
+		_, err = p.Status()
+		assert.ErrorIs(t, err, ErrStatusUnavailable)
+	}
+}
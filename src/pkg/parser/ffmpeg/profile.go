@@ -0,0 +1,97 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// TranscodeArgs 根据转码配置生成 FFmpeg 参数，globalArgs 需要放在输入之前，outputArgs 放在输出文件之前，不包含输出格式。
+// 复制音频到 MP4 且源音频为 AAC 时添加 aac_adtstoasc，sourceAudio 为源音频的编码，未知时为空字符串。
+func TranscodeArgs(p *configs.TranscodeProfile, format, sourceAudio string) (globalArgs, outputArgs []string) {
+	if p.GetCodec() == configs.CodecCopy {
+		outputArgs = append(outputArgs, "-c:v", "copy")
+	} else {
+		if p.IsVaapi() {
+			globalArgs = append(globalArgs, "-vaapi_device", p.GetDevice()) // 指定加速卡
+		}
+		if filter := videoFilter(p); filter != "" {
+			outputArgs = append(outputArgs, "-vf", filter)
+		}
+		outputArgs = append(outputArgs, "-c:v", p.GetEncoder())
+		if p.Bitrate != "" {
+			outputArgs = append(outputArgs, "-b:v", p.Bitrate)
+		}
+		if p.Crf > 0 {
+			outputArgs = append(outputArgs, "-crf", strconv.Itoa(p.Crf))
+		}
+		if p.Preset != "" {
+			outputArgs = append(outputArgs, "-preset", p.Preset)
+		}
+	}
+
+	switch p.AudioCodec {
+	case "", "copy":
+		outputArgs = append(outputArgs, "-c:a", "copy")
+		if format == configs.FormatMp4 && sourceAudio == "aac" {
+			outputArgs = append(outputArgs, "-bsf:a", "aac_adtstoasc")
+		}
+	case "none":
+		outputArgs = append(outputArgs, "-an")
+	default:
+		outputArgs = append(outputArgs, "-c:a", p.AudioCodec)
+		if p.AudioBitrate != "" {
+			outputArgs = append(outputArgs, "-b:a", p.AudioBitrate)
+		}
+	}
+	outputArgs = append(outputArgs, p.ExtraArgs...)
+	return globalArgs, outputArgs
+}
+
+// AudioCodec 使用 ffprobe 返回文件中第一个音频流的编码名称，如 aac，没有音频时返回空字符串。
+func AudioCodec(ctx context.Context, ffprobe, file string) (string, error) {
+	out, err := exec.CommandContext(ctx, ffprobe,
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=codec_name",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		file,
+	).Output()
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// videoFilter 返回缩放和上传到硬件的视频滤镜，不需要时返回空字符串。
+func videoFilter(p *configs.TranscodeProfile) string {
+	filters := make([]string, 0, 2)
+	w, h, scale := strings.Cut(p.Resolution, "x")
+	if p.IsVaapi() {
+		filters = append(filters, "format=nv12,hwupload") // 启用硬件上传
+		if scale {
+			filters = append(filters, "scale_vaapi=w="+w+":h="+h)
+		}
+	} else if scale {
+		filters = append(filters, "scale="+w+":"+h)
+	}
+	return strings.Join(filters, ",")
+}
+
+// FormatArgs 返回录制直播时各输出格式的参数，MP4 使用分片格式，录制中断时已写入的部分仍可播放。
+func FormatArgs(format string) []string {
+	switch format {
+	case configs.FormatMkv:
+		return []string{"-f", "matroska"}
+	case configs.FormatMp4:
+		return []string{"-f", "mp4", "-movflags", "empty_moov+default_base_moof", "-frag_duration", "5000000"}
+	case configs.FormatTs:
+		return []string{"-f", "mpegts"}
+	default:
+		return []string{"-f", "flv"}
+	}
+}
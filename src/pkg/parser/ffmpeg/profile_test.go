@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+func TestTranscodeArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile configs.TranscodeProfile
+		format  string
+		audio   string
+		global  []string
+		output  []string
+	}{
+		{
+			name:    "不转码",
+			profile: configs.TranscodeProfile{},
+			format:  configs.FormatFlv,
+			audio:   "aac",
+			output:  []string{"-c:v", "copy", "-c:a", "copy"},
+		},
+		{
+			name:    "复制 AAC 音频到 MP4",
+			profile: configs.TranscodeProfile{},
+			format:  configs.FormatMp4,
+			audio:   "aac",
+			output:  []string{"-c:v", "copy", "-c:a", "copy", "-bsf:a", "aac_adtstoasc"},
+		},
+		{
+			name:    "复制其他音频到 MP4",
+			profile: configs.TranscodeProfile{},
+			format:  configs.FormatMp4,
+			audio:   "opus",
+			output:  []string{"-c:v", "copy", "-c:a", "copy"},
+		},
+		{
+			name:    "软件编码",
+			profile: configs.TranscodeProfile{Codec: configs.CodecHEVC, Crf: 28, Preset: "veryfast", Resolution: "-2x720", AudioCodec: "aac", AudioBitrate: "128k"},
+			output:  []string{"-vf", "scale=-2:720", "-c:v", "libx265", "-crf", "28", "-preset", "veryfast", "-c:a", "aac", "-b:a", "128k"},
+		},
+		{
+			name:    "VAAPI",
+			profile: configs.TranscodeProfile{Codec: configs.CodecHEVC, Encoder: "hevc_vaapi", Device: "/dev/dri/renderD129", Bitrate: "5M", AudioCodec: "none", ExtraArgs: []string{"-g", "120"}},
+			global:  []string{"-vaapi_device", "/dev/dri/renderD129"},
+			output:  []string{"-vf", "format=nv12,hwupload", "-c:v", "hevc_vaapi", "-b:v", "5M", "-an", "-g", "120"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global, output := TranscodeArgs(&tt.profile, tt.format, tt.audio)
+			assert.Equal(t, tt.global, global)
+			assert.Equal(t, tt.output, output)
+		})
+	}
+}
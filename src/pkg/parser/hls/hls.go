@@ -0,0 +1,285 @@
+// Package hls 实现下载 HLS 直播流的解析器，分片按顺序写入同一个文件，插入的广告分片会被跳过。
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+)
+
+const (
+	Name = configs.ParserHls
+
+	userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/59.0.3071.115 Safari/537.36"
+
+	// requestTimeout 是请求播放列表和分片的超时时间。
+	requestTimeout = 30 * time.Second
+	// retryCount 是播放列表连续请求失败的最大次数。
+	retryCount = 3
+	// staleTargetDurations 是播放列表连续多少个目标时长没有新分片时视为直播结束。
+	staleTargetDurations = 6
+)
+
+var ErrStale = errors.New("播放列表已停止更新")
+
+func init() {
+	parser.Register(Name, new(builder))
+}
+
+type builder struct{}
+
+func (b *builder) Build(cfg map[string]string) (parser.Parser, error) {
+	timeout := requestTimeout
+	if us, err := strconv.Atoi(cfg["timeout_in_us"]); err == nil && us > 0 {
+		timeout = time.Duration(us) * time.Microsecond
+	}
+	return &Parser{
+		hc:     &http.Client{Timeout: timeout},
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// Parser 下载 HLS 直播流。
+type Parser struct {
+	hc *http.Client
+
+	lock      sync.Mutex
+	written   int64   // 已写入的字节数
+	segments  int     // 已写入的分片数
+	ads       int     // 跳过的广告分片数
+	duration  float64 // 已写入分片的总时长（秒）
+	startTime time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// ParseLiveStream 下载直播流直到直播结束或解析器停止。
+func (p *Parser) ParseLiveStream(ctx context.Context, streamUrl *url.URL, l live.Live, file string) error {
+	logger := instance.GetInstance(ctx).Logger
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p.lock.Lock()
+	p.startTime = time.Now()
+	p.lock.Unlock()
+
+	mediaUrl, err := p.mediaPlaylistUrl(streamUrl)
+	if err != nil {
+		return err
+	}
+
+	var (
+		lastSeq    int64 = -1
+		lastMap    string
+		failures   int
+		lastUpdate = time.Now()
+		inAd       bool
+	)
+	for {
+		pl, err := p.fetchPlaylist(mediaUrl)
+		if err != nil {
+			if errors.Is(err, ErrEncrypted) || errors.Is(err, ErrNotPlaylist) {
+				return err
+			}
+			if failures++; failures >= retryCount {
+				return err
+			}
+		} else {
+			failures = 0
+			for _, seg := range pl.Segments {
+				if seg.Sequence <= lastSeq {
+					continue
+				}
+				lastSeq = seg.Sequence
+				lastUpdate = time.Now()
+				if seg.Ad {
+					if !inAd {
+						logger.Infof("跳过广告分片：%s", seg.Url)
+					}
+					inAd = true
+					p.lock.Lock()
+					p.ads++
+					p.lock.Unlock()
+					continue
+				}
+				inAd = false
+				if seg.Map != nil && seg.Map.String() != lastMap {
+					if err := p.download(seg.Map, f); err != nil {
+						return err
+					}
+					lastMap = seg.Map.String()
+				}
+				if err := p.download(seg.Url, f); err != nil {
+					if p.stopped() {
+						return nil
+					}
+					// 单个分片下载失败时跳过，不中断录制
+					logger.WithError(err).Warnf("下载分片失败：%s", seg.Url)
+					continue
+				}
+				p.lock.Lock()
+				p.segments++
+				p.duration += seg.Duration
+				p.lock.Unlock()
+			}
+			if pl.EndList {
+				return nil
+			}
+		}
+
+		target := 2 * time.Second
+		if pl != nil && pl.TargetDuration > 0 {
+			target = time.Duration(pl.TargetDuration * float64(time.Second))
+		}
+		if time.Since(lastUpdate) > staleTargetDurations*target {
+			return ErrStale
+		}
+		select {
+		case <-p.stopCh:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(reloadInterval(pl, target)):
+		}
+	}
+}
+
+// reloadInterval 返回重新请求播放列表的间隔，包含预取分片的低延迟播放列表需要更频繁地刷新。
+func reloadInterval(pl *Playlist, target time.Duration) time.Duration {
+	if pl != nil {
+		for _, seg := range pl.Segments {
+			if seg.Prefetch {
+				return time.Second
+			}
+		}
+	}
+	if interval := target / 2; interval > time.Second {
+		return interval
+	}
+	return time.Second
+}
+
+// mediaPlaylistUrl 返回媒体播放列表的地址，主播放列表中选择码率最高的一个。
+func (p *Parser) mediaPlaylistUrl(u *url.URL) (*url.URL, error) {
+	pl, err := p.fetchPlaylist(u)
+	if err != nil {
+		return nil, err
+	}
+	if len(pl.Variants) == 0 {
+		return u, nil
+	}
+	best := pl.Variants[0]
+	for _, v := range pl.Variants[1:] {
+		if v.Bandwidth > best.Bandwidth {
+			best = v
+		}
+	}
+	return best.Url, nil
+}
+
+// get 发送 GET 请求，状态码不是 200 时返回错误。
+func (p *Parser) get(u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := p.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("请求 %s 失败：%s", u, resp.Status)
+	}
+	return resp, nil
+}
+
+// fetchPlaylist 请求并解析播放列表。
+func (p *Parser) fetchPlaylist(u *url.URL) (*Playlist, error) {
+	resp, err := p.get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlaylist(b, resp.Request.URL)
+}
+
+// download 下载分片并追加到文件。
+func (p *Parser) download(u *url.URL, w io.Writer) error {
+	resp, err := p.get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 先完整读取分片，避免下载中断时写入不完整的数据
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	n, err := w.Write(b)
+	p.lock.Lock()
+	p.written += int64(n)
+	p.lock.Unlock()
+	return err
+}
+
+// stopped 判断解析器是否已停止。
+func (p *Parser) stopped() bool {
+	select {
+	case <-p.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status 返回下载状态。
+func (p *Parser) Status() (map[string]string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	status := map[string]string{
+		"parser":      Name,
+		"total_size":  strconv.FormatInt(p.written, 10),
+		"segments":    strconv.Itoa(p.segments),
+		"ad_segments": strconv.Itoa(p.ads),
+		"out_time":    parser.FormatOutTime(time.Duration(p.duration * float64(time.Second))),
+	}
+	if p.duration > 0 {
+		status["bitrate"] = fmt.Sprintf("%.1fkbits/s", float64(p.written)*8/1000/p.duration)
+	}
+	if !p.startTime.IsZero() {
+		if elapsed := time.Since(p.startTime).Seconds(); elapsed > 0 {
+			status["speed"] = fmt.Sprintf("%.3gx", p.duration/elapsed)
+		}
+	}
+	return status, nil
+}
+
+// Stop 停止下载，正在下载的分片写完后返回。
+func (p *Parser) Stop() error {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	return nil
+}
@@ -0,0 +1,124 @@
+package hls
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+)
+
+func readPlaylist(t *testing.T, name, base string) *Playlist {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	assert.NoError(t, err)
+	u, _ := url.Parse(base)
+	pl, err := ParsePlaylist(b, u)
+	assert.NoError(t, err)
+	return pl
+}
+
+func TestParseMasterPlaylist(t *testing.T) {
+	pl := readPlaylist(t, "master.m3u8", "https://usher.example.net/api/channel/hls/foo.m3u8")
+	assert.Empty(t, pl.Segments)
+	assert.Equal(t, []Variant{
+		{Url: &url.URL{Scheme: "https", Host: "usher.example.net", Path: "/api/channel/hls/chunked/index.m3u8"}, Bandwidth: 6000000},
+		{Url: &url.URL{Scheme: "https", Host: "usher.example.net", Path: "/api/channel/hls/720p30/index.m3u8"}, Bandwidth: 2373000},
+	}, pl.Variants)
+}
+
+func TestParseStitchedAds(t *testing.T) {
+	pl := readPlaylist(t, "media_ad.m3u8", "https://video-edge.example.net/v1/playlist/x.m3u8")
+	assert.Equal(t, 6.0, pl.TargetDuration)
+	assert.False(t, pl.EndList)
+
+	var names []string
+	var ads []bool
+	for _, seg := range pl.Segments {
+		names = append(names, filepath.Base(seg.Url.Path))
+		ads = append(ads, seg.Ad)
+	}
+	assert.Equal(t, []string{"seg100.ts", "seg101.ts", "seg102.ts", "seg103.ts", "seg104.ts", "seg105.ts"}, names)
+	// seg102 位于 DATERANGE 广告时间段内，seg103 的标题不是 live
+	assert.Equal(t, []bool{false, false, true, true, false, false}, ads)
+	assert.Equal(t, int64(105), pl.Segments[5].Sequence)
+	assert.True(t, pl.Segments[5].Prefetch)
+}
+
+func TestParseCueOut(t *testing.T) {
+	pl := readPlaylist(t, "media_cue.m3u8", "https://example.com/live/index.m3u8")
+	assert.True(t, pl.EndList)
+	var ads []bool
+	for _, seg := range pl.Segments {
+		ads = append(ads, seg.Ad)
+	}
+	assert.Equal(t, []bool{false, true, true, false}, ads)
+	assert.Equal(t, int64(7), pl.Segments[0].Sequence)
+}
+
+func TestParseInvalidPlaylist(t *testing.T) {
+	_, err := ParsePlaylist([]byte("<html></html>"), &url.URL{})
+	assert.Equal(t, ErrNotPlaylist, err)
+	_, err = ParsePlaylist([]byte("#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"key\"\n"), &url.URL{})
+	assert.Equal(t, ErrEncrypted, err)
+}
+
+func TestParseLiveStream(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/master.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000\nlow.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=2000\nhigh.m3u8\n")
+	})
+	mux.HandleFunc("/high.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:1\n"+
+			"#EXT-X-TWITCH-ELAPSED-SECS:0\n"+
+			"#EXTINF:2.000,live\na.ts\n#EXTINF:2.000,Amazon\nad.ts\n#EXTINF:2.000,live\nb.ts\n#EXT-X-ENDLIST\n")
+	})
+	for _, name := range []string{"a", "ad", "b"} {
+		name := name
+		mux.HandleFunc("/"+name+".ts", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Config: configs.NewConfig(),
+		Logger: &interfaces.Logger{Logger: logger},
+	})
+
+	p, err := parser.New(Name, map[string]string{})
+	assert.NoError(t, err)
+	u, _ := url.Parse(server.URL + "/master.m3u8")
+	file := filepath.Join(t.TempDir(), "out.ts")
+	assert.NoError(t, p.ParseLiveStream(ctx, u, l, file))
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", string(b))
+
+	status, err := p.(parser.StatusParser).Status()
+	assert.NoError(t, err)
+	assert.Equal(t, "2", status["total_size"])
+	assert.Equal(t, "2", status["segments"])
+	assert.Equal(t, "1", status["ad_segments"])
+	assert.Equal(t, "00:00:04.000000", status["out_time"])
+	assert.NoError(t, p.Stop())
+}
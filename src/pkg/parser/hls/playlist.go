@@ -0,0 +1,222 @@
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotPlaylist = errors.New("不是有效的 m3u8 播放列表")
+	ErrEncrypted   = errors.New("不支持加密的 HLS 直播流")
+)
+
+// Variant 是主播放列表中的一个码率。
+type Variant struct {
+	Url       *url.URL
+	Bandwidth int64
+}
+
+// Segment 是媒体播放列表中的一个分片。
+type Segment struct {
+	Url             *url.URL
+	Sequence        int64
+	Duration        float64
+	Title           string
+	ProgramDateTime time.Time
+	Map             *url.URL // EXT-X-MAP 指定的初始化分片
+	Prefetch        bool     // 低延迟播放列表中尚未完成的预取分片
+	Ad              bool     // 是否为插入的广告
+}
+
+// Playlist 是解析后的播放列表，主播放列表只包含 Variants。
+type Playlist struct {
+	Variants       []Variant
+	Segments       []Segment
+	TargetDuration float64
+	MediaSequence  int64
+	EndList        bool
+}
+
+// adRange 是 EXT-X-DATERANGE 标记的广告时间段。
+type adRange struct {
+	start, end time.Time
+}
+
+// ParsePlaylist 解析 m3u8 播放列表，相对地址根据 base 解析。
+// 广告分片通过以下标记识别：CLASS 为 twitch-stitched-ad、ID 以 stitched-ad 开头或带有 SCTE35-OUT 的 EXT-X-DATERANGE，
+// EXT-X-CUE-OUT 与 EXT-X-CUE-IN 之间的分片，以及 Twitch 播放列表中标题不是 live 的分片。
+func ParsePlaylist(data []byte, base *url.URL) (*Playlist, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("#EXTM3U")) {
+		return nil, ErrNotPlaylist
+	}
+	p := new(Playlist)
+	var (
+		seg       Segment
+		hasInf    bool
+		bandwidth int64
+		variant   bool
+		initMap   *url.URL
+		cueOut    bool
+		twitch    bool
+		ads       []adRange
+		pdt       time.Time
+	)
+	resolve := func(uri string) (*url.URL, error) {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		return base.ResolveReference(u), nil
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		tag, value, _ := strings.Cut(line, ":")
+		switch {
+		case tag == "#EXT-X-STREAM-INF":
+			variant = true
+			bandwidth, _ = strconv.ParseInt(parseAttributes(value)["BANDWIDTH"], 10, 64)
+		case tag == "#EXT-X-TARGETDURATION":
+			p.TargetDuration, _ = strconv.ParseFloat(value, 64)
+		case tag == "#EXT-X-MEDIA-SEQUENCE":
+			p.MediaSequence, _ = strconv.ParseInt(value, 10, 64)
+		case tag == "#EXT-X-ENDLIST":
+			p.EndList = true
+		case tag == "#EXT-X-KEY":
+			if method := parseAttributes(value)["METHOD"]; method != "" && method != "NONE" {
+				return nil, ErrEncrypted
+			}
+		case tag == "#EXT-X-MAP":
+			u, err := resolve(parseAttributes(value)["URI"])
+			if err != nil {
+				return nil, err
+			}
+			initMap = u
+		case tag == "#EXT-X-PROGRAM-DATE-TIME":
+			pdt, _ = time.Parse(time.RFC3339Nano, value)
+		case tag == "#EXT-X-DATERANGE":
+			attrs := parseAttributes(value)
+			if isAdDateRange(attrs) {
+				start, err := time.Parse(time.RFC3339Nano, attrs["START-DATE"])
+				if err != nil {
+					continue
+				}
+				duration, _ := strconv.ParseFloat(attrs["DURATION"], 64)
+				if duration == 0 {
+					duration, _ = strconv.ParseFloat(attrs["PLANNED-DURATION"], 64)
+				}
+				ads = append(ads, adRange{start: start, end: start.Add(time.Duration(duration * float64(time.Second)))})
+			}
+		case tag == "#EXT-X-CUE-OUT":
+			cueOut = true
+		case tag == "#EXT-X-CUE-IN":
+			cueOut = false
+		case tag == "#EXTINF":
+			duration, title, _ := strings.Cut(value, ",")
+			seg.Duration, _ = strconv.ParseFloat(duration, 64)
+			seg.Title = title
+			hasInf = true
+		case tag == "#EXT-X-TWITCH-PREFETCH":
+			twitch = true
+			u, err := resolve(value)
+			if err != nil {
+				return nil, err
+			}
+			p.Segments = append(p.Segments, Segment{Url: u, Map: initMap, Prefetch: true})
+		case strings.HasPrefix(tag, "#EXT-X-TWITCH-"):
+			twitch = true
+		case strings.HasPrefix(line, "#"):
+		default:
+			u, err := resolve(line)
+			if err != nil {
+				return nil, err
+			}
+			if variant {
+				p.Variants = append(p.Variants, Variant{Url: u, Bandwidth: bandwidth})
+				variant = false
+				continue
+			}
+			if !hasInf {
+				continue
+			}
+			seg.Url = u
+			seg.Map = initMap
+			seg.ProgramDateTime = pdt
+			seg.Ad = cueOut
+			p.Segments = append(p.Segments, seg)
+			if !pdt.IsZero() {
+				pdt = pdt.Add(time.Duration(seg.Duration * float64(time.Second)))
+			}
+			seg = Segment{}
+			hasInf = false
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range p.Segments {
+		seg := &p.Segments[i]
+		seg.Sequence = p.MediaSequence + int64(i)
+		if twitch && !seg.Prefetch && seg.Title != "" && seg.Title != "live" {
+			seg.Ad = true
+		}
+		for _, ad := range ads {
+			if !seg.ProgramDateTime.IsZero() && !seg.ProgramDateTime.Before(ad.start) && seg.ProgramDateTime.Before(ad.end) {
+				seg.Ad = true
+			}
+		}
+	}
+	// 预取分片紧接在最后一个分片之后，广告期间的预取分片同样是广告
+	for i := range p.Segments {
+		if seg := &p.Segments[i]; seg.Prefetch && i > 0 && p.Segments[i-1].Ad {
+			seg.Ad = true
+		}
+	}
+	return p, nil
+}
+
+// isAdDateRange 判断 EXT-X-DATERANGE 是否标记了广告。
+func isAdDateRange(attrs map[string]string) bool {
+	if attrs["CLASS"] == "twitch-stitched-ad" || strings.HasPrefix(attrs["ID"], "stitched-ad") {
+		return true
+	}
+	_, ok := attrs["SCTE35-OUT"]
+	return ok
+}
+
+// parseAttributes 解析 m3u8 标签的属性列表，如 BANDWIDTH=1280000,CODECS="avc1.4d401f,mp4a.40.2"。
+func parseAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[strings.TrimSpace(key)] = value
+		s = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return attrs
+}
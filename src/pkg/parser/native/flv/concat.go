@@ -0,0 +1,232 @@
+package flv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// maxFrameGap 是拼接时用于推算文件间隔的最大帧间隔，超出时视为时间戳跳变。
+const maxFrameGap = 1000
+
+// metadataKeys 是拼接后需要重新计算的 onMetaData 数值字段，按 AMF0 编码为键长度、键名和数值类型标记。
+var metadataKeys = map[string][]byte{
+	"duration": append([]byte{0, 8}, "duration\x00"...),
+	"filesize": append([]byte{0, 8}, "filesize\x00"...),
+}
+
+// concatWriter 将多个FLV文件的标签写入同一个输出，并重新计算时间戳。
+type concatWriter struct {
+	w       *bufio.Writer
+	offset  uint32           // 当前文件的时间戳偏移
+	maxTs   uint32           // 已写入的最大时间戳
+	lastTs  uint32           // 上一个视频标签的时间戳
+	gap     uint32           // 最近的视频帧间隔
+	written bool             // 是否已写入过音视频标签
+	pos     int64            // 已写入的字节数
+	fields  map[string]int64 // 脚本标签中需要回写的字段数值在输出中的偏移
+}
+
+// Concat 将多个FLV文件按顺序无损拼接后写入 w。
+// 每个文件的时间戳都从上一个文件的结尾继续，只保留第一个文件的脚本标签，文件末尾不完整的标签会被丢弃。
+// 脚本标签中的 duration 和 filesize 只描述第一个文件，写入时会被清零；w 实现 io.WriterAt（例如 *os.File）时，
+// 拼接完成后回写为合并后文件的时长和大小。
+func Concat(ctx context.Context, w io.Writer, files ...string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	flags, err := headerFlags(files)
+	if err != nil {
+		return err
+	}
+	cw := &concatWriter{w: bufio.NewWriterSize(w, 64*1024), fields: make(map[string]int64)}
+	header := make([]byte, 13)
+	copy(header, flvSign)
+	header[4] = flags
+	binary.BigEndian.PutUint32(header[5:], 9)
+	if _, err := cw.w.Write(header); err != nil {
+		return err
+	}
+	cw.pos = int64(len(header))
+	for i, file := range files {
+		if err := cw.writeFile(ctx, file, i == 0); err != nil {
+			return err
+		}
+		// 下一个文件紧接在当前文件之后
+		if cw.written {
+			gap := cw.gap
+			if gap == 0 || gap > maxFrameGap {
+				gap = 1
+			}
+			cw.offset = cw.maxTs + gap
+		}
+	}
+	if err := cw.w.Flush(); err != nil {
+		return err
+	}
+	if wa, ok := w.(io.WriterAt); ok {
+		return cw.writeMetadata(wa)
+	}
+	return nil
+}
+
+// clearMetadata 清零脚本标签中的 duration 和 filesize，并记录它们在输出中的偏移。
+func (cw *concatWriter) clearMetadata(data []byte) {
+	for name, key := range metadataKeys {
+		i := bytes.Index(data, key)
+		if i < 0 || i+len(key)+8 > len(data) {
+			continue
+		}
+		value := data[i+len(key) : i+len(key)+8]
+		for j := range value {
+			value[j] = 0
+		}
+		// 标签头(11)之后是标签数据
+		cw.fields[name] = cw.pos + 11 + int64(i+len(key))
+	}
+}
+
+// writeMetadata 将合并后文件的时长（秒）和大小回写到脚本标签中。
+func (cw *concatWriter) writeMetadata(w io.WriterAt) error {
+	values := map[string]float64{
+		"duration": float64(cw.maxTs) / 1000,
+		"filesize": float64(cw.pos),
+	}
+	b := make([]byte, 8)
+	for name, offset := range cw.fields {
+		binary.BigEndian.PutUint64(b, math.Float64bits(values[name]))
+		if _, err := w.WriteAt(b, offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// headerFlags 合并所有文件头中的音视频标志位。
+func headerFlags(files []string) (byte, error) {
+	var flags byte
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return 0, err
+		}
+		b := make([]byte, 9)
+		_, err = io.ReadFull(f, b)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(b[:4], flvSign) {
+			return 0, ErrNotFlvStream
+		}
+		flags |= b[4] & 0x05
+	}
+	return flags, nil
+}
+
+// writeFile 写入一个文件的所有完整标签。
+func (cw *concatWriter) writeFile(ctx context.Context, file string, first bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 64*1024)
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return err
+	}
+	if _, err := br.Discard(int(binary.BigEndian.Uint32(header[5:])) - len(header)); err != nil {
+		return err
+	}
+
+	var (
+		base    uint32
+		hasBase bool
+		b       = make([]byte, 15)
+		data    []byte
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		// 前一个标签的长度(4) + 标签头(11)
+		if _, err := io.ReadFull(br, b); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+		tagType := b[4]
+		length := uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7])
+		timestamp := uint32(b[8])<<16 | uint32(b[9])<<8 | uint32(b[10]) | uint32(b[11])<<24
+		if cap(data) < int(length) {
+			data = make([]byte, length)
+		}
+		data = data[:length]
+		if _, err := io.ReadFull(br, data); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+
+		switch tagType {
+		case scriptTag:
+			if !first || cw.written {
+				continue
+			}
+			timestamp = 0
+			cw.clearMetadata(data)
+		case audioTag, videoTag:
+			if !hasBase {
+				base, hasBase = timestamp, true
+			}
+			if timestamp < base {
+				timestamp = base
+			}
+			timestamp = timestamp - base + cw.offset
+			if tagType == videoTag {
+				if timestamp > cw.lastTs {
+					cw.gap = timestamp - cw.lastTs
+				}
+				cw.lastTs = timestamp
+			}
+			if timestamp > cw.maxTs {
+				cw.maxTs = timestamp
+			}
+			cw.written = true
+		default:
+			continue
+		}
+		if err := cw.writeTag(tagType, timestamp, data); err != nil {
+			return err
+		}
+	}
+}
+
+// writeTag 写入一个标签及其后的标签长度。
+func (cw *concatWriter) writeTag(tagType uint8, timestamp uint32, data []byte) error {
+	l := len(data)
+	if _, err := cw.w.Write([]byte{
+		tagType,
+		byte(l >> 16), byte(l >> 8), byte(l),
+		byte(timestamp >> 16), byte(timestamp >> 8), byte(timestamp), byte(timestamp >> 24),
+		0, 0, 0,
+	}); err != nil {
+		return err
+	}
+	if _, err := cw.w.Write(data); err != nil {
+		return err
+	}
+	cw.pos += int64(11 + l + 4)
+	return binary.Write(cw.w, binary.BigEndian, uint32(11+l))
+}
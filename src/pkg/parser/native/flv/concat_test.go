@@ -0,0 +1,98 @@
+package flv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcat(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1.flv")
+	second := filepath.Join(dir, "2.flv")
+	assert.NoError(t, os.WriteFile(first, buildFlv(
+		testTag{scriptTag, 0, []byte{2, 0, 0}},
+		testTag{videoTag, 0, []byte{0x17, 1, 0, 0, 0}},
+		testTag{audioTag, 10, []byte{0xaf, 1, 0}},
+		testTag{videoTag, 40, []byte{0x27, 1, 0, 0, 0}},
+	), 0644))
+	data := buildFlv(
+		testTag{scriptTag, 0, []byte{2, 0, 0}},
+		testTag{videoTag, 5000, []byte{0x17, 1, 0, 0, 0}},
+		testTag{videoTag, 5040, []byte{0x27, 1, 0, 0, 0}},
+		testTag{audioTag, 5050, []byte{0xaf, 1, 0}},
+	)
+	// 第二个文件末尾的标签不完整
+	assert.NoError(t, os.WriteFile(second, data[:len(data)-6], 0644))
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, Concat(context.Background(), buf, first, second))
+	keyframes, last, err := ReadKeyframes(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(120), last)
+	if assert.Len(t, keyframes, 2) {
+		assert.Equal(t, uint32(0), keyframes[0].Timestamp)
+		assert.Equal(t, uint32(80), keyframes[1].Timestamp)
+	}
+	// 只保留第一个文件的脚本标签
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte{scriptTag, 0, 0, 3}))
+
+	assert.ErrorIs(t, Concat(context.Background(), buf, filepath.Join(dir, "none.flv")), os.ErrNotExist)
+}
+
+// amfNumber 编码一个 AMF0 数值字段。
+func amfNumber(key string, value float64) []byte {
+	b := []byte{0, byte(len(key))}
+	b = append(b, key...)
+	b = append(b, 0)
+	return binary.BigEndian.AppendUint64(b, math.Float64bits(value))
+}
+
+// metadataTag 生成包含 duration 和 filesize 的 onMetaData 脚本标签数据。
+func metadataTag(duration, filesize float64) []byte {
+	b := []byte{2, 0, 10}
+	b = append(b, "onMetaData"...)
+	b = append(b, 8, 0, 0, 0, 2)
+	b = append(b, amfNumber("duration", duration)...)
+	b = append(b, amfNumber("filesize", filesize)...)
+	return append(b, 0, 0, 9)
+}
+
+func TestConcatMetadata(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1.flv")
+	second := filepath.Join(dir, "2.flv")
+	assert.NoError(t, os.WriteFile(first, buildFlv(
+		testTag{scriptTag, 0, metadataTag(1, 100)},
+		testTag{videoTag, 0, []byte{0x17, 0, 0, 0, 0}},
+		testTag{videoTag, 1000, []byte{0x27, 1, 0, 0, 0}},
+	), 0644))
+	assert.NoError(t, os.WriteFile(second, buildFlv(
+		testTag{scriptTag, 0, metadataTag(1, 100)},
+		testTag{videoTag, 0, []byte{0x17, 0, 0, 0, 0}},
+		testTag{videoTag, 1000, []byte{0x27, 1, 0, 0, 0}},
+	), 0644))
+
+	output := filepath.Join(dir, "merged.flv")
+	f, err := os.Create(output)
+	assert.NoError(t, err)
+	assert.NoError(t, Concat(context.Background(), f, first, second))
+	assert.NoError(t, f.Close())
+
+	data, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Contains(data, amfNumber("duration", 3)))
+	assert.True(t, bytes.Contains(data, amfNumber("filesize", float64(len(data)))))
+
+	// 无法回写时清零，不保留第一个文件的值
+	buf := new(bytes.Buffer)
+	assert.NoError(t, Concat(context.Background(), buf, first, second))
+	assert.True(t, bytes.Contains(buf.Bytes(), amfNumber("duration", 0)))
+	assert.True(t, bytes.Contains(buf.Bytes(), amfNumber("filesize", 0)))
+}
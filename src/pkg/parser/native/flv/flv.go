@@ -35,6 +35,8 @@ var (
 
 	ErrNotFlvStream = errors.New("非FLV流")
 	ErrUnknownTag   = errors.New("未知标签")
+	// ErrNewSequenceHeader 表示流中出现了新的视频序列头，需要开始新的文件
+	ErrNewSequenceHeader = fmt.Errorf("新的视频序列头，%w", parser.ErrSplit)
 )
 
 func init() {
@@ -50,9 +52,11 @@ func (b *builder) Build(cfg map[string]string) (parser.Parser, error) {
 	// }
 	return &Parser{
 		Metadata:  Metadata{},
+		audioOnly: cfg["audio_only"] == "true",
 		hc:        &http.Client{},
 		stopCh:    make(chan struct{}),
 		closeOnce: new(sync.Once),
+		stats:     newStats(),
 	}, nil
 }
 
@@ -65,8 +69,11 @@ type Parser struct {
 
 	i              *reader.BufferedReader
 	o              io.Writer
-	avcHeaderCount uint8
+	seqHeaderCount uint8
 	tagCount       uint32
+	audioOnly      bool   // 只录制音频，丢弃视频标签
+	lastTagSize    uint32 // 上一个写入的标签长度，丢弃视频标签后用于修正标签长度
+	stats          stats
 
 	hc        *http.Client
 	stopCh    chan struct{}
@@ -94,7 +101,7 @@ func (p *Parser) ParseLiveStream(ctx context.Context, url *url.URL, live live.Li
 	if err != nil {
 		return err
 	}
-	p.o = f
+	p.o = &countWriter{w: f, n: &p.stats.written}
 	defer f.Close()
 
 	// 开始解析
@@ -120,6 +127,10 @@ func (p *Parser) doParse(ctx context.Context) error {
 	if !bytes.Equal(b[:4], flvSign) {
 		return ErrNotFlvStream
 	}
+	// 只录制音频时清除视频标志位
+	if p.audioOnly {
+		b[4] &^= 1 << 2
+	}
 	// 设置视频和音频标志位
 	p.Metadata.HasVideo = uint8(b[4])&(1<<2) != 0
 	p.Metadata.HasAudio = uint8(b[4])&1 != 0
@@ -150,7 +161,12 @@ func (p *Parser) doParse(ctx context.Context) error {
 
 // doCopy 复制数据
 func (p *Parser) doCopy(ctx context.Context, n uint32) error {
-	if writtenCount, err := io.CopyN(p.o, p.i, int64(n)); err != nil || writtenCount != int64(writtenCount) {
+	return p.doCopyTo(ctx, n, p.o)
+}
+
+// doCopyTo 复制数据到 w，用于在写入输出的同时保留需要解析的内容
+func (p *Parser) doCopyTo(ctx context.Context, n uint32, w io.Writer) error {
+	if writtenCount, err := io.CopyN(w, p.i, int64(n)); err != nil || writtenCount != int64(writtenCount) {
 		utils.PrintStack(ctx)
 		if err == nil {
 			err = fmt.Errorf("doCopy(%d), %d 字节已写入", n, writtenCount)
@@ -160,6 +176,12 @@ func (p *Parser) doCopy(ctx context.Context, n uint32) error {
 	return nil
 }
 
+// doDiscard 丢弃数据
+func (p *Parser) doDiscard(n uint32) error {
+	_, err := io.CopyN(io.Discard, p.i, int64(n))
+	return err
+}
+
 // doWrite 写入数据
 func (p *Parser) doWrite(ctx context.Context, b []byte) error {
 	inst := instance.GetInstance(ctx)
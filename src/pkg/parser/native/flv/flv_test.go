@@ -0,0 +1,196 @@
+package flv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/pkg/reader"
+)
+
+func TestParseAudioOnly(t *testing.T) {
+	input := buildFlv(
+		testTag{scriptTag, 0, []byte{2, 0, 0}},
+		testTag{videoTag, 0, []byte{0x17, 0, 0, 0, 0, 1, 2, 3}},
+		testTag{audioTag, 10, []byte{0xaf, 1, 0}},
+		testTag{videoTag, 40, []byte{0x27, 1, 0, 0, 0}},
+		testTag{audioTag, 50, []byte{0xaf, 1, 1, 1}},
+	)
+	out := new(bytes.Buffer)
+	ctx, p := newTestParser(input, out)
+	p.audioOnly = true
+	assert.ErrorIs(t, p.doParse(ctx), io.EOF)
+	assert.False(t, p.Metadata.HasVideo)
+	assert.True(t, p.Metadata.HasAudio)
+
+	// 输出中只有脚本和音频标签，且每个标签前的长度都指向上一个写入的标签
+	b := out.Bytes()
+	assert.Equal(t, byte(0x01), b[4])
+	types := make([]uint8, 0)
+	prevSize := uint32(0)
+	for offset := 9; offset+15 <= len(b); {
+		assert.Equal(t, prevSize, binary.BigEndian.Uint32(b[offset:]))
+		length := uint32(b[offset+5])<<16 | uint32(b[offset+6])<<8 | uint32(b[offset+7])
+		types = append(types, b[offset+4])
+		prevSize = 11 + length
+		offset += 4 + int(prevSize)
+	}
+	assert.Equal(t, []uint8{scriptTag, audioTag, audioTag}, types)
+}
+
+// newTestParser 创建读取 input 并写入 out 的解析器。
+func newTestParser(input []byte, out io.Writer) (context.Context, *Parser) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Logger: &interfaces.Logger{Logger: logger},
+	})
+	p := &Parser{
+		i:         reader.New(bytes.NewReader(input)),
+		stopCh:    make(chan struct{}),
+		closeOnce: new(sync.Once),
+		stats:     newStats(),
+	}
+	p.o = &countWriter{w: out, n: &p.stats.written}
+	return ctx, p
+}
+
+func TestParseVideoTagHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		header   VideoTagHeader
+		left     uint32
+		isSeq    bool
+		codec    FourCC
+		keyframe bool
+	}{
+		{
+			name:   "AVC序列头",
+			data:   []byte{0x17, 0, 0, 0, 0, 1},
+			header: VideoTagHeader{FrameType: KeyFrame, CodeID: AVCCode, AVCPacketType: AVCSeqHeader},
+			left:   4, isSeq: true, codec: FourCCAVC,
+		},
+		{
+			name:   "HEVC非标准扩展",
+			data:   []byte{0x2c, 1, 0, 0, 0x28, 1, 2},
+			header: VideoTagHeader{FrameType: InterFrame, CodeID: HEVCCode, AVCPacketType: AVCNALU, CompositionTime: 40},
+			left:   2, codec: FourCCHEVC,
+		},
+		{
+			name:   "增强型RTMP HEVC序列头",
+			data:   []byte{0x90, 'h', 'v', 'c', '1', 1, 2},
+			header: VideoTagHeader{FrameType: KeyFrame, IsExHeader: true, PacketType: PacketTypeSequenceStart, FourCC: FourCCHEVC},
+			left:   2, isSeq: true, codec: FourCCHEVC,
+		},
+		{
+			name:   "增强型RTMP HEVC视频帧",
+			data:   []byte{0x91, 'h', 'v', 'c', '1', 0, 0, 0x28, 1},
+			header: VideoTagHeader{FrameType: KeyFrame, IsExHeader: true, PacketType: PacketTypeCodedFrames, FourCC: FourCCHEVC, CompositionTime: 40},
+			left:   1, codec: FourCCHEVC,
+		},
+		{
+			name:   "增强型RTMP AV1视频帧",
+			data:   []byte{0xa1, 'a', 'v', '0', '1', 1, 2, 3},
+			header: VideoTagHeader{FrameType: InterFrame, IsExHeader: true, PacketType: PacketTypeCodedFrames, FourCC: FourCCAV1},
+			left:   3, codec: FourCCAV1,
+		},
+		{
+			name:   "增强型RTMP AV1 MPEG2-TS序列头",
+			data:   []byte{0x95, 'a', 'v', '0', '1', 1},
+			header: VideoTagHeader{FrameType: KeyFrame, IsExHeader: true, PacketType: PacketTypeMPEG2TSSequenceStart, FourCC: FourCCAV1},
+			left:   1, isSeq: true, codec: FourCCAV1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, p := newTestParser(tt.data, io.Discard)
+			header, left, err := p.parseVideoTagHeader(uint32(len(tt.data)))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.header, *header)
+			assert.Equal(t, tt.left, left)
+			assert.Equal(t, tt.isSeq, header.IsSequenceHeader())
+			assert.Equal(t, tt.codec, header.Codec())
+		})
+	}
+}
+
+func TestParseHEVCStream(t *testing.T) {
+	input := buildFlv(
+		testTag{scriptTag, 0, []byte{2, 0, 0}},
+		testTag{videoTag, 0, []byte{0x90, 'h', 'v', 'c', '1', 1, 2, 3}},
+		testTag{videoTag, 0, []byte{0x91, 'h', 'v', 'c', '1', 0, 0, 0, 4}},
+		testTag{audioTag, 10, []byte{0xaf, 1, 0}},
+		testTag{videoTag, 40, []byte{0xa3, 'h', 'v', 'c', '1', 5}},
+		// 新的序列头需要开始新的文件
+		testTag{videoTag, 80, []byte{0x90, 'h', 'v', 'c', '1', 6, 7, 8}},
+	)
+	out := new(bytes.Buffer)
+	ctx, p := newTestParser(input, out)
+	assert.ErrorIs(t, p.doParse(ctx), ErrNewSequenceHeader)
+
+	// 新的序列头之前的标签原样写入
+	keyframes, last, err := ReadKeyframes(bytes.NewReader(out.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(40), last)
+	// 扩展头的序列头不算作关键帧
+	assert.Len(t, keyframes, 1)
+	end := bytes.Index(input, []byte{videoTag, 0, 0, 8, 0, 0, 80})
+	assert.Equal(t, input[:end-4], out.Bytes())
+
+	// 统计信息与 FFmpeg 解析器的字段一致
+	status, err := p.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(out.Len()), status["total_size"])
+	assert.Equal(t, "00:00:00.040000", status["out_time"])
+	assert.Equal(t, fmt.Sprintf("%.1fkbits/s", float64(out.Len())*8/1000/0.04), status["bitrate"])
+	// 序列头不计入帧数
+	assert.Equal(t, "50.00", status["fps"])
+	assert.Equal(t, "hevc", status["video_codec"])
+	assert.Equal(t, "aac", status["audio_codec"])
+	assert.Equal(t, "1", status["script_tags"])
+	assert.Equal(t, "3", status["video_tags"])
+	assert.Equal(t, "1", status["audio_tags"])
+	assert.Equal(t, "40", status["timestamp"])
+	// 无效的序列头不影响录制，只是没有分辨率
+	assert.NotContains(t, status, "resolution")
+	assert.Equal(t, "0", status["last_keyframe_timestamp"])
+	assert.Contains(t, status, "last_keyframe_time")
+}
+
+func TestParseAVCStreamStatus(t *testing.T) {
+	seqHeader := append([]byte{0x17, 0, 0, 0, 0}, avcConfig(testAVCSPS720p)...)
+	input := buildFlv(
+		testTag{videoTag, 0, seqHeader},
+		testTag{videoTag, 0, []byte{0x17, 1, 0, 0, 0, 1}},
+		testTag{audioTag, 10, []byte{0x2f, 0}},
+		testTag{videoTag, 40, []byte{0x27, 1, 0, 0, 0, 2}},
+		testTag{videoTag, 80, []byte{0x17, 1, 0, 0, 0, 3}},
+		testTag{videoTag, 120, []byte{0x27, 1, 0, 0, 0, 4}},
+	)
+	out := new(bytes.Buffer)
+	ctx, p := newTestParser(input, out)
+	assert.ErrorIs(t, p.doParse(ctx), io.EOF)
+	// 最后一个标签的长度在读取下一个标签时才写入
+	assert.Equal(t, input[:len(input)-4], out.Bytes())
+
+	status, err := p.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, "h264", status["video_codec"])
+	assert.Equal(t, "mp3", status["audio_codec"])
+	assert.Equal(t, "1280x720", status["resolution"])
+	assert.Equal(t, "5", status["video_tags"])
+	assert.Equal(t, "0", status["script_tags"])
+	assert.Equal(t, "120", status["timestamp"])
+	assert.Equal(t, "80", status["last_keyframe_timestamp"])
+}
@@ -0,0 +1,99 @@
+package flv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Keyframe 表示FLV文件中的一个关键帧位置。
+type Keyframe struct {
+	Timestamp uint32 // 时间戳，单位毫秒
+	Offset    int64  // 标签在文件中的偏移量
+}
+
+// Index 是扫描FLV数据得到的关键帧和时间戳信息。
+type Index struct {
+	Keyframes      []Keyframe // 视频关键帧的位置，不包括序列头
+	FirstTimestamp uint32     // 第一个音频或视频标签的时间戳，播放器和 FFmpeg 以此作为开始时间
+	LastTimestamp  uint32     // 最后一个标签的时间戳
+}
+
+// ReadKeyframes 扫描FLV数据，返回所有视频关键帧的位置以及最后一个标签的时间戳。
+// 遇到不完整的末尾标签时会停止扫描并返回已读取的结果。
+func ReadKeyframes(r io.Reader) (keyframes []Keyframe, lastTimestamp uint32, err error) {
+	index, err := ReadIndex(r)
+	if index == nil {
+		return nil, 0, err
+	}
+	return index.Keyframes, index.LastTimestamp, err
+}
+
+// ReadIndex 扫描FLV数据，返回关键帧的位置以及第一个音视频标签和最后一个标签的时间戳。
+// 遇到不完整的末尾标签时会停止扫描并返回已读取的结果，文件头错误时返回 nil。
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], flvSign) {
+		return nil, ErrNotFlvStream
+	}
+	offset := int64(binary.BigEndian.Uint32(header[5:]))
+	if _, err := br.Discard(int(offset) - len(header)); err != nil {
+		return nil, err
+	}
+
+	index := new(Index)
+	hasAV := false
+	b := make([]byte, 15)
+	for {
+		// 前一个标签的长度(4) + 标签头(11)
+		if _, err := io.ReadFull(br, b); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return index, nil
+			}
+			return index, err
+		}
+		tagOffset := offset + 4
+		tagType := b[4]
+		length := uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7])
+		timestamp := uint32(b[8])<<16 | uint32(b[9])<<8 | uint32(b[10]) | uint32(b[11])<<24
+		remaining := int(length)
+		if !hasAV && (tagType == audioTag || tagType == videoTag) {
+			hasAV = true
+			index.FirstTimestamp = timestamp
+		}
+		if tagType == videoTag && length >= 2 {
+			header, err := br.Peek(2)
+			if err != nil {
+				return index, nil
+			}
+			if isKeyframe(header) {
+				index.Keyframes = append(index.Keyframes, Keyframe{Timestamp: timestamp, Offset: tagOffset})
+			}
+		}
+		if _, err := br.Discard(remaining); err != nil {
+			return index, nil
+		}
+		index.LastTimestamp = timestamp
+		offset = tagOffset + 11 + int64(length)
+	}
+}
+
+// isKeyframe 根据视频标签内容的前两个字节判断是否为关键帧，序列头虽然标记为关键帧但不包含画面，不算作关键帧。
+func isKeyframe(b []byte) bool {
+	// 传统标签头和增强型RTMP的扩展头中帧类型都在第一个字节的高位，最高位是扩展头标志
+	h := VideoTagHeader{FrameType: FrameType(b[0] >> 4 & 7)}
+	if b[0]&exHeaderFlag != 0 {
+		h.IsExHeader = true
+		h.PacketType = VideoPacketType(b[0] & 15)
+	} else {
+		h.CodeID = CodeID(b[0] & 15)
+		h.AVCPacketType = AVCPacketType(b[1])
+	}
+	return h.FrameType == KeyFrame && !h.IsSequenceHeader()
+}
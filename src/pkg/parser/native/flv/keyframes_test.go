@@ -0,0 +1,77 @@
+package flv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTag 描述测试用FLV标签。
+type testTag struct {
+	typ       uint8
+	timestamp uint32
+	data      []byte
+}
+
+// buildFlv 根据标签列表生成一段FLV数据。
+func buildFlv(tags ...testTag) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(flvSign)
+	buf.WriteByte(0x05)
+	binary.Write(buf, binary.BigEndian, uint32(9))
+	binary.Write(buf, binary.BigEndian, uint32(0))
+	for _, tag := range tags {
+		l := len(tag.data)
+		buf.Write([]byte{
+			tag.typ,
+			byte(l >> 16), byte(l >> 8), byte(l),
+			byte(tag.timestamp >> 16), byte(tag.timestamp >> 8), byte(tag.timestamp), byte(tag.timestamp >> 24),
+			0, 0, 0,
+		})
+		buf.Write(tag.data)
+		binary.Write(buf, binary.BigEndian, uint32(11+l))
+	}
+	return buf.Bytes()
+}
+
+func TestReadKeyframes(t *testing.T) {
+	data := buildFlv(
+		testTag{scriptTag, 0, []byte{2, 0, 0}},
+		// 序列头不算作关键帧
+		testTag{videoTag, 0, []byte{0x17, 0, 0, 0, 0}},
+		testTag{videoTag, 0, []byte{0x17, 1, 0, 0, 0}},
+		testTag{audioTag, 10, []byte{0xaf, 1, 0}},
+		testTag{videoTag, 40, []byte{0x27, 1, 0, 0, 0}},
+		testTag{videoTag, 2000, []byte{0x17, 1, 0, 0, 0, 0xff}},
+	)
+	keyframes, last, err := ReadKeyframes(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2000), last)
+	if assert.Len(t, keyframes, 2) {
+		assert.Equal(t, uint32(0), keyframes[0].Timestamp)
+		assert.Equal(t, uint32(2000), keyframes[1].Timestamp)
+		assert.Equal(t, byte(videoTag), data[keyframes[1].Offset])
+	}
+
+	// 末尾标签不完整时返回已读取的部分
+	keyframes, last, err = ReadKeyframes(bytes.NewReader(data[:len(data)-5]))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(40), last)
+	assert.Len(t, keyframes, 2)
+
+	_, _, err = ReadKeyframes(bytes.NewReader([]byte("not a flv file")))
+	assert.Equal(t, ErrNotFlvStream, err)
+
+	// 开始时间为第一个音视频标签的时间戳，脚本标签除外
+	index, err := ReadIndex(bytes.NewReader(buildFlv(
+		testTag{scriptTag, 0, []byte{2, 0, 0}},
+		testTag{audioTag, 5000, []byte{0xaf, 1, 0}},
+		testTag{videoTag, 5000, []byte{0x17, 1, 0, 0, 0}},
+	)))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5000), index.FirstTimestamp)
+	assert.Equal(t, uint32(5000), index.LastTimestamp)
+	assert.Len(t, index.Keyframes, 1)
+}
@@ -0,0 +1,50 @@
+package flv
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// CompleteLength 返回FLV数据中到最后一个完整标签（包括其后的标签长度）为止的长度，
+// 用于截断异常中断的录制文件。标签数据通过 Seek 跳过，不需要读取整个文件。
+func CompleteLength(r io.ReadSeeker) (int64, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, ErrNotFlvStream
+	}
+	if string(header[:4]) != string(flvSign) {
+		return 0, ErrNotFlvStream
+	}
+	// 文件头之后是第一个标签前的标签长度
+	offset := int64(binary.BigEndian.Uint32(header[5:])) + 4
+	if offset > size {
+		return 0, ErrNotFlvStream
+	}
+
+	b := make([]byte, 11)
+	for {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return offset, err
+		}
+		if _, err := io.ReadFull(r, b); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return offset, nil
+			}
+			return offset, err
+		}
+		length := int64(b[1])<<16 | int64(b[2])<<8 | int64(b[3])
+		end := offset + 11 + length + 4
+		if end > size {
+			return offset, nil
+		}
+		offset = end
+	}
+}
@@ -0,0 +1,30 @@
+package flv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteLength(t *testing.T) {
+	data := buildFlv(
+		testTag{videoTag, 0, []byte{0x17, 0, 0, 0, 0}},
+		testTag{audioTag, 10, []byte{0xaf, 1, 0}},
+	)
+	n, err := CompleteLength(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+
+	// 最后一个标签不完整时截断到上一个标签
+	n, err = CompleteLength(bytes.NewReader(data[:len(data)-1]))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)-4-11-3), n)
+
+	n, err = CompleteLength(bytes.NewReader(data[:15]))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), n)
+
+	_, err = CompleteLength(bytes.NewReader([]byte("not flv")))
+	assert.ErrorIs(t, err, ErrNotFlvStream)
+}
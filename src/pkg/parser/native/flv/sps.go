@@ -0,0 +1,405 @@
+package flv
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrInvalidSPS 表示序列头中的SPS无法解析
+var ErrInvalidSPS = errors.New("无效的SPS")
+
+// bitReader 按位读取数据，用于解析SPS中的指数哥伦布编码。
+type bitReader struct {
+	b   []byte
+	pos int // 已读取的位数
+}
+
+// readBit 读取一位。
+func (r *bitReader) readBit() (uint32, error) {
+	if r.pos >= len(r.b)*8 {
+		return 0, ErrInvalidSPS
+	}
+	bit := uint32(r.b[r.pos/8]>>(7-r.pos%8)) & 1
+	r.pos++
+	return bit, nil
+}
+
+// readBits 读取 n 位无符号整数，n 不超过32。
+func (r *bitReader) readBits(n int) (uint32, error) {
+	var v uint32
+	for i := 0; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+	}
+	return v, nil
+}
+
+// skip 跳过 n 位。
+func (r *bitReader) skip(n int) error {
+	if r.pos+n > len(r.b)*8 {
+		return ErrInvalidSPS
+	}
+	r.pos += n
+	return nil
+}
+
+// readUE 读取无符号指数哥伦布编码。
+func (r *bitReader) readUE() (uint32, error) {
+	zeros := 0
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			break
+		}
+		if zeros++; zeros > 31 {
+			return 0, ErrInvalidSPS
+		}
+	}
+	v, err := r.readBits(zeros)
+	if err != nil {
+		return 0, err
+	}
+	return 1<<zeros - 1 + v, nil
+}
+
+// readSE 读取有符号指数哥伦布编码。
+func (r *bitReader) readSE() (int32, error) {
+	v, err := r.readUE()
+	if err != nil {
+		return 0, err
+	}
+	if v&1 == 1 {
+		return int32(v/2 + 1), nil
+	}
+	return -int32(v / 2), nil
+}
+
+// unescapeRBSP 移除NAL单元中的防竞争字节（00 00 03 中的 03）。
+func unescapeRBSP(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c == 3 {
+			zeros = 0
+			continue
+		}
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// chromaSubsampling 返回色度格式对应的水平和垂直采样倍数。
+func chromaSubsampling(chromaFormat uint32) (uint32, uint32) {
+	switch chromaFormat {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+	return 1, 1
+}
+
+// parseAVCConfig 从 AVCDecoderConfigurationRecord 的第一个SPS中读取分辨率。
+func parseAVCConfig(b []byte) (width, height uint32, err error) {
+	if len(b) < 8 || b[5]&0x1f == 0 {
+		return 0, 0, ErrInvalidSPS
+	}
+	n := int(binary.BigEndian.Uint16(b[6:]))
+	if len(b) < 8+n || n < 4 {
+		return 0, 0, ErrInvalidSPS
+	}
+	return parseAVCSPS(b[8 : 8+n])
+}
+
+// parseAVCSPS 解析H.264的SPS，返回裁剪后的分辨率。
+func parseAVCSPS(nalu []byte) (width, height uint32, err error) {
+	r := &bitReader{b: unescapeRBSP(nalu[1:])}
+	profile, err := r.readBits(8)
+	if err != nil {
+		return 0, 0, err
+	}
+	// constraint_set_flags、reserved_zero_2bits、level_idc
+	if err := r.skip(16); err != nil {
+		return 0, 0, err
+	}
+	if _, err := r.readUE(); err != nil { // seq_parameter_set_id
+		return 0, 0, err
+	}
+	chromaFormat := uint32(1)
+	separateColourPlane := uint32(0)
+	switch profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		if chromaFormat, err = r.readUE(); err != nil {
+			return 0, 0, err
+		}
+		if chromaFormat == 3 {
+			if separateColourPlane, err = r.readBit(); err != nil {
+				return 0, 0, err
+			}
+		}
+		// bit_depth_luma_minus8、bit_depth_chroma_minus8
+		for i := 0; i < 2; i++ {
+			if _, err := r.readUE(); err != nil {
+				return 0, 0, err
+			}
+		}
+		if err := r.skip(1); err != nil { // qpprime_y_zero_transform_bypass_flag
+			return 0, 0, err
+		}
+		scalingMatrix, err := r.readBit()
+		if err != nil {
+			return 0, 0, err
+		}
+		if scalingMatrix == 1 {
+			count := 8
+			if chromaFormat == 3 {
+				count = 12
+			}
+			for i := 0; i < count; i++ {
+				present, err := r.readBit()
+				if err != nil {
+					return 0, 0, err
+				}
+				if present == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				if err := skipScalingList(r, size); err != nil {
+					return 0, 0, err
+				}
+			}
+		}
+	}
+	if _, err := r.readUE(); err != nil { // log2_max_frame_num_minus4
+		return 0, 0, err
+	}
+	pocType, err := r.readUE()
+	if err != nil {
+		return 0, 0, err
+	}
+	switch pocType {
+	case 0:
+		if _, err := r.readUE(); err != nil { // log2_max_pic_order_cnt_lsb_minus4
+			return 0, 0, err
+		}
+	case 1:
+		if err := r.skip(1); err != nil { // delta_pic_order_always_zero_flag
+			return 0, 0, err
+		}
+		// offset_for_non_ref_pic、offset_for_top_to_bottom_field
+		for i := 0; i < 2; i++ {
+			if _, err := r.readSE(); err != nil {
+				return 0, 0, err
+			}
+		}
+		cycle, err := r.readUE()
+		if err != nil {
+			return 0, 0, err
+		}
+		for i := uint32(0); i < cycle; i++ {
+			if _, err := r.readSE(); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if _, err := r.readUE(); err != nil { // max_num_ref_frames
+		return 0, 0, err
+	}
+	if err := r.skip(1); err != nil { // gaps_in_frame_num_value_allowed_flag
+		return 0, 0, err
+	}
+	widthInMbs, err := r.readUE()
+	if err != nil {
+		return 0, 0, err
+	}
+	heightInMapUnits, err := r.readUE()
+	if err != nil {
+		return 0, 0, err
+	}
+	frameMbsOnly, err := r.readBit()
+	if err != nil {
+		return 0, 0, err
+	}
+	if frameMbsOnly == 0 {
+		if err := r.skip(1); err != nil { // mb_adaptive_frame_field_flag
+			return 0, 0, err
+		}
+	}
+	if err := r.skip(1); err != nil { // direct_8x8_inference_flag
+		return 0, 0, err
+	}
+	width = (widthInMbs + 1) * 16
+	height = (2 - frameMbsOnly) * (heightInMapUnits + 1) * 16
+
+	cropping, err := r.readBit()
+	if err != nil {
+		return 0, 0, err
+	}
+	if cropping == 1 {
+		var crop [4]uint32 // 左、右、上、下
+		for i := range crop {
+			if crop[i], err = r.readUE(); err != nil {
+				return 0, 0, err
+			}
+		}
+		cropX, cropY := uint32(1), 2-frameMbsOnly
+		if chromaFormat != 0 && separateColourPlane == 0 {
+			subWidth, subHeight := chromaSubsampling(chromaFormat)
+			cropX, cropY = subWidth, subHeight*(2-frameMbsOnly)
+		}
+		width -= cropX * (crop[0] + crop[1])
+		height -= cropY * (crop[2] + crop[3])
+	}
+	return width, height, nil
+}
+
+// skipScalingList 跳过SPS中的缩放列表。
+func skipScalingList(r *bitReader, size int) error {
+	last, next := int32(8), int32(8)
+	for i := 0; i < size; i++ {
+		if next != 0 {
+			delta, err := r.readSE()
+			if err != nil {
+				return err
+			}
+			next = (last + delta + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+	return nil
+}
+
+// hevcSPSType 是HEVC中SPS的NAL单元类型
+const hevcSPSType = 33
+
+// parseHEVCConfig 从 HEVCDecoderConfigurationRecord 的SPS中读取分辨率。
+func parseHEVCConfig(b []byte) (width, height uint32, err error) {
+	if len(b) < 23 {
+		return 0, 0, ErrInvalidSPS
+	}
+	arrays := int(b[22])
+	offset := 23
+	for i := 0; i < arrays; i++ {
+		if len(b) < offset+3 {
+			return 0, 0, ErrInvalidSPS
+		}
+		nalType := b[offset] & 0x3f
+		count := int(binary.BigEndian.Uint16(b[offset+1:]))
+		offset += 3
+		for j := 0; j < count; j++ {
+			if len(b) < offset+2 {
+				return 0, 0, ErrInvalidSPS
+			}
+			n := int(binary.BigEndian.Uint16(b[offset:]))
+			offset += 2
+			if len(b) < offset+n {
+				return 0, 0, ErrInvalidSPS
+			}
+			if nalType == hevcSPSType && n > 2 {
+				return parseHEVCSPS(b[offset : offset+n])
+			}
+			offset += n
+		}
+	}
+	return 0, 0, ErrInvalidSPS
+}
+
+// parseHEVCSPS 解析H.265的SPS，返回裁剪后的分辨率。
+func parseHEVCSPS(nalu []byte) (width, height uint32, err error) {
+	r := &bitReader{b: unescapeRBSP(nalu[2:])}
+	if err := r.skip(4); err != nil { // sps_video_parameter_set_id
+		return 0, 0, err
+	}
+	maxSubLayers, err := r.readBits(3)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := r.skip(1); err != nil { // sps_temporal_id_nesting_flag
+		return 0, 0, err
+	}
+	// profile_tier_level 中的通用部分，包括 general_level_idc 共96位
+	if err := r.skip(96); err != nil {
+		return 0, 0, err
+	}
+	profilePresent := make([]uint32, maxSubLayers)
+	levelPresent := make([]uint32, maxSubLayers)
+	for i := range profilePresent {
+		if profilePresent[i], err = r.readBit(); err != nil {
+			return 0, 0, err
+		}
+		if levelPresent[i], err = r.readBit(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if maxSubLayers > 0 {
+		if err := r.skip(int(8-maxSubLayers) * 2); err != nil {
+			return 0, 0, err
+		}
+	}
+	for i := range profilePresent {
+		if profilePresent[i] == 1 {
+			if err := r.skip(88); err != nil {
+				return 0, 0, err
+			}
+		}
+		if levelPresent[i] == 1 {
+			if err := r.skip(8); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if _, err := r.readUE(); err != nil { // sps_seq_parameter_set_id
+		return 0, 0, err
+	}
+	chromaFormat, err := r.readUE()
+	if err != nil {
+		return 0, 0, err
+	}
+	separateColourPlane := uint32(0)
+	if chromaFormat == 3 {
+		if separateColourPlane, err = r.readBit(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if width, err = r.readUE(); err != nil {
+		return 0, 0, err
+	}
+	if height, err = r.readUE(); err != nil {
+		return 0, 0, err
+	}
+	window, err := r.readBit()
+	if err != nil {
+		return 0, 0, err
+	}
+	if window == 1 {
+		var crop [4]uint32 // 左、右、上、下
+		for i := range crop {
+			if crop[i], err = r.readUE(); err != nil {
+				return 0, 0, err
+			}
+		}
+		subWidth, subHeight := uint32(1), uint32(1)
+		if separateColourPlane == 0 {
+			subWidth, subHeight = chromaSubsampling(chromaFormat)
+		}
+		width -= subWidth * (crop[0] + crop[1])
+		height -= subHeight * (crop[2] + crop[3])
+	}
+	return width, height, nil
+}
@@ -0,0 +1,82 @@
+package flv
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mustDecodeHex 解码测试用的十六进制字符串。
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	// 1920x1080 High Profile，带有裁剪和防竞争字节
+	testAVCSPS1080p = mustDecodeHex("67640028acd940780227e5c044000003000400000300f03c60c658")
+	// 1280x720 Main Profile
+	testAVCSPS720p = mustDecodeHex("674d401fe8802802dd80b501010140000003004000000c83c60c4480")
+	// 640x360 Baseline Profile
+	testAVCSPS360p = mustDecodeHex("6742c01ed900a02ff9701100000303e90000ea600f162e48")
+	// 1280x720 Main Profile
+	testHEVCSPS720p = mustDecodeHex("42010101600000030090000003000003005da00280802d165959a4932bc05a70200000030020000003003c")
+)
+
+// avcConfig 构造只包含一个SPS的 AVCDecoderConfigurationRecord。
+func avcConfig(sps []byte) []byte {
+	b := []byte{1, sps[1], sps[2], sps[3], 0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(b[6:], uint16(len(sps)))
+	return append(append(b, sps...), 0)
+}
+
+// hevcConfig 构造只包含一个SPS的 HEVCDecoderConfigurationRecord。
+func hevcConfig(sps []byte) []byte {
+	b := make([]byte, 22)
+	b[0] = 1
+	b = append(b, 1, 0x80|hevcSPSType, 0, 1, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-2:], uint16(len(sps)))
+	return append(b, sps...)
+}
+
+func TestParseAVCConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		sps           []byte
+		width, height uint32
+	}{
+		{"1080p", testAVCSPS1080p, 1920, 1080},
+		{"720p", testAVCSPS720p, 1280, 720},
+		{"360p", testAVCSPS360p, 640, 360},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, err := parseAVCConfig(avcConfig(tt.sps))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.width, width)
+			assert.Equal(t, tt.height, height)
+		})
+	}
+
+	_, _, err := parseAVCConfig([]byte{1, 0x64, 0, 0x28, 0xff, 0xe1, 0, 30, 0x67})
+	assert.ErrorIs(t, err, ErrInvalidSPS)
+}
+
+func TestParseHEVCConfig(t *testing.T) {
+	width, height, err := parseHEVCConfig(hevcConfig(testHEVCSPS720p))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1280), width)
+	assert.Equal(t, uint32(720), height)
+
+	_, _, err = parseHEVCConfig(make([]byte, 23))
+	assert.ErrorIs(t, err, ErrInvalidSPS)
+}
+
+func TestUnescapeRBSP(t *testing.T) {
+	assert.Equal(t, []byte{0, 0, 1, 0, 0, 0, 0, 3}, unescapeRBSP([]byte{0, 0, 3, 1, 0, 0, 3, 0, 0, 3, 3}))
+}
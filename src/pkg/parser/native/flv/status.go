@@ -0,0 +1,204 @@
+package flv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+)
+
+// stats 是解析器的统计信息，在解析协程中更新，可以在其他协程中读取。
+type stats struct {
+	written        int64 // 已写入的字节数
+	firstTimestamp int64 // 第一个标签的时间戳（毫秒），-1 表示尚未读取标签
+	lastTimestamp  int64 // 最新标签的时间戳（毫秒）
+	videoFrames    int64 // 已写入的视频帧数，不含序列头
+	audioTags      int64 // 已写入的音频标签数
+	videoTags      int64 // 已写入的视频标签数
+	scriptTags     int64 // 已写入的脚本标签数
+
+	// 以下字段由 mu 保护
+	mu                    sync.Mutex
+	videoCodec            string
+	audioCodec            string
+	width, height         uint32
+	lastKeyframeTimestamp int64     // 最新关键帧的时间戳（毫秒），-1 表示尚未读取关键帧
+	lastKeyframeTime      time.Time // 读取到最新关键帧的时间
+}
+
+// newStats 创建尚未读取标签的统计信息。
+func newStats() stats {
+	return stats{firstTimestamp: -1, lastKeyframeTimestamp: -1}
+}
+
+// countWriter 在写入时累加写入的字节数。
+type countWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	atomic.AddInt64(w.n, int64(n))
+	return n, err
+}
+
+// videoCodecNames 是视频编码在状态中显示的名称
+var videoCodecNames = map[FourCC]string{
+	FourCCAVC:  "h264",
+	FourCCHEVC: "hevc",
+	FourCCAV1:  "av1",
+	FourCCVP9:  "vp9",
+}
+
+// legacyVideoCodecNames 是传统标签头中其他编码标识对应的名称
+var legacyVideoCodecNames = map[CodeID]string{
+	H263Code:          "h263",
+	ScreenVideoCode:   "flashsv",
+	VP6Code:           "vp6",
+	VP6AlphaCode:      "vp6a",
+	ScreenVideoV2Code: "flashsv2",
+}
+
+// audioCodecNames 是音频编码在状态中显示的名称
+var audioCodecNames = map[SoundFormat]string{
+	LPCM_PE:  "pcm",
+	ADPCM:    "adpcm",
+	MP3:      "mp3",
+	LPCM_LE:  "pcm",
+	AAC:      "aac",
+	Speex:    "speex",
+	MP3_8kHz: "mp3",
+}
+
+// videoCodecName 返回视频标签的编码名称，未知时为空。
+func videoCodecName(tag *VideoTagHeader) string {
+	if name, ok := videoCodecNames[tag.Codec()]; ok {
+		return name
+	}
+	if tag.IsExHeader {
+		return string(tag.FourCC)
+	}
+	return legacyVideoCodecNames[tag.CodeID]
+}
+
+// onTimestamp 记录写入的音视频标签的时间戳。
+func (s *stats) onTimestamp(timestamp uint32) {
+	atomic.CompareAndSwapInt64(&s.firstTimestamp, -1, int64(timestamp))
+	atomic.StoreInt64(&s.lastTimestamp, int64(timestamp))
+}
+
+// onAudioTag 记录写入的音频标签。
+func (s *stats) onAudioTag(timestamp uint32, tag *AudioTagHeader) {
+	s.onTimestamp(timestamp)
+	atomic.AddInt64(&s.audioTags, 1)
+	if name, ok := audioCodecNames[tag.SoundFormat]; ok {
+		s.mu.Lock()
+		s.audioCodec = name
+		s.mu.Unlock()
+	}
+}
+
+// onVideoTag 记录写入的视频标签。
+func (s *stats) onVideoTag(timestamp uint32, tag *VideoTagHeader) {
+	s.onTimestamp(timestamp)
+	atomic.AddInt64(&s.videoTags, 1)
+	if tag.IsSequenceHeader() {
+		return
+	}
+	atomic.AddInt64(&s.videoFrames, 1)
+	name := videoCodecName(tag)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name != "" {
+		s.videoCodec = name
+	}
+	if tag.FrameType == KeyFrame {
+		s.lastKeyframeTimestamp = int64(timestamp)
+		s.lastKeyframeTime = time.Now()
+	}
+}
+
+// onSequenceHeader 从视频序列头中读取分辨率，payload 是标签头之后的内容。
+// 解析失败时只记录编码，不影响录制。
+func (s *stats) onSequenceHeader(tag *VideoTagHeader, payload []byte) {
+	var (
+		width, height uint32
+		err           error
+	)
+	if !tag.IsExHeader && len(payload) >= 3 {
+		// 传统标签头的序列头同样带有3字节的CompositionTime
+		payload = payload[3:]
+	}
+	switch tag.Codec() {
+	case FourCCAVC:
+		width, height, err = parseAVCConfig(payload)
+	case FourCCHEVC:
+		width, height, err = parseHEVCConfig(payload)
+	default:
+		err = ErrInvalidSPS
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name := videoCodecName(tag); name != "" {
+		s.videoCodec = name
+	}
+	if err == nil {
+		s.width, s.height = width, height
+	}
+}
+
+// duration 返回已写入内容的时长。
+func (s *stats) duration() time.Duration {
+	first := atomic.LoadInt64(&s.firstTimestamp)
+	if first < 0 {
+		return 0
+	}
+	d := atomic.LoadInt64(&s.lastTimestamp) - first
+	if d < 0 {
+		return 0
+	}
+	return time.Duration(d) * time.Millisecond
+}
+
+// Status 返回解析状态，通用字段与 FFmpeg 解析器一致。
+func (p *Parser) Status() (map[string]string, error) {
+	written := atomic.LoadInt64(&p.stats.written)
+	duration := p.stats.duration()
+	status := map[string]string{
+		"parser":      Name,
+		"total_size":  strconv.FormatInt(written, 10),
+		"out_time":    parser.FormatOutTime(duration),
+		"timestamp":   strconv.FormatInt(atomic.LoadInt64(&p.stats.lastTimestamp), 10),
+		"audio_tags":  strconv.FormatInt(atomic.LoadInt64(&p.stats.audioTags), 10),
+		"video_tags":  strconv.FormatInt(atomic.LoadInt64(&p.stats.videoTags), 10),
+		"script_tags": strconv.FormatInt(atomic.LoadInt64(&p.stats.scriptTags), 10),
+	}
+	if seconds := duration.Seconds(); seconds > 0 {
+		status["bitrate"] = fmt.Sprintf("%.1fkbits/s", float64(written)*8/1000/seconds)
+		if frames := atomic.LoadInt64(&p.stats.videoFrames); frames > 0 {
+			status["fps"] = fmt.Sprintf("%.2f", float64(frames)/seconds)
+		}
+	}
+
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+	if p.stats.videoCodec != "" {
+		status["video_codec"] = p.stats.videoCodec
+	}
+	if p.stats.audioCodec != "" {
+		status["audio_codec"] = p.stats.audioCodec
+	}
+	if p.stats.width > 0 && p.stats.height > 0 {
+		status["resolution"] = fmt.Sprintf("%dx%d", p.stats.width, p.stats.height)
+	}
+	if p.stats.lastKeyframeTimestamp >= 0 {
+		status["last_keyframe_timestamp"] = strconv.FormatInt(p.stats.lastKeyframeTimestamp, 10)
+		status["last_keyframe_time"] = p.stats.lastKeyframeTime.Format(time.RFC3339)
+	}
+	return status, nil
+}
@@ -1,6 +1,10 @@
 package flv
 
-import "context"
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+)
 
 // parseTag 解析FLV文件中的标签。
 func (p *Parser) parseTag(ctx context.Context) error {
@@ -17,18 +21,36 @@ func (p *Parser) parseTag(ctx context.Context) error {
 	length := uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7])
 	timeStamp := uint32(b[8])<<16 | uint32(b[9])<<8 | uint32(b[10]) | uint32(b[11])<<24
 
+	if p.audioOnly {
+		// 只录制音频时丢弃视频标签
+		if tagType == videoTag {
+			p.i.Reset()
+			return p.doDiscard(length)
+		}
+		// 前一个标签的长度需要指向上一个写入的标签
+		binary.BigEndian.PutUint32(b[:4], p.lastTagSize)
+		p.lastTagSize = 11 + length
+	}
+
 	// 根据标签类型进行不同的处理
 	switch tagType {
 	case audioTag:
-		if _, err := p.parseAudioTag(ctx, length, timeStamp); err != nil {
+		tag, err := p.parseAudioTag(ctx, length, timeStamp)
+		if err != nil {
 			return err
 		}
+		p.stats.onAudioTag(timeStamp, tag)
 	case videoTag:
-		if _, err := p.parseVideoTag(ctx, length, timeStamp); err != nil {
+		tag, err := p.parseVideoTag(ctx, length, timeStamp)
+		if err != nil {
 			return err
 		}
+		p.stats.onVideoTag(timeStamp, tag)
 	case scriptTag:
-		return p.parseScriptTag(ctx, length)
+		if err := p.parseScriptTag(ctx, length); err != nil {
+			return err
+		}
+		atomic.AddInt64(&p.stats.scriptTags, 1)
 	default:
 		return ErrUnknownTag
 	}
@@ -1,20 +1,28 @@
 package flv
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"io"
 )
 
 type (
-	FrameType     uint8
-	CodeID        uint8
-	AVCPacketType uint8
+	FrameType       uint8
+	CodeID          uint8
+	AVCPacketType   uint8
+	VideoPacketType uint8
+	FourCC          string
 
 	VideoTagHeader struct {
 		FrameType       FrameType
 		CodeID          CodeID
 		AVCPacketType   AVCPacketType
 		CompositionTime uint32
+
+		// 以下字段只用于增强型RTMP的扩展头
+		IsExHeader bool
+		PacketType VideoPacketType
+		FourCC     FourCC
 	}
 )
 
@@ -27,53 +35,140 @@ const (
 	VideoInfoFrame       FrameType = 5 // 视频信息/命令帧
 
 	// 编码标识
-	H263Code          CodeID = 2 // Sorenson H.263
-	ScreenVideoCode   CodeID = 3 // 屏幕视频
-	VP6Code           CodeID = 4 // On2 VP6
-	VP6AlphaCode      CodeID = 5 // 带Alpha通道的On2 VP6
-	ScreenVideoV2Code CodeID = 6 // 屏幕视频版本2
-	AVCCode           CodeID = 7 // AVC
-
-	// AVC包类型
+	H263Code          CodeID = 2  // Sorenson H.263
+	ScreenVideoCode   CodeID = 3  // 屏幕视频
+	VP6Code           CodeID = 4  // On2 VP6
+	VP6AlphaCode      CodeID = 5  // 带Alpha通道的On2 VP6
+	ScreenVideoV2Code CodeID = 6  // 屏幕视频版本2
+	AVCCode           CodeID = 7  // AVC
+	HEVCCode          CodeID = 12 // HEVC，国内平台常用的非标准扩展
+	AV1Code           CodeID = 13 // AV1，国内平台常用的非标准扩展
+
+	// AVC包类型，HEVC和AV1的非标准扩展沿用相同的格式
 	AVCSeqHeader AVCPacketType = 0 // AVC序列头
 	AVCNALU      AVCPacketType = 1 // NAL单元
 	AVCEndSeq    AVCPacketType = 2 // AVC序列结束（不需要或不支持较低级别的NALU序列结束）
+
+	// 增强型RTMP的视频包类型
+	PacketTypeSequenceStart        VideoPacketType = 0 // 序列头
+	PacketTypeCodedFrames          VideoPacketType = 1 // 视频帧，HEVC时带有CompositionTime
+	PacketTypeSequenceEnd          VideoPacketType = 2 // 序列结束
+	PacketTypeCodedFramesX         VideoPacketType = 3 // 视频帧，CompositionTime为0
+	PacketTypeMetadata             VideoPacketType = 4 // 元数据
+	PacketTypeMPEG2TSSequenceStart VideoPacketType = 5 // MPEG2-TS格式的序列头
+	PacketTypeMultitrack           VideoPacketType = 6 // 多轨道
+
+	// 增强型RTMP的编码标识
+	FourCCAVC  FourCC = "avc1"
+	FourCCHEVC FourCC = "hvc1"
+	FourCCAV1  FourCC = "av01"
+	FourCCVP9  FourCC = "vp09"
+
+	// exHeaderFlag 是视频标签头第一个字节中表示扩展头的标志位
+	exHeaderFlag = 0x80
 )
 
-func (p *Parser) parseVideoTag(ctx context.Context, length, timestamp uint32) (*VideoTagHeader, error) {
-	// 解析标签头部
+// IsSequenceHeader 判断标签是否为视频序列头（AVC的sps和pps、HEVC的vps、sps和pps或AV1的序列头）。
+func (h *VideoTagHeader) IsSequenceHeader() bool {
+	if h.IsExHeader {
+		return h.PacketType == PacketTypeSequenceStart || h.PacketType == PacketTypeMPEG2TSSequenceStart
+	}
+	switch h.CodeID {
+	case AVCCode, HEVCCode, AV1Code:
+		return h.AVCPacketType == AVCSeqHeader
+	}
+	return false
+}
+
+// Codec 返回视频的编码名称，如 avc1、hvc1 和 av01。
+func (h *VideoTagHeader) Codec() FourCC {
+	if h.IsExHeader {
+		return h.FourCC
+	}
+	switch h.CodeID {
+	case AVCCode:
+		return FourCCAVC
+	case HEVCCode:
+		return FourCCHEVC
+	case AV1Code:
+		return FourCCAV1
+	}
+	return ""
+}
+
+// parseVideoTagHeader 读取视频标签头，返回标签头以及内容中除标签头外的剩余长度。
+// 支持传统的标签头（包括HEVC和AV1的非标准扩展）和增强型RTMP的扩展头。
+func (p *Parser) parseVideoTagHeader(length uint32) (*VideoTagHeader, uint32, error) {
 	b, err := p.i.ReadByte()
 	l := length - 1
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	tag := new(VideoTagHeader)
+
+	if b&exHeaderFlag != 0 {
+		// 增强型RTMP：UB[1] IsExHeader、UB[3] FrameType、UB[4] PacketType、FourCC
+		tag.IsExHeader = true
+		tag.FrameType = FrameType(b >> 4 & 7)
+		tag.PacketType = VideoPacketType(b & 15)
+		if tag.PacketType == PacketTypeMultitrack {
+			// 多轨道的标签头长度不固定，直接复制内容
+			return tag, l, nil
+		}
+		fourCC, err := p.i.ReadN(4)
+		l -= 4
+		if err != nil {
+			return nil, 0, err
+		}
+		tag.FourCC = FourCC(fourCC)
+		if tag.PacketType == PacketTypeCodedFrames && (tag.FourCC == FourCCAVC || tag.FourCC == FourCCHEVC) {
+			// 读取CompositionTime
+			b, err := p.i.ReadN(3)
+			l -= 3
+			if err != nil {
+				return nil, 0, err
+			}
+			tag.CompositionTime = uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return tag, l, nil
+	}
+
 	tag.FrameType = FrameType(b >> 4 & 15)
 	tag.CodeID = CodeID(b & 15)
-
-	if tag.CodeID == AVCCode {
+	switch tag.CodeID {
+	case AVCCode, HEVCCode, AV1Code:
 		// 读取AVCPacketType
 		b, err := p.i.ReadByte()
 		l -= 1
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		tag.AVCPacketType = AVCPacketType(b)
-		switch tag.AVCPacketType {
-		case AVCNALU:
+		if tag.AVCPacketType == AVCNALU {
 			// 读取CompositionTime
 			b, err := p.i.ReadN(3)
 			l -= 3
 			if err != nil {
-				return nil, err
+				return nil, 0, err
 			}
 			tag.CompositionTime = uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
-		case AVCSeqHeader:
-			p.avcHeaderCount++
-			if p.avcHeaderCount > 1 {
-				// 新的sps和pps
-				return nil, errors.New("EOF 新的sps和pps")
-			}
+		}
+	}
+	return tag, l, nil
+}
+
+func (p *Parser) parseVideoTag(ctx context.Context, length, timestamp uint32) (*VideoTagHeader, error) {
+	// 解析标签头部
+	tag, l, err := p.parseVideoTagHeader(length)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.IsSequenceHeader() {
+		p.seqHeaderCount++
+		if p.seqHeaderCount > 1 {
+			// 新的序列头，分辨率或编码可能已改变，需要开始新的文件
+			return nil, ErrNewSequenceHeader
 		}
 	}
 
@@ -83,9 +178,18 @@ func (p *Parser) parseVideoTag(ctx context.Context, length, timestamp uint32) (*
 	}
 	p.i.Reset()
 	// 写入内容
-	if err := p.doCopy(ctx, l); err != nil {
+	if !tag.IsSequenceHeader() {
+		if err := p.doCopy(ctx, l); err != nil {
+			return nil, err
+		}
+		return tag, nil
+	}
+	// 序列头在写入的同时保留一份，用于读取分辨率
+	payload := new(bytes.Buffer)
+	if err := p.doCopyTo(ctx, l, io.MultiWriter(p.o, payload)); err != nil {
 		return nil, err
 	}
+	p.stats.onSequenceHeader(tag, payload.Bytes())
 
 	return tag, nil
 }
@@ -4,11 +4,17 @@ package parser
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/yuhaohwang/bililive-go/src/live"
 )
 
+// ErrSplit 表示解析器因达到分割条件结束了当前文件，ParseLiveStream 返回的错误满足 errors.Is(err, ErrSplit) 时
+// 录制器会立即开始新的文件，不视为重连。
+var ErrSplit = errors.New("达到分割条件，开始新的文件")
+
 // Builder 定义了解析器构建器的接口。
 type Builder interface {
 	Build(cfg map[string]string) (Parser, error)
@@ -26,6 +32,16 @@ type StatusParser interface {
 	Status() (map[string]string, error)
 }
 
+// FormatOutTime 按 FFmpeg 进度中 out_time 的格式输出时长，用于各解析器 Status 中的 out_time。
+func FormatOutTime(d time.Duration) string {
+	d = d.Round(time.Microsecond)
+	h := d / time.Hour
+	m := d % time.Hour / time.Minute
+	sec := d % time.Minute / time.Second
+	us := d % time.Second / time.Microsecond
+	return fmt.Sprintf("%02d:%02d:%02d.%06d", h, m, sec, us)
+}
+
 var m = make(map[string]Builder)
 
 // Register 用于注册解析器构建器。
@@ -0,0 +1,175 @@
+package roomlist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// bililiveRecorderVersion 是录播姬配置文件的版本
+const bililiveRecorderVersion = 3
+
+// bililiveRecorderConfig 是录播姬 config.json 中与直播间有关的部分。
+type bililiveRecorderConfig struct {
+	Version int                    `json:"version"`
+	Rooms   []bililiveRecorderRoom `json:"rooms"`
+}
+
+type bililiveRecorderRoom struct {
+	RoomId     bililiveRecorderValue[int64] `json:"RoomId"`
+	AutoRecord bililiveRecorderValue[bool]  `json:"AutoRecord"`
+}
+
+// bililiveRecorderValue 是录播姬配置项的格式，HasValue 为 false 时使用默认值。
+type bililiveRecorderValue[T any] struct {
+	HasValue bool `json:"HasValue"`
+	Value    T    `json:"Value"`
+}
+
+// parseBililiveRecorder 解析录播姬的 config.json，自动录制映射为监听和录制。
+func parseBililiveRecorder(b []byte) ([]configs.LiveRoom, []Invalid, error) {
+	var cfg bililiveRecorderConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, nil, err
+	}
+	if cfg.Version != 0 && cfg.Version != bililiveRecorderVersion {
+		return nil, nil, fmt.Errorf("不支持的录播姬配置文件版本：%d", cfg.Version)
+	}
+	rooms := make([]configs.LiveRoom, 0, len(cfg.Rooms))
+	invalid := make([]Invalid, 0)
+	for _, r := range cfg.Rooms {
+		if r.RoomId.Value <= 0 {
+			invalid = append(invalid, Invalid{Value: strconv.FormatInt(r.RoomId.Value, 10), Error: "无效的房间号"})
+			continue
+		}
+		autoRecord := !r.AutoRecord.HasValue || r.AutoRecord.Value
+		rooms = append(rooms, configs.LiveRoom{
+			Url:    bilibiliRoomUrl(r.RoomId.Value),
+			Listen: autoRecord,
+			Record: autoRecord,
+		})
+	}
+	return rooms, invalid, nil
+}
+
+// formatBililiveRecorder 输出录播姬的 config.json，跳过不是哔哩哔哩的直播间。
+func formatBililiveRecorder(rooms []configs.LiveRoom) ([]byte, []configs.LiveRoom, error) {
+	cfg := bililiveRecorderConfig{
+		Version: bililiveRecorderVersion,
+		Rooms:   make([]bililiveRecorderRoom, 0, len(rooms)),
+	}
+	skipped := make([]configs.LiveRoom, 0)
+	for _, room := range rooms {
+		roomId, err := bilibiliRoomId(room.Url)
+		if err != nil {
+			skipped = append(skipped, room)
+			continue
+		}
+		id, _ := strconv.ParseInt(roomId, 10, 64)
+		cfg.Rooms = append(cfg.Rooms, bililiveRecorderRoom{
+			RoomId:     bililiveRecorderValue[int64]{HasValue: true, Value: id},
+			AutoRecord: bililiveRecorderValue[bool]{HasValue: true, Value: room.Listen && room.Record},
+		})
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	return b, skipped, err
+}
+
+// parseBlrec 解析 blrec 的 settings.toml 中的 [[tasks]]，只读取 room_id、enable_monitor 和 enable_recorder，
+// 其他的表和键会被忽略。
+func parseBlrec(b []byte) ([]configs.LiveRoom, []Invalid, error) {
+	rooms := make([]configs.LiveRoom, 0)
+	invalid := make([]Invalid, 0)
+	var (
+		room     *configs.LiveRoom
+		roomLine int
+	)
+	flush := func() {
+		if room == nil {
+			return
+		}
+		if room.Url == "" {
+			invalid = append(invalid, Invalid{Line: roomLine, Value: "[[tasks]]", Error: "缺少 room_id"})
+		} else {
+			rooms = append(rooms, *room)
+		}
+		room = nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		text := stripTomlComment(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			flush()
+			if text == "[[tasks]]" {
+				room = &configs.LiveRoom{Listen: true, Record: true}
+				roomLine = line
+			}
+			continue
+		}
+		if room == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("第 %d 行：无效的 TOML：%s", line, text)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "room_id":
+			var id int64
+			if id, err = strconv.ParseInt(value, 10, 64); err == nil {
+				if id <= 0 {
+					err = errors.New("无效的房间号")
+				} else {
+					room.Url = bilibiliRoomUrl(id)
+				}
+			}
+		case "enable_monitor":
+			room.Listen, err = strconv.ParseBool(value)
+		case "enable_recorder":
+			room.Record, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			invalid = append(invalid, Invalid{Line: line, Value: text, Error: err.Error()})
+			room = nil
+		}
+	}
+	flush()
+	return rooms, invalid, scanner.Err()
+}
+
+// stripTomlComment 去掉 TOML 行中的注释和空白，blrec 使用的键和值中不会出现 #。
+func stripTomlComment(line string) string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// formatBlrec 输出 blrec 的 settings.toml 中的 [[tasks]]，跳过不是哔哩哔哩的直播间。
+func formatBlrec(rooms []configs.LiveRoom) ([]byte, []configs.LiveRoom, error) {
+	buf := new(bytes.Buffer)
+	skipped := make([]configs.LiveRoom, 0)
+	for _, room := range rooms {
+		roomId, err := bilibiliRoomId(room.Url)
+		if err != nil {
+			skipped = append(skipped, room)
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "[[tasks]]\nroom_id = %s\nenable_monitor = %t\nenable_recorder = %t\n", roomId, room.Listen, room.Record)
+	}
+	return buf.Bytes(), skipped, nil
+}
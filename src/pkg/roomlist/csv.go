@@ -0,0 +1,147 @@
+package roomlist
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// csvHeader 是 CSV 格式的列，标签之间使用分号分隔
+var csvHeader = []string{"url", "listen", "record", "push", "rtmp", "quality", "host_name", "room_name", "group", "tags"}
+
+// csvTagSeparator 是 CSV 格式中标签的分隔符
+const csvTagSeparator = ";"
+
+// parseCSV 解析 CSV 格式，表头可以省略，省略时按 csvHeader 的顺序读取。
+// listen 和 record 为空时为 true。
+func parseCSV(b []byte) ([]configs.LiveRoom, []Invalid, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b, utf8BOM)))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	columns := csvHeader
+	rooms := make([]configs.LiveRoom, 0)
+	invalid := make([]Invalid, 0)
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				invalid = append(invalid, Invalid{Line: parseErr.Line, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := r.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "url") {
+			columns = make([]string, len(record))
+			for i, column := range record {
+				columns[i] = strings.ToLower(strings.TrimSpace(column))
+			}
+			continue
+		}
+		room, err := csvRecordToRoom(columns, record)
+		if err != nil {
+			invalid = append(invalid, Invalid{Line: line, Value: strings.Join(record, ","), Error: err.Error()})
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, invalid, nil
+}
+
+// csvRecordToRoom 将一行 CSV 转换为直播间配置。
+func csvRecordToRoom(columns, record []string) (configs.LiveRoom, error) {
+	room := configs.LiveRoom{Listen: true, Record: true}
+	for i, value := range record {
+		if i >= len(columns) {
+			break
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		var err error
+		switch columns[i] {
+		case "url":
+			room.Url = value
+		case "listen":
+			room.Listen, err = strconv.ParseBool(value)
+		case "record":
+			room.Record, err = strconv.ParseBool(value)
+		case "push":
+			room.Push, err = strconv.ParseBool(value)
+		case "rtmp":
+			room.Rtmp = value
+		case "quality":
+			room.Quality, err = strconv.Atoi(value)
+		case "host_name":
+			room.HostName = value
+		case "room_name":
+			room.RoomName = value
+		case "group":
+			room.Group = value
+		case "tags":
+			room.Tags = splitTags(value)
+		}
+		if err != nil {
+			return room, errors.New(columns[i] + "：" + err.Error())
+		}
+	}
+	if room.Url == "" {
+		return room, errors.New("地址为空")
+	}
+	return room, nil
+}
+
+// splitTags 拆分用分号分隔的标签，去掉空白和空的标签。
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, csvTagSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// formatCSV 输出带表头的 CSV 格式。
+func formatCSV(rooms []configs.LiveRoom) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, room := range rooms {
+		quality := ""
+		if room.Quality != 0 {
+			quality = strconv.Itoa(room.Quality)
+		}
+		record := []string{
+			room.Url,
+			strconv.FormatBool(room.Listen),
+			strconv.FormatBool(room.Record),
+			strconv.FormatBool(room.Push),
+			room.Rtmp,
+			quality,
+			room.HostName,
+			room.RoomName,
+			room.Group,
+			strings.Join(room.Tags, csvTagSeparator),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
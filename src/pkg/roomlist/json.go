@@ -0,0 +1,90 @@
+package roomlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// jsonRoom 是 JSON 格式中的直播间，字段与 POST /api/lives 相同。
+type jsonRoom struct {
+	Url      string   `json:"url"`
+	Listen   *bool    `json:"listen,omitempty"` // 为空时为 true
+	Record   *bool    `json:"record,omitempty"` // 为空时为 true
+	Push     bool     `json:"push,omitempty"`
+	Rtmp     string   `json:"rtmp,omitempty"`
+	Quality  int      `json:"quality,omitempty"`
+	HostName string   `json:"host_name,omitempty"`
+	RoomName string   `json:"room_name,omitempty"`
+	Group    string   `json:"group,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// parseJSON 解析直播间数组。
+func parseJSON(b []byte) ([]configs.LiveRoom, []Invalid, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return nil, nil, err
+	}
+	rooms := make([]configs.LiveRoom, 0, len(items))
+	invalid := make([]Invalid, 0)
+	for _, item := range items {
+		var r jsonRoom
+		if err := json.Unmarshal(item, &r); err != nil {
+			invalid = append(invalid, Invalid{Value: string(item), Error: err.Error()})
+			continue
+		}
+		if strings.TrimSpace(r.Url) == "" {
+			invalid = append(invalid, Invalid{Value: string(item), Error: "地址为空"})
+			continue
+		}
+		room := configs.LiveRoom{
+			Url:      strings.TrimSpace(r.Url),
+			Listen:   r.Listen == nil || *r.Listen,
+			Record:   r.Record == nil || *r.Record,
+			Push:     r.Push,
+			Rtmp:     strings.TrimSpace(r.Rtmp),
+			Quality:  r.Quality,
+			HostName: r.HostName,
+			RoomName: r.RoomName,
+			Group:    strings.TrimSpace(r.Group),
+		}
+		for _, tag := range r.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				room.Tags = append(room.Tags, tag)
+			}
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, invalid, nil
+}
+
+// formatJSON 输出直播间数组，可以直接用于 POST /api/lives。
+func formatJSON(rooms []configs.LiveRoom) ([]byte, error) {
+	items := make([]jsonRoom, 0, len(rooms))
+	for _, room := range rooms {
+		listen, record := room.Listen, room.Record
+		items = append(items, jsonRoom{
+			Url:      room.Url,
+			Listen:   &listen,
+			Record:   &record,
+			Push:     room.Push,
+			Rtmp:     room.Rtmp,
+			Quality:  room.Quality,
+			HostName: room.HostName,
+			RoomName: room.RoomName,
+			Group:    room.Group,
+			Tags:     room.Tags,
+		})
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(items); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,262 @@
+// Package roomlist 提供直播间列表在多种格式之间的导入和导出，用于在不同录制工具之间迁移。
+package roomlist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+// 支持的格式
+const (
+	FormatAuto             = "auto"             // 根据内容自动识别，只用于导入
+	FormatCSV              = "csv"              // 每行一个直播间，第一行为表头
+	FormatJSON             = "json"             // 与 POST /api/lives 相同的直播间数组
+	FormatURLs             = "urls"             // 每行一个直播间地址
+	FormatBililiveRecorder = "bililiverecorder" // 录播姬（BililiveRecorder）的 config.json，只支持哔哩哔哩
+	FormatBlrec            = "blrec"            // blrec 的 settings.toml，只支持哔哩哔哩
+)
+
+// Formats 是支持导入和导出的格式
+var Formats = []string{FormatCSV, FormatJSON, FormatURLs, FormatBililiveRecorder, FormatBlrec}
+
+var (
+	// ErrUnknownFormat 表示不支持的格式
+	ErrUnknownFormat = errors.New("不支持的格式")
+	// ErrNotBilibili 表示只支持哔哩哔哩直播间的格式中出现了其他平台的直播间
+	ErrNotBilibili = errors.New("只支持哔哩哔哩直播间")
+)
+
+// utf8BOM 是部分编辑器（如 Windows 记事本、Excel）在 UTF-8 文件开头写入的字节顺序标记
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// bilibiliHost 是哔哩哔哩直播间地址的域名
+const bilibiliHost = "live.bilibili.com"
+
+// bilibiliRoomIdRegex 用于从哔哩哔哩直播间地址中读取房间号
+var bilibiliRoomIdRegex = regexp.MustCompile(`^/(?:h5/)?(\d+)`)
+
+// Invalid 是无法导入的一项。
+type Invalid struct {
+	Line  int    `json:"line,omitempty"` // 所在的行号，无法确定时为0
+	Value string `json:"value"`
+	Error string `json:"error"`
+}
+
+// Duplicate 是与已有直播间或列表中前面的直播间重复的一项。
+type Duplicate struct {
+	Url      string  `json:"url"`
+	ID       live.ID `json:"id"`
+	Existing string  `json:"existing"` // 已有的直播间地址
+}
+
+// Plan 是导入的计划，包括需要添加、重复和无效的直播间。
+type Plan struct {
+	Add        []configs.LiveRoom `json:"add"`
+	Duplicates []Duplicate        `json:"duplicates"`
+	Invalid    []Invalid          `json:"invalid"`
+}
+
+// ParseUrl 解析直播间地址，没有协议时使用 https。
+func ParseUrl(rawUrl string) (*url.URL, error) {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if rawUrl == "" {
+		return nil, errors.New("地址为空")
+	}
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "https://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("无效的地址：%s", rawUrl)
+	}
+	return u, nil
+}
+
+// Key 返回用于去重的直播间标识：去掉域名的 www. 前缀和路径末尾的 /，
+// 哔哩哔哩直播间地址只保留房间号（去掉 h5/ 和后面的路径），再按 live.GenLiveId 生成标识。
+// 不会请求平台接口，因此同一个哔哩哔哩直播间的短号和长号会得到不同的标识。
+func Key(u *url.URL) live.ID {
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.TrimRight(u.Path, "/")
+	if host == bilibiliHost {
+		if m := bilibiliRoomIdRegex.FindStringSubmatch(u.Path); m != nil {
+			path = "/" + m[1]
+		}
+	}
+	return live.GenLiveId(&url.URL{Host: host, Path: path})
+}
+
+// NewPlan 规范化直播间地址，并按 Key 和直播 ID 去掉与 existing 中已有的直播间和列表中前面的直播间重复的项。
+// existing 是已有直播间的 ID 到地址的映射，见 ExistingRooms。
+func NewPlan(rooms []configs.LiveRoom, invalid []Invalid, existing map[live.ID]string) *Plan {
+	plan := &Plan{
+		Add:        make([]configs.LiveRoom, 0, len(rooms)),
+		Duplicates: make([]Duplicate, 0),
+		Invalid:    append(make([]Invalid, 0, len(invalid)), invalid...),
+	}
+	seen := make(map[live.ID]string, len(existing)+len(rooms))
+	for id, u := range existing {
+		seen[id] = u
+	}
+	for _, room := range rooms {
+		u, err := ParseUrl(room.Url)
+		if err != nil {
+			plan.Invalid = append(plan.Invalid, Invalid{Value: room.Url, Error: err.Error()})
+			continue
+		}
+		room.Url = u.String()
+		id, liveId := Key(u), live.GenLiveId(u)
+		if existingUrl, ok := seen[id]; ok {
+			plan.Duplicates = append(plan.Duplicates, Duplicate{Url: room.Url, ID: id, Existing: existingUrl})
+			continue
+		}
+		if existingUrl, ok := seen[liveId]; ok {
+			plan.Duplicates = append(plan.Duplicates, Duplicate{Url: room.Url, ID: liveId, Existing: existingUrl})
+			continue
+		}
+		seen[id] = room.Url
+		seen[liveId] = room.Url
+		plan.Add = append(plan.Add, room)
+	}
+	return plan
+}
+
+// ExistingRooms 返回配置中直播间的 ID 到地址的映射，用于去重。
+func ExistingRooms(rooms []configs.LiveRoom) map[live.ID]string {
+	existing := make(map[live.ID]string, len(rooms))
+	for _, room := range rooms {
+		if room.LiveId != "" {
+			existing[room.LiveId] = room.Url
+		}
+		AddExisting(existing, room.Url)
+	}
+	return existing
+}
+
+// AddExisting 将直播间地址按 Key 和直播 ID 加入 existing，地址无效时忽略。
+func AddExisting(existing map[live.ID]string, rawUrl string) {
+	u, err := ParseUrl(rawUrl)
+	if err != nil {
+		return
+	}
+	existing[Key(u)] = rawUrl
+	existing[live.GenLiveId(u)] = rawUrl
+}
+
+// Detect 根据内容识别格式。
+func Detect(b []byte) string {
+	s := strings.TrimSpace(string(b))
+	switch {
+	case strings.HasPrefix(s, "["):
+		if strings.Contains(s, "[[tasks]]") {
+			return FormatBlrec
+		}
+		return FormatJSON
+	case strings.HasPrefix(s, "{"):
+		return FormatBililiveRecorder
+	case strings.Contains(s, "[[tasks]]"):
+		return FormatBlrec
+	}
+	firstLine := strings.SplitN(s, "\n", 2)[0]
+	if strings.Contains(firstLine, ",") {
+		return FormatCSV
+	}
+	return FormatURLs
+}
+
+// Parse 解析指定格式的直播间列表，格式为 auto 或空时自动识别。
+// 返回解析出的直播间、无法解析的项和实际使用的格式，格式本身错误时返回错误。
+func Parse(format string, b []byte) ([]configs.LiveRoom, []Invalid, string, error) {
+	b = bytes.TrimPrefix(b, utf8BOM)
+	if format == "" || format == FormatAuto {
+		format = Detect(b)
+	}
+	var (
+		rooms   []configs.LiveRoom
+		invalid []Invalid
+		err     error
+	)
+	switch format {
+	case FormatCSV:
+		rooms, invalid, err = parseCSV(b)
+	case FormatJSON:
+		rooms, invalid, err = parseJSON(b)
+	case FormatURLs:
+		rooms, invalid = parseURLs(b)
+	case FormatBililiveRecorder:
+		rooms, invalid, err = parseBililiveRecorder(b)
+	case FormatBlrec:
+		rooms, invalid, err = parseBlrec(b)
+	default:
+		return nil, nil, format, fmt.Errorf("%w：%s", ErrUnknownFormat, format)
+	}
+	if invalid == nil {
+		invalid = make([]Invalid, 0)
+	}
+	return rooms, invalid, format, err
+}
+
+// Format 将直播间列表输出为指定格式，返回因格式不支持而跳过的直播间。
+func Format(format string, rooms []configs.LiveRoom) ([]byte, []configs.LiveRoom, error) {
+	switch format {
+	case FormatCSV:
+		b, err := formatCSV(rooms)
+		return b, nil, err
+	case FormatJSON:
+		b, err := formatJSON(rooms)
+		return b, nil, err
+	case FormatURLs:
+		return formatURLs(rooms), nil, nil
+	case FormatBililiveRecorder:
+		return formatBililiveRecorder(rooms)
+	case FormatBlrec:
+		return formatBlrec(rooms)
+	}
+	return nil, nil, fmt.Errorf("%w：%s", ErrUnknownFormat, format)
+}
+
+// ContentType 返回格式对应的 Content-Type 和默认的文件名。
+func ContentType(format string) (string, string) {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8", "rooms.csv"
+	case FormatJSON:
+		return "application/json", "rooms.json"
+	case FormatBililiveRecorder:
+		return "application/json", "config.json"
+	case FormatBlrec:
+		return "application/toml", "settings.toml"
+	}
+	return "text/plain; charset=utf-8", "rooms.txt"
+}
+
+// bilibiliRoomId 返回哔哩哔哩直播间地址中的房间号。
+func bilibiliRoomId(rawUrl string) (string, error) {
+	u, err := ParseUrl(rawUrl)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != bilibiliHost {
+		return "", ErrNotBilibili
+	}
+	m := bilibiliRoomIdRegex.FindStringSubmatch(u.Path)
+	if m == nil {
+		return "", fmt.Errorf("无法读取房间号：%s", rawUrl)
+	}
+	return m[1], nil
+}
+
+// bilibiliRoomUrl 返回哔哩哔哩房间号对应的直播间地址。
+func bilibiliRoomUrl(roomId int64) string {
+	return fmt.Sprintf("https://%s/%d", bilibiliHost, roomId)
+}
@@ -0,0 +1,191 @@
+package roomlist
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, FormatJSON, Detect([]byte(` [{"url":"https://live.bilibili.com/1"}]`)))
+	assert.Equal(t, FormatBililiveRecorder, Detect([]byte(`{"version":3,"rooms":[]}`)))
+	assert.Equal(t, FormatBlrec, Detect([]byte("[output]\npath = \"x\"\n\n[[tasks]]\nroom_id = 1\n")))
+	assert.Equal(t, FormatCSV, Detect([]byte("url,listen,record\nhttps://live.bilibili.com/1,true,true\n")))
+	assert.Equal(t, FormatURLs, Detect([]byte("https://live.bilibili.com/1\nhttps://www.douyu.com/2\n")))
+}
+
+func TestParseCSV(t *testing.T) {
+	rooms, invalid, format, err := Parse(FormatAuto, []byte(
+		"url,record,group,tags\n"+
+			"https://live.bilibili.com/1,false,news,a; b\n"+
+			",true,,\n"+
+			"https://www.douyu.com/2,yes,,\n"+
+			"www.huya.com/3,,,\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	assert.Equal(t, []configs.LiveRoom{
+		{Url: "https://live.bilibili.com/1", Listen: true, Group: "news", Tags: []string{"a", "b"}},
+		{Url: "www.huya.com/3", Listen: true, Record: true},
+	}, rooms)
+	if assert.Len(t, invalid, 2) {
+		assert.Equal(t, 3, invalid[0].Line)
+		assert.Equal(t, 4, invalid[1].Line)
+	}
+
+	// 没有表头时按默认的列读取
+	rooms, invalid, _, err = Parse(FormatCSV, []byte("https://live.bilibili.com/1,true,false,true,rtmp://a/b,10000\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, invalid)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/1", Listen: true, Push: true, Rtmp: "rtmp://a/b", Quality: 10000}}, rooms)
+}
+
+func TestParseURLsAndJSON(t *testing.T) {
+	rooms, invalid, _, err := Parse(FormatURLs, []byte("# 关注\nhttps://live.bilibili.com/1\n\nhttp://\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/1", Listen: true, Record: true}}, rooms)
+	assert.Equal(t, []Invalid{{Line: 4, Value: "http://", Error: "无效的地址：http://"}}, invalid)
+
+	rooms, invalid, _, err = Parse(FormatJSON, []byte(`[{"url":"https://live.bilibili.com/1","record":false,"tags":["a"," "]},{"listen":true},1]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/1", Listen: true, Tags: []string{"a"}}}, rooms)
+	assert.Len(t, invalid, 2)
+
+	_, _, _, err = Parse(FormatJSON, []byte(`{`))
+	assert.Error(t, err)
+	_, _, _, err = Parse("xml", nil)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestBilibiliFormats(t *testing.T) {
+	rooms, invalid, _, err := Parse(FormatAuto, []byte(`{
+  "$schema": "https://raw.githubusercontent.com/BililiveRecorder/BililiveRecorder/dev/configV3.schema.json",
+  "version": 3,
+  "global": {},
+  "rooms": [
+    {"RoomId": {"HasValue": true, "Value": 123}, "AutoRecord": {"HasValue": true, "Value": false}},
+    {"RoomId": {"HasValue": true, "Value": 456}},
+    {"RoomId": {"HasValue": false}}
+  ]
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []configs.LiveRoom{
+		{Url: "https://live.bilibili.com/123"},
+		{Url: "https://live.bilibili.com/456", Listen: true, Record: true},
+	}, rooms)
+	assert.Len(t, invalid, 1)
+
+	rooms, invalid, _, err = Parse(FormatAuto, []byte(`# blrec
+[header]
+user_agent = "x"
+
+[[tasks]]
+room_id = 123 # 注释
+enable_monitor = true
+enable_recorder = false
+
+[[tasks]]
+enable_monitor = true
+
+[[tasks]]
+room_id = 456
+
+[danmaku]
+danmu_uname = false
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []configs.LiveRoom{
+		{Url: "https://live.bilibili.com/123", Listen: true},
+		{Url: "https://live.bilibili.com/456", Listen: true, Record: true},
+	}, rooms)
+	assert.Equal(t, []Invalid{{Line: 10, Value: "[[tasks]]", Error: "缺少 room_id"}}, invalid)
+
+	// 导出时跳过其他平台的直播间
+	export := []configs.LiveRoom{
+		{Url: "https://live.bilibili.com/h5/123?x=1", Listen: true, Record: false},
+		{Url: "https://www.douyu.com/2", Listen: true, Record: true},
+	}
+	for _, format := range []string{FormatBililiveRecorder, FormatBlrec} {
+		b, skipped, err := Format(format, export)
+		assert.NoError(t, err)
+		assert.Equal(t, export[1:], skipped)
+		rooms, invalid, detected, err := Parse(FormatAuto, b)
+		assert.NoError(t, err)
+		assert.Equal(t, format, detected)
+		assert.Empty(t, invalid)
+		if format == FormatBlrec {
+			assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/123", Listen: true}}, rooms)
+		} else {
+			assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/123"}}, rooms)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	rooms := []configs.LiveRoom{
+		{Url: "https://live.bilibili.com/1", Listen: true, Record: true, Group: "news", Tags: []string{"a", "b"}},
+		{Url: "https://www.douyu.com/2", Listen: false, Record: true, Push: true, Rtmp: "rtmp://a/b", Quality: 1, HostName: "h", RoomName: "r"},
+	}
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		b, skipped, err := Format(format, rooms)
+		assert.NoError(t, err)
+		assert.Empty(t, skipped)
+		parsed, invalid, detected, err := Parse(FormatAuto, b)
+		assert.NoError(t, err)
+		assert.Equal(t, format, detected)
+		assert.Empty(t, invalid)
+		assert.Equal(t, rooms, parsed, format)
+	}
+	b, _, err := Format(FormatURLs, rooms)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://live.bilibili.com/1\nhttps://www.douyu.com/2\n", string(b))
+}
+
+func TestNewPlan(t *testing.T) {
+	u, _ := url.Parse("https://live.bilibili.com/1")
+	existing := map[live.ID]string{live.GenLiveId(u): u.String()}
+	plan := NewPlan([]configs.LiveRoom{
+		{Url: "live.bilibili.com/1"},
+		{Url: "https://www.douyu.com/2"},
+		{Url: "www.douyu.com/2"},
+		{Url: " "},
+	}, []Invalid{{Line: 9, Value: "x", Error: "e"}}, existing)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://www.douyu.com/2"}}, plan.Add)
+	if assert.Len(t, plan.Duplicates, 2) {
+		assert.Equal(t, "https://live.bilibili.com/1", plan.Duplicates[0].Existing)
+		assert.Equal(t, "https://www.douyu.com/2", plan.Duplicates[1].Existing)
+	}
+	assert.Len(t, plan.Invalid, 2)
+
+	existing = ExistingRooms([]configs.LiveRoom{{Url: "www.douyu.com/2"}})
+	assert.Equal(t, "www.douyu.com/2", existing[plan.Duplicates[1].ID])
+}
+
+func TestNewPlanNormalise(t *testing.T) {
+	existing := ExistingRooms([]configs.LiveRoom{{Url: "https://live.bilibili.com/1"}, {Url: "https://www.douyu.com/2"}})
+	plan := NewPlan([]configs.LiveRoom{
+		{Url: "https://live.bilibili.com/h5/1"},
+		{Url: "https://live.bilibili.com/1/?spm_id_from=x"},
+		{Url: "https://douyu.com/2/"},
+		{Url: "https://live.bilibili.com/3"},
+		{Url: "https://live.bilibili.com/h5/3"},
+	}, nil, existing)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/3"}}, plan.Add)
+	if assert.Len(t, plan.Duplicates, 4) {
+		assert.Equal(t, "https://live.bilibili.com/1", plan.Duplicates[0].Existing)
+		assert.Equal(t, "https://live.bilibili.com/1", plan.Duplicates[1].Existing)
+		assert.Equal(t, "https://www.douyu.com/2", plan.Duplicates[2].Existing)
+		assert.Equal(t, "https://live.bilibili.com/3", plan.Duplicates[3].Existing)
+	}
+}
+
+func TestParseBOM(t *testing.T) {
+	rooms, invalid, format, err := Parse(FormatAuto, []byte("\xEF\xBB\xBFurl,listen\nlive.bilibili.com/1,false\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	assert.Empty(t, invalid)
+	assert.Equal(t, []configs.LiveRoom{{Url: "live.bilibili.com/1", Record: true}}, rooms)
+}
@@ -0,0 +1,38 @@
+package roomlist
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+// parseURLs 解析每行一个直播间地址的列表，忽略空行和 # 开头的注释。
+func parseURLs(b []byte) ([]configs.LiveRoom, []Invalid) {
+	rooms := make([]configs.LiveRoom, 0)
+	invalid := make([]Invalid, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" || strings.HasPrefix(value, "#") {
+			continue
+		}
+		if _, err := ParseUrl(value); err != nil {
+			invalid = append(invalid, Invalid{Line: line, Value: value, Error: err.Error()})
+			continue
+		}
+		rooms = append(rooms, configs.LiveRoom{Url: value, Listen: true, Record: true})
+	}
+	return rooms, invalid
+}
+
+// formatURLs 输出每行一个直播间地址的列表。
+func formatURLs(rooms []configs.LiveRoom) []byte {
+	buf := new(bytes.Buffer)
+	for _, room := range rooms {
+		buf.WriteString(room.Url)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
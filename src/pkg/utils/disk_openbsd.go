@@ -0,0 +1,12 @@
+package utils
+
+import "golang.org/x/sys/unix"
+
+// DiskFree 返回路径所在磁盘对当前用户可用的剩余空间（字节）。
+func DiskFree(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.F_bavail) * uint64(stat.F_bsize), nil
+}
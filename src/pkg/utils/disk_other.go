@@ -0,0 +1,10 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+
+package utils
+
+import "errors"
+
+// DiskFree 在不支持的系统上总是返回错误，依赖剩余空间的告警和指标会被跳过。
+func DiskFree(path string) (uint64, error) {
+	return 0, errors.New("当前系统不支持获取磁盘剩余空间")
+}
@@ -0,0 +1,14 @@
+//go:build aix || darwin || dragonfly || freebsd || linux
+
+package utils
+
+import "syscall"
+
+// DiskFree 返回路径所在磁盘对当前用户可用的剩余空间（字节）。
+func DiskFree(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,14 @@
+//go:build netbsd || solaris
+
+package utils
+
+import "golang.org/x/sys/unix"
+
+// DiskFree 返回路径所在磁盘对当前用户可用的剩余空间（字节），illumos 也使用 solaris 的实现。
+func DiskFree(path string) (uint64, error) {
+	var stat unix.Statvfs_t
+	if err := unix.Statvfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Frsize), nil
+}
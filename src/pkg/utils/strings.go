@@ -6,6 +6,7 @@ import (
 	"html"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type StringFilter interface {
@@ -72,3 +73,28 @@ var RemoveSymbolOtherChar = StringFilterFunc(func(str string) string {
 	}
 	return str
 })
+
+// countUnits 是平台显示人数时使用的单位。
+var countUnits = []struct {
+	suffix string
+	value  float64
+}{
+	{"亿", 1e8}, {"万", 1e4}, {"w", 1e4}, {"W", 1e4}, {"k", 1e3}, {"K", 1e3},
+}
+
+// ParseCount 解析平台显示的人数，如 "12345"、"1.2万"、"3.4w"、"1,234"，无法解析时返回 0。
+func ParseCount(str string) int64 {
+	str = strings.TrimSuffix(strings.ReplaceAll(strings.TrimSpace(str), ",", ""), "+")
+	multiplier := 1.0
+	for _, unit := range countUnits {
+		if strings.HasSuffix(str, unit.suffix) {
+			str, multiplier = strings.TrimSuffix(str, unit.suffix), unit.value
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f * multiplier)
+}
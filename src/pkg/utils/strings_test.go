@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCount(t *testing.T) {
+	for str, want := range map[string]int64{
+		"12345":  12345,
+		"1,234":  1234,
+		"1.2万":   12000,
+		"3.4w":   34000,
+		"2亿":     200000000,
+		"10万+":   100000,
+		" 5.5k ": 5500,
+		"":       0,
+		"abc":    0,
+	} {
+		assert.Equal(t, want, ParseCount(str), str)
+	}
+}
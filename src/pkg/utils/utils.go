@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime/debug"
 	"strings"
@@ -34,6 +35,28 @@ func GetFFmpegPath(ctx context.Context) (string, error) {
 	return path, err
 }
 
+// GetFFprobePath 返回 ffprobe 的路径，配置了 ffmpeg_path 时使用同一目录下的 ffprobe。
+func GetFFprobePath(ctx context.Context) (string, error) {
+	return FFprobePath(instance.GetInstance(ctx).Config.FfmpegPath)
+}
+
+// FFprobePath 返回与 ffmpegPath 同一目录下的 ffprobe，ffmpegPath 为空时在 PATH 中查找。
+func FFprobePath(ffmpegPath string) (string, error) {
+	if ffmpegPath != "" {
+		// Windows 下为 ffprobe.exe
+		probe := filepath.Join(filepath.Dir(ffmpegPath), "ffprobe"+filepath.Ext(ffmpegPath))
+		if _, err := os.Stat(probe); err != nil {
+			return "", err
+		}
+		return probe, nil
+	}
+	path, err := exec.LookPath("ffprobe")
+	if errors.Is(err, exec.ErrDot) {
+		path, err = exec.LookPath("./ffprobe")
+	}
+	return path, err
+}
+
 func IsFFmpegExist(ctx context.Context) bool {
 	_, err := GetFFmpegPath(ctx)
 	return err == nil
@@ -100,3 +123,9 @@ func PrintStack(ctx context.Context) {
 	logger := inst.Logger
 	logger.Debugf(string(debug.Stack()))
 }
+
+// SidecarFile 返回与视频文件同名的附属文件路径，例如 a.flv 对应 a.metadata.json。
+func SidecarFile(fileName, suffix string) string {
+	ext := filepath.Ext(fileName)
+	return fileName[:len(fileName)-len(ext)] + suffix
+}
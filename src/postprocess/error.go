@@ -0,0 +1,11 @@
+package postprocess
+
+import "errors"
+
+var (
+	ErrJobNotFound  = errors.New("任务不存在")
+	ErrNoSourceFile = errors.New("文件不存在")
+	ErrStepCanceled = errors.New("任务已取消")
+	ErrJobRunning   = errors.New("任务正在执行或等待执行")
+	ErrJobFinished  = errors.New("任务已结束")
+)
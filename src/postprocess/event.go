@@ -0,0 +1,6 @@
+package postprocess
+
+import "github.com/yuhaohwang/bililive-go/src/pkg/events"
+
+// JobFinished 是一个事件类型，表示后处理任务执行结束（成功、失败或取消），事件对象为任务的快照 *Job。
+const JobFinished events.EventType = "PostProcessJobFinished"
@@ -0,0 +1,204 @@
+package postprocess
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+// maxFinishedJobs 是保存的已结束任务的最大数量，超出时丢弃最早的任务。
+const maxFinishedJobs = 500
+
+// Status 表示任务或步骤的状态。
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 等待执行
+	StatusRunning   Status = "running"   // 正在执行
+	StatusSucceeded Status = "succeeded" // 执行成功
+	StatusFailed    Status = "failed"    // 执行失败
+	StatusCanceled  Status = "canceled"  // 已取消
+	StatusSkipped   Status = "skipped"   // 不满足条件而跳过
+)
+
+// IsFinished 判断状态是否是结束状态。
+func (s Status) IsFinished() bool {
+	return s != StatusPending && s != StatusRunning
+}
+
+// LiveInfo 是任务中保存的直播信息快照。
+type LiveInfo struct {
+	ID       live.ID `json:"id"`
+	Url      string  `json:"url"`
+	Platform string  `json:"platform"`
+	HostName string  `json:"host_name"`
+	RoomName string  `json:"room_name"`
+}
+
+// NewLiveInfo 根据直播信息创建快照。
+func NewLiveInfo(l live.Live, info *live.Info) LiveInfo {
+	li := LiveInfo{}
+	if l != nil {
+		li.ID = l.GetLiveId()
+		li.Url = l.GetRawUrl()
+		li.Platform = l.GetPlatformCNName()
+	}
+	if info != nil {
+		li.HostName = info.HostName
+		li.RoomName = info.RoomName
+	}
+	return li
+}
+
+// ReadLiveInfo 从录制文件旁的 .metadata.json 中读取直播信息，文件不存在时返回空的信息。
+func ReadLiveInfo(fileName string) LiveInfo {
+	b, err := os.ReadFile(utils.SidecarFile(fileName, ".metadata.json"))
+	if err != nil {
+		return LiveInfo{}
+	}
+	metadata := gjson.ParseBytes(b)
+	return LiveInfo{
+		ID:       live.ID(metadata.Get("id").String()),
+		Url:      metadata.Get("live_url").String(),
+		Platform: metadata.Get("platform_cn_name").String(),
+		HostName: metadata.Get("host_name").String(),
+		RoomName: metadata.Get("room_name").String(),
+	}
+}
+
+// StepState 是任务中一个步骤的执行状态。
+type StepState struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Status     Status     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	Input      string     `json:"input,omitempty"`  // 步骤的输入文件
+	Output     string     `json:"output,omitempty"` // 步骤生成的文件
+	Error      string     `json:"error,omitempty"`
+	Stdout     string     `json:"stdout,omitempty"`    // 最后一次执行的标准输出或 HTTP 响应内容的末尾部分
+	Stderr     string     `json:"stderr,omitempty"`    // 最后一次执行的标准错误的末尾部分
+	ExitCode   *int       `json:"exit_code,omitempty"` // 最后一次执行的命令退出码
+	Duration   int64      `json:"duration_ms"`         // 最后一次执行的耗时（毫秒）
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Job 是一个录制文件的后处理任务。
+type Job struct {
+	ID         string       `json:"id"`
+	Live       LiveInfo     `json:"live"`
+	SourceFile string       `json:"source_file"`        // 录制生成的文件，合并任务中为合并后的文件
+	Segments   []string     `json:"segments,omitempty"` // 需要合并的分段文件，按录制顺序排列
+	File       string       `json:"file"`               // 当前文件，随步骤执行而变化
+	Status     Status       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	Steps      []*StepState `json:"steps"`
+	Duration   int64        `json:"duration_ms"` // 最后一次执行的耗时（毫秒）
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// finish 结束任务并记录耗时。
+func (j *Job) finish(status Status) {
+	j.Status = status
+	j.FinishedAt = now()
+	if j.StartedAt != nil {
+		j.Duration = j.FinishedAt.Sub(*j.StartedAt).Milliseconds()
+	}
+}
+
+// newStepStates 根据步骤配置生成等待执行的步骤状态。
+func newStepStates(steps []configs.PostProcessStep) []*StepState {
+	states := make([]*StepState, len(steps))
+	for i := range steps {
+		states[i] = &StepState{Name: steps[i].StepName(), Type: steps[i].Type, Status: StatusPending}
+	}
+	return states
+}
+
+// clone 返回任务的深拷贝。
+func (j *Job) clone() *Job {
+	c := *j
+	c.Segments = append([]string(nil), j.Segments...)
+	c.Steps = make([]*StepState, len(j.Steps))
+	for i, s := range j.Steps {
+		step := *s
+		c.Steps[i] = &step
+	}
+	return &c
+}
+
+// now 返回当前时间的指针。
+func now() *time.Time {
+	t := time.Now()
+	return &t
+}
+
+// store 负责将任务状态保存到文件。
+type store struct {
+	path string
+}
+
+// load 读取保存的任务，文件不存在时返回空列表。
+func (s *store) load() ([]*Job, error) {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0)
+	if err := json.Unmarshal(b, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// save 保存任务列表，先写入临时文件再替换，避免写入中断时损坏原文件。
+func (s *store) save(jobs []*Job) error {
+	b, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// sortJobs 将任务按创建时间倒序排列。
+func sortJobs(jobs []*Job) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+}
+
+// trimJobs 保留所有未结束的任务和最近的 maxFinishedJobs 个已结束任务，返回被丢弃的任务。
+// jobs 需要已按创建时间倒序排列。
+func trimJobs(jobs []*Job) (kept, dropped []*Job) {
+	finished := 0
+	for _, job := range jobs {
+		if job.Status.IsFinished() {
+			if finished++; finished > maxFinishedJobs {
+				dropped = append(dropped, job)
+				continue
+			}
+		}
+		kept = append(kept, job)
+	}
+	return kept, dropped
+}
@@ -0,0 +1,652 @@
+// Package postprocess 负责在录制完成后按顺序执行配置的后处理步骤。
+// 每个录制文件对应一个任务，任务在后台协程中执行，状态会保存到文件中以便重启后继续。
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/listeners"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+const (
+	// StateDirName 是输出路径下保存程序状态的目录名，文件列表中不会显示该目录。
+	StateDirName = ".bililive-go"
+	// defaultRetryDelay 是未设置 retry_delay 时的重试间隔。
+	defaultRetryDelay = 10 * time.Second
+	// drainPollInterval 是退出时检查任务是否完成的间隔。
+	drainPollInterval = 100 * time.Millisecond
+	// drainLogInterval 是退出时输出等待进度的间隔。
+	drainLogInterval = 5 * time.Second
+)
+
+// Manager 定义后处理管理器的接口。
+type Manager interface {
+	interfaces.Module
+	// Enqueue 为文件创建一个后处理任务。
+	Enqueue(info LiveInfo, fileName string) (*Job, error)
+	// Jobs 返回所有任务的快照，按创建时间倒序排列。
+	Jobs() []*Job
+	// GetJob 返回指定任务的快照。
+	GetJob(id string) (*Job, error)
+	// Cancel 取消等待执行或正在执行的任务。
+	Cancel(id string) error
+	// Rerun 重新执行已结束的任务。fromFailed 为 true 时跳过已成功的步骤，
+	// 否则使用当前配置从录制生成的文件重新执行所有步骤。
+	Rerun(id string, fromFailed bool) (*Job, error)
+	// Drain 为未合并的场次创建任务，并等待所有任务执行完毕，ctx 结束时返回其错误。
+	// 之后调用 Close 时未完成的任务会被保存，在下次启动时继续。
+	Drain(ctx context.Context) error
+}
+
+// manager 是 Manager 的实现。
+type manager struct {
+	inst  *instance.Instance
+	store *store
+
+	lock    sync.Mutex
+	jobs    map[string]*Job
+	pending []string                      // 等待执行的任务 ID
+	cancels map[string]context.CancelFunc // 正在执行的任务的取消函数
+	sems    map[string]chan struct{}      // 各步骤的并发限制
+
+	sessions map[string]*session // 开启分段合并时，尚未合并的直播场次
+	open     map[live.ID]string  // 各直播间当前场次的标识
+	flushing sync.WaitGroup      // 正在为场次创建任务的协程
+	closed   bool                // 是否已关闭，关闭后不再接收新的分段
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager 创建一个新的后处理管理器实例。
+func NewManager(ctx context.Context) Manager {
+	inst := instance.GetInstance(ctx)
+	m := &manager{
+		inst:     inst,
+		store:    &store{path: stateFile(inst.Config)},
+		jobs:     make(map[string]*Job),
+		cancels:  make(map[string]context.CancelFunc),
+		sems:     make(map[string]chan struct{}),
+		sessions: make(map[string]*session),
+		open:     make(map[live.ID]string),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	inst.PostProcessManager = m
+	return m
+}
+
+// stateFile 返回任务状态的保存路径。
+func stateFile(cfg *configs.Config) string {
+	if cfg.PostProcess.StateFile != "" {
+		return cfg.PostProcess.StateFile
+	}
+	return filepath.Join(cfg.OutPutPath, StateDirName, "jobs.json")
+}
+
+// Start 恢复保存的任务，监听录制完成事件并启动工作协程。
+func (m *manager) Start(ctx context.Context) error {
+	jobs, err := m.store.load()
+	if err != nil {
+		m.inst.Logger.WithError(err).Warnf("读取后处理任务失败：%s", m.store.path)
+	}
+	sortJobs(jobs)
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		m.jobs[job.ID] = job
+		// 上次退出时未完成的任务重新排队，已成功的步骤不会重复执行
+		if !job.Status.IsFinished() {
+			job.Status = StatusPending
+			m.enqueueLocked(job.ID)
+		}
+	}
+
+	if ed, ok := m.inst.EventDispatcher.(events.Dispatcher); ok {
+		ed.AddEventListener(recorders.RecordFileFinished, events.NewEventListener(func(event *events.Event) {
+			param := event.Object.(*recorders.FileFinishedParam)
+			info := NewLiveInfo(param.Live, param.Info)
+			if param.Info == nil {
+				// 启动时恢复的文件只有元数据中的直播信息
+				info = ReadLiveInfo(param.FileName)
+			}
+			// 开启分段合并时，分段在直播结束后统一处理，无法确定所属场次的文件直接处理
+			if m.inst.Config.PostProcess.Merge.Enable && !param.StartTime.IsZero() {
+				m.addSegment(sessionKey(info.ID, param.StartTime), info, param.FileName)
+				return
+			}
+			if len(m.inst.Config.GetSteps()) == 0 {
+				return
+			}
+			if _, err := m.Enqueue(info, param.FileName); err != nil {
+				m.inst.Logger.WithError(err).Warnf("创建后处理任务失败：%s", param.FileName)
+			}
+		}))
+		ed.AddEventListener(listeners.LiveStart, events.NewEventListener(func(event *events.Event) {
+			l := event.Object.(live.Live)
+			if m.inst.Config.PostProcess.Merge.Enable {
+				m.openSession(l.GetLiveId(), l.GetLastStartTime())
+			}
+		}))
+		endListener := events.NewEventListener(func(event *events.Event) {
+			l := event.Object.(live.Live)
+			if !m.inst.Config.PostProcess.Merge.Enable {
+				return
+			}
+			m.endSession(l.GetLiveId())
+		})
+		ed.AddEventListener(listeners.LiveEnd, endListener)
+		ed.AddEventListener(listeners.ListenStop, endListener)
+	}
+
+	workers := m.inst.Config.PostProcess.Workers
+	if workers <= 0 {
+		workers = configs.NewConfig().PostProcess.Workers
+	}
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.run(ctx)
+	}
+	m.signal()
+	return nil
+}
+
+// Close 为未合并的场次创建任务，取消正在执行的任务并等待工作协程退出，被取消的任务会在下次启动时继续。
+// 关闭后合并计时器不会再创建任务。
+func (m *manager) Close(ctx context.Context) {
+	m.lock.Lock()
+	m.closed = true
+	m.lock.Unlock()
+	m.flushSessions()
+	m.flushing.Wait()
+	close(m.stop)
+	m.lock.Lock()
+	for _, cancel := range m.cancels {
+		cancel()
+	}
+	m.lock.Unlock()
+	m.wg.Wait()
+}
+
+// Drain 为未合并的场次创建任务，并等待所有任务执行完毕。
+func (m *manager) Drain(ctx context.Context) error {
+	m.flushSessions()
+	poll := time.NewTicker(drainPollInterval)
+	defer poll.Stop()
+	lastLog := time.Now()
+	for {
+		n := m.unfinished()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			m.inst.Logger.Warnf("等待超时，%d 个后处理任务将在下次启动时继续", n)
+			return ctx.Err()
+		case <-poll.C:
+		}
+		if time.Since(lastLog) >= drainLogInterval {
+			m.inst.Logger.Infof("正在等待 %d 个后处理任务完成", n)
+			lastLog = time.Now()
+		}
+	}
+}
+
+// unfinished 返回等待执行和正在执行的任务数。
+func (m *manager) unfinished() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	n := 0
+	for _, job := range m.jobs {
+		if !job.Status.IsFinished() {
+			n++
+		}
+	}
+	return n
+}
+
+// Enqueue 为文件创建一个后处理任务。
+func (m *manager) Enqueue(info LiveInfo, fileName string) (*Job, error) {
+	if _, err := os.Stat(fileName); err != nil {
+		return nil, ErrNoSourceFile
+	}
+	job := &Job{
+		ID:         time.Now().Format("20060102150405") + "-" + utils.GenRandomName(6),
+		Live:       info,
+		SourceFile: fileName,
+		File:       fileName,
+		Status:     StatusPending,
+		CreatedAt:  time.Now(),
+	}
+	m.lock.Lock()
+	m.jobs[job.ID] = job
+	m.enqueueLocked(job.ID)
+	m.saveLocked()
+	snapshot := job.clone()
+	m.lock.Unlock()
+	m.signal()
+	return snapshot, nil
+}
+
+// Jobs 返回所有任务的快照。
+func (m *manager) Jobs() []*Job {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.clone())
+	}
+	sortJobs(jobs)
+	return jobs
+}
+
+// GetJob 返回指定任务的快照。
+func (m *manager) GetJob(id string) (*Job, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return job.clone(), nil
+}
+
+// Cancel 取消等待执行或正在执行的任务。
+func (m *manager) Cancel(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	switch job.Status {
+	case StatusPending:
+		m.dequeueLocked(id)
+		job.Error = ErrStepCanceled.Error()
+		job.finish(StatusCanceled)
+		m.saveLocked()
+	case StatusRunning:
+		// 由执行任务的工作协程更新状态
+		if cancel, ok := m.cancels[id]; ok {
+			cancel()
+		}
+	default:
+		return ErrJobFinished
+	}
+	return nil
+}
+
+// Rerun 重新执行已结束的任务。
+func (m *manager) Rerun(id string, fromFailed bool) (*Job, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if !job.Status.IsFinished() {
+		return nil, ErrJobRunning
+	}
+	if fromFailed {
+		for _, state := range job.Steps {
+			if state.Status != StatusSucceeded && state.Status != StatusSkipped {
+				state.Status = StatusPending
+			}
+		}
+	} else {
+		if !hasSource(job) {
+			return nil, ErrNoSourceFile
+		}
+		job.File = job.SourceFile
+		job.Steps = nil
+	}
+	job.Status = StatusPending
+	job.Error = ""
+	job.FinishedAt = nil
+	job.Duration = 0
+	m.enqueueLocked(job.ID)
+	m.saveLocked()
+	m.signal()
+	return job.clone(), nil
+}
+
+// signal 唤醒一个空闲的工作协程。
+func (m *manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueLocked 将任务加入等待队列，已在队列中时忽略，调用方需要持有锁。
+func (m *manager) enqueueLocked(id string) {
+	for _, pending := range m.pending {
+		if pending == id {
+			return
+		}
+	}
+	m.pending = append(m.pending, id)
+}
+
+// dequeueLocked 将任务移出等待队列，调用方需要持有锁。
+func (m *manager) dequeueLocked(id string) {
+	for i, pending := range m.pending {
+		if pending == id {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// next 取出下一个等待执行的任务，没有时返回 nil。
+// 任务在锁内被标记为正在执行并登记取消函数，避免多个工作协程取出同一个任务，也避免开始执行前的取消请求丢失。
+func (m *manager) next(ctx context.Context) (*Job, context.Context) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for len(m.pending) > 0 {
+		id := m.pending[0]
+		m.pending = m.pending[1:]
+		job, ok := m.jobs[id]
+		if !ok || job.Status != StatusPending {
+			continue
+		}
+		jobCtx, cancel := context.WithCancel(ctx)
+		m.cancels[id] = cancel
+		job.Status = StatusRunning
+		if len(m.pending) > 0 {
+			m.signal()
+		}
+		return job, jobCtx
+	}
+	return nil, nil
+}
+
+// run 是工作协程的主循环。
+func (m *manager) run(ctx context.Context) {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+		job, jobCtx := m.next(ctx)
+		if job == nil {
+			select {
+			case <-m.stop:
+				return
+			case <-m.wake:
+			}
+			continue
+		}
+		m.process(jobCtx, job)
+	}
+}
+
+// saveLocked 保存任务状态，调用方需要持有锁。
+func (m *manager) saveLocked() {
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	kept, dropped := trimJobs(jobs)
+	for _, job := range dropped {
+		delete(m.jobs, job.ID)
+	}
+	if err := m.store.save(kept); err != nil {
+		m.inst.Logger.WithError(err).Warnf("保存后处理任务失败：%s", m.store.path)
+	}
+}
+
+// update 在锁内修改任务状态并保存。
+func (m *manager) update(fn func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	fn()
+	m.saveLocked()
+}
+
+// dispatchFinished 分发任务结束事件，事件对象为任务的快照。
+func (m *manager) dispatchFinished(job *Job) {
+	ed, ok := m.inst.EventDispatcher.(events.Dispatcher)
+	if !ok {
+		return
+	}
+	m.lock.Lock()
+	snapshot := job.clone()
+	m.lock.Unlock()
+	ed.DispatchEvent(events.NewEvent(JobFinished, snapshot))
+}
+
+// getLogger 返回带有任务信息的日志记录器。
+func (m *manager) getLogger(job *Job) *logrus.Entry {
+	return m.inst.Logger.WithFields(map[string]interface{}{
+		"job":     job.ID,
+		"file":    job.SourceFile,
+		"live_id": job.Live.ID,
+	})
+}
+
+// acquire 获取步骤的并发许可，返回释放函数。
+func (m *manager) acquire(ctx context.Context, step *configs.PostProcessStep) (func(), error) {
+	if step.Concurrency <= 0 {
+		return func() {}, nil
+	}
+	m.lock.Lock()
+	key := step.StepName()
+	sem, ok := m.sems[key]
+	if !ok || cap(sem) != step.Concurrency {
+		sem = make(chan struct{}, step.Concurrency)
+		m.sems[key] = sem
+	}
+	m.lock.Unlock()
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// templateData 生成执行步骤时的模板数据。
+func (m *manager) templateData(ctx context.Context, job *Job) *TemplateData {
+	l, _ := m.inst.GetLive(job.Live.ID)
+	info := &live.Info{Live: l, HostName: job.Live.HostName, RoomName: job.Live.RoomName}
+	if l != nil && m.inst.Cache != nil {
+		if obj, err := m.inst.Cache.Get(l); err == nil {
+			snapshot := *obj.(*live.Info)
+			info = &snapshot
+		}
+	}
+	data := &TemplateData{
+		Info:       info,
+		Live:       l,
+		LiveID:     job.Live.ID,
+		Platform:   job.Live.Platform,
+		HostName:   job.Live.HostName,
+		RoomName:   job.Live.RoomName,
+		LiveUrl:    job.Live.Url,
+		JobID:      job.ID,
+		FileName:   job.File,
+		SourceFile: job.SourceFile,
+	}
+	if stat, err := os.Stat(job.File); err == nil {
+		data.FileSize = stat.Size()
+	}
+	data.Ffmpeg, _ = utils.GetFFmpegPath(ctx)
+	return data
+}
+
+// process 按顺序执行任务的所有步骤，jobCtx 为 next 创建的任务上下文。
+func (m *manager) process(jobCtx context.Context, job *Job) {
+	cfg := m.inst.Config
+	steps := jobSteps(cfg, job)
+	m.update(func() {
+		job.Status = StatusRunning
+		job.Error = ""
+		job.StartedAt = now()
+		job.FinishedAt = nil
+		job.Duration = 0
+		// 步骤配置变化时重新生成步骤状态
+		if !sameSteps(job.Steps, steps) {
+			job.Steps = newStepStates(steps)
+		}
+	})
+	defer func() {
+		m.lock.Lock()
+		cancel := m.cancels[job.ID]
+		delete(m.cancels, job.ID)
+		m.lock.Unlock()
+		cancel()
+	}()
+	logger := m.getLogger(job)
+	logger.Infof("开始后处理")
+
+	for i := range steps {
+		step := &steps[i]
+		state := job.Steps[i]
+		if state.Status == StatusSucceeded || state.Status == StatusSkipped {
+			continue
+		}
+		err := m.runStep(jobCtx, cfg, step, job, state)
+		if err == nil {
+			continue
+		}
+		if jobCtx.Err() != nil {
+			select {
+			case <-m.stop:
+				// 关闭时被取消的任务在下次启动时继续
+				m.update(func() {
+					state.Status = StatusPending
+					job.Status = StatusPending
+				})
+			default:
+				m.update(func() {
+					state.Status = StatusCanceled
+					job.Error = ErrStepCanceled.Error()
+					job.finish(StatusCanceled)
+				})
+				m.dispatchFinished(job)
+				logger.Infof("后处理已取消")
+			}
+			return
+		}
+		logger.WithError(err).Warnf("后处理步骤 %s 执行失败", step.StepName())
+		if !step.ContinueOnError {
+			m.update(func() {
+				job.Error = fmt.Sprintf("%s：%s", step.StepName(), err)
+				job.finish(StatusFailed)
+			})
+			m.dispatchFinished(job)
+			return
+		}
+	}
+	m.update(func() {
+		job.finish(StatusSucceeded)
+	})
+	m.dispatchFinished(job)
+	logger.Infof("后处理完成：%s", job.File)
+}
+
+// runStep 执行一个步骤，包括条件判断、并发限制和重试。
+func (m *manager) runStep(ctx context.Context, cfg *configs.Config, step *configs.PostProcessStep, job *Job, state *StepState) error {
+	data := m.templateData(ctx, job)
+	ok, err := match(cfg, step, data)
+	if err != nil || !ok {
+		m.update(func() {
+			state.Input = job.File
+			state.Status = StatusSkipped
+			if err != nil {
+				state.Status = StatusFailed
+				state.Error = err.Error()
+			}
+			state.FinishedAt = now()
+		})
+		return err
+	}
+
+	release, err := m.acquire(ctx, step)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	retryDelay := step.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		m.update(func() {
+			state.Status = StatusRunning
+			state.Attempts++
+			state.Input = job.File
+			state.Error = ""
+			state.Stdout, state.Stderr, state.ExitCode, state.Duration = "", "", nil, 0
+			state.StartedAt = now()
+			state.FinishedAt = nil
+		})
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.Timeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		}
+		env := newStepEnv(cfg, step, job, data)
+		output, err := runStepFunc(stepCtx, env)
+		cancel()
+		m.update(func() {
+			state.Stdout = env.stdout.String()
+			state.Stderr = env.stderr.String()
+			state.ExitCode = env.exitCode
+			state.FinishedAt = now()
+			state.Duration = state.FinishedAt.Sub(*state.StartedAt).Milliseconds()
+			if err != nil {
+				state.Status = StatusFailed
+				state.Error = err.Error()
+				return
+			}
+			state.Status = StatusSucceeded
+			state.Output = output
+			if output != "" {
+				job.File = output
+			}
+		})
+		if err == nil {
+			return nil
+		}
+		if attempt >= step.Retries || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// sameSteps 判断步骤状态是否与当前配置的步骤一致。
+func sameSteps(states []*StepState, steps []configs.PostProcessStep) bool {
+	if len(states) != len(steps) {
+		return false
+	}
+	for i := range steps {
+		if states[i].Name != steps[i].StepName() || states[i].Type != steps[i].Type {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,412 @@
+package postprocess
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bluele/gcache"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
+)
+
+// newTestManager 创建一个以临时目录为输出路径的管理器。
+func newTestManager(t *testing.T, steps ...configs.PostProcessStep) (context.Context, *manager) {
+	cfg := configs.NewConfig()
+	cfg.OutPutPath = t.TempDir()
+	cfg.PostProcess.Steps = steps
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Config: cfg,
+		Logger: &interfaces.Logger{Logger: logger},
+	})
+	return ctx, NewManager(ctx).(*manager)
+}
+
+// writeFile 在输出路径下创建一个文件。
+func writeFile(t *testing.T, m *manager, name, content string) string {
+	fileName := filepath.Join(m.inst.Config.OutPutPath, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(fileName), os.ModePerm))
+	assert.NoError(t, os.WriteFile(fileName, []byte(content), 0644))
+	return fileName
+}
+
+// waitJob 等待任务结束并返回其快照。
+func waitJob(t *testing.T, m *manager, id string) *Job {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.GetJob(id)
+		assert.NoError(t, err)
+		if job.Status.IsFinished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("任务 %s 未在规定时间内结束", id)
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	var webhook map[string]*Job
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &webhook)
+	}))
+	defer server.Close()
+
+	ctx, m := newTestManager(t,
+		configs.PostProcessStep{Type: configs.StepCommand, Command: `printf '{{ .HostName }}' > "{{ .FileName }}.txt"`},
+		configs.PostProcessStep{Name: "skip-small", Type: configs.StepCommand, Command: "exit 1", If: configs.PostProcessCondition{MinSize: 1}},
+		configs.PostProcessStep{Type: configs.StepMove, Target: "{{ .Platform }}/{{ .HostName }}"},
+		configs.PostProcessStep{Type: configs.StepWebhook, Url: server.URL + "/{{ .JobID }}"},
+	)
+	fileName := writeFile(t, m, "a/[x][y].flv", "flv")
+	writeFile(t, m, "a/[x][y].metadata.json", "{}")
+	writeFile(t, m, "a/[x][y]z.flv", "other")
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	job, err := m.Enqueue(LiveInfo{Platform: "哔哩哔哩", HostName: "主播"}, fileName)
+	assert.NoError(t, err)
+	job = waitJob(t, m, job.ID)
+
+	target := filepath.Join(m.inst.Config.OutPutPath, "哔哩哔哩", "主播")
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, filepath.Join(target, "[x][y].flv"), job.File)
+	assert.Equal(t, []Status{StatusSucceeded, StatusSkipped, StatusSucceeded, StatusSucceeded},
+		[]Status{job.Steps[0].Status, job.Steps[1].Status, job.Steps[2].Status, job.Steps[3].Status})
+
+	// 附属文件随之移动，名称相似的其他文件不受影响
+	b, err := os.ReadFile(filepath.Join(target, "[x][y].flv.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "主播", string(b))
+	assert.FileExists(t, filepath.Join(target, "[x][y].metadata.json"))
+	assert.FileExists(t, filepath.Join(m.inst.Config.OutPutPath, "a", "[x][y]z.flv"))
+	if assert.NotNil(t, webhook["job"]) {
+		assert.Equal(t, job.ID, webhook["job"].ID)
+	}
+}
+
+func TestPipelineRetryAndFailure(t *testing.T) {
+	ctx, m := newTestManager(t,
+		configs.PostProcessStep{Type: configs.StepCommand, Command: "exit 3", Retries: 2, RetryDelay: time.Millisecond},
+		configs.PostProcessStep{Type: configs.StepCommand, Command: "touch {{ .FileName }}.never"},
+	)
+	fileName := writeFile(t, m, "a.flv", "flv")
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	job, err := m.Enqueue(LiveInfo{}, fileName)
+	assert.NoError(t, err)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, 3, job.Steps[0].Attempts)
+	assert.Contains(t, job.Steps[0].Error, "exit status 3")
+	assert.Equal(t, StatusPending, job.Steps[1].Status)
+	assert.NoFileExists(t, fileName+".never")
+
+	_, err = m.Enqueue(LiveInfo{}, filepath.Join(m.inst.Config.OutPutPath, "missing.flv"))
+	assert.Equal(t, ErrNoSourceFile, err)
+}
+
+func TestPipelineTimeout(t *testing.T) {
+	ctx, m := newTestManager(t,
+		configs.PostProcessStep{Type: configs.StepCommand, Command: "sleep 10", Timeout: 50 * time.Millisecond},
+	)
+	fileName := writeFile(t, m, "a.flv", "flv")
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	job, _ := m.Enqueue(LiveInfo{}, fileName)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Less(t, job.FinishedAt.Sub(*job.StartedAt), 5*time.Second)
+}
+
+func TestLegacyTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetLiveId().Return(live.ID("a")).AnyTimes()
+	l.EXPECT().GetRawUrl().Return("https://live.bilibili.com/1").AnyTimes()
+
+	ctx, m := newTestManager(t)
+	m.inst.Config.OnRecordFinished.CustomCommandline = `printf '{{ .Status }} {{ .AudioOnly }} {{ .Live.GetRawUrl }} {{ .HostName }} {{ .RoomName }}' > "{{ .FileName }}.txt"`
+	m.inst.Cache = gcache.New(4).LRU().Build()
+	m.inst.Lives = map[live.ID]live.Live{"a": l}
+	m.inst.Cache.Set(l, &live.Info{Live: l, HostName: "主播", RoomName: "房间", Status: true})
+	fileName := writeFile(t, m, "a.flv", "flv")
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	// 旧版 custom_commandline 的模板数据为直播信息加上 FileName 和 Ffmpeg
+	job, err := m.Enqueue(LiveInfo{ID: "a", HostName: "主播", RoomName: "房间"}, fileName)
+	assert.NoError(t, err)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	b, err := os.ReadFile(fileName + ".txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "true false https://live.bilibili.com/1 主播 房间", string(b))
+}
+
+func TestRecoverJobs(t *testing.T) {
+	ctx, m := newTestManager(t,
+		configs.PostProcessStep{Name: "first", Type: configs.StepCommand, Command: "echo 1 >> {{ .FileName }}.first"},
+		configs.PostProcessStep{Name: "second", Type: configs.StepCommand, Command: "echo 2 >> {{ .FileName }}.second"},
+	)
+	fileName := writeFile(t, m, "a.flv", "flv")
+	// 模拟上次退出时第一个步骤已完成、第二个步骤正在执行的任务
+	running := &Job{
+		ID:         "1",
+		SourceFile: fileName,
+		File:       fileName,
+		Status:     StatusRunning,
+		CreatedAt:  time.Now(),
+		Steps: []*StepState{
+			{Name: "first", Type: configs.StepCommand, Status: StatusSucceeded},
+			{Name: "second", Type: configs.StepCommand, Status: StatusRunning},
+		},
+	}
+	finished := &Job{ID: "2", SourceFile: fileName, File: fileName, Status: StatusFailed, CreatedAt: time.Now()}
+	assert.NoError(t, m.store.save([]*Job{running, finished}))
+
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+	job := waitJob(t, m, "1")
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.NoFileExists(t, fileName+".first")
+	assert.FileExists(t, fileName+".second")
+	job, _ = m.GetJob("2")
+	assert.Equal(t, StatusFailed, job.Status)
+
+	jobs, err := m.store.load()
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
+
+func TestOutputFile(t *testing.T) {
+	assert.Equal(t, "/a/b.mp4", outputFile("/a/b.flv", "remux", "mp4"))
+	assert.Equal(t, "/a/b.transcode.mp4", outputFile("/a/b.mp4", "transcode", "mp4"))
+}
+
+func TestCancelAndRerun(t *testing.T) {
+	ctx, m := newTestManager(t,
+		configs.PostProcessStep{Name: "echo", Type: configs.StepCommand, Command: "echo out; echo err >&2; echo {{ .JobID }} >> {{ .FileName }}.runs"},
+		configs.PostProcessStep{Name: "wait", Type: configs.StepCommand, Command: `test -f "{{ .FileName }}.ok" || sleep 10`},
+	)
+	fileName := writeFile(t, m, "a.flv", "flv")
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	job, _ := m.Enqueue(LiveInfo{}, fileName)
+	for {
+		j, _ := m.GetJob(job.ID)
+		if len(j.Steps) == 2 && j.Steps[1].Status == StatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err := m.Rerun(job.ID, false)
+	assert.Equal(t, ErrJobRunning, err)
+	assert.NoError(t, m.Cancel(job.ID))
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusCanceled, job.Status)
+	assert.Equal(t, StatusCanceled, job.Steps[1].Status)
+	assert.Equal(t, ErrJobFinished, m.Cancel(job.ID))
+
+	first := job.Steps[0]
+	assert.Equal(t, "out\n", first.Stdout)
+	assert.Equal(t, "err\n", first.Stderr)
+	if assert.NotNil(t, first.ExitCode) {
+		assert.Equal(t, 0, *first.ExitCode)
+	}
+
+	// 只重新执行未成功的步骤
+	writeFile(t, m, "a.flv.ok", "")
+	_, err = m.Rerun(job.ID, true)
+	assert.NoError(t, err)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	b, _ := os.ReadFile(fileName + ".runs")
+	assert.Equal(t, job.ID+"\n", string(b))
+
+	// 重新执行全部步骤
+	_, err = m.Rerun(job.ID, false)
+	assert.NoError(t, err)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	b, _ = os.ReadFile(fileName + ".runs")
+	assert.Equal(t, job.ID+"\n"+job.ID+"\n", string(b))
+
+	_, err = m.Rerun("missing", false)
+	assert.Equal(t, ErrJobNotFound, err)
+}
+
+func TestCancelPendingAndRerun(t *testing.T) {
+	ctx, m := newTestManager(t,
+		configs.PostProcessStep{Name: "wait", Type: configs.StepCommand, Command: `while ! test -f "{{ .FileName }}.ok"; do sleep 0.01; done; echo {{ .JobID }} >> "{{ .FileName }}.runs"`},
+	)
+	m.inst.Config.PostProcess.Workers = 2
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	// 两个工作协程都在执行任务时，第三个任务在队列中等待
+	busy := make([]*Job, 0, 2)
+	for _, name := range []string{"a.flv", "b.flv"} {
+		job, err := m.Enqueue(LiveInfo{}, writeFile(t, m, name, "flv"))
+		assert.NoError(t, err)
+		busy = append(busy, job)
+	}
+	for _, job := range busy {
+		for {
+			j, _ := m.GetJob(job.ID)
+			if j.Status == StatusRunning {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	fileName := writeFile(t, m, "c.flv", "flv")
+	job, err := m.Enqueue(LiveInfo{}, fileName)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Cancel(job.ID))
+	_, err = m.Rerun(job.ID, false)
+	assert.NoError(t, err)
+
+	// 取消后重新执行的任务只执行一次
+	for _, name := range []string{"a.flv", "b.flv", "c.flv"} {
+		writeFile(t, m, name+".ok", "")
+	}
+	for _, j := range busy {
+		waitJob(t, m, j.ID)
+	}
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	b, _ := os.ReadFile(fileName + ".runs")
+	assert.Equal(t, job.ID+"\n", string(b))
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := newTailBuffer(4)
+	b.Write([]byte("ab"))
+	b.Write([]byte("cde"))
+	assert.Equal(t, "bcde", b.String())
+	b.Write([]byte("123456"))
+	assert.Equal(t, "3456", b.String())
+}
+
+// testFlv 生成一个只包含一个视频关键帧的 FLV 文件内容。
+func testFlv(timestamp uint32) string {
+	tag := []byte{0x17, 1, 0, 0, 0}
+	b := []byte{'F', 'L', 'V', 1, 1, 0, 0, 0, 9, 0, 0, 0, 0}
+	b = append(b, 9, 0, 0, byte(len(tag)), byte(timestamp>>16), byte(timestamp>>8), byte(timestamp), byte(timestamp>>24), 0, 0, 0)
+	b = append(b, tag...)
+	return string(append(b, 0, 0, 0, byte(11+len(tag))))
+}
+
+// waitJobs 等待出现 n 个任务并返回。
+func waitJobs(t *testing.T, m *manager, n int) []*Job {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if jobs := m.Jobs(); len(jobs) >= n {
+			return jobs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("未在规定时间内创建 %d 个任务", n)
+	return nil
+}
+
+func TestMergeSessionClose(t *testing.T) {
+	ctx, m := newTestManager(t, configs.PostProcessStep{Type: configs.StepCommand, Command: "true"})
+	m.inst.Config.PostProcess.Merge = configs.SessionMerge{Enable: true, Wait: time.Hour}
+	assert.NoError(t, m.Start(ctx))
+
+	start := time.Now()
+	m.openSession("a", start)
+	m.addSegment(sessionKey("a", start), LiveInfo{ID: "a"}, writeFile(t, m, "[1].flv", testFlv(0)))
+	m.endSession("a")
+	m.Close(ctx)
+	assert.Len(t, m.Jobs(), 1)
+
+	// 关闭后不再接收分段，也不会创建任务
+	m.openSession("b", start)
+	m.addSegment(sessionKey("b", start), LiveInfo{ID: "b"}, writeFile(t, m, "[2].flv", testFlv(0)))
+	m.endSession("b")
+	assert.Empty(t, m.sessions)
+	assert.Len(t, m.Jobs(), 1)
+}
+
+func TestMergeSession(t *testing.T) {
+	ctx, m := newTestManager(t, configs.PostProcessStep{Type: configs.StepCommand, Command: `printf ok > "{{ .FileName }}.txt"`})
+	m.inst.Config.PostProcess.Merge = configs.SessionMerge{Enable: true, Wait: 50 * time.Millisecond, DeleteSegments: true}
+	first := writeFile(t, m, "[1].flv", testFlv(1000))
+	writeFile(t, m, "[1].metadata.json", "{}")
+	second := writeFile(t, m, "[2].flv", testFlv(5000))
+	assert.NoError(t, m.Start(ctx))
+	defer m.Close(ctx)
+
+	start := time.Now()
+	key := sessionKey("a", start)
+	m.openSession("a", start)
+	m.addSegment(key, LiveInfo{ID: "a"}, first)
+	m.endSession("a")
+	// 再次开播后才完成的上一场的分段仍属于上一场
+	next := start.Add(time.Hour)
+	m.openSession("a", next)
+	m.addSegment(key, LiveInfo{ID: "a"}, second)
+	// 另一场直播只有一个分段，按普通文件处理
+	single := writeFile(t, m, "[3].flv", testFlv(0))
+	m.addSegment(sessionKey("a", next), LiveInfo{ID: "a"}, single)
+	m.endSession("a")
+
+	jobs := waitJobs(t, m, 2)
+	var merged, other *Job
+	for _, job := range jobs {
+		job = waitJob(t, m, job.ID)
+		if len(job.Segments) > 0 {
+			merged = job
+		} else {
+			other = job
+		}
+	}
+	if assert.NotNil(t, merged) && assert.Len(t, merged.Steps, 2) {
+		assert.Equal(t, StatusSucceeded, merged.Status)
+		assert.Equal(t, []string{first, second}, merged.Segments)
+		assert.Equal(t, configs.StepMerge, merged.Steps[0].Type)
+		assert.Equal(t, filepath.Join(m.inst.Config.OutPutPath, "[1].merge.flv"), merged.File)
+		assert.FileExists(t, merged.File+".txt")
+		assert.FileExists(t, filepath.Join(m.inst.Config.OutPutPath, "[1].merge.metadata.json"))
+		assert.NoFileExists(t, first)
+		assert.NoFileExists(t, second)
+
+		f, err := os.Open(merged.File)
+		assert.NoError(t, err)
+		keyframes, _, err := flv.ReadKeyframes(f)
+		f.Close()
+		assert.NoError(t, err)
+		if assert.Len(t, keyframes, 2) {
+			assert.Equal(t, uint32(0), keyframes[0].Timestamp)
+			assert.Equal(t, uint32(1), keyframes[1].Timestamp)
+		}
+	}
+	if assert.NotNil(t, other) {
+		assert.Equal(t, single, other.SourceFile)
+		assert.Len(t, other.Steps, 1)
+	}
+}
@@ -0,0 +1,310 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+// mergeStep 是合并任务自动添加的第一个步骤。
+var mergeStep = configs.PostProcessStep{Type: configs.StepMerge}
+
+// session 保存一场直播中已完成的分段文件。
+type session struct {
+	info     LiveInfo
+	segments []string
+	ended    bool        // 是否已收到直播结束事件
+	timer    *time.Timer // 直播结束后等待最后一个分段的计时器
+}
+
+// sessionKey 根据直播 ID 和直播开始时间生成场次的标识。
+func sessionKey(id live.ID, start time.Time) string {
+	return fmt.Sprintf("%s@%d", id, start.Unix())
+}
+
+// mergeWait 返回直播结束后等待最后一个分段写完的时间。
+func mergeWait(cfg *configs.Config) time.Duration {
+	if wait := cfg.PostProcess.Merge.Wait; wait > 0 {
+		return wait
+	}
+	return configs.NewConfig().PostProcess.Merge.Wait
+}
+
+// openSession 在直播开始时记录场次的标识，直播结束时据此找到对应的场次，不受之后再次开播的影响。
+func (m *manager) openSession(id live.ID, start time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return
+	}
+	m.open[id] = sessionKey(id, start)
+}
+
+// addSegment 将完成的分段加入所属场次，直播已结束时重新开始等待。
+func (m *manager) addSegment(key string, info LiveInfo, fileName string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return
+	}
+	s, ok := m.sessions[key]
+	if !ok {
+		s = &session{}
+		m.sessions[key] = s
+	}
+	s.info = info
+	s.segments = append(s.segments, fileName)
+	if s.ended {
+		s.timer.Reset(mergeWait(m.inst.Config))
+	} else if _, ok := m.open[info.ID]; !ok {
+		// 未收到开播事件时以分段所属的场次为准
+		m.open[info.ID] = key
+	}
+}
+
+// endSession 标记直播间当前的场次结束，等待最后一个分段写完后合并。
+func (m *manager) endSession(id live.ID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key, ok := m.open[id]
+	if m.closed || !ok {
+		return
+	}
+	delete(m.open, id)
+	s, ok := m.sessions[key]
+	if !ok {
+		// 最后一个分段可能在直播结束事件之后才完成
+		s = &session{}
+		m.sessions[key] = s
+	}
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.timer = time.AfterFunc(mergeWait(m.inst.Config), func() {
+		m.flushSession(key)
+	})
+}
+
+// takeSessionLocked 移除场次并停止其计时器，调用时需要持有锁。
+func (m *manager) takeSessionLocked(key string) *session {
+	s, ok := m.sessions[key]
+	if !ok {
+		return nil
+	}
+	delete(m.sessions, key)
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	m.flushing.Add(1)
+	return s
+}
+
+// flushSession 为场次创建后处理任务。
+func (m *manager) flushSession(key string) {
+	m.lock.Lock()
+	s := m.takeSessionLocked(key)
+	m.lock.Unlock()
+	if s != nil {
+		m.enqueueSession(s)
+	}
+}
+
+// flushSessions 为所有未结束的场次创建任务，用于程序退出时保存已录制的分段。
+func (m *manager) flushSessions() {
+	m.lock.Lock()
+	sessions := make([]*session, 0, len(m.sessions))
+	for key := range m.sessions {
+		sessions = append(sessions, m.takeSessionLocked(key))
+	}
+	m.lock.Unlock()
+	for _, s := range sessions {
+		m.enqueueSession(s)
+	}
+}
+
+// enqueueSession 多个分段时创建合并任务，只有一个分段时按普通文件处理。
+func (m *manager) enqueueSession(s *session) {
+	defer m.flushing.Done()
+	if len(s.segments) == 0 {
+		return
+	}
+	var err error
+	if len(s.segments) == 1 {
+		if len(m.inst.Config.GetSteps()) == 0 {
+			return
+		}
+		_, err = m.Enqueue(s.info, s.segments[0])
+	} else {
+		_, err = m.enqueueMerge(s.info, s.segments)
+	}
+	if err != nil {
+		m.inst.Logger.WithError(err).Warnf("创建后处理任务失败：%s", s.segments[0])
+	}
+}
+
+// enqueueMerge 创建一个合并分段的任务，合并后的文件以第一个分段命名。
+func (m *manager) enqueueMerge(info LiveInfo, segments []string) (*Job, error) {
+	first := segments[0]
+	merged := outputFile(first, configs.StepMerge, strings.TrimPrefix(filepath.Ext(first), "."))
+	job := &Job{
+		ID:         time.Now().Format("20060102150405") + "-" + utils.GenRandomName(6),
+		Live:       info,
+		SourceFile: merged,
+		File:       merged,
+		Segments:   append([]string(nil), segments...),
+		Status:     StatusPending,
+		CreatedAt:  time.Now(),
+	}
+	m.lock.Lock()
+	m.jobs[job.ID] = job
+	m.enqueueLocked(job.ID)
+	m.saveLocked()
+	snapshot := job.clone()
+	m.lock.Unlock()
+	m.signal()
+	return snapshot, nil
+}
+
+// jobSteps 返回任务需要执行的步骤，合并任务会在最前面加上合并步骤。
+func jobSteps(cfg *configs.Config, job *Job) []configs.PostProcessStep {
+	steps := cfg.GetSteps()
+	if len(job.Segments) == 0 {
+		return steps
+	}
+	return append([]configs.PostProcessStep{mergeStep}, steps...)
+}
+
+// hasSource 判断任务的源文件是否还存在。
+func hasSource(job *Job) bool {
+	if _, err := os.Stat(job.SourceFile); err == nil {
+		return true
+	}
+	for _, segment := range job.Segments {
+		if _, err := os.Stat(segment); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runMerge 将任务的分段文件无损合并为一个文件。
+// FLV 分段使用内置的标签拼接，其他格式使用 FFmpeg concat demuxer，两种方式都会重新计算时间戳。
+func runMerge(ctx context.Context, env *stepEnv) (string, error) {
+	output := env.job.SourceFile
+	segments := make([]string, 0, len(env.job.Segments))
+	for _, segment := range env.job.Segments {
+		if _, err := os.Stat(segment); err == nil {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		// 分段已在上次合并成功后删除
+		if _, err := os.Stat(output); err == nil {
+			return output, nil
+		}
+		return "", ErrNoSourceFile
+	}
+
+	var err error
+	if useNativeMerge(env.cfg.PostProcess.Merge.Method, segments) {
+		err = mergeFlv(ctx, output, segments)
+	} else {
+		err = mergeFfmpeg(ctx, env, output, segments)
+	}
+	if err != nil {
+		os.Remove(output)
+		return "", err
+	}
+
+	if env.cfg.PostProcess.Merge.DeleteSegments {
+		deleteSegments(output, segments)
+	}
+	return output, nil
+}
+
+// deleteSegments 删除分段文件及其附属文件，第一个分段的元数据作为合并后文件的元数据保留。
+func deleteSegments(output string, segments []string) {
+	name := filepath.Base(output)
+	prefix := strings.TrimSuffix(name, filepath.Ext(name)) + "."
+	metadata := utils.SidecarFile(segments[0], ".metadata.json")
+	for _, segment := range segments {
+		files, err := sidecarFiles(segment)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			// 合并后的文件与第一个分段同名，其附属文件也会被匹配到
+			if file == metadata || strings.HasPrefix(filepath.Base(file), prefix) {
+				continue
+			}
+			os.Remove(file)
+		}
+	}
+	if _, err := os.Stat(metadata); err == nil {
+		moveFile(metadata, utils.SidecarFile(output, ".metadata.json"))
+	}
+}
+
+// useNativeMerge 判断是否使用内置的 FLV 合并。
+func useNativeMerge(method string, segments []string) bool {
+	switch method {
+	case configs.MergeNative:
+		return true
+	case configs.MergeFfmpeg:
+		return false
+	}
+	for _, segment := range segments {
+		if !strings.EqualFold(filepath.Ext(segment), ".flv") {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeFlv 使用内置的 FLV 标签拼接合并分段。
+func mergeFlv(ctx context.Context, output string, segments []string) error {
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := flv.Concat(ctx, f, segments...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mergeFfmpeg 使用 FFmpeg concat demuxer 合并分段。
+func mergeFfmpeg(ctx context.Context, env *stepEnv, output string, segments []string) error {
+	list, err := os.CreateTemp(filepath.Dir(output), ".merge-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(list.Name())
+	for _, segment := range segments {
+		abs, err := filepath.Abs(segment)
+		if err != nil {
+			abs = segment
+		}
+		fmt.Fprintf(list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	if err := list.Close(); err != nil {
+		return err
+	}
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-f", "concat", "-safe", "0", "-i", list.Name(), "-c", "copy"}
+	if ext := strings.ToLower(filepath.Ext(output)); ext == ".mp4" || ext == ".mov" {
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args, output)
+	return env.execute(exec.CommandContext(ctx, env.data.Ffmpeg, args...))
+}
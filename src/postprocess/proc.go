@@ -0,0 +1,17 @@
+//go:build !windows && !plan9
+
+package postprocess
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 使命令在独立的进程组中运行，取消时结束整个进程组，
+// 避免通过 shell 启动的子进程在超时后继续运行。
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build plan9
+
+package postprocess
+
+import "os/exec"
+
+// setProcessGroup 在 Plan 9 上不做处理，取消时只结束命令本身。
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package postprocess
+
+import "os/exec"
+
+// setProcessGroup 在 Windows 上不做处理，取消时只结束命令本身。
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,387 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/ffmpeg"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+const (
+	// outputTailSize 是保存的命令输出或响应内容的最大长度，超出时只保留末尾部分。
+	outputTailSize = 4096
+	// waitDelay 是命令被取消后等待其输出管道关闭的时间。
+	waitDelay = 5 * time.Second
+)
+
+// tailBuffer 只保留最后写入的 max 字节。
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+// newTailBuffer 创建一个新的 tailBuffer。
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write 写入数据，超出长度时丢弃最早的部分。
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= b.max {
+		b.buf = append(b.buf[:0], p[len(p)-b.max:]...)
+		return n, nil
+	}
+	if overflow := len(b.buf) + len(p) - b.max; overflow > 0 {
+		b.buf = b.buf[overflow:]
+	}
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+// String 返回保存的内容。
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// stepEnv 是执行一个步骤所需的环境。
+type stepEnv struct {
+	cfg  *configs.Config
+	step *configs.PostProcessStep
+	job  *Job
+	data *TemplateData
+
+	stdout   *tailBuffer // 命令的标准输出或 HTTP 响应内容
+	stderr   *tailBuffer // 命令的标准错误
+	exitCode *int        // 命令的退出码，未执行命令时为 nil
+}
+
+// newStepEnv 创建一个新的 stepEnv。
+func newStepEnv(cfg *configs.Config, step *configs.PostProcessStep, job *Job, data *TemplateData) *stepEnv {
+	return &stepEnv{
+		cfg:    cfg,
+		step:   step,
+		job:    job,
+		data:   data,
+		stdout: newTailBuffer(outputTailSize),
+		stderr: newTailBuffer(outputTailSize),
+	}
+}
+
+// stepFunc 执行一个步骤，返回步骤生成的新文件，文件未变化时返回空字符串。
+type stepFunc func(ctx context.Context, env *stepEnv) (string, error)
+
+// stepFuncs 是各类型步骤的实现。
+var stepFuncs = map[string]stepFunc{
+	configs.StepRemux:     runRemux,
+	configs.StepTranscode: runTranscode,
+	configs.StepDanmaku:   runDanmaku,
+	configs.StepUpload:    runUpload,
+	configs.StepMove:      runMove,
+	configs.StepCommand:   runCommand,
+	configs.StepWebhook:   runWebhook,
+	configs.StepMerge:     runMerge,
+}
+
+// runStepFunc 根据步骤类型执行步骤。
+func runStepFunc(ctx context.Context, env *stepEnv) (string, error) {
+	fn, ok := stepFuncs[env.step.Type]
+	if !ok {
+		return "", fmt.Errorf("不支持的后处理步骤类型：%s", env.step.Type)
+	}
+	return fn(ctx, env)
+}
+
+// outputFile 返回转换步骤的输出文件，与输入文件相同时在扩展名前加上步骤名称。
+func outputFile(input, stepName, format string) string {
+	output := utils.SidecarFile(input, "."+format)
+	if output == input {
+		output = utils.SidecarFile(input, "."+stepName+"."+format)
+	}
+	return output
+}
+
+// runFfmpeg 使用 FFmpeg 将当前文件转换为 format 格式，format 为空时使用 mp4，globalArgs 为输入前的参数，args 为输出参数。
+func runFfmpeg(ctx context.Context, env *stepEnv, format string, globalArgs, args []string) (string, error) {
+	if format == "" {
+		format = "mp4"
+	}
+	input := env.job.File
+	output := outputFile(input, env.step.StepName(), format)
+	if env.step.AppendExt {
+		output = input + "." + format
+	}
+	cmdArgs := []string{"-hide_banner", "-loglevel", "error", "-y"}
+	cmdArgs = append(cmdArgs, globalArgs...)
+	cmdArgs = append(cmdArgs, "-i", input)
+	cmdArgs = append(cmdArgs, args...)
+	if format == "mp4" || format == "mov" {
+		cmdArgs = append(cmdArgs, "-movflags", "+faststart")
+	}
+	cmdArgs = append(cmdArgs, output)
+	if err := env.execute(exec.CommandContext(ctx, env.data.Ffmpeg, cmdArgs...)); err != nil {
+		os.Remove(output)
+		return "", err
+	}
+	if env.step.DeleteSource {
+		os.Remove(input)
+	}
+	return output, nil
+}
+
+// runRemux 无损转封装。
+func runRemux(ctx context.Context, env *stepEnv) (string, error) {
+	return runFfmpeg(ctx, env, env.step.Format, nil, []string{"-c", "copy"})
+}
+
+// runTranscode 按转码配置或参数转码。
+// 使用转码配置时，步骤未设置 format 则使用转码配置的输出格式。
+func runTranscode(ctx context.Context, env *stepEnv) (string, error) {
+	profile, ok := transcodeProfile(env)
+	if !ok && env.step.Profile != "" {
+		return "", fmt.Errorf("转码配置 %s 不存在", env.step.Profile)
+	}
+	if !ok {
+		return runFfmpeg(ctx, env, env.step.Format, nil, env.step.Args)
+	}
+	format := env.step.Format
+	if format == "" {
+		format = profile.GetFormat()
+	}
+	globalArgs, args := ffmpeg.TranscodeArgs(profile, format, sourceAudio(ctx, env, profile, format))
+	return runFfmpeg(ctx, env, format, globalArgs, args)
+}
+
+// sourceAudio 在复制音频到 MP4 时返回当前文件的音频编码，其他情况或无法检测时返回空字符串。
+func sourceAudio(ctx context.Context, env *stepEnv, profile *configs.TranscodeProfile, format string) string {
+	if format != configs.FormatMp4 || (profile.AudioCodec != "" && profile.AudioCodec != "copy") {
+		return ""
+	}
+	ffprobe, err := utils.GetFFprobePath(ctx)
+	if err != nil {
+		return ""
+	}
+	codec, err := ffmpeg.AudioCodec(ctx, ffprobe, env.job.File)
+	if err != nil {
+		return ""
+	}
+	return codec
+}
+
+// transcodeProfile 返回 transcode 步骤使用的转码配置，直播间设置的 post_process_profile 优先于步骤的 profile。
+func transcodeProfile(env *stepEnv) (*configs.TranscodeProfile, bool) {
+	if room, err := env.cfg.GetLiveRoomByUrl(env.job.Live.Url); err == nil && room.PostProcessProfile != "" {
+		if profile, ok := env.cfg.GetTranscodeProfile(room.PostProcessProfile); ok {
+			return profile, true
+		}
+	}
+	if env.step.Profile == "" {
+		return nil, false
+	}
+	return env.cfg.GetTranscodeProfile(env.step.Profile)
+}
+
+// runDanmaku 渲染录制文件旁的 .xml 弹幕文件，弹幕文件不存在时不做处理。
+func runDanmaku(ctx context.Context, env *stepEnv) (string, error) {
+	danmaku := utils.SidecarFile(env.job.File, ".xml")
+	if _, err := os.Stat(danmaku); err != nil {
+		return "", nil
+	}
+	env.data.Danmaku = danmaku
+	env.data.Ass = utils.SidecarFile(env.job.File, ".ass")
+	return "", runShell(ctx, env)
+}
+
+// runUpload 通过命令上传文件，未设置命令时以 PUT 请求上传到 url。
+func runUpload(ctx context.Context, env *stepEnv) (string, error) {
+	if env.step.Command != "" {
+		return "", runShell(ctx, env)
+	}
+	rawUrl, err := renderTemplate(env.cfg, env.step.StepName(), env.step.Url, env.data)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(env.job.File)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSpace(rawUrl), f)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = stat.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	for k, v := range env.step.Headers {
+		req.Header.Set(k, v)
+	}
+	return "", env.doRequest(req)
+}
+
+// runMove 将当前文件及其同名的附属文件（如 .metadata.json、缩略图）移动到目标目录。
+// 目标目录为相对路径时相对于输出路径。
+func runMove(ctx context.Context, env *stepEnv) (string, error) {
+	target, err := renderTemplate(env.cfg, env.step.StepName(), env.step.Target, env.data)
+	if err != nil {
+		return "", err
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(env.cfg.OutPutPath, target)
+	}
+	if err := os.MkdirAll(target, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	files, err := sidecarFiles(env.job.File)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if err := moveFile(file, filepath.Join(target, filepath.Base(file))); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(target, filepath.Base(env.job.File)), nil
+}
+
+// sidecarFiles 返回文件本身及其同名的附属文件。
+func sidecarFiles(file string) ([]string, error) {
+	dir, name := filepath.Split(file)
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() != name && !strings.HasPrefix(entry.Name(), base+".") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	return files, nil
+}
+
+// moveFile 移动文件，跨设备时复制后删除源文件。
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
+
+// runCommand 执行自定义命令，成功后按配置删除当前文件。
+func runCommand(ctx context.Context, env *stepEnv) (string, error) {
+	if err := runShell(ctx, env); err != nil {
+		return "", err
+	}
+	if env.step.DeleteSource {
+		os.Remove(env.job.File)
+	}
+	return "", nil
+}
+
+// runWebhook 将任务信息以 JSON 格式 POST 到 url。
+func runWebhook(ctx context.Context, env *stepEnv) (string, error) {
+	rawUrl, err := renderTemplate(env.cfg, env.step.StepName(), env.step.Url, env.data)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"event": "post_process",
+		"job":   env.job,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSpace(rawUrl), bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range env.step.Headers {
+		req.Header.Set(k, v)
+	}
+	return "", env.doRequest(req)
+}
+
+// doRequest 发送请求并将响应内容保存到 stdout，响应状态码不是 2xx 时返回错误。
+func (env *stepEnv) doRequest(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(env.stdout, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runShell 渲染步骤的命令模板并通过系统 shell 执行。
+func runShell(ctx context.Context, env *stepEnv) error {
+	cmdStr, err := renderTemplate(env.cfg, env.step.StepName(), env.step.Command, env.data)
+	if err != nil {
+		return err
+	}
+	return env.execute(shellCommand(ctx, cmdStr))
+}
+
+// shellCommand 创建通过系统 shell 执行命令的 exec.Cmd。
+func shellCommand(ctx context.Context, cmdStr string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", cmdStr)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", cmdStr)
+}
+
+// execute 执行命令，并保存其输出的末尾部分和退出码。
+func (env *stepEnv) execute(cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	cmd.Stdout = env.stdout
+	cmd.Stderr = env.stderr
+	err := cmd.Run()
+	if cmd.ProcessState != nil {
+		code := cmd.ProcessState.ExitCode()
+		env.exitCode = &code
+	}
+	return err
+}
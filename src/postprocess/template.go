@@ -0,0 +1,77 @@
+package postprocess
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+// TemplateData 是渲染步骤中的命令、地址和条件模板时可用的数据。
+// 嵌入的 *live.Info 是执行步骤时的直播信息快照，使旧版 custom_commandline 中的
+// {{ .Status }}、{{ .AudioOnly }} 等写法继续可用。
+type TemplateData struct {
+	*live.Info
+	Live       live.Live // 直播对象，房间已被移除时为 nil
+	LiveID     live.ID   // 直播 ID
+	Platform   string    // 平台名称
+	HostName   string    // 主播名
+	RoomName   string    // 房间名
+	LiveUrl    string    // 直播间地址
+	JobID      string    // 任务 ID
+	FileName   string    // 当前文件
+	SourceFile string    // 录制生成的文件
+	FileSize   int64     // 当前文件大小（字节）
+	Ffmpeg     string    // FFmpeg 路径
+	Danmaku    string    // 弹幕文件，仅 danmaku 步骤
+	Ass        string    // 弹幕渲染的输出文件，仅 danmaku 步骤
+}
+
+// renderTemplate 渲染一段模板文本。
+func renderTemplate(cfg *configs.Config, name, text string, data *TemplateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(utils.GetFuncMap(cfg)).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// match 判断步骤的执行条件是否满足。
+func match(cfg *configs.Config, step *configs.PostProcessStep, data *TemplateData) (bool, error) {
+	cond := step.If
+	if len(cond.Platforms) > 0 && !contains(cond.Platforms, data.Platform, false) {
+		return false, nil
+	}
+	if len(cond.Extensions) > 0 && !contains(cond.Extensions, filepath.Ext(data.FileName), true) {
+		return false, nil
+	}
+	if cond.MinSize > 0 && data.FileSize < cond.MinSize*1024*1024 {
+		return false, nil
+	}
+	if cond.When != "" {
+		result, err := renderTemplate(cfg, step.StepName()+"_when", cond.When, data)
+		if err != nil {
+			return false, err
+		}
+		return strings.TrimSpace(result) == "true", nil
+	}
+	return true, nil
+}
+
+// contains 判断列表中是否包含指定的值。
+func contains(list []string, value string, ignoreCase bool) bool {
+	for _, item := range list {
+		if item == value || ignoreCase && strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -23,4 +23,7 @@ var (
 
 	// ErrPushNotEnabled 表示推送未启用
 	ErrPushNotEnabled = errors.New("push is not enabled")
+
+	// ErrNoStreamUrl 表示未获取到直播流地址
+	ErrNoStreamUrl = errors.New("no stream url")
 )
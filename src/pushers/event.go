@@ -10,3 +10,6 @@ const PusherStop events.EventType = "PusherStop"
 
 // PusherRestart 是一个事件类型，表示录制器重新启动录制。
 const PusherRestart events.EventType = "PusherRestart"
+
+// PusherError 是一个事件类型，表示推送过程中发生错误，事件对象为 *live.ErrorParam。
+const PusherError events.EventType = "PusherError"
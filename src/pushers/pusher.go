@@ -3,6 +3,7 @@ package pushers
 
 import (
 	"context"
+	"net/url"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -77,6 +78,10 @@ func (r *pusher) tryPush(ctx context.Context) {
 	urls, err := r.Live.GetStreamUrls()
 	if err != nil || len(urls) == 0 {
 		r.getLogger().WithError(err).Warn("无法获取直播流URL，将在5秒后重试...")
+		if err == nil {
+			err = ErrNoStreamUrl
+		}
+		r.dispatchError(err, nil)
 		time.Sleep(5 * time.Second)
 		return
 	}
@@ -102,6 +107,7 @@ func (r *pusher) tryPush(ctx context.Context) {
 	p, err := parser.New(ffmpeg.Name, parserCfg)
 	if err != nil {
 		r.getLogger().WithError(err).Error("初始化解析器失败")
+		r.dispatchError(err, url)
 		return
 	}
 
@@ -111,6 +117,9 @@ func (r *pusher) tryPush(ctx context.Context) {
 	// 推送缓存流并记录结果
 	result := r.parser.ParseLiveStream(ctx, url, r.Live, room.Rtmp)
 	r.getLogger().Println(result)
+	if result != nil && !r.isStopping() {
+		r.dispatchError(result, url)
+	}
 
 	// 记录结束时间
 
@@ -183,6 +192,26 @@ func (r *pusher) Close() {
 	r.ed.DispatchEvent(events.NewEvent(PusherStop, r.Live))
 }
 
+// isStopping 判断推送器是否正在关闭。
+func (r *pusher) isStopping() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatchError 分发推送错误事件。
+func (r *pusher) dispatchError(err error, streamUrl *url.URL) {
+	r.ed.DispatchEvent(events.NewEvent(PusherError, &live.ErrorParam{
+		Live:      r.Live,
+		Component: "pusher",
+		Err:       err,
+		StreamUrl: streamUrl,
+	}))
+}
+
 // getLogger 返回记录器实例。
 func (r *pusher) getLogger() *logrus.Entry {
 	return r.logger.WithFields(r.getFields())
@@ -190,15 +219,17 @@ func (r *pusher) getLogger() *logrus.Entry {
 
 // getFields 返回记录器的字段。
 func (r *pusher) getFields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"live_id": r.Live.GetLiveId(),
+	}
 	obj, err := r.cache.Get(r.Live)
 	if err != nil {
-		return nil
+		return fields
 	}
 	info := obj.(*live.Info)
-	return map[string]interface{}{
-		"host": info.HostName,
-		"room": info.RoomName,
-	}
+	fields["host"] = info.HostName
+	fields["room"] = info.RoomName
+	return fields
 }
 
 // GetStatus 获取录制器的状态。
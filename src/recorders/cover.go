@@ -0,0 +1,150 @@
+package recorders
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+const (
+	// CoverSuffix 是直播封面文件的后缀，扩展名取决于图片地址。
+	CoverSuffix = ".cover"
+	// AvatarSuffix 是主播头像文件的后缀，扩展名取决于图片地址。
+	AvatarSuffix = ".avatar"
+
+	imageUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	// maxImageSize 是下载图片的最大字节数。
+	maxImageSize = 20 << 20
+)
+
+var imageClient = &http.Client{Timeout: 30 * time.Second}
+
+// imageExt 返回图片地址对应的扩展名，无法识别时使用 .jpg。
+func imageExt(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ".jpg"
+	}
+	// 部分平台的图片地址带有处理参数，如 a.jpg@100w_100h.webp 或 a.jpg~tplv-xxx.image
+	name := path.Base(u.Path)
+	if i := strings.IndexAny(name, "@~"); i > 0 {
+		name = name[:i]
+	}
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		return ext
+	}
+	return ".jpg"
+}
+
+// imageFile 返回视频文件旁保存图片的路径，命名方式与 .metadata.json 相同。
+func imageFile(fileName, suffix, rawUrl string) string {
+	return utils.SidecarFile(fileName, suffix+imageExt(rawUrl))
+}
+
+// downloadImage 下载图片到文件，先写入临时文件，成功后再替换，避免留下不完整的图片。
+func downloadImage(ctx context.Context, rawUrl, file string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", imageUserAgent)
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载图片失败：%s", resp.Status)
+	}
+
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, io.LimitReader(resp.Body, maxImageSize))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// replaceImage 下载图片到文件，成功后删除扩展名不同的旧图片。
+func replaceImage(ctx context.Context, rawUrl, file, previous string) error {
+	if err := downloadImage(ctx, rawUrl, file); err != nil {
+		return err
+	}
+	if previous != "" && previous != file {
+		os.Remove(previous)
+	}
+	return nil
+}
+
+// saveImage 下载图片保存到视频文件旁并返回保存的路径，previous 为之前保存的同类图片。
+// 失败时只记录日志，并返回 previous。
+func (r *recorder) saveImage(ctx context.Context, fileName, suffix, rawUrl, previous string) string {
+	if rawUrl == "" {
+		return previous
+	}
+	file := imageFile(fileName, suffix, rawUrl)
+	if err := replaceImage(ctx, rawUrl, file, previous); err != nil {
+		r.getLogger().WithError(err).Warnf("保存图片失败：%s", rawUrl)
+		return previous
+	}
+	r.getLogger().Debugf("已保存图片至：%s", file)
+	return file
+}
+
+// watchCover 在开始录制时保存封面和头像，并在录制期间封面变化时重新保存，直到 done 关闭。
+func (r *recorder) watchCover(ctx context.Context, fileName string, info *live.Info, done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	coverUrl := info.CoverUrl
+	coverFile := r.saveImage(ctx, fileName, CoverSuffix, coverUrl, "")
+	r.saveImage(ctx, fileName, AvatarSuffix, info.AvatarUrl, "")
+
+	interval := time.Duration(r.config.Interval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 直播信息由监听器定期刷新到缓存中
+			obj, err := r.cache.Get(r.Live)
+			if err != nil {
+				continue
+			}
+			if latest := obj.(*live.Info).CoverUrl; latest != "" && latest != coverUrl {
+				coverUrl = latest
+				coverFile = r.saveImage(ctx, fileName, CoverSuffix, coverUrl, coverFile)
+			}
+		}
+	}
+}
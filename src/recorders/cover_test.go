@@ -0,0 +1,66 @@
+package recorders
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageFile(t *testing.T) {
+	assert.Equal(t, "/a/b.cover.jpg", imageFile("/a/b.flv", CoverSuffix, "https://i0.hdslb.com/bfs/live/cover.jpg"))
+	assert.Equal(t, "/a/b.cover.png", imageFile("/a/b.flv", CoverSuffix, "https://example.com/cover.PNG?x=1"))
+	assert.Equal(t, "/a/b.avatar.jpg", imageFile("/a/b.ts", AvatarSuffix, "https://i0.hdslb.com/bfs/face/a.jpg@100w_100h.webp"))
+	assert.Equal(t, "/a/b.avatar.jpeg", imageFile("/a/b.ts", AvatarSuffix, "https://p3.douyinpic.com/img/a.jpeg~tplv-resize:100:100.image"))
+	assert.Equal(t, "/a/b.cover.jpg", imageFile("/a/b.ts", CoverSuffix, "https://example.com/cover"))
+}
+
+func TestDownloadImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cover.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "image data")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.cover.jpg")
+	assert.NoError(t, downloadImage(context.Background(), server.URL+"/cover.jpg", file))
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "image data", string(b))
+
+	// 下载失败时不覆盖已有的图片
+	assert.Error(t, downloadImage(context.Background(), server.URL+"/missing.jpg", file))
+	b, err = os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "image data", string(b))
+	_, err = os.Stat(file + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReplaceImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "image data")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	jpg := filepath.Join(dir, "a.cover.jpg")
+	png := filepath.Join(dir, "a.cover.png")
+	assert.NoError(t, replaceImage(context.Background(), server.URL+"/cover.jpg", jpg, ""))
+	assert.NoError(t, replaceImage(context.Background(), server.URL+"/cover.jpg", jpg, jpg))
+	assert.FileExists(t, jpg)
+
+	// 封面的扩展名变化时删除旧的封面
+	assert.NoError(t, replaceImage(context.Background(), server.URL+"/cover.png", png, jpg))
+	assert.FileExists(t, png)
+	assert.NoFileExists(t, jpg)
+}
@@ -20,4 +20,10 @@ var (
 
 	// ErrRecordNotEnabled 表示录制未启用
 	ErrRecordNotEnabled = errors.New("record is not enabled")
+
+	// ErrNoStreamUrl 表示未获取到直播流地址
+	ErrNoStreamUrl = errors.New("no stream url")
+
+	// ErrDraining 表示程序正在退出，不再接受新的录制
+	ErrDraining = errors.New("recorder manager is draining")
 )
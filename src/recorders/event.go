@@ -1,6 +1,11 @@
 package recorders
 
-import "github.com/yuhaohwang/bililive-go/src/pkg/events"
+import (
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+)
 
 // RecorderStart 是一个事件类型，表示录制器开始录制。
 const RecorderStart events.EventType = "RecorderStart"
@@ -10,3 +15,21 @@ const RecorderStop events.EventType = "RecorderStop"
 
 // RecorderRestart 是一个事件类型，表示录制器重新启动录制。
 const RecorderRestart events.EventType = "RecorderRestart"
+
+// RecorderSplit 是一个事件类型，表示解析器达到分割条件（文件大小或新的视频序列头）后开始新的文件，事件对象为 live.Live。
+// 达到最大时长时重启录制器分发的是 RecorderRestart。
+const RecorderSplit events.EventType = "RecorderSplit"
+
+// RecorderError 是一个事件类型，表示录制过程中发生错误，事件对象为 *live.ErrorParam。
+const RecorderError events.EventType = "RecorderError"
+
+// RecordFileFinished 是一个事件类型，表示一个录制文件已完成写入。
+const RecordFileFinished events.EventType = "RecordFileFinished"
+
+// FileFinishedParam 是 RecordFileFinished 事件携带的参数。
+type FileFinishedParam struct {
+	Live      live.Live  // 直播实例
+	Info      *live.Info // 录制开始时的直播信息快照
+	FileName  string     // 录制文件的完整路径
+	StartTime time.Time  // 所属直播场次的开始时间，与直播 ID 一起标识同一场直播
+}
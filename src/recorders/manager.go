@@ -17,6 +17,7 @@ import (
 func NewManager(ctx context.Context) Manager {
 	rm := &manager{
 		recorders: make(map[live.ID]Recorder),
+		stats:     make(map[live.ID]*recordStats),
 		cfg:       instance.GetInstance(ctx).Config,
 	}
 	instance.GetInstance(ctx).RecorderManager = rm
@@ -32,18 +33,25 @@ type Manager interface {
 	RestartRecorder(ctx context.Context, liveId live.Live) error
 	GetRecorder(ctx context.Context, liveId live.ID) (Recorder, error)
 	HasRecorder(ctx context.Context, liveId live.ID) bool
+	// Drain 停止接受新的录制，关闭所有录制器并等待最后的文件写完，ctx 结束时返回其错误。
+	Drain(ctx context.Context) error
 }
 
+// drainLogInterval 是退出时输出等待进度的间隔。
+const drainLogInterval = 5 * time.Second
+
 // 用于测试的变量
 var (
-	newRecorder = NewRecorder
+	newRecorder = newRecorderWithStats
 )
 
 // manager 是 Recorder Manager 的实现。
 type manager struct {
 	lock      sync.RWMutex
 	recorders map[live.ID]Recorder
+	stats     map[live.ID]*recordStats // 各直播间的重连统计，分割视频重启录制器时保留
 	cfg       *configs.Config
+	draining  bool // 是否正在退出
 }
 
 // registryListener 注册事件监听器以响应直播开始、房间名称更改、监听停止等事件。
@@ -139,8 +147,52 @@ func (m *manager) Close(ctx context.Context) {
 	inst.WaitGroup.Done()
 }
 
+// Drain 停止接受新的录制，关闭所有录制器并等待最后的文件写完。
+func (m *manager) Drain(ctx context.Context) error {
+	// 1. 标记为正在退出并关闭所有录制器。
+	m.lock.Lock()
+	m.draining = true
+	closing := make([]Recorder, 0, len(m.recorders))
+	for id, recorder := range m.recorders {
+		recorder.Close()
+		closing = append(closing, recorder)
+		delete(m.recorders, id)
+	}
+	m.lock.Unlock()
+
+	// 2. 等待录制器写完最后的文件，并定期输出进度。
+	logger := instance.GetInstance(ctx).Logger
+	ticker := time.NewTicker(drainLogInterval)
+	defer ticker.Stop()
+	for i, recorder := range closing {
+		for waiting := true; waiting; {
+			select {
+			case <-recorder.Done():
+				waiting = false
+			case <-ticker.C:
+				logger.Infof("正在等待 %d 个录制文件写完", len(closing)-i)
+			case <-ctx.Done():
+				logger.Warnf("等待超时，仍有 %d 个录制文件未写完", len(closing)-i)
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// isDraining 判断是否正在退出。
+func (m *manager) isDraining() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.draining
+}
+
 // AddRecorder 添加一个录制器。
 func (m *manager) AddRecorder(ctx context.Context, live live.Live) error {
+	// 程序正在退出时不再开始新的录制
+	if m.isDraining() {
+		return ErrDraining
+	}
 
 	// 获取应用程序实例
 	inst := instance.GetInstance(ctx)
@@ -172,12 +224,20 @@ func (m *manager) AddRecorder(ctx context.Context, live live.Live) error {
 	// 1. 加锁以同步操作。
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	if m.draining {
+		return ErrDraining
+	}
 	// 2. 检查是否已存在该录制器。
 	if _, ok := m.recorders[live.GetLiveId()]; ok {
 		return ErrRecorderExist
 	}
 	// 3. 创建新的录制器。
-	recorder, err := newRecorder(ctx, live)
+	stats, ok := m.stats[live.GetLiveId()]
+	if !ok {
+		stats = new(recordStats)
+		m.stats[live.GetLiveId()] = stats
+	}
+	recorder, err := newRecorder(ctx, live, stats)
 	if err != nil {
 		return err
 	}
@@ -213,19 +273,28 @@ func (m *manager) cronRestart(ctx context.Context, live live.Live) {
 
 // RestartRecorder 重新启动录制器，用于分割视频。
 func (m *manager) RestartRecorder(ctx context.Context, live live.Live) error {
-	// 1. 移除当前录制器。
-	if err := m.RemoveRecorder(ctx, live.GetLiveId()); err != nil {
+	// 1. 移除当前录制器，保留重连统计，新的录制器开始解析不算作重连。
+	if err := m.removeRecorder(live.GetLiveId(), true); err != nil {
 		return err
 	}
 	// 2. 添加新的录制器。
 	if err := m.AddRecorder(ctx, live); err != nil {
 		return err
 	}
+	// 3. 分发录制器重启（视频分割）事件。
+	if ed, ok := instance.GetInstance(ctx).EventDispatcher.(events.Dispatcher); ok {
+		ed.DispatchEvent(events.NewEvent(RecorderRestart, live))
+	}
 	return nil
 }
 
 // RemoveRecorder 移除录制器。
 func (m *manager) RemoveRecorder(ctx context.Context, liveId live.ID) error {
+	return m.removeRecorder(liveId, false)
+}
+
+// removeRecorder 移除录制器，split 为 true 时保留直播间的重连统计并标记下一次解析为分割视频。
+func (m *manager) removeRecorder(liveId live.ID, split bool) error {
 	// 1. 加锁以同步操作。
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -238,6 +307,11 @@ func (m *manager) RemoveRecorder(ctx context.Context, liveId live.ID) error {
 	recorder.Close()
 	// 4. 从管理器中移除录制器。
 	delete(m.recorders, liveId)
+	if split {
+		m.stats[liveId].markSplit()
+	} else {
+		delete(m.stats, liveId)
+	}
 	return nil
 }
 
@@ -2,13 +2,17 @@ package recorders
 
 import (
 	"context"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/yuhaohwang/bililive-go/src/configs"
 	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
 	"github.com/yuhaohwang/bililive-go/src/live"
 	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
 )
@@ -22,7 +26,7 @@ func TestManagerAddAndRemoveRecorder(t *testing.T) {
 	})
 	m := NewManager(ctx)
 	backup := newRecorder
-	newRecorder = func(ctx context.Context, live live.Live) (Recorder, error) {
+	newRecorder = func(ctx context.Context, live live.Live, stats *recordStats) (Recorder, error) {
 		r := NewMockRecorder(ctrl)
 		r.EXPECT().Start(ctx).Return(nil)
 		r.EXPECT().Close()
@@ -44,3 +48,33 @@ func TestManagerAddAndRemoveRecorder(t *testing.T) {
 	assert.Equal(t, ErrRecorderNotExist, err)
 	assert.False(t, m.HasRecorder(context.Background(), "test"))
 }
+
+func TestManagerDrain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Config: new(configs.Config),
+		Logger: &interfaces.Logger{Logger: logger},
+	})
+	m := NewManager(ctx).(*manager)
+	done := make(chan struct{})
+	r := NewMockRecorder(ctrl)
+	r.EXPECT().Close()
+	r.EXPECT().Done().Return((<-chan struct{})(done)).AnyTimes()
+	m.recorders["test"] = r
+
+	// 录制器写完最后的文件前等待超时
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, m.Drain(timeout))
+	assert.False(t, m.HasRecorder(ctx, "test"))
+	assert.Equal(t, ErrDraining, m.AddRecorder(ctx, livemock.NewMockLive(ctrl)))
+
+	close(done)
+	m.recorders["test"] = r
+	r.EXPECT().Close()
+	assert.NoError(t, m.Drain(ctx))
+}
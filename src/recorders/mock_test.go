@@ -48,6 +48,20 @@ func (mr *MockRecorderMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRecorder)(nil).Close))
 }
 
+// Done mocks base method.
+func (m *MockRecorder) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockRecorderMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockRecorder)(nil).Done))
+}
+
 // GetStatus mocks base method.
 func (m *MockRecorder) GetStatus() (map[string]string, error) {
 	m.ctrl.T.Helper()
@@ -140,6 +154,20 @@ func (mr *MockManagerMockRecorder) Close(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockManager)(nil).Close), arg0)
 }
 
+// Drain mocks base method.
+func (m *MockManager) Drain(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drain", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Drain indicates an expected call of Drain.
+func (mr *MockManagerMockRecorder) Drain(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockManager)(nil).Drain), arg0)
+}
+
 // GetRecorder mocks base method.
 func (m *MockManager) GetRecorder(arg0 context.Context, arg1 live.ID) (Recorder, error) {
 	m.ctrl.T.Helper()
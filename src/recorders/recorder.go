@@ -4,12 +4,13 @@ package recorders
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,7 +27,9 @@ import (
 	"github.com/yuhaohwang/bililive-go/src/live"
 	"github.com/yuhaohwang/bililive-go/src/pkg/events"
 	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/external"
 	"github.com/yuhaohwang/bililive-go/src/pkg/parser/ffmpeg"
+	_ "github.com/yuhaohwang/bililive-go/src/pkg/parser/hls"
 	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
 	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
 )
@@ -41,11 +44,7 @@ const (
 // for test
 var (
 	newParser = func(u *url.URL, useNativeFlvParser bool, cfg map[string]string) (parser.Parser, error) {
-		parserName := ffmpeg.Name
-		if strings.Contains(u.Path, ".flv") && useNativeFlvParser {
-			parserName = flv.Name
-		}
-		return parser.New(parserName, cfg)
+		return parser.New(parserName(u, useNativeFlvParser, cfg), cfg)
 	}
 
 	mkdir = func(path string) error {
@@ -59,6 +58,33 @@ var (
 	}
 )
 
+// parserName 根据直播流地址和解析器配置选择解析器。
+// 直播间的域名配置了解析器时优先使用；只录制音频时，内置 FLV 解析器只能输出 FLV 格式；录制时转码只能使用 FFmpeg。
+func parserName(u *url.URL, useNativeFlvParser bool, cfg map[string]string) string {
+	if name := cfg["parser"]; name != "" {
+		return name
+	}
+	// 平台默认的解析器不支持只录制音频和录制时转码
+	if name := cfg["default_parser"]; name != "" && cfg["audio_only"] != "true" && cfg["transcode_profile"] == "" {
+		return name
+	}
+	if !useNativeFlvParser || !strings.HasPrefix(u.Scheme, "http") || !strings.Contains(u.Path, ".flv") || cfg["transcode_profile"] != "" {
+		return ffmpeg.Name
+	}
+	if cfg["audio_only"] == "true" && cfg["audio_format"] != configs.AudioFormatFlv {
+		return ffmpeg.Name
+	}
+	return flv.Name
+}
+
+// audioExts 是只录制音频时各格式使用 FFmpeg 输出的扩展名。
+var audioExts = map[string]string{
+	configs.AudioFormatAac:  ".aac",
+	configs.AudioFormatM4a:  ".m4a",
+	configs.AudioFormatOpus: ".opus",
+	configs.AudioFormatFlv:  ".flv",
+}
+
 // 默认的文件名模板
 func getDefaultFileNameTmpl(config *configs.Config) *template.Template {
 	return template.Must(template.New("filename").Funcs(utils.GetFuncMap(config)).
@@ -71,6 +97,8 @@ type Recorder interface {
 	StartTime() time.Time
 	GetStatus() (map[string]string, error)
 	Close()
+	// Done 返回在录制器停止并写完最后一个文件及其元数据后关闭的通道。
+	Done() <-chan struct{}
 }
 
 // recorder 是 Recorder 接口的实现。
@@ -87,11 +115,19 @@ type recorder struct {
 	parserLock *sync.RWMutex
 
 	stop  chan struct{}
+	done  chan struct{}
 	state uint32
+
+	stats *recordStats // 直播间的重连统计，由管理器在分割视频重启录制器时保留
 }
 
 // NewRecorder 创建一个新的 Recorder 实例。
 func NewRecorder(ctx context.Context, live live.Live) (Recorder, error) {
+	return newRecorderWithStats(ctx, live, new(recordStats))
+}
+
+// newRecorderWithStats 创建一个使用给定重连统计的 Recorder 实例。
+func newRecorderWithStats(ctx context.Context, live live.Live, stats *recordStats) (Recorder, error) {
 	inst := instance.GetInstance(ctx)
 	return &recorder{
 		Live:       live,
@@ -103,16 +139,25 @@ func NewRecorder(ctx context.Context, live live.Live) (Recorder, error) {
 		logger:     inst.Logger,
 		state:      begin,
 		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
 		parserLock: new(sync.RWMutex),
+		stats:      stats,
 	}, nil
 }
 
 // tryRecord 尝试录制直播流。
 func (r *recorder) tryRecord(ctx context.Context) {
 	// 获取直播流的URL列表
-	urls, err := r.Live.GetStreamUrls()
+	urls, err := r.getStreamUrls()
 	if err != nil || len(urls) == 0 {
 		r.getLogger().WithError(err).Warn("无法获取直播流URL，将在5秒后重试...")
+		if err == nil {
+			err = ErrNoStreamUrl
+		}
+		// 直播结束后监听器发现下播前获取不到直播流是正常的，不作为错误通知
+		if !r.isLiveEnded() {
+			r.dispatchError(err, nil)
+		}
 		time.Sleep(5 * time.Second)
 		return
 	}
@@ -120,6 +165,7 @@ func (r *recorder) tryRecord(ctx context.Context) {
 	// 从缓存中获取直播信息
 	obj, _ := r.cache.Get(r.Live)
 	info := obj.(*live.Info)
+	sessionStart := r.Live.GetLastStartTime()
 
 	isCache := false
 
@@ -134,6 +180,31 @@ func (r *recorder) tryRecord(ctx context.Context) {
 
 	url := urls[0]
 
+	// 初始化解析器配置
+	parserCfg := map[string]string{
+		"timeout_in_us": strconv.Itoa(r.config.TimeoutInUs),
+	}
+	if r.config.Debug {
+		parserCfg["debug"] = "true"
+	}
+	if name := r.config.GetParser(r.Live.GetRawUrl()); name != "" {
+		parserCfg["parser"] = name
+	} else if name := configs.DefaultParser(r.Live.GetRawUrl()); name != "" && strings.Contains(url.Path, ".m3u8") {
+		parserCfg["default_parser"] = name
+	}
+	var profile *configs.TranscodeProfile
+	if room, err := r.config.GetLiveRoomByUrl(r.Live.GetRawUrl()); err == nil {
+		if room.AudioOnly {
+			parserCfg["audio_only"] = "true"
+			parserCfg["audio_format"] = room.GetAudioFormat()
+		}
+		// 域名配置了 FFmpeg 以外的解析器时不转码
+		if tp, ok := r.config.GetTranscodeProfile(room.TranscodeProfile); ok && (parserCfg["parser"] == "" || parserCfg["parser"] == ffmpeg.Name) {
+			parserCfg["transcode_profile"] = room.TranscodeProfile
+			profile = tp
+		}
+	}
+
 	if !isCache {
 		// 设置文件名模板
 		tmpl := getDefaultFileNameTmpl(r.config)
@@ -161,16 +232,24 @@ func (r *recorder) tryRecord(ctx context.Context) {
 			fileName = fileName[:strings.LastIndex(fileName, ".")] + ".aac"
 		}
 
-		// metadata.json
-		// 如果 fileName 有扩展名，则替换为 ".json"，否则添加 ".json"
-		ext := filepath.Ext(fileName)
-		if ext != "" {
-			// 有扩展名，直接替换为 ".metadata.json"
-			jsonFilePath = fileName[:len(fileName)-len(ext)] + ".metadata.json"
-		} else {
-			// 没有扩展名，直接加上 ".metadata.json"
-			jsonFilePath = fileName + ".metadata.json"
+		// 直播间设置了只录制音频时，按输出格式修改扩展名
+		if parserCfg["audio_only"] == "true" {
+			ext := audioExts[parserCfg["audio_format"]]
+			fileName = fileName[:strings.LastIndex(fileName, ".")] + ext
+		}
+
+		// 外部工具下载的直播流通常为 MPEG-TS 格式
+		if name := parserCfg["parser"]; name == external.StreamlinkName || name == external.YtDlpName {
+			fileName = fileName[:strings.LastIndex(fileName, ".")] + ".ts"
 		}
+
+		// 录制时转码，按转码配置的输出格式修改扩展名
+		if profile != nil {
+			fileName = fileName[:strings.LastIndex(fileName, ".")] + "." + profile.GetFormat()
+		}
+
+		// metadata.json
+		jsonFilePath = utils.SidecarFile(fileName, ".metadata.json")
 	}
 
 	outputPath, _ := filepath.Split(fileName)
@@ -178,21 +257,15 @@ func (r *recorder) tryRecord(ctx context.Context) {
 	// 创建输出目录
 	if err = mkdir(outputPath); err != nil {
 		r.getLogger().WithError(err).Errorf("无法创建输出目录[%s]", outputPath)
+		r.dispatchError(err, url)
 		return
 	}
 
-	// 初始化解析器配置
-	parserCfg := map[string]string{
-		"timeout_in_us": strconv.Itoa(r.config.TimeoutInUs),
-	}
-	if r.config.Debug {
-		parserCfg["debug"] = "true"
-	}
-
 	// 根据 URL 初始化解析器
 	p, err := newParser(url, r.config.Feature.UseNativeFlvParser, parserCfg)
 	if err != nil {
 		r.getLogger().WithError(err).Error("初始化解析器失败")
+		r.dispatchError(err, url)
 		return
 	}
 
@@ -208,9 +281,29 @@ func (r *recorder) tryRecord(ctx context.Context) {
 	// 保存 JSON 数据到文件
 	r.saveJSONToFile(jsonFilePath, jsonData)
 
+	// 保存直播封面和主播头像
+	parseDone := make(chan struct{})
+	if r.config.Feature.SaveCover && !isCache {
+		go r.watchCover(ctx, fileName, info, parseDone)
+	}
+
+	// 记录重连次数和中断时长
+	r.stats.begin()
+
 	// 解析直播流并记录结果
 	result := r.parser.ParseLiveStream(ctx, url, r.Live, fileName)
+	close(parseDone)
 	r.getLogger().Println(result)
+	if !r.isStopping() {
+		// 解析器因达到分割条件结束时立即开始新的文件，不算作重连
+		split := errors.Is(result, parser.ErrSplit)
+		r.stats.end(split)
+		if split {
+			r.ed.DispatchEvent(events.NewEvent(RecorderSplit, r.Live))
+		} else if result != nil && !isNormalEnd(result, fileName) && !r.isLiveEnded() {
+			r.dispatchError(result, url)
+		}
+	}
 
 	// 记录结束时间
 	r.getLogger().Debug("结束解析直播流(" + url.String() + ", " + fileName + ")")
@@ -222,83 +315,21 @@ func (r *recorder) tryRecord(ctx context.Context) {
 	// 移除空文件
 	removeEmptyFile(fileName)
 
-	// 获取 FFmpeg 路径
-	ffmpegPath, err := utils.GetFFmpegPath(ctx)
-	if err != nil {
-		r.getLogger().WithError(err).Error("无法找到 FFmpeg")
-		return
-	}
-
-	// 执行自定义命令或转换
-	cmdStr := strings.Trim(r.config.OnRecordFinished.CustomCommandline, "")
-	if len(cmdStr) > 0 {
-		tmpl, err := template.New("custom_commandline").Funcs(utils.GetFuncMap(r.config)).Parse(cmdStr)
-		if err != nil {
-			r.getLogger().WithError(err).Error("自定义命令行解析失败")
-			return
-		}
-
-		buf := new(bytes.Buffer)
-		if err := tmpl.Execute(buf, struct {
-			*live.Info
-			FileName string
-			Ffmpeg   string
-		}{
-			Info:     info,
-			FileName: fileName,
-			Ffmpeg:   ffmpegPath,
-		}); err != nil {
-			r.getLogger().WithError(err).Errorln("无法渲染自定义命令行")
-			return
-		}
-
-		bash := ""
-		args := []string{}
-		switch runtime.GOOS {
-		case "linux":
-			bash = "sh"
-			args = []string{"-c"}
-		case "windows":
-			bash = "cmd"
-			args = []string{"/C"}
-		default:
-			r.getLogger().Warnln("不支持的操作系统", runtime.GOOS)
-		}
-
-		args = append(args, buf.String())
-		r.getLogger().Debugf("开始执行自定义命令行: %s", args[1])
-		cmd := exec.Command(bash, args...)
-		if r.config.Debug {
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-		}
-		if err = cmd.Run(); err != nil {
-			r.getLogger().WithError(err).Debugf("自定义命令行执行失败(%s %s)\n", bash, strings.Join(args, " "))
-		} else if r.config.OnRecordFinished.DeleteFlvAfterConvert {
-			os.Remove(fileName)
-		}
-		r.getLogger().Debugf("结束执行自定义命令行: %s", args[1])
-	} else if r.config.OnRecordFinished.ConvertToMp4 {
-		convertCmd := exec.Command(
-			ffmpegPath,
-			"-hide_banner",
-			"-i",
-			fileName,
-			"-c",
-			"copy",
-			fileName+".mp4",
-		)
-		if err = convertCmd.Run(); err != nil {
-			convertCmd.Process.Kill()
-			r.getLogger().Debugln(err)
-		} else if r.config.OnRecordFinished.DeleteFlvAfterConvert {
-			os.Remove(fileName)
-		}
+	// 通知录制文件已完成，后处理由 postprocess 在后台执行
+	if _, err := os.Stat(fileName); err == nil {
+		snapshot := *info
+		r.ed.DispatchEvent(events.NewEvent(RecordFileFinished, &FileFinishedParam{
+			Live:      r.Live,
+			Info:      &snapshot,
+			FileName:  fileName,
+			StartTime: sessionStart,
+		}))
 	}
 }
 
 // run 启动录制器的主循环。
 func (r *recorder) run(ctx context.Context) {
+	defer close(r.done)
 	for {
 		select {
 		case <-r.stop:
@@ -309,6 +340,18 @@ func (r *recorder) run(ctx context.Context) {
 	}
 }
 
+// getStreamUrls 获取直播流的URL列表，域名配置了外部工具时外部工具直接处理直播间地址，不需要获取直播流。
+func (r *recorder) getStreamUrls() ([]*url.URL, error) {
+	if _, _, ok := r.config.GetExternalTool(r.Live.GetRawUrl()); ok {
+		u, err := url.Parse(r.Live.GetRawUrl())
+		if err != nil {
+			return nil, err
+		}
+		return []*url.URL{u}, nil
+	}
+	return r.Live.GetStreamUrls()
+}
+
 // getParser 获取当前解析器。
 func (r *recorder) getParser() parser.Parser {
 	r.parserLock.RLock()         // 获取解析器互斥锁，允许多个协程同时读取解析器
@@ -358,6 +401,53 @@ func (r *recorder) Close() {
 	r.ed.DispatchEvent(events.NewEvent(RecorderStop, r.Live))
 }
 
+// Done 返回在录制器停止并写完最后一个文件及其元数据后关闭的通道。
+func (r *recorder) Done() <-chan struct{} {
+	return r.done
+}
+
+// isStopping 判断录制器是否正在关闭。
+func (r *recorder) isStopping() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNormalEnd 判断解析结果是否为直播流的正常结束：读到流的末尾，或外部进程在写出数据后退出（FFmpeg 在直播流结束时返回非零退出码）。
+func isNormalEnd(err error, fileName string) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		stat, err := os.Stat(fileName)
+		return err == nil && stat.Size() > 0
+	}
+	return false
+}
+
+// isLiveEnded 判断直播是否已经结束，缓存中的状态仍为直播中时重新获取一次直播间信息。
+func (r *recorder) isLiveEnded() bool {
+	if obj, err := r.cache.Get(r.Live); err == nil && !obj.(*live.Info).Status {
+		return true
+	}
+	info, err := r.Live.GetInfo()
+	return err == nil && !info.Status
+}
+
+// dispatchError 分发录制错误事件。
+func (r *recorder) dispatchError(err error, streamUrl *url.URL) {
+	r.ed.DispatchEvent(events.NewEvent(RecorderError, &live.ErrorParam{
+		Live:      r.Live,
+		Component: "recorder",
+		Err:       err,
+		StreamUrl: streamUrl,
+	}))
+}
+
 // getLogger 返回记录器实例。
 func (r *recorder) getLogger() *logrus.Entry {
 	return r.logger.WithFields(r.getFields())
@@ -365,15 +455,17 @@ func (r *recorder) getLogger() *logrus.Entry {
 
 // getFields 返回记录器的字段。
 func (r *recorder) getFields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"live_id": r.Live.GetLiveId(),
+	}
 	obj, err := r.cache.Get(r.Live)
 	if err != nil {
-		return nil
+		return fields
 	}
 	info := obj.(*live.Info)
-	return map[string]interface{}{
-		"host": info.HostName,
-		"room": info.RoomName,
-	}
+	fields["host"] = info.HostName
+	fields["room"] = info.RoomName
+	return fields
 }
 
 // GetStatus 获取录制器的状态。
@@ -382,7 +474,19 @@ func (r *recorder) GetStatus() (map[string]string, error) {
 	if !ok {
 		return nil, ErrParserNotSupportStatus
 	}
-	return statusP.Status()
+	status, err := statusP.Status()
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		// FFmpeg 暂时没有输出进度时返回空的状态
+		status = make(map[string]string)
+	}
+	// 加入直播间的重连统计，解析结束后再次开始解析视为重连，分割视频除外
+	reconnects, stall := r.stats.reconnects()
+	status["reconnects"] = strconv.FormatUint(uint64(reconnects), 10)
+	status["stall_seconds"] = strconv.FormatFloat(stall.Seconds(), 'f', 3, 64)
+	return status, nil
 }
 
 // saveJSONToFile 将 JSON 数据保存到文件
@@ -0,0 +1,139 @@
+package recorders
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluele/gcache"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	eventsmock "github.com/yuhaohwang/bililive-go/src/pkg/events/mock"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/external"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/ffmpeg"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/hls"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
+)
+
+func TestParserName(t *testing.T) {
+	flvUrl, _ := url.Parse("https://example.com/live/a.flv?token=1")
+	m3u8Url, _ := url.Parse("https://example.com/live/a.m3u8")
+
+	assert.Equal(t, flv.Name, parserName(flvUrl, true, map[string]string{}))
+	assert.Equal(t, ffmpeg.Name, parserName(flvUrl, false, map[string]string{}))
+	assert.Equal(t, ffmpeg.Name, parserName(m3u8Url, true, map[string]string{}))
+
+	// 只录制音频时，只有 flv 格式使用内置解析器
+	audio := map[string]string{"audio_only": "true", "audio_format": "aac"}
+	assert.Equal(t, ffmpeg.Name, parserName(flvUrl, true, audio))
+	audio["audio_format"] = "flv"
+	assert.Equal(t, flv.Name, parserName(flvUrl, true, audio))
+	assert.Equal(t, ffmpeg.Name, parserName(m3u8Url, true, audio))
+
+	// 录制时转码只能使用 FFmpeg
+	assert.Equal(t, ffmpeg.Name, parserName(flvUrl, true, map[string]string{"transcode_profile": "hevc"}))
+
+	// 平台默认的解析器在只录制音频和录制时转码时不使用
+	assert.Equal(t, hls.Name, parserName(m3u8Url, true, map[string]string{"default_parser": hls.Name}))
+	assert.Equal(t, ffmpeg.Name, parserName(m3u8Url, true, map[string]string{"default_parser": hls.Name, "audio_only": "true", "audio_format": "aac"}))
+	assert.Equal(t, ffmpeg.Name, parserName(m3u8Url, true, map[string]string{"default_parser": hls.Name, "transcode_profile": "hevc"}))
+
+	// 域名配置的解析器优先
+	assert.Equal(t, external.StreamlinkName, parserName(m3u8Url, true, map[string]string{"parser": external.StreamlinkName, "transcode_profile": "hevc"}))
+}
+
+// resultParser 写入一些数据后返回指定的结果。
+type resultParser struct {
+	result error
+}
+
+func (p resultParser) ParseLiveStream(ctx context.Context, url *url.URL, live live.Live, file string) error {
+	if err := os.WriteFile(file, []byte("flv"), 0644); err != nil {
+		return err
+	}
+	return p.result
+}
+
+func (p resultParser) Stop() error { return nil }
+
+func TestRecorderErrorOnNormalEnd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	streamUrl, _ := url.Parse("https://example.com/live/a.flv")
+	living := true
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetLiveId().Return(live.ID("a")).AnyTimes()
+	l.EXPECT().GetRawUrl().Return("https://live.bilibili.com/1").AnyTimes()
+	l.EXPECT().GetPlatformCNName().Return("哔哩哔哩").AnyTimes()
+	l.EXPECT().GetLastStartTime().Return(time.Now()).AnyTimes()
+	l.EXPECT().GetStreamUrls().Return([]*url.URL{streamUrl}, nil).AnyTimes()
+	l.EXPECT().GetInfo().DoAndReturn(func() (*live.Info, error) {
+		return &live.Info{Live: l, Status: living}, nil
+	}).AnyTimes()
+
+	cfg := configs.NewConfig()
+	cfg.OutPutPath = t.TempDir()
+	cfg.Feature.SaveCover = false
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cache := gcache.New(4).LRU().Build()
+	cache.Set(l, &live.Info{Live: l, HostName: "主播", RoomName: "房间", Status: true})
+	var errs []error
+	ed := eventsmock.NewMockDispatcher(ctrl)
+	ed.EXPECT().DispatchEvent(gomock.Any()).Do(func(event *events.Event) {
+		if event.Type == RecorderError {
+			errs = append(errs, event.Object.(*live.ErrorParam).Err)
+		}
+	}).AnyTimes()
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{Config: cfg})
+	r := &recorder{
+		Live:       l,
+		OutPutPath: cfg.OutPutPath,
+		config:     cfg,
+		ed:         ed,
+		logger:     &interfaces.Logger{Logger: logger},
+		cache:      cache,
+		parserLock: new(sync.RWMutex),
+		stop:       make(chan struct{}),
+		stats:      new(recordStats),
+	}
+	result := io.EOF
+	defer func(f func(*url.URL, bool, map[string]string) (parser.Parser, error)) { newParser = f }(newParser)
+	newParser = func(u *url.URL, useNativeFlvParser bool, cfg map[string]string) (parser.Parser, error) {
+		return resultParser{result: result}, nil
+	}
+
+	// 读到直播流末尾是正常结束
+	r.tryRecord(ctx)
+	assert.Empty(t, errs)
+
+	// 直播中的其他错误需要通知
+	result = errors.New("connection reset")
+	r.tryRecord(ctx)
+	assert.Equal(t, []error{result}, errs)
+
+	// 直播已经结束时不通知
+	living = false
+	errs = nil
+	r.tryRecord(ctx)
+	assert.Empty(t, errs)
+
+	// 监听器已发现下播时不再重新获取直播间信息
+	living = true
+	cache.Set(l, &live.Info{Live: l, Status: false})
+	assert.True(t, r.isLiveEnded())
+}
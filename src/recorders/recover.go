@@ -0,0 +1,148 @@
+package recorders
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
+)
+
+const (
+	// metadataSuffix 是录制文件旁的元数据文件后缀。
+	metadataSuffix = ".metadata.json"
+	// tsPacketSize 是 MPEG-TS 包的长度。
+	tsPacketSize = 188
+	// flvHeaderLength 是FLV文件头及第一个标签长度的长度，只有这部分的文件不包含任何数据。
+	flvHeaderLength = 13
+)
+
+// recordExts 是录制文件可能使用的扩展名。
+var recordExts = []string{".flv", ".ts", ".aac", ".m4a", ".opus", ".mp4", ".mkv"}
+
+// RecoverFiles 扫描输出路径，修复上次异常退出时未完成的录制文件。
+// 元数据中仍标记为正在录制的文件会被截断到最后一个完整的标签，元数据更新后分发 RecordFileFinished 事件，
+// 按正常录制完成的流程进行后处理。需要在开始录制前调用，返回修复的录制文件。
+func RecoverFiles(ctx context.Context) []string {
+	inst := instance.GetInstance(ctx)
+	logger := inst.Logger
+	recovered := make([]string, 0)
+	filepath.WalkDir(inst.Config.OutPutPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			// 跳过保存程序状态等隐藏目录
+			if path != inst.Config.OutPutPath && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), metadataSuffix) {
+			return nil
+		}
+		metadata, recording, err := readMetadata(path)
+		if err != nil || !recording {
+			return nil
+		}
+
+		fileName := findRecordFile(path)
+		if fileName != "" {
+			if err := repairFile(fileName); err != nil {
+				logger.WithError(err).Warnf("修复录制文件失败：%s", fileName)
+			} else {
+				removeEmptyFile(fileName)
+			}
+		}
+		metadata["recording"] = false
+		if b, err := json.Marshal(metadata); err == nil {
+			if err := os.WriteFile(path, b, 0644); err != nil {
+				logger.WithError(err).Warnf("更新元数据失败：%s", path)
+			}
+		}
+		if fileName == "" {
+			return nil
+		}
+		if _, err := os.Stat(fileName); err != nil {
+			return nil
+		}
+		logger.Infof("已恢复未完成的录制文件：%s", fileName)
+		recovered = append(recovered, fileName)
+
+		if ed, ok := inst.EventDispatcher.(events.Dispatcher); ok {
+			id, _ := metadata["id"].(string)
+			l, _ := inst.GetLive(live.ID(id))
+			ed.DispatchEvent(events.NewEvent(RecordFileFinished, &FileFinishedParam{
+				Live:     l,
+				FileName: fileName,
+			}))
+		}
+		return nil
+	})
+	return recovered
+}
+
+// readMetadata 读取元数据文件，返回其内容以及是否标记为正在录制。
+func readMetadata(path string) (map[string]interface{}, bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	metadata := make(map[string]interface{})
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, false, err
+	}
+	recording, _ := metadata["recording"].(bool)
+	return metadata, recording, nil
+}
+
+// findRecordFile 查找元数据文件对应的录制文件，不存在时返回空字符串。
+func findRecordFile(metadataFile string) string {
+	base := strings.TrimSuffix(metadataFile, metadataSuffix)
+	for _, ext := range recordExts {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// repairFile 将录制文件截断到最后一个完整的标签或数据包，不包含任何数据的文件会被截断为空文件。
+func repairFile(fileName string) error {
+	f, err := os.OpenFile(fileName, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := stat.Size()
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".flv":
+		n, err := flv.CompleteLength(f)
+		if err == flv.ErrNotFlvStream {
+			n = 0
+		} else if err != nil {
+			return err
+		}
+		if n <= flvHeaderLength {
+			n = 0
+		}
+		size = n
+	case ".ts":
+		size -= size % tsPacketSize
+	}
+	if size == stat.Size() {
+		return nil
+	}
+	return f.Truncate(size)
+}
@@ -0,0 +1,91 @@
+package recorders
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+)
+
+func TestRecoverFiles(t *testing.T) {
+	dir := t.TempDir()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	inst := &instance.Instance{
+		Config: &configs.Config{OutPutPath: dir},
+		Logger: &interfaces.Logger{Logger: logger},
+	}
+	ctx := context.WithValue(context.Background(), instance.Key, inst)
+	ed := events.NewDispatcher(ctx)
+	finished := make(chan *FileFinishedParam, 4)
+	ed.AddEventListener(RecordFileFinished, events.NewEventListener(func(event *events.Event) {
+		finished <- event.Object.(*FileFinishedParam)
+	}))
+
+	write := func(name string, data []byte) string {
+		fileName := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fileName), os.ModePerm))
+		assert.NoError(t, os.WriteFile(fileName, data, 0644))
+		return fileName
+	}
+	// FLV 文件头 + 一个完整的标签 + 一个不完整的标签
+	flvData := []byte{'F', 'L', 'V', 1, 5, 0, 0, 0, 9, 0, 0, 0, 0}
+	flvData = append(flvData, 9, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0x17, 0, 0, 0, 12)
+	complete := len(flvData)
+	flvData = append(flvData, 8, 0, 0, 3, 0, 0, 10, 0, 0, 0, 0, 0xaf)
+	crashed := write("a/[1].flv", flvData)
+	write("a/[1].metadata.json", []byte(`{"id":"x","host_name":"主播","recording":true}`))
+	ts := write("a/[2].ts", make([]byte, 188*2+100))
+	write("a/[2].metadata.json", []byte(`{"recording":true}`))
+	empty := write("a/[3].flv", flvData[:complete-16])
+	write("a/[3].metadata.json", []byte(`{"recording":true}`))
+	finishedFile := write("a/[4].flv", flvData[:complete])
+	write("a/[4].metadata.json", []byte(`{"recording":false}`))
+	write(".bililive-go/[5].flv", flvData)
+	write(".bililive-go/[5].metadata.json", []byte(`{"recording":true}`))
+
+	recovered := RecoverFiles(ctx)
+	assert.ElementsMatch(t, []string{crashed, ts}, recovered)
+
+	stat, err := os.Stat(crashed)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(complete), stat.Size())
+	stat, err = os.Stat(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(188*2), stat.Size())
+	assert.NoFileExists(t, empty)
+	stat, err = os.Stat(finishedFile)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(complete), stat.Size())
+
+	metadata, recording, err := readMetadata(filepath.Join(dir, "a/[1].metadata.json"))
+	assert.NoError(t, err)
+	assert.False(t, recording)
+	assert.Equal(t, "主播", metadata["host_name"])
+	_, recording, _ = readMetadata(filepath.Join(dir, "a/[3].metadata.json"))
+	assert.False(t, recording)
+	_, recording, _ = readMetadata(filepath.Join(dir, ".bililive-go/[5].metadata.json"))
+	assert.True(t, recording)
+
+	files := make([]string, 0)
+	for range recovered {
+		select {
+		case param := <-finished:
+			assert.Nil(t, param.Live)
+			files = append(files, param.FileName)
+		case <-time.After(5 * time.Second):
+			t.Fatal("未收到录制完成事件")
+		}
+	}
+	assert.ElementsMatch(t, recovered, files)
+}
@@ -0,0 +1,59 @@
+package recorders
+
+import (
+	"sync"
+	"time"
+)
+
+// recordStats 是一个直播间在一场直播内的录制统计，录制器因分割视频重启时保留。
+type recordStats struct {
+	lock         sync.Mutex
+	parseCount   uint32        // 开始解析直播流的次数
+	splits       uint32        // 因分割视频开始新文件的次数
+	stall        time.Duration // 两次解析之间没有写入数据的累计时长
+	lastParseEnd time.Time     // 上一次解析结束的时间
+	split        bool          // 上一次解析是否因分割视频结束
+}
+
+// begin 记录一次解析开始，上一次解析不是因分割视频结束时视为重连。
+func (s *recordStats) begin() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.parseCount++
+	if s.split {
+		s.split = false
+		s.splits++
+		return
+	}
+	if !s.lastParseEnd.IsZero() {
+		s.stall += time.Since(s.lastParseEnd)
+	}
+}
+
+// end 记录一次解析结束。
+func (s *recordStats) end(split bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastParseEnd = time.Now()
+	if split {
+		s.split = true
+	}
+}
+
+// markSplit 标记下一次解析是因分割视频开始的，用于达到最大时长等由管理器重启录制器的情况。
+func (s *recordStats) markSplit() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.split = true
+}
+
+// reconnects 返回重连次数和中断时长。
+func (s *recordStats) reconnects() (uint32, time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := s.parseCount - s.splits
+	if n > 0 {
+		n--
+	}
+	return n, s.stall
+}
@@ -0,0 +1,27 @@
+package recorders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordStats(t *testing.T) {
+	s := new(recordStats)
+	s.begin()
+	s.end(false)
+	// 断线重连
+	s.begin()
+	s.end(true)
+	// 按文件大小分割
+	s.begin()
+	s.end(false)
+	// 达到最大时长，录制器重启
+	s.markSplit()
+	s.begin()
+
+	n, stall := s.reconnects()
+	assert.Equal(t, uint32(1), n)
+	assert.Greater(t, int64(stall), int64(0))
+	assert.Equal(t, uint32(2), s.splits)
+}
@@ -114,7 +114,11 @@ func (l *rtmp) refresh() {
 	liveRooms := l.config.LiveRooms
 	for _, v := range liveRooms {
 		if v.Rtmp == "" {
-			info, err := l.inst.Lives[v.LiveId].GetInfo()
+			live, ok := l.inst.GetLive(v.LiveId)
+			if !ok {
+				continue
+			}
+			info, err := live.GetInfo()
 			if err == nil {
 				// 将 info 结构体转换为 JSON 格式
 				jsonData, _ := info.MarshalJSON()
@@ -0,0 +1,91 @@
+package servers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+// bulkRequest 是批量操作的请求，筛选条件与 GET /api/lives 的查询参数相同。
+//
+//	{"tags": ["运营部"], "resource": "record", "action": "start"}
+type bulkRequest struct {
+	liveFilter
+	Resource string `json:"resource"` // listen、record 或 push，为空时为 listen
+	Action   string `json:"action"`   // start 或 stop
+}
+
+// bulkFailure 是批量操作中失败的直播间。
+type bulkFailure struct {
+	ID      live.ID `json:"id"`
+	LiveUrl string  `json:"live_url"`
+	Error   string  `json:"error"`
+}
+
+// bulkResult 是批量操作的结果。
+type bulkResult struct {
+	Succeeded liveSlice     `json:"succeeded"`
+	Failed    []bulkFailure `json:"failed"`
+}
+
+// bulkAction 对满足筛选条件的所有直播间执行同一个操作，如按标签开始录制。
+// 为了避免误操作全部直播间，筛选条件不能为空。
+func bulkAction(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: err.Error(),
+		})
+		return
+	}
+	if req.Resource == "" {
+		req.Resource = "listen"
+	}
+	if _, ok := actionMap[req.Resource][req.Action]; !ok {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: fmt.Sprintf("无效操作：%s/%s", req.Resource, req.Action),
+		})
+		return
+	}
+	if req.IsEmpty() {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: "筛选条件不能为空",
+		})
+		return
+	}
+
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
+	// 按直播 ID 排序，使每次执行的顺序一致
+	lives := liveSlice(make([]*live.Info, 0))
+	for _, l := range inst.GetLives() {
+		if info := parseInfo(r.Context(), l); req.Match(info) {
+			lives = append(lives, info)
+		}
+	}
+	sort.Sort(lives)
+
+	result := bulkResult{Succeeded: make(liveSlice, 0), Failed: make([]bulkFailure, 0)}
+	for _, info := range lives {
+		l := info.Live
+		room, err := inst.Config.GetLiveRoomByUrl(l.GetRawUrl())
+		if err == nil {
+			err = executeAction(r.Context(), l, room, req.Resource, req.Action)
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, bulkFailure{ID: l.GetLiveId(), LiveUrl: l.GetRawUrl(), Error: err.Error()})
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, parseInfo(r.Context(), l))
+	}
+	inst.Logger.Infof("批量操作 %s/%s：%d 个成功，%d 个失败", req.Resource, req.Action, len(result.Succeeded), len(result.Failed))
+	writeJSON(writer, result)
+}
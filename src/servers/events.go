@@ -0,0 +1,398 @@
+package servers
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/listeners"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/pushers"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+// 事件主题，客户端可以按主题订阅。
+const (
+	TopicLive     = "live"     // 直播状态与监听相关事件
+	TopicRecorder = "recorder" // 录制器相关事件
+	TopicPusher   = "pusher"   // 转推相关事件
+	TopicError    = "error"    // 各组件的错误事件
+	TopicProgress = "progress" // 录制进度
+)
+
+// RecorderProgress 是周期性推送的录制进度事件类型。
+const RecorderProgress events.EventType = "RecorderProgress"
+
+// progressInterval 是推送录制进度的间隔。
+const progressInterval = 5 * time.Second
+
+// statusTimeout 是推送进度时获取一个录制器状态的最长等待时间，超时的录制器本次不推送。
+var statusTimeout = 3 * time.Second
+
+// eventBufferSize 是保存最近事件的环形缓冲区大小，用于客户端断线重连后补发事件。
+const eventBufferSize = 256
+
+// RoomRemoved 是一个事件类型，表示直播间已被删除，事件对象为 live.Live。
+const RoomRemoved events.EventType = "RoomRemoved"
+
+// eventTopics 定义了需要转发给客户端的分发器事件及其所属主题。
+var eventTopics = map[events.EventType]string{
+	listeners.ListenStart:              TopicLive,
+	listeners.ListenStop:               TopicLive,
+	listeners.LiveStart:                TopicLive,
+	listeners.LiveEnd:                  TopicLive,
+	listeners.RoomNameChanged:          TopicLive,
+	listeners.RoomInitializingFinished: TopicLive,
+	recorders.RecorderStart:            TopicRecorder,
+	recorders.RecorderStop:             TopicRecorder,
+	recorders.RecorderRestart:          TopicRecorder,
+	recorders.RecorderSplit:            TopicRecorder,
+	recorders.RecordFileFinished:       TopicRecorder,
+	pushers.PusherStart:                TopicPusher,
+	pushers.PusherStop:                 TopicPusher,
+	pushers.PusherRestart:              TopicPusher,
+	listeners.ListenError:              TopicError,
+	recorders.RecorderError:            TopicError,
+	pushers.PusherError:                TopicError,
+}
+
+// StreamEvent 是推送给客户端的结构化事件消息。
+type StreamEvent struct {
+	ID     uint64      `json:"id"`                // 单调递增的事件序号
+	Topic  string      `json:"topic"`             // 事件主题
+	Event  string      `json:"event"`             // 事件类型，如 LiveStart
+	LiveID live.ID     `json:"live_id,omitempty"` // 相关直播的 ID
+	Time   int64       `json:"time"`              // 事件发生时间的 UNIX 毫秒时间戳
+	Data   interface{} `json:"data,omitempty"`    // 事件数据
+}
+
+// errorData 是错误事件的数据部分，只包含错误信息，直播间由事件的 live_id 标识，避免历史记录中保存完整的直播信息。
+type errorData struct {
+	Component  string `json:"component"`
+	Message    string `json:"message"`
+	StreamHost string `json:"stream_host,omitempty"`
+}
+
+// fileFinishedData 是录制文件完成事件的数据部分。
+type fileFinishedData struct {
+	FileName string     `json:"file_name"`
+	Live     *live.Info `json:"live,omitempty"`
+}
+
+// progressData 是录制进度事件的数据部分。
+type progressData struct {
+	Status map[string]string `json:"status"`
+	Live   *live.Info        `json:"live,omitempty"`
+}
+
+// EventFilter 表示客户端的订阅条件，为空表示不过滤。
+type EventFilter struct {
+	Topics  map[string]bool  `json:"-"`
+	LiveIDs map[live.ID]bool `json:"-"`
+}
+
+// NewEventFilter 根据主题和直播 ID 列表创建订阅条件。
+func NewEventFilter(topics []string, liveIDs []string) *EventFilter {
+	f := &EventFilter{
+		Topics:  make(map[string]bool),
+		LiveIDs: make(map[live.ID]bool),
+	}
+	f.Add(topics, liveIDs)
+	return f
+}
+
+// newEventFilterFromQuery 从形如 ?topics=live,recorder&live_ids=a,b 的查询参数创建订阅条件。
+func newEventFilterFromQuery(query url.Values) *EventFilter {
+	return NewEventFilter(splitQuery(query.Get("topics")), splitQuery(query.Get("live_ids")))
+}
+
+// splitQuery 按逗号分割查询参数并去掉空白项。
+func splitQuery(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Add 添加订阅的主题和直播 ID。
+func (f *EventFilter) Add(topics []string, liveIDs []string) {
+	for _, topic := range topics {
+		f.Topics[topic] = true
+	}
+	for _, id := range liveIDs {
+		f.LiveIDs[live.ID(id)] = true
+	}
+}
+
+// Remove 取消订阅的主题和直播 ID。
+func (f *EventFilter) Remove(topics []string, liveIDs []string) {
+	for _, topic := range topics {
+		delete(f.Topics, topic)
+	}
+	for _, id := range liveIDs {
+		delete(f.LiveIDs, live.ID(id))
+	}
+}
+
+// Match 判断事件是否满足订阅条件。
+func (f *EventFilter) Match(e *StreamEvent) bool {
+	if len(f.Topics) > 0 && !f.Topics[e.Topic] {
+		return false
+	}
+	if len(f.LiveIDs) > 0 && !f.LiveIDs[e.LiveID] {
+		return false
+	}
+	return true
+}
+
+// eventSubscriber 表示结构化事件的接收方。
+// PublishEvent 在事件桥的锁内按序号顺序调用，不能阻塞。
+type eventSubscriber interface {
+	PublishEvent(e *StreamEvent)
+}
+
+// eventBridge 将分发器中的事件转换为结构化消息并推送给订阅者。
+type eventBridge struct {
+	inst *instance.Instance
+
+	lock        sync.Mutex
+	lastID      uint64
+	buffer      *eventRing             // 最近事件的环形缓冲区，不包含进度事件
+	history     map[live.ID]*eventRing // 每个直播间最近事件的环形缓冲区，不包含进度事件
+	subscribers []eventSubscriber
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// newEventBridge 创建一个新的事件桥。
+func newEventBridge(ctx context.Context) *eventBridge {
+	return &eventBridge{
+		inst:    instance.GetInstance(ctx),
+		buffer:  newEventRing(eventBufferSize),
+		history: make(map[live.ID]*eventRing),
+		stop:    make(chan struct{}),
+	}
+}
+
+// subscribe 注册一个订阅者。
+func (b *eventBridge) subscribe(s eventSubscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribers = append(b.subscribers, s)
+}
+
+// subscribeSince 注册一个订阅者，并返回缓冲区中序号大于 lastID 的事件。
+// 两者在同一把锁内完成，因此返回的事件与之后推送给订阅者的事件既不重复也不遗漏。
+// lastID 大于当前最大序号时（例如服务重启后序号被重置）返回缓冲区中的全部事件。
+func (b *eventBridge) subscribeSince(s eventSubscriber, lastID uint64) []*StreamEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribers = append(b.subscribers, s)
+	if lastID > b.lastID {
+		lastID = 0
+	}
+	return b.buffer.since(lastID)
+}
+
+// unsubscribe 移除一个订阅者。
+func (b *eventBridge) unsubscribe(s eventSubscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for i, subscriber := range b.subscribers {
+		if subscriber == s {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// start 注册分发器监听并启动进度推送。
+func (b *eventBridge) start(ctx context.Context) {
+	ed, ok := b.inst.EventDispatcher.(events.Dispatcher)
+	if !ok {
+		return
+	}
+	for eventType := range eventTopics {
+		ed.AddEventListener(eventType, events.NewEventListener(func(event *events.Event) {
+			b.publish(b.convert(ctx, event))
+		}))
+	}
+	ed.AddEventListener(RoomRemoved, events.NewEventListener(func(event *events.Event) {
+		b.removeHistory(event.Object.(live.Live).GetLiveId())
+	}))
+	go b.runProgress(ctx)
+}
+
+// close 停止进度推送。
+func (b *eventBridge) close() {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+	})
+}
+
+// publish 为事件分配序号，写入缓冲区和所属直播间的缓冲区，并推送给所有订阅者。
+func (b *eventBridge) publish(e *StreamEvent) {
+	if e == nil {
+		return
+	}
+	b.lock.Lock()
+	b.lastID++
+	e.ID = b.lastID
+	// 进度事件频繁且可以随时重新获取，不放入缓冲区以免挤掉其他事件
+	if e.Topic != TopicProgress {
+		b.buffer.push(e)
+		if e.LiveID != "" {
+			ring, ok := b.history[e.LiveID]
+			if !ok {
+				ring = newEventRing(liveEventBufferSize)
+				b.history[e.LiveID] = ring
+			}
+			ring.push(e)
+		}
+	}
+	// 在锁内推送，保证订阅者按序号顺序收到事件
+	for _, s := range b.subscribers {
+		s.PublishEvent(e)
+	}
+	b.lock.Unlock()
+}
+
+// liveInfo 获取直播的最新信息，缓存中不存在时返回 nil。
+func (b *eventBridge) liveInfo(ctx context.Context, l live.Live) *live.Info {
+	if l == nil || b.inst.Cache == nil {
+		return nil
+	}
+	if _, err := b.inst.Cache.Get(l); err != nil {
+		return nil
+	}
+	return parseInfo(ctx, l)
+}
+
+// convert 将分发器事件转换为结构化消息。
+func (b *eventBridge) convert(ctx context.Context, event *events.Event) *StreamEvent {
+	e := &StreamEvent{
+		Topic: eventTopics[event.Type],
+		Event: string(event.Type),
+		Time:  time.Now().UnixMilli(),
+	}
+	switch obj := event.Object.(type) {
+	case live.Live:
+		e.LiveID = obj.GetLiveId()
+		e.Data = b.liveInfo(ctx, obj)
+	case live.InitializingFinishedParam:
+		e.LiveID = obj.Live.GetLiveId()
+		e.Data = obj.Info
+	case *live.ErrorParam:
+		data := errorData{
+			Component: obj.Component,
+		}
+		if obj.Err != nil {
+			data.Message = obj.Err.Error()
+		}
+		if obj.StreamUrl != nil {
+			data.StreamHost = obj.StreamUrl.Host
+		}
+		e.LiveID = obj.Live.GetLiveId()
+		e.Data = data
+	case *recorders.FileFinishedParam:
+		data := fileFinishedData{
+			FileName: obj.FileName,
+			Live:     obj.Info,
+		}
+		// 启动时恢复的文件没有录制时的直播信息，直播间也可能已被删除
+		if obj.Live != nil {
+			e.LiveID = obj.Live.GetLiveId()
+			if data.Live == nil {
+				data.Live = b.liveInfo(ctx, obj.Live)
+			}
+		}
+		e.Data = data
+	default:
+		return nil
+	}
+	return e
+}
+
+// runProgress 周期性地推送所有录制器的进度。
+func (b *eventBridge) runProgress(ctx context.Context) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.publishProgress(ctx)
+		}
+	}
+}
+
+// publishProgress 推送一次所有录制器的进度。
+// 各录制器的状态同时获取，每个最多等待 statusTimeout，一个录制器没有响应不会影响其他直播间的进度。
+func (b *eventBridge) publishProgress(ctx context.Context) {
+	rm, ok := b.inst.RecorderManager.(recorders.Manager)
+	if !ok {
+		return
+	}
+	lives := b.inst.GetLives()
+	statuses := make(map[live.ID]chan map[string]string, len(lives))
+	for id := range lives {
+		r, err := rm.GetRecorder(ctx, id)
+		if err != nil {
+			continue
+		}
+		ch := make(chan map[string]string, 1)
+		statuses[id] = ch
+		go func() {
+			status, err := r.GetStatus()
+			if err != nil {
+				status = nil
+			}
+			ch <- status
+		}()
+	}
+	deadline := time.After(statusTimeout)
+	timeout := false
+	for id, ch := range statuses {
+		var status map[string]string
+		if !timeout {
+			select {
+			case status = <-ch:
+			case <-deadline:
+				timeout = true
+			}
+		}
+		if timeout {
+			// 已经超时，只取已经返回的状态
+			select {
+			case status = <-ch:
+			default:
+			}
+		}
+		if status == nil {
+			continue
+		}
+		// 直播信息中包含直播间的配置，在锁内读取
+		b.inst.RoomLock.RLock()
+		info := b.liveInfo(ctx, lives[id])
+		b.inst.RoomLock.RUnlock()
+		b.publish(&StreamEvent{
+			Topic:  TopicProgress,
+			Event:  string(RecorderProgress),
+			LiveID: id,
+			Time:   time.Now().UnixMilli(),
+			Data: progressData{
+				Status: status,
+				Live:   info,
+			},
+		})
+	}
+}
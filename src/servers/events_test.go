@@ -0,0 +1,113 @@
+package servers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	e := &StreamEvent{Topic: TopicLive, LiveID: "a"}
+	assert.True(t, NewEventFilter(nil, nil).Match(e))
+
+	f := newEventFilterFromQuery(url.Values{"topics": {"live, error"}, "live_ids": {"b"}})
+	assert.False(t, f.Match(e))
+	f.Add(nil, []string{"a"})
+	assert.True(t, f.Match(e))
+	f.Remove([]string{"live"}, nil)
+	assert.False(t, f.Match(e))
+	assert.True(t, f.Match(&StreamEvent{Topic: TopicError, LiveID: "b"}))
+}
+
+func TestEventBridgeConvert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetLiveId().Return(live.ID("a")).AnyTimes()
+
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{})
+	b := newEventBridge(ctx)
+
+	e := b.convert(ctx, events.NewEvent(recorders.RecorderError, &live.ErrorParam{
+		Live:      l,
+		Component: "recorder",
+		Err:       errors.New("boom"),
+		StreamUrl: &url.URL{Scheme: "https", Host: "cdn.example.com"},
+	}))
+	if assert.NotNil(t, e) {
+		assert.Equal(t, TopicError, e.Topic)
+		assert.Equal(t, string(recorders.RecorderError), e.Event)
+		assert.Equal(t, live.ID("a"), e.LiveID)
+		assert.Equal(t, errorData{Component: "recorder", Message: "boom", StreamHost: "cdn.example.com"}, e.Data)
+	}
+
+	e = b.convert(ctx, events.NewEvent(recorders.RecordFileFinished, &recorders.FileFinishedParam{Live: l, FileName: "a.flv"}))
+	if assert.NotNil(t, e) {
+		assert.Equal(t, TopicRecorder, e.Topic)
+		assert.Equal(t, fileFinishedData{FileName: "a.flv"}, e.Data)
+	}
+
+	assert.Nil(t, b.convert(ctx, events.NewEvent(recorders.RecorderStart, "unknown")))
+}
+
+// statusRecorder 返回固定的状态，block 不为空时一直阻塞。
+type statusRecorder struct {
+	recorders.Recorder
+	status map[string]string
+	block  chan struct{}
+}
+
+func (r *statusRecorder) GetStatus() (map[string]string, error) {
+	if r.block != nil {
+		<-r.block
+	}
+	return r.status, nil
+}
+
+// recorderGetter 返回指定的录制器。
+type recorderGetter struct {
+	recorders.Manager
+	recorders map[live.ID]recorders.Recorder
+}
+
+func (m *recorderGetter) GetRecorder(ctx context.Context, id live.ID) (recorders.Recorder, error) {
+	if r, ok := m.recorders[id]; ok {
+		return r, nil
+	}
+	return nil, recorders.ErrRecorderNotExist
+}
+
+func TestPublishProgressTimeout(t *testing.T) {
+	timeout := statusTimeout
+	statusTimeout = 10 * time.Millisecond
+	defer func() { statusTimeout = timeout }()
+
+	block := make(chan struct{})
+	defer close(block)
+	inst := &instance.Instance{
+		Lives: map[live.ID]live.Live{"a": nil, "b": nil, "c": nil},
+		RecorderManager: &recorderGetter{recorders: map[live.ID]recorders.Recorder{
+			"a": &statusRecorder{block: block},
+			"b": &statusRecorder{status: map[string]string{"time": "1"}},
+		}},
+	}
+	ctx := context.WithValue(context.Background(), instance.Key, inst)
+	b := newEventBridge(ctx)
+	r := &idRecorder{}
+	b.subscribe(r)
+
+	// 没有响应的录制器不影响其他录制器的进度
+	b.publishProgress(ctx)
+	assert.Len(t, r.ids, 1)
+}
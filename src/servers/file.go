@@ -0,0 +1,313 @@
+package servers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/thumbnails"
+)
+
+var (
+	// errInvalidOutputPath 表示输出目录无效。
+	errInvalidOutputPath = errors.New("无效输出目录")
+	// errInvalidPath 表示请求路径无效。
+	errInvalidPath = errors.New("无效路径")
+	// errOutsideOutputPath 表示请求路径不在输出目录内。
+	errOutsideOutputPath = errors.New("异常路径")
+)
+
+// playableContentTypes 是支持在浏览器中直接播放的文件类型。
+var playableContentTypes = map[string]string{
+	".flv": "video/x-flv",
+	".ts":  "video/mp2t",
+	".mp4": "video/mp4",
+}
+
+// resolveOutputPath 将相对于输出目录的路径转换为绝对路径，并确保其不会越出输出目录。
+func resolveOutputPath(inst *instance.Instance, path string) (base string, absPath string, err error) {
+	base, err = filepath.Abs(inst.Config.OutPutPath)
+	if err != nil {
+		return "", "", errInvalidOutputPath
+	}
+	absPath, err = filepath.Abs(filepath.Join(base, path))
+	if err != nil {
+		return "", "", errInvalidPath
+	}
+	if absPath != base && !strings.HasPrefix(absPath, base+string(filepath.Separator)) {
+		return "", "", errOutsideOutputPath
+	}
+	return base, absPath, nil
+}
+
+// writeFileError 以统一格式返回文件操作的错误。
+func writeFileError(writer http.ResponseWriter, code int, err error) {
+	writeJsonWithStatusCode(writer, code, commonResp{
+		ErrNo:  code,
+		ErrMsg: err.Error(),
+	})
+}
+
+// openOutputFile 解析请求中的路径并获取对应文件（或目录）的信息。
+func openOutputFile(writer http.ResponseWriter, r *http.Request) (absPath string, info fs.FileInfo, ok bool) {
+	inst := instance.GetInstance(r.Context())
+	_, absPath, err := resolveOutputPath(inst, mux.Vars(r)["path"])
+	if err != nil {
+		writeFileError(writer, http.StatusBadRequest, err)
+		return "", nil, false
+	}
+	info, err = os.Stat(absPath)
+	if err != nil {
+		writeFileError(writer, http.StatusNotFound, fmt.Errorf("文件不存在：%s", mux.Vars(r)["path"]))
+		return "", nil, false
+	}
+	return absPath, info, true
+}
+
+// serveOutputFile 输出单个文件，由 http.ServeContent 处理 Range 请求。
+func serveOutputFile(writer http.ResponseWriter, r *http.Request, absPath string, info fs.FileInfo) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		writeFileError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+	http.ServeContent(writer, r, info.Name(), info.ModTime(), f)
+}
+
+// contentDisposition 生成支持非 ASCII 文件名的 Content-Disposition 头。
+func contentDisposition(disposition, name string) string {
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`,
+		disposition, strings.ReplaceAll(name, `"`, "_"), url.PathEscape(name))
+}
+
+// downloadFile 下载文件，目录则打包为 zip 下载。
+func downloadFile(writer http.ResponseWriter, r *http.Request) {
+	absPath, info, ok := openOutputFile(writer, r)
+	if !ok {
+		return
+	}
+	if info.IsDir() {
+		writeZip(writer, r, absPath, info.Name())
+		return
+	}
+	writer.Header().Set("Content-Disposition", contentDisposition("attachment", info.Name()))
+	serveOutputFile(writer, r, absPath, info)
+}
+
+// playFile 以浏览器可直接播放的方式输出录制文件。
+func playFile(writer http.ResponseWriter, r *http.Request) {
+	absPath, info, ok := openOutputFile(writer, r)
+	if !ok {
+		return
+	}
+	ct, playable := playableContentTypes[strings.ToLower(filepath.Ext(info.Name()))]
+	if info.IsDir() || !playable {
+		writeFileError(writer, http.StatusBadRequest, fmt.Errorf("不支持播放的文件：%s", info.Name()))
+		return
+	}
+	writer.Header().Set(contentType, ct)
+	writer.Header().Set("Content-Disposition", contentDisposition("inline", info.Name()))
+	serveOutputFile(writer, r, absPath, info)
+}
+
+// writeZip 将目录打包为 zip 并以流的形式输出。
+func writeZip(writer http.ResponseWriter, r *http.Request, dir string, name string) {
+	inst := instance.GetInstance(r.Context())
+	writer.Header().Set(contentType, "application/zip")
+	writer.Header().Set("Content-Disposition", contentDisposition("attachment", name+".zip"))
+
+	zw := zip.NewWriter(writer)
+	defer zw.Close()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		// 视频文件几乎无法再压缩，直接存储以节省 CPU
+		header.Method = zip.Store
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		// 响应头已发送，只能记录错误
+		inst.Logger.WithError(err).Errorf("打包目录失败：%s", dir)
+	}
+}
+
+// deleteFile 删除文件，删除非空目录需要指定 recursive=true。
+func deleteFile(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	base, absPath, err := resolveOutputPath(inst, mux.Vars(r)["path"])
+	if err != nil {
+		writeFileError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if absPath == base {
+		writeFileError(writer, http.StatusBadRequest, errors.New("不能删除输出目录"))
+		return
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		writeFileError(writer, http.StatusNotFound, fmt.Errorf("文件不存在：%s", mux.Vars(r)["path"]))
+		return
+	}
+	if info.IsDir() && r.URL.Query().Get("recursive") == "true" {
+		err = os.RemoveAll(absPath)
+	} else {
+		err = os.Remove(absPath)
+	}
+	if err != nil {
+		writeFileError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	inst.Logger.Infof("已删除文件：%s", absPath)
+	writeJSON(writer, commonResp{
+		Data: "OK",
+	})
+}
+
+/*
+Put 数据示例
+
+重命名：{"action": "rename", "name": "new.flv"}
+移动：{"action": "move", "target": "哔哩哔哩/归档"}
+*/
+func updateFile(writer http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeFileError(writer, http.StatusBadRequest, err)
+		return
+	}
+	inst := instance.GetInstance(r.Context())
+	base, absPath, err := resolveOutputPath(inst, mux.Vars(r)["path"])
+	if err != nil {
+		writeFileError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if absPath == base {
+		writeFileError(writer, http.StatusBadRequest, errors.New("不能修改输出目录"))
+		return
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		writeFileError(writer, http.StatusNotFound, fmt.Errorf("文件不存在：%s", mux.Vars(r)["path"]))
+		return
+	}
+
+	body := gjson.ParseBytes(b)
+	var dest string
+	switch action := body.Get("action").String(); action {
+	case "rename":
+		name := strings.TrimSpace(body.Get("name").String())
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			writeFileError(writer, http.StatusBadRequest, fmt.Errorf("无效文件名：%s", name))
+			return
+		}
+		dest = filepath.Join(filepath.Dir(absPath), name)
+	case "move":
+		_, targetDir, err := resolveOutputPath(inst, body.Get("target").String())
+		if err != nil {
+			writeFileError(writer, http.StatusBadRequest, err)
+			return
+		}
+		if targetDir == absPath || strings.HasPrefix(targetDir, absPath+string(filepath.Separator)) {
+			writeFileError(writer, http.StatusBadRequest, errors.New("不能移动到自身目录下"))
+			return
+		}
+		if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+			writeFileError(writer, http.StatusInternalServerError, err)
+			return
+		}
+		dest = filepath.Join(targetDir, filepath.Base(absPath))
+	default:
+		writeFileError(writer, http.StatusBadRequest, fmt.Errorf("无效操作：%s", action))
+		return
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		writeFileError(writer, http.StatusConflict, fmt.Errorf("目标已存在：%s", filepath.Base(dest)))
+		return
+	}
+	if err := os.Rename(absPath, dest); err != nil {
+		writeFileError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	inst.Logger.Infof("已移动文件：%s -> %s", absPath, dest)
+	rel, _ := filepath.Rel(base, dest)
+	writeJSON(writer, commonResp{
+		Data: filepath.ToSlash(rel),
+	})
+}
+
+// getThumbnail 获取录制文件的封面缩略图（type=poster）或联系表（type=sheet）。
+// 缩略图不存在时会加入生成队列并返回 202，无法生成（如未找到 FFmpeg 或生成失败）时返回 404。
+func getThumbnail(writer http.ResponseWriter, r *http.Request) {
+	absPath, info, ok := openOutputFile(writer, r)
+	if !ok {
+		return
+	}
+	if info.IsDir() || !thumbnails.IsVideoFile(absPath) {
+		writeFileError(writer, http.StatusBadRequest, fmt.Errorf("不支持生成缩略图的文件：%s", info.Name()))
+		return
+	}
+
+	imagePath := thumbnails.PosterFile(absPath)
+	if r.URL.Query().Get("type") == "sheet" {
+		imagePath = thumbnails.SheetFile(absPath)
+	}
+	if imageInfo, err := os.Stat(imagePath); err == nil {
+		writer.Header().Set(contentType, "image/jpeg")
+		serveOutputFile(writer, r, imagePath, imageInfo)
+		return
+	}
+
+	inst := instance.GetInstance(r.Context())
+	manager, ok := inst.ThumbnailManager.(thumbnails.Manager)
+	if !ok {
+		writeFileError(writer, http.StatusNotFound, errors.New("缩略图不存在"))
+		return
+	}
+	if err := manager.Unavailable(r.Context(), absPath); err != nil {
+		writeFileError(writer, http.StatusNotFound, err)
+		return
+	}
+	manager.Enqueue(absPath)
+	writeJsonWithStatusCode(writer, http.StatusAccepted, commonResp{
+		Data: "缩略图生成中",
+	})
+}
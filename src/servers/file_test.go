@@ -0,0 +1,131 @@
+package servers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+)
+
+// newFileTestServer 创建仅包含文件相关路由的测试服务器。
+func newFileTestServer(t *testing.T) (*httptest.Server, string) {
+	dir := t.TempDir()
+	inst := &instance.Instance{
+		Config: &configs.Config{OutPutPath: dir},
+		Logger: &interfaces.Logger{Logger: logrus.New()},
+	}
+	m := mux.NewRouter()
+	m.Use(func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), instance.Key, inst)))
+		})
+	})
+	m.HandleFunc("/api/file/{path:.*}", updateFile).Methods("PUT")
+	m.HandleFunc("/api/file/{path:.*}", deleteFile).Methods("DELETE")
+	m.HandleFunc("/api/download/{path:.*}", downloadFile).Methods("GET")
+	m.HandleFunc("/api/play/{path:.*}", playFile).Methods("GET")
+	s := httptest.NewServer(m)
+	t.Cleanup(s.Close)
+	return s, dir
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestDownloadFileWithRange(t *testing.T) {
+	s, dir := newFileTestServer(t)
+	writeTestFile(t, filepath.Join(dir, "room", "a.flv"), "0123456789")
+
+	req, _ := http.NewRequest("GET", s.URL+"/api/download/room/a.flv", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "2345", string(b))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+
+	resp, err = http.Get(s.URL + "/api/play/room/a.flv")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "video/x-flv", resp.Header.Get("Content-Type"))
+}
+
+func TestDownloadFolderAsZip(t *testing.T) {
+	s, dir := newFileTestServer(t)
+	writeTestFile(t, filepath.Join(dir, "day", "a.flv"), "aaa")
+	writeTestFile(t, filepath.Join(dir, "day", "sub", "b.flv"), "bbbb")
+
+	resp, err := http.Get(s.URL + "/api/download/day")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{"a.flv", "sub/b.flv"}, names)
+}
+
+func TestFileOperationsOutsideOutputPath(t *testing.T) {
+	s, dir := newFileTestServer(t)
+	writeTestFile(t, filepath.Join(filepath.Dir(dir), filepath.Base(dir)+"-other", "secret"), "x")
+
+	for _, path := range []string{"../" + filepath.Base(dir) + "-other/secret", "..%2f..%2fetc%2fpasswd"} {
+		resp, err := http.Get(s.URL + "/api/download/" + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	req, _ := http.NewRequest("DELETE", s.URL+"/api/file/", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestRenameMoveAndDeleteFile(t *testing.T) {
+	s, dir := newFileTestServer(t)
+	writeTestFile(t, filepath.Join(dir, "a.flv"), "a")
+
+	do := func(method, path, body string) int {
+		req, _ := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/api/file/a.flv", `{"action":"rename","name":"../b.flv"}`))
+	assert.Equal(t, http.StatusOK, do("PUT", "/api/file/a.flv", `{"action":"rename","name":"b.flv"}`))
+	assert.FileExists(t, filepath.Join(dir, "b.flv"))
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/api/file/b.flv", `{"action":"move","target":"../"}`))
+	assert.Equal(t, http.StatusOK, do("PUT", "/api/file/b.flv", `{"action":"move","target":"archive/2024"}`))
+	assert.FileExists(t, filepath.Join(dir, "archive", "2024", "b.flv"))
+
+	assert.NotEqual(t, http.StatusOK, do("DELETE", "/api/file/archive", ""))
+	assert.Equal(t, http.StatusOK, do("DELETE", "/api/file/archive?recursive=true", ""))
+	assert.NoDirExists(t, filepath.Join(dir, "archive"))
+}
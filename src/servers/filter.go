@@ -0,0 +1,115 @@
+package servers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+// 可以用于筛选的直播间状态
+const (
+	LiveStatusLiving    = "living"    // 正在直播
+	LiveStatusOffline   = "offline"   // 未开播
+	LiveStatusListening = "listening" // 正在监听
+	LiveStatusStopped   = "stopped"   // 未监听
+	LiveStatusRecording = "recording" // 正在录制
+	LiveStatusPushing   = "pushing"   // 正在转推
+)
+
+// liveFilter 是直播间的筛选条件，不同条件之间需要同时满足，同一条件的多个值满足其一即可，为空表示不筛选。
+type liveFilter struct {
+	IDs       []string `json:"ids"`       // 直播 ID
+	Tags      []string `json:"tags"`      // 标签，不区分大小写
+	Groups    []string `json:"groups"`    // 分组
+	Platforms []string `json:"platforms"` // 平台的中文名或直播间地址的域名，如 哔哩哔哩、bilibili.com
+	Statuses  []string `json:"statuses"`  // 状态，如 living、recording
+}
+
+// newLiveFilterFromQuery 从形如 ?tag=a,b&group=c&platform=bilibili.com&status=living 的查询参数创建筛选条件。
+func newLiveFilterFromQuery(query url.Values) *liveFilter {
+	return &liveFilter{
+		IDs:       splitQuery(query.Get("id")),
+		Tags:      splitQuery(query.Get("tag")),
+		Groups:    splitQuery(query.Get("group")),
+		Platforms: splitQuery(query.Get("platform")),
+		Statuses:  splitQuery(query.Get("status")),
+	}
+}
+
+// IsEmpty 判断是否没有任何筛选条件。
+func (f *liveFilter) IsEmpty() bool {
+	return len(f.IDs) == 0 && len(f.Tags) == 0 && len(f.Groups) == 0 && len(f.Platforms) == 0 && len(f.Statuses) == 0
+}
+
+// Match 判断直播间是否满足筛选条件。
+func (f *liveFilter) Match(info *live.Info) bool {
+	if len(f.IDs) > 0 && !matchAny(f.IDs, func(id string) bool { return live.ID(id) == info.Live.GetLiveId() }) {
+		return false
+	}
+	if len(f.Tags) > 0 && !matchAny(f.Tags, func(tag string) bool { return hasTag(info.Tags, tag) }) {
+		return false
+	}
+	if len(f.Groups) > 0 && !matchAny(f.Groups, func(group string) bool { return strings.EqualFold(group, info.Group) }) {
+		return false
+	}
+	if len(f.Platforms) > 0 && !matchAny(f.Platforms, func(platform string) bool { return matchPlatform(info.Live, platform) }) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !matchAny(f.Statuses, func(status string) bool { return matchStatus(info, status) }) {
+		return false
+	}
+	return true
+}
+
+// matchAny 判断是否有值满足条件。
+func matchAny(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTag 判断标签列表中是否有指定的标签，不区分大小写。
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPlatform 判断直播间是否属于指定的平台，平台可以是中文名、完整的域名或上级域名。
+func matchPlatform(l live.Live, platform string) bool {
+	if platform == l.GetPlatformCNName() {
+		return true
+	}
+	u, err := url.Parse(l.GetRawUrl())
+	if err != nil {
+		return false
+	}
+	host, platform := strings.ToLower(u.Hostname()), strings.ToLower(platform)
+	return host == platform || strings.HasSuffix(host, "."+platform)
+}
+
+// matchStatus 判断直播间是否处于指定的状态，未知的状态不匹配任何直播间。
+func matchStatus(info *live.Info, status string) bool {
+	switch strings.ToLower(status) {
+	case LiveStatusLiving:
+		return info.Status
+	case LiveStatusOffline:
+		return !info.Status
+	case LiveStatusListening:
+		return info.Listening
+	case LiveStatusStopped:
+		return !info.Listening
+	case LiveStatusRecording:
+		return info.Recording
+	case LiveStatusPushing:
+		return info.Pushing
+	}
+	return false
+}
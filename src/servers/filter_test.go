@@ -0,0 +1,71 @@
+package servers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+)
+
+func TestLiveFilterMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetLiveId().Return(live.ID("a")).AnyTimes()
+	l.EXPECT().GetRawUrl().Return("https://live.bilibili.com/1").AnyTimes()
+	l.EXPECT().GetPlatformCNName().Return("哔哩哔哩").AnyTimes()
+	info := &live.Info{Live: l, Group: "运营部", Tags: []string{"VTuber", "game"}, Status: true, Listening: true, Recording: true}
+
+	tests := []struct {
+		query string
+		match bool
+	}{
+		{"", true},
+		{"tag=vtuber", true},
+		{"tag=music,game", true},
+		{"tag=music", false},
+		{"group=运营部", true},
+		{"group=技术部", false},
+		{"platform=哔哩哔哩", true},
+		{"platform=bilibili.com", true},
+		{"platform=live.bilibili.com", true},
+		{"platform=ilibili.com", false},
+		{"status=living,offline", true},
+		{"status=recording", true},
+		{"status=pushing", false},
+		{"status=unknown", false},
+		{"id=a,b", true},
+		{"id=b", false},
+		{"tag=game&group=运营部&status=living", true},
+		{"tag=game&status=stopped", false},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		assert.Equal(t, tt.match, newLiveFilterFromQuery(query).Match(info), tt.query)
+	}
+}
+
+func TestBulkActionInvalidRequest(t *testing.T) {
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{})
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/lives/bulk", strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		bulkAction(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do(`{"tags": ["a"], "resource": "record", "action": "pause"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(`{"tags": ["a"], "resource": "download", "action": "start"}`).Code)
+	// 筛选条件为空时拒绝执行
+	assert.Equal(t, http.StatusBadRequest, do(`{"resource": "record", "action": "start"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(`tags`).Code)
+}
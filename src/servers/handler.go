@@ -22,10 +22,16 @@ import (
 	"github.com/yuhaohwang/bililive-go/src/instance"
 	"github.com/yuhaohwang/bililive-go/src/listeners"
 	"github.com/yuhaohwang/bililive-go/src/live"
+	logpkg "github.com/yuhaohwang/bililive-go/src/log"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
 	"github.com/yuhaohwang/bililive-go/src/pushers"
 	"github.com/yuhaohwang/bililive-go/src/recorders"
 )
 
+// errLiveExist 表示平台返回的直播 ID 已存在，地址不同的同一个直播间只有创建后才能发现重复
+var errLiveExist = errors.New("直播间已存在")
+
 // parseInfo 从直播信息对象中提取相关数据并构建一个 live.Info 结构。
 func parseInfo(ctx context.Context, l live.Live) *live.Info {
 	// 获取应用程序实例
@@ -45,6 +51,9 @@ func parseInfo(ctx context.Context, l live.Live) *live.Info {
 		info.Record = room.Record
 		info.Push = room.Push
 		info.RtmpUrl = room.Rtmp
+		info.Group = room.Group
+		info.Tags = room.Tags
+		info.Source = room.Source
 	}
 
 	// 检查是否有监听器和录制器，并将结果存储在相应的字段中
@@ -60,12 +69,18 @@ func parseInfo(ctx context.Context, l live.Live) *live.Info {
 func getAllLives(writer http.ResponseWriter, r *http.Request) {
 	// 获取应用程序实例
 	inst := instance.GetInstance(r.Context())
+	inst.RoomLock.RLock()
+	defer inst.RoomLock.RUnlock()
 	// 创建直播信息切片
 	lives := liveSlice(make([]*live.Info, 0, 4))
+	// 按查询参数中的标签、分组、平台和状态筛选
+	filter := newLiveFilterFromQuery(r.URL.Query())
 	// 遍历所有直播
-	for _, v := range inst.Lives {
+	for _, v := range inst.GetLives() {
 		// 解析直播信息并添加到切片中
-		lives = append(lives, parseInfo(r.Context(), v))
+		if info := parseInfo(r.Context(), v); filter.Match(info) {
+			lives = append(lives, info)
+		}
 	}
 
 	// 按某个标准排序直播信息切片
@@ -78,10 +93,12 @@ func getAllLives(writer http.ResponseWriter, r *http.Request) {
 func getLive(writer http.ResponseWriter, r *http.Request) {
 	// 获取应用程序实例
 	inst := instance.GetInstance(r.Context())
+	inst.RoomLock.RLock()
+	defer inst.RoomLock.RUnlock()
 	// 获取请求中的直播 ID
 	vars := mux.Vars(r)
 	// 根据直播 ID 查找直播
-	live, ok := inst.Lives[live.ID(vars["id"])]
+	live, ok := inst.GetLive(live.ID(vars["id"]))
 	if !ok {
 		// 直播不存在，返回错误响应
 		writeJsonWithStatusCode(writer, http.StatusNotFound, commonResp{
@@ -125,16 +142,28 @@ func addLives(writer http.ResponseWriter, r *http.Request) {
 	info := liveSlice(make([]*live.Info, 0))
 	// 创建错误消息切片
 	errorMessages := make([]string, 0, 4)
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
 	// 遍历请求中的直播信息
 	gjson.ParseBytes(b).ForEach(func(key, value gjson.Result) bool {
-		urlStr := strings.Trim(value.Get("url").String(), " ")
-		isListen := value.Get("listen").Bool()
-		isRecord := value.Get("record").Bool()
-		rtmpStr := strings.Trim(value.Get("rtmp").String(), " ")
-		isPush := value.Get("push").Bool()
+		room := configs.LiveRoom{
+			Url:      strings.Trim(value.Get("url").String(), " "),
+			Listen:   value.Get("listen").Bool(),
+			Record:   value.Get("record").Bool(),
+			Rtmp:     strings.Trim(value.Get("rtmp").String(), " "),
+			Push:     value.Get("push").Bool(),
+			HostName: value.Get("host_name").String(),
+			RoomName: value.Get("room_name").String(),
+			Group:    strings.TrimSpace(value.Get("group").String()),
+		}
+		for _, tag := range value.Get("tags").Array() {
+			if t := strings.TrimSpace(tag.String()); t != "" {
+				room.Tags = append(room.Tags, t)
+			}
+		}
 		// 调用添加直播信息的实现函数
-		if retInfo, err := addLiveImpl(r.Context(), urlStr, isListen, isRecord, rtmpStr, isPush); err != nil {
-			msg := urlStr + "：" + err.Error()
+		if retInfo, err := addLiveImpl(r.Context(), room); err != nil {
+			msg := room.Url + "：" + err.Error()
 			inst.Logger.Error(msg)
 			errorMessages = append(errorMessages, msg)
 			return true
@@ -150,9 +179,12 @@ func addLives(writer http.ResponseWriter, r *http.Request) {
 }
 
 // 添加直播信息的实现函数
-func addLiveImpl(ctx context.Context, urlStr string, isListen bool, isRecord bool, rtmpStr string, isPush bool) (info *live.Info, err error) {
-	// 如果 URL 不以 "http://" 或 "https://" 开头，则添加 "https://" 前缀
-	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
+// 直播间的其他设置（如只录制音频、转码配置）会原样保存到配置中，调用方需要持有 inst.RoomLock
+func addLiveImpl(ctx context.Context, room configs.LiveRoom) (info *live.Info, err error) {
+	urlStr, rtmpStr := room.Url, room.Rtmp
+	isListen, isRecord, isPush := room.Listen, room.Record, room.Push
+	// 如果 URL 没有协议，则添加 "https://" 前缀，直接的直播流地址可以使用 rtmp:// 等其他协议
+	if !strings.Contains(urlStr, "://") {
 		urlStr = "https://" + urlStr
 	}
 	// 解析 URL
@@ -168,14 +200,18 @@ func addLiveImpl(ctx context.Context, urlStr string, isListen bool, isRecord boo
 	if v, ok := inst.Config.Cookies[u.Host]; ok {
 		opts = append(opts, live.WithKVStringCookies(u, v))
 	}
+	opts = append(opts, live.WithQuality(room.Quality), live.WithHostName(room.HostName), live.WithRoomName(room.RoomName), live.WithLowLatency(room.LowLatency), live.WithFfmpegPath(inst.Config.FfmpegPath))
+	if name, tool, ok := inst.Config.GetExternalTool(u.String()); ok {
+		opts = append(opts, live.WithExternalTool(name, tool.Path))
+	}
 	// 创建新的直播实例
 	newLive, err := live.New(u, inst.Cache, opts...)
 	if err != nil {
 		return nil, err
 	}
 	// 如果直播信息尚未存在于应用程序中，则添加
-	if _, ok := inst.Lives[newLive.GetLiveId()]; !ok {
-		inst.Lives[newLive.GetLiveId()] = newLive
+	if _, ok := inst.GetLive(newLive.GetLiveId()); !ok {
+		inst.SetLive(newLive)
 		if isListen {
 			inst.ListenerManager.(listeners.Manager).AddListener(ctx, newLive)
 		}
@@ -183,6 +219,9 @@ func addLiveImpl(ctx context.Context, urlStr string, isListen bool, isRecord boo
 
 		info.Listen = isListen
 		info.Record = isRecord
+		info.Group = room.Group
+		info.Tags = room.Tags
+		info.Source = room.Source
 
 		// 如果rtmp不为空则添加相关字段
 		if rtmpStr != "" {
@@ -199,14 +238,10 @@ func addLiveImpl(ctx context.Context, urlStr string, isListen bool, isRecord boo
 			info.Push = isPush
 		}
 
-		liveRoom := configs.LiveRoom{
-			LiveId: newLive.GetLiveId(),
-			Url:    u.String(),
-			Listen: isListen,
-			Record: isRecord,
-			Rtmp:   rtmpStr,
-			Push:   isPush,
-		}
+		liveRoom := room
+		liveRoom.LiveId = newLive.GetLiveId()
+		liveRoom.Url = u.String()
+		liveRoom.Rtmp = rtmpStr
 		inst.Config.LiveRooms = append(inst.Config.LiveRooms, liveRoom)
 	}
 	return info, nil
@@ -216,10 +251,12 @@ func addLiveImpl(ctx context.Context, urlStr string, isListen bool, isRecord boo
 func removeLive(writer http.ResponseWriter, r *http.Request) {
 	// 获取应用程序实例
 	inst := instance.GetInstance(r.Context())
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
 	// 获取请求中的直播 ID
 	vars := mux.Vars(r)
 	// 根据直播 ID 查找直播
-	live, ok := inst.Lives[live.ID(vars["id"])]
+	live, ok := inst.GetLive(live.ID(vars["id"]))
 	if !ok {
 		// 直播不存在，返回错误响应
 		writeJsonWithStatusCode(writer, http.StatusNotFound, commonResp{
@@ -242,7 +279,7 @@ func removeLive(writer http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// 移除直播信息的实现函数
+// 移除直播信息的实现函数，调用方需要持有 inst.RoomLock
 func removeLiveImpl(ctx context.Context, live live.Live) error {
 	// 获取应用程序实例
 	inst := instance.GetInstance(ctx)
@@ -254,9 +291,15 @@ func removeLiveImpl(ctx context.Context, live live.Live) error {
 		}
 	}
 	// 从应用程序中移除直播信息
-	delete(inst.Lives, live.GetLiveId())
+	inst.DeleteLive(live.GetLiveId())
 	// 从配置中移除直播房间信息
 	inst.Config.RemoveLiveRoomByUrl(live.GetRawUrl())
+	// 关闭直播间的日志文件
+	logpkg.RemoveRoom(inst.Logger, live.GetLiveId())
+	// 通知直播间已删除，清理其事件记录
+	if ed, ok := inst.EventDispatcher.(events.Dispatcher); ok {
+		ed.DispatchEvent(events.NewEvent(RoomRemoved, live))
+	}
 	return nil
 }
 
@@ -269,7 +312,10 @@ func getConfig(writer http.ResponseWriter, r *http.Request) {
 // 更新配置信息
 func putConfig(writer http.ResponseWriter, r *http.Request) {
 	// 获取应用程序实例
-	config := instance.GetInstance(r.Context()).Config
+	inst := instance.GetInstance(r.Context())
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
+	config := inst.Config
 	// 刷新直播房间索引缓存
 	config.RefreshLiveRoomIndexCache()
 	// 将配置信息持久化到文件
@@ -334,6 +380,8 @@ func putRawConfig(writer http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
 	oldConfig := inst.Config
 	newConfig.File = oldConfig.File
 	if err := applyLiveRoomsByConfig(ctx, newConfig.LiveRooms); err != nil {
@@ -353,7 +401,7 @@ func putRawConfig(writer http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// 根据新的配置应用直播房间信息
+// 根据新的配置应用直播房间信息，调用方需要持有 inst.RoomLock
 func applyLiveRoomsByConfig(ctx context.Context, newLiveRooms []configs.LiveRoom) error {
 	inst := instance.GetInstance(ctx)
 	currentConfig := inst.Config
@@ -363,11 +411,11 @@ func applyLiveRoomsByConfig(ctx context.Context, newLiveRooms []configs.LiveRoom
 		newUrlMap[newRoom.Url] = &newRoom
 		if room, err := currentConfig.GetLiveRoomByUrl(newRoom.Url); err != nil {
 			// 添加直播信息
-			if _, err := addLiveImpl(ctx, newRoom.Url, newRoom.Listen, newRoom.Record, newRoom.Rtmp, newRoom.Push); err != nil {
+			if _, err := addLiveImpl(ctx, newRoom); err != nil {
 				return err
 			}
 		} else {
-			live, ok := inst.Lives[live.ID(room.LiveId)]
+			live, ok := inst.GetLive(live.ID(room.LiveId))
 			if !ok {
 				return fmt.Errorf("live id: %s 找不到", room.LiveId)
 			}
@@ -384,6 +432,7 @@ func applyLiveRoomsByConfig(ctx context.Context, newLiveRooms []configs.LiveRoom
 					}
 				}
 				room.Listen = newRoom.Listen
+				room.SourceStopped = false
 			}
 		}
 	}
@@ -391,7 +440,7 @@ func applyLiveRoomsByConfig(ctx context.Context, newLiveRooms []configs.LiveRoom
 	for _, room := range loopRooms {
 		if _, ok := newUrlMap[room.Url]; !ok {
 			// 移除直播信息
-			live, ok := inst.Lives[live.ID(room.LiveId)]
+			live, ok := inst.GetLive(live.ID(room.LiveId))
 			if !ok {
 				return fmt.Errorf("live id: %s 找不到", room.LiveId)
 			}
@@ -414,24 +463,10 @@ func getFileInfo(writer http.ResponseWriter, r *http.Request) {
 	path := vars["path"]
 
 	inst := instance.GetInstance(r.Context())
-	base, err := filepath.Abs(inst.Config.OutPutPath)
+	base, absPath, err := resolveOutputPath(inst, path)
 	if err != nil {
 		writeJSON(writer, commonResp{
-			ErrMsg: "无效输出目录",
-		})
-		return
-	}
-
-	absPath, err := filepath.Abs(filepath.Join(base, path))
-	if err != nil {
-		writeJSON(writer, commonResp{
-			ErrMsg: "无效路径",
-		})
-		return
-	}
-	if !strings.HasPrefix(absPath, base) {
-		writeJSON(writer, commonResp{
-			ErrMsg: "异常路径",
+			ErrMsg: err.Error(),
 		})
 		return
 	}
@@ -450,17 +485,22 @@ func getFileInfo(writer http.ResponseWriter, r *http.Request) {
 		LastModified int64  `json:"last_modified"`
 		Size         int64  `json:"size"`
 	}
-	jsonFiles := make([]jsonFile, len(files))
+	jsonFiles := make([]jsonFile, 0, len(files))
 	json := struct {
 		Files []jsonFile `json:"files"`
 		Path  string     `json:"path"`
 	}{
-		Files: jsonFiles,
-		Path:  path,
+		Path: path,
 	}
-	for i, file := range files {
-		jsonFiles[i].IsFolder = file.IsDir()
-		jsonFiles[i].Name = file.Name()
+	for _, file := range files {
+		// 不显示保存程序状态的目录
+		if absPath == base && file.Name() == postprocess.StateDirName {
+			continue
+		}
+		f := jsonFile{
+			IsFolder: file.IsDir(),
+			Name:     file.Name(),
+		}
 
 		// 使用 os.Stat 获取文件的详细信息
 		fileInfo, err := os.Stat(filepath.Join(absPath, file.Name()))
@@ -470,10 +510,11 @@ func getFileInfo(writer http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		jsonFiles[i].LastModified = fileInfo.ModTime().Unix()
+		f.LastModified = fileInfo.ModTime().Unix()
 		if !file.IsDir() {
-			jsonFiles[i].Size = fileInfo.Size()
+			f.Size = fileInfo.Size()
 		}
+		jsonFiles = append(jsonFiles, f)
 	}
 	json.Files = jsonFiles
 
@@ -497,11 +538,13 @@ func setRtmp(writer http.ResponseWriter, r *http.Request) {
 
 	// 获取应用程序实例
 	inst := instance.GetInstance(r.Context())
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
 	// 获取请求中的变量
 	vars := mux.Vars(r)
 	resp := commonResp{}
 	// 根据直播 ID 查找直播
-	live, ok := inst.Lives[live.ID(vars["id"])]
+	live, ok := inst.GetLive(live.ID(vars["id"]))
 	if !ok {
 		// 直播不存在，返回错误响应
 		resp.ErrNo = http.StatusNotFound
@@ -610,6 +653,9 @@ func executeAction(ctx context.Context, live live.Live, room *configs.LiveRoom,
 
 	err := errors.New("")
 
+	// 手动修改后不再由来源恢复监听
+	room.SourceStopped = false
+
 	switch resource {
 	case "listen":
 		if action == "start" {
@@ -687,10 +733,12 @@ func executeAction(ctx context.Context, live live.Live, room *configs.LiveRoom,
 
 func mainHandler(writer http.ResponseWriter, r *http.Request) {
 	inst := instance.GetInstance(r.Context())
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
 	vars := mux.Vars(r)
 	resp := commonResp{}
 
-	live, exists := inst.Lives[live.ID(vars["id"])]
+	live, exists := inst.GetLive(live.ID(vars["id"]))
 	if !exists {
 		resp.ErrNo = http.StatusBadRequest
 		resp.ErrMsg = fmt.Sprintf("live id: %s 找不到", vars["id"])
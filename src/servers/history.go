@@ -0,0 +1,105 @@
+package servers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+// liveEventBufferSize 是每个直播间保存的最近事件数，用于排查某个直播间的历史错误。
+const liveEventBufferSize = 100
+
+// eventRing 是保存最近事件的环形缓冲区。
+type eventRing struct {
+	size   int
+	events []*StreamEvent
+	start  int
+}
+
+// newEventRing 创建最多保存 size 个事件的环形缓冲区。
+func newEventRing(size int) *eventRing {
+	return &eventRing{size: size}
+}
+
+// push 写入一个事件，缓冲区已满时覆盖最早的事件。
+func (r *eventRing) push(e *StreamEvent) {
+	if len(r.events) < r.size {
+		r.events = append(r.events, e)
+		return
+	}
+	r.events[r.start] = e
+	r.start = (r.start + 1) % r.size
+}
+
+// since 按时间顺序返回序号大于 lastID 的事件。
+func (r *eventRing) since(lastID uint64) []*StreamEvent {
+	events := make([]*StreamEvent, 0)
+	for i := 0; i < len(r.events); i++ {
+		if e := r.events[(r.start+i)%len(r.events)]; e.ID > lastID {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// liveEvents 按时间顺序返回直播间序号大于 lastID 且满足订阅条件的事件，limit 大于0时只返回最新的 limit 个。
+// 第二个返回值表示是否有该直播间的事件记录。
+func (b *eventBridge) liveEvents(id live.ID, filter *EventFilter, lastID uint64, limit int) ([]*StreamEvent, bool) {
+	b.lock.Lock()
+	ring, ok := b.history[id]
+	var events []*StreamEvent
+	if ok {
+		events = ring.since(lastID)
+	}
+	b.lock.Unlock()
+
+	matched := make([]*StreamEvent, 0, len(events))
+	for _, e := range events {
+		if filter.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if limit > 0 && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+	return matched, ok
+}
+
+// removeHistory 删除直播间的事件记录，用于直播间被删除时。
+func (b *eventBridge) removeHistory(id live.ID) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.history, id)
+}
+
+// liveHistory 是通过 WebSocket 查询直播间历史事件的应答。
+type liveHistory struct {
+	LiveID live.ID        `json:"live_id"`
+	Events []*StreamEvent `json:"events"`
+}
+
+// getLiveEvents 返回直播间最近的事件和错误。
+// 支持 topics 查询参数过滤主题，since 只返回序号更大的事件，limit 限制返回的数量。
+func (b *eventBridge) getLiveEvents(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	id := live.ID(mux.Vars(r)["id"])
+	query := r.URL.Query()
+	filter := NewEventFilter(splitQuery(query.Get("topics")), nil)
+	since, _ := strconv.ParseUint(query.Get("since"), 10, 64)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	events, ok := b.liveEvents(id, filter, since, limit)
+	if _, exists := inst.GetLive(id); !exists && !ok {
+		writeJsonWithStatusCode(writer, http.StatusNotFound, commonResp{
+			ErrNo:  http.StatusNotFound,
+			ErrMsg: fmt.Sprintf("live id: %s 找不到", id),
+		})
+		return
+	}
+	writeJSON(writer, events)
+}
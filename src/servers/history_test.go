@@ -0,0 +1,83 @@
+package servers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+func TestEventBridgeLiveEvents(t *testing.T) {
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{})
+	b := newEventBridge(ctx)
+	for i := 0; i < liveEventBufferSize+5; i++ {
+		b.publish(&StreamEvent{Topic: TopicLive, LiveID: "a"})
+	}
+	b.publish(&StreamEvent{Topic: TopicError, Event: "RecorderError", LiveID: "a"})
+	b.publish(&StreamEvent{Topic: TopicProgress, LiveID: "a"})
+	b.publish(&StreamEvent{Topic: TopicError, Event: "ListenError", LiveID: "b"})
+
+	events, ok := b.liveEvents("a", NewEventFilter(nil, nil), 0, 0)
+	assert.True(t, ok)
+	// 只保留最近的事件，且不包含进度事件
+	if assert.Len(t, events, liveEventBufferSize) {
+		assert.Equal(t, uint64(7), events[0].ID)
+		assert.Equal(t, "RecorderError", events[len(events)-1].Event)
+	}
+
+	events, _ = b.liveEvents("a", NewEventFilter([]string{TopicError}, nil), 0, 0)
+	assert.Len(t, events, 1)
+	events, _ = b.liveEvents("a", NewEventFilter(nil, nil), 0, 3)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, uint64(liveEventBufferSize+6), events[2].ID)
+	}
+	events, _ = b.liveEvents("a", NewEventFilter(nil, nil), liveEventBufferSize+4, 0)
+	assert.Len(t, events, 2)
+
+	_, ok = b.liveEvents("c", NewEventFilter(nil, nil), 0, 0)
+	assert.False(t, ok)
+
+	// 删除直播间后不再保留其事件
+	b.removeHistory("a")
+	_, ok = b.liveEvents("a", NewEventFilter(nil, nil), 0, 0)
+	assert.False(t, ok)
+}
+
+func TestGetLiveEvents(t *testing.T) {
+	inst := &instance.Instance{Lives: map[live.ID]live.Live{"c": nil}}
+	ctx := context.WithValue(context.Background(), instance.Key, inst)
+	b := newEventBridge(ctx)
+	b.publish(&StreamEvent{Topic: TopicLive, Event: "LiveStart", LiveID: "a"})
+	b.publish(&StreamEvent{Topic: TopicError, Event: "RecorderError", LiveID: "a"})
+
+	m := mux.NewRouter()
+	m.HandleFunc("/lives/{id}/events", b.getLiveEvents)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/lives/a/events?topics=error")
+	assert.Equal(t, http.StatusOK, w.Code)
+	events := make([]*StreamEvent, 0)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "RecorderError", events[0].Event)
+	}
+
+	// 直播间存在但还没有事件时返回空列表
+	w = get("/lives/c/events")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/lives/d/events").Code)
+}
@@ -0,0 +1,148 @@
+package servers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	"github.com/tidwall/gjson"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
+)
+
+var (
+	// errNoPostProcess 表示后处理管理器未启动。
+	errNoPostProcess = errors.New("后处理未启用")
+	// errNoSteps 表示没有配置后处理步骤。
+	errNoSteps = errors.New("未配置后处理步骤")
+)
+
+// getPostProcessManager 获取后处理管理器，未启动时返回错误响应。
+func getPostProcessManager(writer http.ResponseWriter, r *http.Request) (postprocess.Manager, bool) {
+	pm, ok := instance.GetInstance(r.Context()).PostProcessManager.(postprocess.Manager)
+	if !ok {
+		writeFileError(writer, http.StatusServiceUnavailable, errNoPostProcess)
+	}
+	return pm, ok
+}
+
+// writeJobError 根据错误类型返回对应状态码的错误响应。
+func writeJobError(writer http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch err {
+	case postprocess.ErrJobNotFound:
+		code = http.StatusNotFound
+	case postprocess.ErrJobRunning, postprocess.ErrJobFinished:
+		code = http.StatusConflict
+	case postprocess.ErrNoSourceFile:
+		code = http.StatusBadRequest
+	}
+	writeFileError(writer, code, err)
+}
+
+// getJobs 返回后处理任务列表，可以通过 status 和 live_id 查询参数过滤。
+func getJobs(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	pm, ok := inst.PostProcessManager.(postprocess.Manager)
+	if !ok {
+		writeJSON(writer, make([]*postprocess.Job, 0))
+		return
+	}
+	query := r.URL.Query()
+	status := postprocess.Status(query.Get("status"))
+	liveID := live.ID(query.Get("live_id"))
+	jobs := make([]*postprocess.Job, 0)
+	for _, job := range pm.Jobs() {
+		if status != "" && job.Status != status {
+			continue
+		}
+		if liveID != "" && job.Live.ID != liveID {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	writeJSON(writer, jobs)
+}
+
+// getJob 返回单个后处理任务。
+func getJob(writer http.ResponseWriter, r *http.Request) {
+	pm, ok := getPostProcessManager(writer, r)
+	if !ok {
+		return
+	}
+	job, err := pm.GetJob(mux.Vars(r)["id"])
+	if err != nil {
+		writeJobError(writer, err)
+		return
+	}
+	writeJSON(writer, job)
+}
+
+// addJob 为输出目录中的文件创建后处理任务。
+//
+//	{"path": "哔哩哔哩/怕上火暴王老菊/a.flv"}
+func addJob(writer http.ResponseWriter, r *http.Request) {
+	pm, ok := getPostProcessManager(writer, r)
+	if !ok {
+		return
+	}
+	inst := instance.GetInstance(r.Context())
+	if len(inst.Config.GetSteps()) == 0 {
+		writeFileError(writer, http.StatusBadRequest, errNoSteps)
+		return
+	}
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeFileError(writer, http.StatusBadRequest, err)
+		return
+	}
+	_, absPath, err := resolveOutputPath(inst, gjson.GetBytes(b, "path").String())
+	if err != nil {
+		writeFileError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if stat, err := os.Stat(absPath); err != nil || stat.IsDir() {
+		writeJobError(writer, postprocess.ErrNoSourceFile)
+		return
+	}
+	job, err := pm.Enqueue(postprocess.ReadLiveInfo(absPath), absPath)
+	if err != nil {
+		writeJobError(writer, err)
+		return
+	}
+	writeJSON(writer, job)
+}
+
+// cancelJob 取消等待执行或正在执行的后处理任务。
+func cancelJob(writer http.ResponseWriter, r *http.Request) {
+	pm, ok := getPostProcessManager(writer, r)
+	if !ok {
+		return
+	}
+	if err := pm.Cancel(mux.Vars(r)["id"]); err != nil {
+		writeJobError(writer, err)
+		return
+	}
+	writeJSON(writer, commonResp{Data: "OK"})
+}
+
+// rerunJob 重新执行已结束的后处理任务。
+//
+//	{"from_failed": true}
+func rerunJob(writer http.ResponseWriter, r *http.Request) {
+	pm, ok := getPostProcessManager(writer, r)
+	if !ok {
+		return
+	}
+	b, _ := io.ReadAll(r.Body)
+	job, err := pm.Rerun(mux.Vars(r)["id"], gjson.GetBytes(b, "from_failed").Bool())
+	if err != nil {
+		writeJobError(writer, err)
+		return
+	}
+	writeJSON(writer, job)
+}
@@ -0,0 +1,102 @@
+package servers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
+)
+
+func TestJobsApi(t *testing.T) {
+	dir := t.TempDir()
+	cfg := configs.NewConfig()
+	cfg.OutPutPath = dir
+	cfg.PostProcess.Steps = []configs.PostProcessStep{{Type: configs.StepCommand, Command: "exit 2"}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	inst := &instance.Instance{Config: cfg, Logger: &interfaces.Logger{Logger: logger}}
+	ctx := context.WithValue(context.Background(), instance.Key, inst)
+	pm := postprocess.NewManager(ctx)
+	assert.NoError(t, pm.Start(ctx))
+	defer pm.Close(ctx)
+
+	m := mux.NewRouter()
+	m.Use(func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), instance.Key, inst)))
+		})
+	})
+	m.HandleFunc("/api/jobs", getJobs).Methods("GET")
+	m.HandleFunc("/api/jobs", addJob).Methods("POST")
+	m.HandleFunc("/api/jobs/{id}", getJob).Methods("GET")
+	m.HandleFunc("/api/jobs/{id}/cancel", cancelJob).Methods("POST")
+	m.HandleFunc("/api/jobs/{id}/rerun", rerunJob).Methods("POST")
+	s := httptest.NewServer(m)
+	defer s.Close()
+
+	writeTestFile(t, filepath.Join(dir, "room", "a.flv"), "flv")
+	writeTestFile(t, filepath.Join(dir, "room", "a.metadata.json"), `{"id":"abc","host_name":"主播","platform_cn_name":"哔哩哔哩"}`)
+
+	do := func(method, path, body string) (int, []byte) {
+		req, _ := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0, nil
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, b
+	}
+
+	code, _ := do("POST", "/api/jobs", `{"path":"../a.flv"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("POST", "/api/jobs", `{"path":"room/missing.flv"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, b := do("POST", "/api/jobs", `{"path":"room/a.flv"}`)
+	assert.Equal(t, http.StatusOK, code)
+	job := new(postprocess.Job)
+	assert.NoError(t, json.Unmarshal(b, job))
+	assert.Equal(t, live.ID("abc"), job.Live.ID)
+	assert.Equal(t, "主播", job.Live.HostName)
+
+	for i := 0; i < 500 && !job.Status.IsFinished(); i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, b = do("GET", "/api/jobs/"+job.ID, "")
+		json.Unmarshal(b, job)
+	}
+	assert.Equal(t, postprocess.StatusFailed, job.Status)
+	if assert.Len(t, job.Steps, 1) && assert.NotNil(t, job.Steps[0].ExitCode) {
+		assert.Equal(t, 2, *job.Steps[0].ExitCode)
+	}
+
+	code, _ = do("POST", "/api/jobs/"+job.ID+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do("POST", "/api/jobs/"+job.ID+"/rerun", `{"from_failed":true}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/api/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	jobs := make([]*postprocess.Job, 0)
+	_, b = do("GET", "/api/jobs?live_id=abc", "")
+	assert.NoError(t, json.Unmarshal(b, &jobs))
+	assert.Len(t, jobs, 1)
+	_, b = do("GET", "/api/jobs?live_id=other", "")
+	assert.NoError(t, json.Unmarshal(b, &jobs))
+	assert.Len(t, jobs, 0)
+}
@@ -0,0 +1,47 @@
+package servers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+)
+
+// logLevel 是日志级别接口的请求和应答。
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// getLogLevel 返回当前的日志级别。
+func getLogLevel(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	writeJSON(writer, logLevel{Level: inst.Logger.GetLevel().String()})
+}
+
+// putLogLevel 在运行时修改日志级别，立即生效，不会写入配置文件。
+func putLogLevel(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	var req logLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: err.Error(),
+		})
+		return
+	}
+	level, err := logrus.ParseLevel(req.Level)
+	if err != nil || !configs.IsLogLevel(req.Level) {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: fmt.Sprintf("不支持的日志级别：%s", req.Level),
+		})
+		return
+	}
+	inst.Logger.SetLevel(level)
+	inst.Logger.Infof("日志级别已修改为 %s", level)
+	writeJSON(writer, logLevel{Level: level.String()})
+}
@@ -0,0 +1,42 @@
+package servers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+)
+
+func TestLogLevel(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Logger: &interfaces.Logger{Logger: logger},
+	})
+	do := func(handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/log/level", strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := do(getLogLevel, http.MethodGet, "")
+	assert.JSONEq(t, `{"level":"info"}`, w.Body.String())
+
+	w = do(putLogLevel, http.MethodPut, `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+
+	assert.Equal(t, http.StatusBadRequest, do(putLogLevel, http.MethodPut, `{"level":"verbose"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(putLogLevel, http.MethodPut, `level`).Code)
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+}
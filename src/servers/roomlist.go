@@ -0,0 +1,136 @@
+package servers
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/pkg/roomlist"
+)
+
+// importFailure 是导入时添加失败的直播间。
+type importFailure struct {
+	Url   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// importResult 是导入的结果，dry_run 时只返回计划，不添加直播间。
+type importResult struct {
+	Format string          `json:"format"`
+	DryRun bool            `json:"dry_run"`
+	Plan   *roomlist.Plan  `json:"plan"`
+	Added  liveSlice       `json:"added"`
+	Failed []importFailure `json:"failed"`
+}
+
+// importLives 从请求体导入直播间列表，按直播 ID 跳过已有的和重复的直播间。
+// 与 POST /api/lives 一样，导入的直播间只保存在内存中的配置，需要 PUT /api/config 才会写入配置文件。
+func importLives(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: err.Error(),
+		})
+		return
+	}
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+	rooms, invalid, format, err := roomlist.Parse(query.Get("format"), b)
+	if err != nil {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: err.Error(),
+		})
+		return
+	}
+
+	inst.RoomLock.Lock()
+	defer inst.RoomLock.Unlock()
+	existing := roomlist.ExistingRooms(inst.Config.LiveRooms)
+	// 平台返回的直播 ID 可能与地址生成的不同，两者都用于去重
+	for id, l := range inst.GetLives() {
+		existing[id] = l.GetRawUrl()
+		roomlist.AddExisting(existing, l.GetRawUrl())
+	}
+	result := importResult{
+		Format: format,
+		DryRun: dryRun,
+		Plan:   roomlist.NewPlan(rooms, invalid, existing),
+		Added:  make(liveSlice, 0),
+		Failed: make([]importFailure, 0),
+	}
+	if !dryRun {
+		for _, room := range result.Plan.Add {
+			info, err := addLiveImpl(r.Context(), room)
+			if err == nil && info == nil {
+				// 平台返回的直播 ID 与地址生成的不同时，只有创建后才能发现重复
+				err = errLiveExist
+			}
+			if err != nil {
+				inst.Logger.Error(room.Url + "：" + err.Error())
+				result.Failed = append(result.Failed, importFailure{Url: room.Url, Error: err.Error()})
+				continue
+			}
+			result.Added = append(result.Added, info)
+		}
+		sort.Sort(result.Added)
+		inst.Logger.Infof("导入直播间：%d 个添加，%d 个重复，%d 个无效，%d 个失败",
+			len(result.Added), len(result.Plan.Duplicates), len(result.Plan.Invalid), len(result.Failed))
+	}
+	writeJSON(writer, result)
+}
+
+// exportLives 将满足筛选条件的直播间导出为指定格式，筛选条件与 GET /api/lives 的查询参数相同。
+// 只支持哔哩哔哩的格式会跳过其他平台的直播间，跳过的数量在 X-Skipped-Rooms 响应头中。
+func exportLives(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = roomlist.FormatCSV
+	}
+	filter := newLiveFilterFromQuery(query)
+	inst.RoomLock.RLock()
+	lives := liveSlice(make([]*live.Info, 0))
+	for _, l := range inst.GetLives() {
+		if info := parseInfo(r.Context(), l); filter.Match(info) {
+			lives = append(lives, info)
+		}
+	}
+	sort.Sort(lives)
+	rooms := make([]configs.LiveRoom, 0, len(lives))
+	for _, info := range lives {
+		if room, err := inst.Config.GetLiveRoomByUrl(info.Live.GetRawUrl()); err == nil {
+			rooms = append(rooms, *room)
+		}
+	}
+	inst.RoomLock.RUnlock()
+
+	b, skipped, err := roomlist.Format(format, rooms)
+	if err != nil {
+		writeJsonWithStatusCode(writer, http.StatusBadRequest, commonResp{
+			ErrNo:  http.StatusBadRequest,
+			ErrMsg: err.Error(),
+		})
+		return
+	}
+	contentType, fileName := roomlist.ContentType(format)
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	writer.Header().Set("X-Skipped-Rooms", strconv.Itoa(len(skipped)))
+	if len(skipped) > 0 {
+		urls := make([]string, 0, len(skipped))
+		for _, room := range skipped {
+			urls = append(urls, room.Url)
+		}
+		inst.Logger.Warnf("导出为 %s 时跳过了 %d 个直播间：%s", format, len(skipped), strings.Join(urls, ", "))
+	}
+	writer.Write(b)
+}
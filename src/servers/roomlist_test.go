@@ -0,0 +1,78 @@
+package servers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	livemock "github.com/yuhaohwang/bililive-go/src/live/mock"
+)
+
+func TestImportLivesDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// 平台返回的直播 ID 与地址生成的不同
+	l := livemock.NewMockLive(ctrl)
+	l.EXPECT().GetRawUrl().Return("https://www.douyu.com/2").AnyTimes()
+	cfg := configs.NewConfig()
+	cfg.LiveRooms = []configs.LiveRoom{{Url: "https://live.bilibili.com/1"}}
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{
+		Config: cfg,
+		Lives:  map[live.ID]live.Live{"custom": l},
+	})
+	do := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/lives/import?"+query, strings.NewReader(body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		importLives(rec, req)
+		return rec
+	}
+
+	rec := do("dry_run=true", "live.bilibili.com/1\nhttps://www.douyu.com/3\nwww.douyu.com/3\nhttp://\n")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var result struct {
+		Format string `json:"format"`
+		DryRun bool   `json:"dry_run"`
+		Plan   struct {
+			Add        []configs.LiveRoom `json:"add"`
+			Duplicates []struct {
+				Url      string `json:"url"`
+				Existing string `json:"existing"`
+			} `json:"duplicates"`
+			Invalid []struct {
+				Line int `json:"line"`
+			} `json:"invalid"`
+		} `json:"plan"`
+		Added []interface{} `json:"added"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "urls", result.Format)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://www.douyu.com/3", Listen: true, Record: true}}, result.Plan.Add)
+	if assert.Len(t, result.Plan.Duplicates, 2) {
+		assert.Equal(t, "https://live.bilibili.com/1", result.Plan.Duplicates[0].Existing)
+		assert.Equal(t, "https://www.douyu.com/3", result.Plan.Duplicates[1].Existing)
+	}
+	if assert.Len(t, result.Plan.Invalid, 1) {
+		assert.Equal(t, 4, result.Plan.Invalid[0].Line)
+	}
+	assert.Empty(t, result.Added)
+	assert.Len(t, cfg.LiveRooms, 1)
+
+	// 已存在的直播间即使直播 ID 不是由地址生成，也按地址去重
+	rec = do("dry_run=1&format=urls", "https://www.douyu.com/2")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Empty(t, result.Plan.Add)
+	assert.Len(t, result.Plan.Duplicates, 1)
+
+	rec = do("format=xml", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -19,10 +19,11 @@ const (
 // Server 结构体表示服务器对象。
 type Server struct {
 	server *http.Server
+	bridge *eventBridge
 }
 
 // initMux 函数初始化路由处理器，并添加中间件。
-func initMux(ctx context.Context) *mux.Router {
+func initMux(ctx context.Context, bridge *eventBridge) *mux.Router {
 	m := mux.NewRouter()
 	m.Use(func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -39,6 +40,8 @@ func initMux(ctx context.Context) *mux.Router {
 	}, log) // 使用 log 中间件记录请求日志
 
 	var wsManager = NewWebSocketManager(ctx)
+	wsManager.bridge = bridge
+	bridge.subscribe(wsManager)
 
 	// 设置 API 路由
 	apiRoute := m.PathPrefix(apiRouterPrefix).Subrouter()
@@ -50,12 +53,32 @@ func initMux(ctx context.Context) *mux.Router {
 	apiRoute.HandleFunc("/raw-config", putRawConfig).Methods("PUT")
 	apiRoute.HandleFunc("/lives", getAllLives).Methods("GET")
 	apiRoute.HandleFunc("/lives", addLives).Methods("POST")
+	apiRoute.HandleFunc("/lives/bulk", bulkAction).Methods("POST")
+	apiRoute.HandleFunc("/lives/import", importLives).Methods("POST")
+	apiRoute.HandleFunc("/lives/export", exportLives).Methods("GET")
 	apiRoute.HandleFunc("/lives/{id}", getLive).Methods("GET")
 	apiRoute.HandleFunc("/lives/{id}", removeLive).Methods("DELETE")
+	apiRoute.HandleFunc("/lives/{id}/events", bridge.getLiveEvents).Methods("GET")
 	apiRoute.HandleFunc("/lives/{id}/{action}", mainHandler).Methods("GET")
 	apiRoute.HandleFunc("/file/{path:.*}", getFileInfo).Methods("GET")
+	apiRoute.HandleFunc("/file/{path:.*}", updateFile).Methods("PUT")
+	apiRoute.HandleFunc("/file/{path:.*}", deleteFile).Methods("DELETE")
+	apiRoute.HandleFunc("/download/{path:.*}", downloadFile).Methods("GET")
+	apiRoute.HandleFunc("/play/{path:.*}", playFile).Methods("GET")
+	apiRoute.HandleFunc("/thumbnail/{path:.*}", getThumbnail).Methods("GET")
 	apiRoute.HandleFunc("/lives/{id}/push", setRtmp).Methods("put")
 	apiRoute.HandleFunc("/lives/{id}/{resource}/{action}", mainHandler).Methods("GET")
+	apiRoute.HandleFunc("/events", bridge.serveSSE).Methods("GET")
+	apiRoute.HandleFunc("/jobs", getJobs).Methods("GET")
+	apiRoute.HandleFunc("/jobs", addJob).Methods("POST")
+	apiRoute.HandleFunc("/jobs/{id}", getJob).Methods("GET")
+	apiRoute.HandleFunc("/jobs/{id}/cancel", cancelJob).Methods("POST")
+	apiRoute.HandleFunc("/jobs/{id}/rerun", rerunJob).Methods("POST")
+	apiRoute.HandleFunc("/shutdown", shutdownApp).Methods("POST")
+	apiRoute.HandleFunc("/log/level", getLogLevel).Methods("GET")
+	apiRoute.HandleFunc("/log/level", putLogLevel).Methods("PUT")
+	apiRoute.HandleFunc("/sources", getSources).Methods("GET")
+	apiRoute.HandleFunc("/sources/{name}/sync", syncSource).Methods("POST")
 	apiRoute.Handle("/metrics", promhttp.Handler()) // 用于处理 Prometheus 监控数据
 	m.HandleFunc("/ws", wsManager.HandleConnection) //开启websocket服务器
 
@@ -80,12 +103,14 @@ func initMux(ctx context.Context) *mux.Router {
 func NewServer(ctx context.Context) *Server {
 	inst := instance.GetInstance(ctx)
 	config := inst.Config
+	bridge := newEventBridge(ctx)
 	httpServer := &http.Server{
 		Addr:    config.RPC.Bind,
-		Handler: initMux(ctx),
+		Handler: initMux(ctx, bridge),
 	}
 	server := &Server{
 		server: httpServer,
+		bridge: bridge,
 	}
 	inst.Server = server
 	return server
@@ -95,6 +120,7 @@ func NewServer(ctx context.Context) *Server {
 func (s *Server) Start(ctx context.Context) error {
 	inst := instance.GetInstance(ctx)
 	inst.WaitGroup.Add(1)
+	s.bridge.start(ctx)
 	go func() {
 		switch err := s.server.ListenAndServe(); err {
 		case nil, http.ErrServerClosed:
@@ -110,6 +136,7 @@ func (s *Server) Start(ctx context.Context) error {
 func (s *Server) Close(ctx context.Context) {
 	inst := instance.GetInstance(ctx)
 	inst.WaitGroup.Done()
+	s.bridge.close()
 	ctx2, cancel := context.WithCancel(ctx)
 	if err := s.server.Shutdown(ctx2); err != nil {
 		inst.Logger.WithError(err).Error("failed to shutdown server")
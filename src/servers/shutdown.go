@@ -0,0 +1,24 @@
+package servers
+
+import (
+	"net/http"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/shutdown"
+)
+
+// shutdownApp 以排空模式退出程序：停止录制并在宽限时间内完成后处理任务后退出。
+// 退出在后台进行，重复请求不会产生影响。
+func shutdownApp(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	sm, ok := inst.ShutdownManager.(shutdown.Manager)
+	if !ok {
+		writeJsonWithStatusCode(writer, http.StatusServiceUnavailable, commonResp{
+			ErrNo:  http.StatusServiceUnavailable,
+			ErrMsg: "退出管理器未启动",
+		})
+		return
+	}
+	sm.Shutdown("api")
+	writeJSON(writer, commonResp{Data: "OK"})
+}
@@ -0,0 +1,145 @@
+package servers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+	"github.com/yuhaohwang/bililive-go/src/sources"
+)
+
+// sourceStatus 是自动发现来源的配置和最近一次同步的结果。
+type sourceStatus struct {
+	Name         string          `json:"name"`
+	Platform     string          `json:"platform"`
+	Type         string          `json:"type"`
+	Category     string          `json:"category,omitempty"`
+	Interval     string          `json:"interval"`
+	AddPolicy    string          `json:"add_policy"`
+	RemovePolicy string          `json:"remove_policy"`
+	Rooms        int             `json:"rooms"` // 当前由该来源管理的直播间数量
+	LastResult   *sources.Result `json:"last_result,omitempty"`
+}
+
+// getSources 返回所有自动发现来源的状态。
+func getSources(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	var results map[string]*sources.Result
+	if sm, ok := inst.SourceManager.(sources.Manager); ok {
+		results = sm.Results()
+	}
+	managed := make(map[string]int)
+	inst.RoomLock.RLock()
+	for _, room := range inst.Config.LiveRooms {
+		if room.Source != "" {
+			managed[room.Source]++
+		}
+	}
+	inst.RoomLock.RUnlock()
+	statuses := make([]sourceStatus, 0, len(inst.Config.Sources))
+	for _, source := range inst.Config.Sources {
+		statuses = append(statuses, newSourceStatus(source, managed[source.Name], results[source.Name]))
+	}
+	writeJSON(writer, statuses)
+}
+
+// newSourceStatus 创建来源的状态。
+func newSourceStatus(source configs.Source, rooms int, result *sources.Result) sourceStatus {
+	return sourceStatus{
+		Name:         source.Name,
+		Platform:     source.Platform,
+		Type:         source.Type,
+		Category:     source.Category,
+		Interval:     source.GetInterval().String(),
+		AddPolicy:    source.GetAddPolicy(),
+		RemovePolicy: source.GetRemovePolicy(),
+		Rooms:        rooms,
+		LastResult:   result,
+	}
+}
+
+// syncSource 立即同步指定的来源，返回同步的结果。
+func syncSource(writer http.ResponseWriter, r *http.Request) {
+	inst := instance.GetInstance(r.Context())
+	sm, ok := inst.SourceManager.(sources.Manager)
+	if !ok {
+		writeJsonWithStatusCode(writer, http.StatusServiceUnavailable, commonResp{
+			ErrNo:  http.StatusServiceUnavailable,
+			ErrMsg: "自动发现未启动",
+		})
+		return
+	}
+	result, err := sm.Sync(r.Context(), mux.Vars(r)["name"])
+	if errors.Is(err, sources.ErrSourceNotExist) {
+		writeJsonWithStatusCode(writer, http.StatusNotFound, commonResp{
+			ErrNo:  http.StatusNotFound,
+			ErrMsg: err.Error(),
+		})
+		return
+	}
+	writeJSON(writer, result)
+}
+
+// roomApplier 使用与接口相同的实现应用自动发现的修改。
+type roomApplier struct{}
+
+// NewRoomApplier 创建应用自动发现修改的 sources.Applier。
+func NewRoomApplier() sources.Applier {
+	return roomApplier{}
+}
+
+// AddRoom 添加直播间。
+func (roomApplier) AddRoom(ctx context.Context, room configs.LiveRoom) error {
+	info, err := addLiveImpl(ctx, room)
+	if err == nil && info == nil {
+		err = errLiveExist
+	}
+	return err
+}
+
+// SetListen 开始或停止监听直播间。
+func (roomApplier) SetListen(ctx context.Context, room configs.LiveRoom, listen bool) error {
+	l, current, err := findLiveRoom(ctx, room.Url)
+	if err != nil {
+		return err
+	}
+	action := "stop"
+	if listen {
+		action = "start"
+	}
+	if err := executeAction(ctx, l, current, "listen", action); err != nil {
+		return err
+	}
+	// executeAction 会清除该标记，由来源停止监听时重新设置，以便重新出现在列表中时恢复监听
+	current.SourceStopped = !listen
+	return nil
+}
+
+// RemoveRoom 移除直播间。
+func (roomApplier) RemoveRoom(ctx context.Context, room configs.LiveRoom) error {
+	l, _, err := findLiveRoom(ctx, room.Url)
+	if err != nil {
+		return err
+	}
+	return removeLiveImpl(ctx, l)
+}
+
+// findLiveRoom 返回配置中的直播间及其直播。
+func findLiveRoom(ctx context.Context, url string) (live.Live, *configs.LiveRoom, error) {
+	inst := instance.GetInstance(ctx)
+	room, err := inst.Config.GetLiveRoomByUrl(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, ok := inst.GetLive(room.LiveId)
+	if !ok {
+		return nil, nil, fmt.Errorf("live id: %s 找不到", room.LiveId)
+	}
+	return l, room, nil
+}
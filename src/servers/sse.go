@@ -0,0 +1,121 @@
+package servers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// sseBufferSize 是每个 SSE 客户端待发送事件的缓冲长度，写满时断开连接，由客户端重连补发。
+	sseBufferSize = 64
+	// sseHeartbeatInterval 是发送心跳注释的间隔，避免连接被代理判定为空闲。
+	sseHeartbeatInterval = 15 * time.Second
+	// sseRetry 是建议客户端断线后重连的等待时间（毫秒）。
+	sseRetry = 3000
+)
+
+// sseClient 是一个 SSE 连接的订阅者。
+type sseClient struct {
+	filter   *EventFilter
+	events   chan *StreamEvent
+	overflow chan struct{}
+}
+
+// PublishEvent 将事件放入客户端的发送队列，队列已满时通知连接断开。
+func (c *sseClient) PublishEvent(e *StreamEvent) {
+	if !c.filter.Match(e) {
+		return
+	}
+	select {
+	case c.events <- e:
+	default:
+		select {
+		case c.overflow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// parseLastEventID 从 Last-Event-ID 请求头或 last_event_id 查询参数中读取上次收到的事件序号。
+func parseLastEventID(r *http.Request) uint64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	id, _ := strconv.ParseUint(value, 10, 64)
+	return id
+}
+
+// writeSSEEvent 按 SSE 格式写入一个事件。
+func writeSSEEvent(w http.ResponseWriter, e *StreamEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.ID, data)
+	return err
+}
+
+// serveSSE 以 Server-Sent Events 的形式推送事件。
+// 支持与 /ws 相同的 topics 和 live_ids 查询参数，
+// 重连时根据 Last-Event-ID 从缓冲区中补发错过的事件。
+func (b *eventBridge) serveSSE(writer http.ResponseWriter, r *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writeJsonWithStatusCode(writer, http.StatusInternalServerError, commonResp{
+			ErrNo:  http.StatusInternalServerError,
+			ErrMsg: "streaming unsupported",
+		})
+		return
+	}
+
+	client := &sseClient{
+		filter:   newEventFilterFromQuery(r.URL.Query()),
+		events:   make(chan *StreamEvent, sseBufferSize),
+		overflow: make(chan struct{}, 1),
+	}
+	backlog := b.subscribeSince(client, parseLastEventID(r))
+	defer b.unsubscribe(client)
+
+	header := writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+	fmt.Fprintf(writer, "retry: %d\n\n", sseRetry)
+	for _, e := range backlog {
+		if !client.filter.Match(e) {
+			continue
+		}
+		if err := writeSSEEvent(writer, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-b.stop:
+			return
+		case <-client.overflow:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case e := <-client.events:
+			if err := writeSSEEvent(writer, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,121 @@
+package servers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+func TestEventBridgeBuffer(t *testing.T) {
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{})
+	b := newEventBridge(ctx)
+	for i := 0; i < eventBufferSize+10; i++ {
+		b.publish(&StreamEvent{Topic: TopicLive})
+	}
+	b.publish(&StreamEvent{Topic: TopicProgress})
+
+	backlog := b.subscribeSince(&sseClient{}, 0)
+	if assert.Len(t, backlog, eventBufferSize) {
+		assert.Equal(t, uint64(11), backlog[0].ID)
+		assert.Equal(t, uint64(eventBufferSize+10), backlog[len(backlog)-1].ID)
+	}
+	assert.Len(t, b.subscribeSince(&sseClient{}, eventBufferSize+5), 5)
+	// 序号比当前最大值还大，说明服务已重启，补发全部事件
+	assert.Len(t, b.subscribeSince(&sseClient{}, 100000), eventBufferSize)
+}
+
+// idRecorder 记录收到的事件序号。
+type idRecorder struct {
+	ids []uint64
+}
+
+func (r *idRecorder) PublishEvent(e *StreamEvent) {
+	r.ids = append(r.ids, e.ID)
+}
+
+func TestEventBridgeOrder(t *testing.T) {
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{})
+	b := newEventBridge(ctx)
+	r := &idRecorder{}
+	b.subscribe(r)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.publish(&StreamEvent{Topic: TopicLive})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if assert.Len(t, r.ids, 800) {
+		for i, id := range r.ids {
+			assert.Equal(t, uint64(i+1), id)
+		}
+	}
+}
+
+func TestServeSSEResume(t *testing.T) {
+	ctx := context.WithValue(context.Background(), instance.Key, &instance.Instance{})
+	b := newEventBridge(ctx)
+	b.publish(&StreamEvent{Topic: TopicLive, Event: "LiveStart", LiveID: "a"})
+	b.publish(&StreamEvent{Topic: TopicLive, Event: "LiveStart", LiveID: "b"})
+	b.publish(&StreamEvent{Topic: TopicLive, Event: "LiveEnd", LiveID: "a"})
+
+	server := httptest.NewServer(http.HandlerFunc(b.serveSSE))
+	defer server.Close()
+	defer b.close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?live_ids=a", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() *StreamEvent {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return nil
+			}
+			if data := strings.TrimPrefix(line, "data: "); data != line {
+				e := new(StreamEvent)
+				assert.NoError(t, json.Unmarshal([]byte(data), e))
+				return e
+			}
+		}
+	}
+
+	// 补发 Last-Event-ID 之后且符合过滤条件的事件
+	e := readEvent()
+	if assert.NotNil(t, e) {
+		assert.Equal(t, uint64(3), e.ID)
+		assert.Equal(t, "LiveEnd", e.Event)
+	}
+
+	// 之后发布的事件实时推送
+	b.publish(&StreamEvent{Topic: TopicLive, Event: "LiveStart", LiveID: "b"})
+	b.publish(&StreamEvent{Topic: TopicLive, Event: "LiveStart", LiveID: "a"})
+	e = readEvent()
+	if assert.NotNil(t, e) {
+		assert.Equal(t, uint64(5), e.ID)
+		assert.Equal(t, live.ID("a"), e.LiveID)
+	}
+}
@@ -3,32 +3,125 @@ package servers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/live"
 )
 
+const (
+	// writeTimeout 是向单个客户端写入消息的超时时间。
+	writeTimeout = 10 * time.Second
+	// wsBufferSize 是每个 WebSocket 客户端待发送消息的缓冲长度，推送事件时写满则断开连接。
+	wsBufferSize = 64
+)
+
+// errClientClosed 表示客户端连接已关闭或发送队列已满。
+var errClientClosed = errors.New("websocket 连接已关闭或发送队列已满")
+
+// wsClient 是一个 WebSocket 连接，消息由单独的协程按顺序写入，慢客户端不会阻塞广播。
+type wsClient struct {
+	conn      *websocket.Conn
+	filter    *EventFilter
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newWsClient 创建客户端并启动写入协程。
+func newWsClient(conn *websocket.Conn, filter *EventFilter) *wsClient {
+	c := &wsClient{
+		conn:   conn,
+		filter: filter,
+		send:   make(chan []byte, wsBufferSize),
+		done:   make(chan struct{}),
+	}
+	go c.writeLoop()
+	return c
+}
+
+// enqueue 将消息放入发送队列，队列已满或连接已关闭时返回 false。
+func (c *wsClient) enqueue(b []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.send <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeLoop 按顺序写入发送队列中的消息，写入失败时关闭连接。
+func (c *wsClient) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case b := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// close 关闭连接，读取协程随之退出并移除客户端。
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
 // WebSocketManager 管理与客户端的WebSocket连接。
 type WebSocketManager struct {
-	clients  map[*websocket.Conn]bool // 当前已连接的客户端列表。
-	upgrader websocket.Upgrader       // 用于升级HTTP连接到WebSocket连接的工具。
-	lock     sync.Mutex               // 用于同步对clients的访问。
+	clients  map[*websocket.Conn]*wsClient // 当前已连接的客户端及其订阅条件。
+	upgrader websocket.Upgrader            // 用于升级HTTP连接到WebSocket连接的工具。
+	lock     sync.Mutex                    // 用于同步对clients和订阅条件的访问，持有时只把消息放入发送队列。
+	bridge   *eventBridge                  // 用于查询直播间的历史事件，可能为空。
 }
 
 // NewWebSocketManager 初始化一个新的WebSocketManager并返回其指针。
 func NewWebSocketManager(ctx context.Context) *WebSocketManager {
 	wsm := &WebSocketManager{
-		clients:  make(map[*websocket.Conn]bool),
+		clients:  make(map[*websocket.Conn]*wsClient),
 		upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }},
 	}
 	instance.GetInstance(ctx).WebsocketManager = wsm
 	return wsm
 }
 
+// subscribeRequest 是客户端发送的订阅请求或历史事件查询。
+//
+//	{"action": "subscribe", "topics": ["live", "recorder"], "live_ids": ["..."]}
+//	{"action": "unsubscribe", "topics": ["progress"]}
+//	{"action": "history", "topics": ["error"], "live_ids": ["..."], "limit": 20}
+type subscribeRequest struct {
+	Action  string   `json:"action"`
+	Topics  []string `json:"topics"`
+	LiveIDs []string `json:"live_ids"`
+	Limit   int      `json:"limit"`
+}
+
+// subscription 是订阅请求的应答。
+type subscription struct {
+	Topics  []string `json:"topics"`
+	LiveIDs []string `json:"live_ids"`
+}
+
 // HandleConnection 处理新的WebSocket连接请求。
+// 可以通过 /ws?topics=live,recorder&live_ids=a,b 在连接时指定订阅条件。
 func (wsm *WebSocketManager) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	inst := instance.GetInstance(r.Context())
 	conn, err := wsm.upgrader.Upgrade(w, r, nil)
@@ -37,20 +130,68 @@ func (wsm *WebSocketManager) HandleConnection(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	client := newWsClient(conn, newEventFilterFromQuery(r.URL.Query()))
 	wsm.lock.Lock()
-	wsm.clients[conn] = true
+	wsm.clients[conn] = client
 	wsm.lock.Unlock()
 
-	// 仅仅为了检测连接断开
+	// 处理订阅请求并检测连接断开
 	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
 			wsm.RemoveClient(conn)
-			conn.Close()
+			client.close()
 			break
 		}
+		var req subscribeRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			continue
+		}
+		switch req.Action {
+		case "history":
+			if err := wsm.sendHistory(conn, req); err != nil {
+				inst.Logger.WithError(err).Debug("failed to reply ws history")
+			}
+			continue
+		case "subscribe", "unsubscribe":
+		default:
+			continue
+		}
+		resp := subscription{Topics: make([]string, 0), LiveIDs: make([]string, 0)}
+		wsm.lock.Lock()
+		if req.Action == "subscribe" {
+			client.filter.Add(req.Topics, req.LiveIDs)
+		} else {
+			client.filter.Remove(req.Topics, req.LiveIDs)
+		}
+		for topic := range client.filter.Topics {
+			resp.Topics = append(resp.Topics, topic)
+		}
+		for id := range client.filter.LiveIDs {
+			resp.LiveIDs = append(resp.LiveIDs, string(id))
+		}
+		wsm.lock.Unlock()
+		if err := wsm.SendEvent(conn, "subscribed", resp); err != nil {
+			inst.Logger.WithError(err).Debug("failed to reply ws subscription")
+		}
 	}
 }
 
+// sendHistory 为每个请求的直播间回复一条 history 事件，包含其最近的事件。
+func (wsm *WebSocketManager) sendHistory(conn *websocket.Conn, req subscribeRequest) error {
+	if wsm.bridge == nil {
+		return nil
+	}
+	filter := NewEventFilter(req.Topics, nil)
+	for _, id := range req.LiveIDs {
+		events, _ := wsm.bridge.liveEvents(live.ID(id), filter, 0, req.Limit)
+		if err := wsm.SendEvent(conn, "history", liveHistory{LiveID: live.ID(id), Events: events}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // RemoveClient 从管理器中移除一个WebSocket客户端连接。
 func (wsm *WebSocketManager) RemoveClient(conn *websocket.Conn) {
 	wsm.lock.Lock()
@@ -64,7 +205,7 @@ type EventMessage struct {
 	Data  interface{} `json:"data"`
 }
 
-// SendEvent 将事件消息发送到给定的WebSocket连接。
+// SendEvent 将事件消息放入给定WebSocket连接的发送队列。
 func (wsm *WebSocketManager) SendEvent(conn *websocket.Conn, event string, data interface{}) error {
 	msg := EventMessage{
 		Event: event,
@@ -77,7 +218,33 @@ func (wsm *WebSocketManager) SendEvent(conn *websocket.Conn, event string, data
 		return err
 	}
 
-	return conn.WriteMessage(websocket.TextMessage, jsonData)
+	wsm.lock.Lock()
+	client, ok := wsm.clients[conn]
+	wsm.lock.Unlock()
+	if !ok || !client.enqueue(jsonData) {
+		return errClientClosed
+	}
+	return nil
+}
+
+// PublishEvent 将结构化事件放入订阅了该事件的客户端的发送队列，队列已满的客户端会被断开，由客户端重连。
+func (wsm *WebSocketManager) PublishEvent(e *StreamEvent) {
+	jsonData, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	wsm.lock.Lock()
+	defer wsm.lock.Unlock()
+	for conn, client := range wsm.clients {
+		if !client.filter.Match(e) {
+			continue
+		}
+		if !client.enqueue(jsonData) {
+			client.close()
+			delete(wsm.clients, conn)
+		}
+	}
 }
 
 // SendMessageToClient 将消息发送到特定的WebSocket客户端。
@@ -88,23 +255,25 @@ func (wsm *WebSocketManager) SendMessageToClient(conn *websocket.Conn, event str
 // BroadcastMessage 将消息广播到所有连接的WebSocket客户端。
 // 如果发送失败，返回发送失败的连接列表和最后一个错误。
 func (wsm *WebSocketManager) BroadcastMessage(event string, data interface{}) ([]*websocket.Conn, error) {
+	jsonData, err := json.MarshalIndent(EventMessage{Event: event, Data: data}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
 	wsm.lock.Lock()
 	defer wsm.lock.Unlock()
 
 	var failedConns []*websocket.Conn
-	var lastError error
-
-	for client := range wsm.clients {
-		if err := wsm.SendEvent(client, event, data); err != nil {
-			client.Close()
-			delete(wsm.clients, client)
-			failedConns = append(failedConns, client)
-			lastError = err
+	for conn, client := range wsm.clients {
+		if !client.enqueue(jsonData) {
+			client.close()
+			delete(wsm.clients, conn)
+			failedConns = append(failedConns, conn)
 		}
 	}
 
 	if len(failedConns) > 0 {
-		return failedConns, lastError
+		return failedConns, errClientClosed
 	}
 	return nil, nil
 }
@@ -112,9 +281,9 @@ func (wsm *WebSocketManager) BroadcastMessage(event string, data interface{}) ([
 // Close 关闭所有的WebSocket连接并清除clients。
 func (wsm *WebSocketManager) Close(ctx context.Context) {
 	wsm.lock.Lock()
-	for client := range wsm.clients {
-		client.Close()
+	for _, client := range wsm.clients {
+		client.close()
 	}
-	wsm.clients = make(map[*websocket.Conn]bool)
+	wsm.clients = make(map[*websocket.Conn]*wsClient)
 	wsm.lock.Unlock()
 }
@@ -0,0 +1,147 @@
+// Package shutdown 负责程序退出时的排空流程：停止接受新的录制，等待录制文件和元数据写完，
+// 在宽限时间内完成后处理任务，超时未完成的任务会被保存并在下次启动时继续，最后关闭各个模块。
+package shutdown
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+// Manager 定义退出管理器的接口。
+type Manager interface {
+	interfaces.Module
+	// Shutdown 在后台开始退出流程，已经在退出时返回 false。
+	Shutdown(reason string) bool
+	// Draining 判断是否正在退出。
+	Draining() bool
+	// Done 返回退出流程完成后关闭的通道。
+	Done() <-chan struct{}
+}
+
+// manager 是 Manager 的实现。
+type manager struct {
+	ctx  context.Context
+	inst *instance.Instance
+
+	once sync.Once
+	lock sync.Mutex
+	// draining 表示是否已开始退出
+	draining bool
+	done     chan struct{}
+}
+
+// NewManager 创建一个新的退出管理器实例。
+func NewManager(ctx context.Context) Manager {
+	inst := instance.GetInstance(ctx)
+	m := &manager{
+		ctx:  ctx,
+		inst: inst,
+		done: make(chan struct{}),
+	}
+	inst.ShutdownManager = m
+	return m
+}
+
+// Start 实现 interfaces.Module。
+func (m *manager) Start(ctx context.Context) error {
+	return nil
+}
+
+// Close 执行退出流程并等待其完成。
+func (m *manager) Close(ctx context.Context) {
+	m.Shutdown("close")
+	<-m.done
+}
+
+// Shutdown 在后台开始退出流程。
+// 退出流程占用 inst.WaitGroup 的一个计数，关闭监听器等模块后程序也会等到退出流程完成再退出。
+func (m *manager) Shutdown(reason string) bool {
+	started := false
+	m.once.Do(func() {
+		m.lock.Lock()
+		m.draining = true
+		m.lock.Unlock()
+		started = true
+		m.inst.WaitGroup.Add(1)
+		go m.drain(reason)
+	})
+	return started
+}
+
+// Draining 判断是否正在退出。
+func (m *manager) Draining() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.draining
+}
+
+// Done 返回退出流程完成后关闭的通道。
+func (m *manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// gracePeriod 返回退出时的最长等待时间。
+func (m *manager) gracePeriod() time.Duration {
+	if grace := m.inst.Config.Shutdown.GracePeriod; grace > 0 {
+		return grace
+	}
+	return configs.NewConfig().Shutdown.GracePeriod
+}
+
+// drain 按顺序关闭各个模块。
+func (m *manager) drain(reason string) {
+	defer m.inst.WaitGroup.Done()
+	defer close(m.done)
+	logger := m.inst.Logger
+	grace := m.gracePeriod()
+	start := time.Now()
+	logger.Infof("开始退出（%s），最长等待 %s", reason, grace)
+	ctx, cancel := context.WithTimeout(m.ctx, grace)
+	defer cancel()
+
+	// 1. 停止自动发现和监听，不再添加直播间和产生新的直播开始事件。
+	if m.inst.SourceManager != nil {
+		m.inst.SourceManager.Close(m.ctx)
+	}
+	if m.inst.ListenerManager != nil {
+		m.inst.ListenerManager.Close(m.ctx)
+	}
+
+	// 2. 停止录制并等待最后的文件和元数据写完。
+	if rm, ok := m.inst.RecorderManager.(recorders.Manager); ok {
+		if err := rm.Drain(ctx); err == nil {
+			logger.Infof("所有录制文件已写完")
+		}
+		rm.Close(m.ctx)
+	}
+	if m.inst.PusherManager != nil {
+		m.inst.PusherManager.Close(m.ctx)
+	}
+
+	// 3. 在剩余时间内完成后处理任务，未完成的任务保存后在下次启动时继续。
+	if pm, ok := m.inst.PostProcessManager.(postprocess.Manager); ok {
+		if err := pm.Drain(ctx); err == nil {
+			logger.Infof("所有后处理任务已完成")
+		}
+		pm.Close(m.ctx)
+	}
+	if m.inst.ThumbnailManager != nil {
+		m.inst.ThumbnailManager.Close(m.ctx)
+	}
+	if m.inst.NotifyManager != nil {
+		m.inst.NotifyManager.Close(m.ctx)
+	}
+
+	// 4. 最后关闭服务器，退出过程中仍可以通过接口查看状态。
+	if m.inst.Config.RPC.Enable && m.inst.Server != nil {
+		m.inst.Server.Close(m.ctx)
+	}
+	logger.Infof("退出完成，用时 %s", time.Since(start).Round(time.Millisecond))
+}
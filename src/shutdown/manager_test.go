@@ -0,0 +1,115 @@
+package shutdown
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/postprocess"
+)
+
+func TestShutdown(t *testing.T) {
+	dir := t.TempDir()
+	cfg := configs.NewConfig()
+	cfg.OutPutPath = dir
+	cfg.Shutdown.GracePeriod = 500 * time.Millisecond
+	cfg.PostProcess.Steps = []configs.PostProcessStep{{
+		Type:    configs.StepCommand,
+		Command: `case "{{ .FileName }}" in *slow*) sleep 10;; esac`,
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	inst := &instance.Instance{Config: cfg, Logger: &interfaces.Logger{Logger: logger}}
+	ctx := context.WithValue(context.Background(), instance.Key, inst)
+	pm := postprocess.NewManager(ctx)
+	assert.NoError(t, pm.Start(ctx))
+
+	ids := make(map[string]string)
+	for _, name := range []string{"fast.flv", "slow.flv"} {
+		fileName := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(fileName, []byte("flv"), 0644))
+		job, err := pm.Enqueue(postprocess.LiveInfo{}, fileName)
+		assert.NoError(t, err)
+		ids[name] = job.ID
+	}
+
+	m := NewManager(ctx)
+	assert.Equal(t, m, inst.ShutdownManager)
+	assert.False(t, m.Draining())
+	assert.True(t, m.Shutdown("test"))
+	assert.False(t, m.Shutdown("test"))
+	assert.True(t, m.Draining())
+	select {
+	case <-m.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("退出流程未在规定时间内完成")
+	}
+
+	// 宽限时间内完成的任务正常结束，超时的任务保存后在下次启动时继续
+	job, err := pm.GetJob(ids["fast.flv"])
+	assert.NoError(t, err)
+	assert.Equal(t, postprocess.StatusSucceeded, job.Status)
+	job, err = pm.GetJob(ids["slow.flv"])
+	assert.NoError(t, err)
+	assert.Equal(t, postprocess.StatusPending, job.Status)
+}
+
+// module 在关闭时调用 close。
+type module struct {
+	close func()
+}
+
+func (m module) Start(ctx context.Context) error { return nil }
+func (m module) Close(ctx context.Context)       { m.close() }
+
+// drainManager 在 Drain 时等待一段时间，模拟正在执行的后处理任务。
+type drainManager struct {
+	postprocess.Manager
+	drained chan struct{}
+}
+
+func (m *drainManager) Drain(ctx context.Context) error {
+	time.Sleep(50 * time.Millisecond)
+	close(m.drained)
+	return nil
+}
+
+func (m *drainManager) Close(ctx context.Context) {}
+
+func TestShutdownWaitGroup(t *testing.T) {
+	cfg := configs.NewConfig()
+	cfg.RPC.Enable = false
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	inst := &instance.Instance{Config: cfg, Logger: &interfaces.Logger{Logger: logger}}
+	ctx := context.WithValue(context.Background(), instance.Key, inst)
+
+	// 没有开启接口时只有监听器管理器等模块占用计数，关闭监听器后计数可能归零
+	inst.WaitGroup.Add(1)
+	inst.ListenerManager = module{close: inst.WaitGroup.Done}
+	pm := &drainManager{drained: make(chan struct{})}
+	inst.PostProcessManager = pm
+
+	m := NewManager(ctx)
+	assert.True(t, m.Shutdown("test"))
+	inst.WaitGroup.Wait()
+	select {
+	case <-pm.drained:
+	default:
+		t.Fatal("程序在后处理任务完成前退出")
+	}
+	select {
+	case <-m.Done():
+	default:
+		t.Fatal("程序在退出流程完成前退出")
+	}
+}
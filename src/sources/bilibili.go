@@ -0,0 +1,114 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/yuhaohwang/requests"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+const bilibiliHost = "live.bilibili.com"
+
+// 哔哩哔哩的接口地址，测试时替换为本地服务。
+var (
+	bilibiliFollowingUrl = "https://api.live.bilibili.com/xlive/web-ucenter/user/following"
+	bilibiliCategoryUrl  = "https://api.live.bilibili.com/room/v3/area/getRoomList"
+)
+
+// bilibiliGet 请求哔哩哔哩的接口，返回 data 字段。
+func bilibiliGet(ctx context.Context, apiUrl string, cookies map[string]string, opts ...requests.RequestOption) (gjson.Result, error) {
+	opts = append(opts, live.CommonUserAgent, requests.Cookies(cookies))
+	resp, err := do(ctx, http.MethodGet, apiUrl, opts...)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if code := gjson.GetBytes(body, "code").Int(); code != 0 {
+		return gjson.Result{}, fmt.Errorf("哔哩哔哩接口返回错误：%d %s", code, gjson.GetBytes(body, "message").String())
+	}
+	return gjson.GetBytes(body, "data"), nil
+}
+
+// bilibiliRoom 将接口返回的直播间转换为 Room。
+func bilibiliRoom(item gjson.Result, living bool) Room {
+	return Room{
+		Url:      fmt.Sprintf("https://%s/%d", bilibiliHost, item.Get("roomid").Int()),
+		HostName: item.Get("uname").String(),
+		Living:   living,
+	}
+}
+
+// bilibiliFollowing 获取登录账号关注的主播的直播间。
+func bilibiliFollowing(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	rooms := make([]Room, 0)
+	for page := 1; !full(rooms, source.Limit); page++ {
+		if page > maxPages {
+			return rooms, errTruncated()
+		}
+		data, err := bilibiliGet(ctx, bilibiliFollowingUrl, cookies,
+			requests.Query("page", strconv.Itoa(page)),
+			requests.Query("page_size", "10"),
+			requests.Query("ignoreRecord", "1"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		list := data.Get("list").Array()
+		for _, item := range list {
+			rooms = append(rooms, bilibiliRoom(item, item.Get("live_status").Int() == 1))
+		}
+		if page >= int(data.Get("totalPage").Int()) {
+			break
+		}
+		if len(list) == 0 {
+			return rooms, errEmptyPage(page)
+		}
+	}
+	return rooms, nil
+}
+
+// bilibiliCategory 获取分区中正在直播的直播间，分区格式为“父分区ID/分区ID”，分区ID为0时获取整个父分区。
+func bilibiliCategory(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	parentAreaId, areaId, ok := strings.Cut(source.Category, "/")
+	if !ok {
+		areaId = "0"
+	}
+	rooms := make([]Room, 0)
+	for page := 1; !full(rooms, source.Limit); page++ {
+		if page > maxPages {
+			return rooms, errTruncated()
+		}
+		data, err := bilibiliGet(ctx, bilibiliCategoryUrl, cookies,
+			requests.Query("platform", "web"),
+			requests.Query("parent_area_id", parentAreaId),
+			requests.Query("area_id", areaId),
+			requests.Query("sort_type", "online"),
+			requests.Query("page", strconv.Itoa(page)),
+			requests.Query("page_size", "99"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		list := data.Get("list").Array()
+		for _, item := range list {
+			rooms = append(rooms, bilibiliRoom(item, true))
+		}
+		if data.Get("has_more").Int() == 0 {
+			break
+		}
+		if len(list) == 0 {
+			return rooms, errEmptyPage(page)
+		}
+	}
+	return rooms, nil
+}
@@ -0,0 +1,110 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+	"github.com/yuhaohwang/requests"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+const douyuHost = "www.douyu.com"
+
+// 斗鱼的接口地址，测试时替换为本地服务。
+var (
+	douyuFollowingUrl = "https://www.douyu.com/wgapi/livenc/liveweb/follow/list"
+	douyuCategoryUrl  = "https://www.douyu.com/gapi/rkc/directory/mixList/2_%s/%d"
+)
+
+// douyuGet 请求斗鱼的接口，返回 data 字段，codeKey 为接口表示错误码的字段。
+func douyuGet(ctx context.Context, apiUrl, codeKey string, cookies map[string]string, opts ...requests.RequestOption) (gjson.Result, error) {
+	opts = append(opts, live.CommonUserAgent, requests.Cookies(cookies))
+	resp, err := do(ctx, http.MethodGet, apiUrl, opts...)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if code := gjson.GetBytes(body, codeKey).Int(); code != 0 {
+		return gjson.Result{}, fmt.Errorf("斗鱼接口返回错误：%d %s", code, gjson.GetBytes(body, "msg").String())
+	}
+	return gjson.GetBytes(body, "data"), nil
+}
+
+// douyuRoomUrl 返回斗鱼房间号对应的直播间地址。
+func douyuRoomUrl(roomId int64) string {
+	return fmt.Sprintf("https://%s/%d", douyuHost, roomId)
+}
+
+// douyuFollowing 获取登录账号关注的主播的直播间，录像轮播不视为正在直播。
+func douyuFollowing(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	rooms := make([]Room, 0)
+	for page := 1; !full(rooms, source.Limit); page++ {
+		if page > maxPages {
+			return rooms, errTruncated()
+		}
+		data, err := douyuGet(ctx, douyuFollowingUrl, "error", cookies,
+			requests.Query("sort", "0"),
+			requests.Query("cid1", "0"),
+			requests.Query("page", strconv.Itoa(page)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		list := data.Get("list").Array()
+		for _, item := range list {
+			rooms = append(rooms, Room{
+				Url:      douyuRoomUrl(item.Get("room_id").Int()),
+				HostName: item.Get("nickname").String(),
+				Living:   item.Get("show_status").Int() == 1 && item.Get("videoLoop").Int() == 0,
+			})
+		}
+		if page >= int(data.Get("pageCount").Int()) {
+			break
+		}
+		if len(list) == 0 {
+			return rooms, errEmptyPage(page)
+		}
+	}
+	return rooms, nil
+}
+
+// douyuCategory 获取分区中正在直播的直播间，分区为分区ID（cate2_id）。
+func douyuCategory(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	rooms := make([]Room, 0)
+	for page := 1; !full(rooms, source.Limit); page++ {
+		if page > maxPages {
+			return rooms, errTruncated()
+		}
+		data, err := douyuGet(ctx, fmt.Sprintf(douyuCategoryUrl, source.Category, page), "code", cookies)
+		if err != nil {
+			return nil, err
+		}
+		list := data.Get("rl").Array()
+		for _, item := range list {
+			// type 为1的是直播间，其他的是推荐的视频等
+			if item.Get("type").Int() != 1 {
+				continue
+			}
+			rooms = append(rooms, Room{
+				Url:      douyuRoomUrl(item.Get("rid").Int()),
+				HostName: item.Get("nn").String(),
+				Living:   true,
+			})
+		}
+		if page >= int(data.Get("pgcnt").Int()) {
+			break
+		}
+		if len(list) == 0 {
+			return rooms, errEmptyPage(page)
+		}
+	}
+	return rooms, nil
+}
@@ -0,0 +1,226 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+// checkInterval 是检查来源是否需要同步的间隔，来源的配置在运行中修改后也能生效。
+const checkInterval = time.Minute
+
+// Applier 将同步的修改应用到正在运行的程序，调用时已持有 inst.RoomLock。
+type Applier interface {
+	// AddRoom 添加直播间。
+	AddRoom(ctx context.Context, room configs.LiveRoom) error
+	// SetListen 开始或停止监听直播间。
+	SetListen(ctx context.Context, room configs.LiveRoom, listen bool) error
+	// RemoveRoom 移除直播间。
+	RemoveRoom(ctx context.Context, room configs.LiveRoom) error
+}
+
+// Manager 定义自动发现管理器的接口。
+type Manager interface {
+	interfaces.Module
+	// Sync 立即获取指定来源的列表并同步到直播间列表。
+	Sync(ctx context.Context, name string) (*Result, error)
+	// Results 返回每个来源最近一次同步的结果，键为来源名称。
+	Results() map[string]*Result
+}
+
+// manager 是 Manager 的实现。
+type manager struct {
+	inst    *instance.Instance
+	applier Applier
+
+	// ctx 在 Close 时取消，用于停止正在进行的获取和应用
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// syncLock 保证同一时间只有一个来源在同步
+	syncLock sync.Mutex
+
+	lock    sync.Mutex
+	results map[string]*Result
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewManager 创建一个新的自动发现管理器实例，applier 用于应用同步的修改。
+func NewManager(ctx context.Context, applier Applier) Manager {
+	inst := instance.GetInstance(ctx)
+	m := &manager{
+		inst:    inst,
+		applier: applier,
+		results: make(map[string]*Result),
+		stop:    make(chan struct{}),
+	}
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	inst.SourceManager = m
+	return m
+}
+
+// Start 启动定期同步，启动时立即同步一次所有来源。
+func (m *manager) Start(ctx context.Context) error {
+	go m.run()
+	return nil
+}
+
+// Close 停止定期同步，取消正在进行的同步并等待其退出。
+func (m *manager) Close(ctx context.Context) {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+		m.cancel()
+	})
+	m.syncLock.Lock()
+	m.syncLock.Unlock()
+}
+
+// run 定期检查并同步到期的来源。
+func (m *manager) run() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		for _, source := range m.inst.Config.Sources {
+			if m.ctx.Err() != nil {
+				return
+			}
+			if m.due(source) {
+				m.sync(m.ctx, source)
+			}
+		}
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// due 返回来源是否需要同步。
+func (m *manager) due(source configs.Source) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	result, ok := m.results[source.Name]
+	return !ok || time.Since(result.Time) >= source.GetInterval()
+}
+
+// Sync 立即同步指定来源，ctx 取消或管理器关闭时停止同步。
+func (m *manager) Sync(ctx context.Context, name string) (*Result, error) {
+	for _, source := range m.inst.Config.Sources {
+		if source.Name == name {
+			syncCtx, cancel := context.WithCancel(m.ctx)
+			defer cancel()
+			stop := context.AfterFunc(ctx, cancel)
+			defer stop()
+			return m.sync(syncCtx, source), nil
+		}
+	}
+	return nil, ErrSourceNotExist
+}
+
+// Results 返回每个来源最近一次同步的结果。
+func (m *manager) Results() map[string]*Result {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	results := make(map[string]*Result, len(m.results))
+	for name, result := range m.results {
+		results[name] = result
+	}
+	return results
+}
+
+// sync 获取来源的列表并将修改应用到直播间列表，获取失败时保持直播间列表不变。
+// 获取列表时不持有 inst.RoomLock，应用时与接口修改直播间列表的操作互斥，并根据最新的直播间列表计算修改。
+func (m *manager) sync(ctx context.Context, source configs.Source) *Result {
+	m.syncLock.Lock()
+	defer m.syncLock.Unlock()
+	logger := m.inst.Logger.WithField("source", source.Name)
+
+	result := newResult(source.Name)
+	rooms, err := Fetch(ctx, source, m.inst.Config.Cookies)
+	if err != nil && !errors.Is(err, ErrIncomplete) {
+		logger.WithError(err).Error("获取自动发现的直播间失败")
+		result.Error = err.Error()
+		m.setResult(result)
+		return result
+	}
+	complete := err == nil
+	if !complete {
+		logger.WithError(err).Warn("获取的列表不完整，本次不停止监听或移除直播间")
+		result.Error = err.Error()
+	}
+	result.Found = len(rooms)
+
+	m.inst.RoomLock.Lock()
+	changes := Reconcile(source, m.inst.Config.LiveRooms, rooms, complete, m.recording(ctx))
+	m.apply(ctx, changes, result)
+	m.inst.RoomLock.Unlock()
+
+	if result.Changed() || len(result.Failed) > 0 {
+		logger.Infof("同步自动发现的直播间：获取到 %d 个，添加 %d 个，恢复 %d 个，停止 %d 个，移除 %d 个，失败 %d 个",
+			result.Found, len(result.Added), len(result.Resumed), len(result.Stopped), len(result.Removed), len(result.Failed))
+	} else {
+		logger.Debugf("同步自动发现的直播间：获取到 %d 个，没有变化", result.Found)
+	}
+	m.setResult(result)
+	return result
+}
+
+// apply 逐个应用修改，失败的直播间记录到结果中并继续应用其他修改，ctx 取消时停止。
+func (m *manager) apply(ctx context.Context, changes *Changes, result *Result) {
+	logger := m.inst.Logger.WithField("source", result.Source)
+	run := func(rooms []configs.LiveRoom, done *[]string, apply func(room configs.LiveRoom) error) {
+		for _, room := range rooms {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := apply(room); err != nil {
+				logger.WithError(err).Errorf("应用自动发现的修改失败：%s", room.Url)
+				result.Failed = append(result.Failed, Failure{Url: room.Url, Error: err.Error()})
+				continue
+			}
+			*done = append(*done, room.Url)
+		}
+	}
+	run(changes.Add, &result.Added, func(room configs.LiveRoom) error {
+		return m.applier.AddRoom(ctx, room)
+	})
+	run(changes.Resume, &result.Resumed, func(room configs.LiveRoom) error {
+		return m.applier.SetListen(ctx, room, true)
+	})
+	run(changes.Stop, &result.Stopped, func(room configs.LiveRoom) error {
+		return m.applier.SetListen(ctx, room, false)
+	})
+	run(changes.Remove, &result.Removed, func(room configs.LiveRoom) error {
+		return m.applier.RemoveRoom(ctx, room)
+	})
+	if err := ctx.Err(); err != nil && result.Error == "" {
+		result.Error = err.Error()
+	}
+}
+
+// setResult 保存来源最近一次同步的结果。
+func (m *manager) setResult(result *Result) {
+	m.lock.Lock()
+	m.results[result.Source] = result
+	m.lock.Unlock()
+}
+
+// recording 返回判断直播间是否正在录制的函数，正在录制的直播间等到录制结束后再停止监听或移除。
+func (m *manager) recording(ctx context.Context) func(room configs.LiveRoom) bool {
+	rm, ok := m.inst.RecorderManager.(recorders.Manager)
+	if !ok {
+		return nil
+	}
+	return func(room configs.LiveRoom) bool {
+		return room.LiveId != "" && rm.HasRecorder(ctx, room.LiveId)
+	}
+}
@@ -0,0 +1,249 @@
+// Package sources 负责自动发现直播间，定期通过平台接口获取登录账号关注的主播或分区中的直播间，
+// 并按添加和移除策略同步到直播间列表。
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yuhaohwang/requests"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+// maxPages 是获取一个列表时最多请求的页数，避免接口异常时无限翻页，超过时返回 ErrIncomplete。
+const maxPages = 50
+
+var (
+	// ErrNoCookies 表示获取关注列表时没有设置平台的 cookies
+	ErrNoCookies = errors.New("获取关注列表需要在 cookies 中设置登录信息")
+	// ErrSourceNotExist 表示来源不存在
+	ErrSourceNotExist = errors.New("来源不存在")
+	// ErrIncomplete 表示获取的列表可能不完整，如超过最大页数、中间的页为空或整个列表为空（登录信息过期时接口可能返回空列表），
+	// 此时只添加直播间，不停止监听或移除直播间
+	ErrIncomplete = errors.New("获取的列表不完整")
+)
+
+// Room 是来源获取的直播间。
+type Room struct {
+	Url      string // 直播间地址
+	HostName string // 主播名
+	Living   bool   // 是否正在直播
+}
+
+// fetcher 获取来源的直播间列表，cookies 为平台直播间域名对应的 cookies。
+// 列表不完整时返回已获取的直播间和 ErrIncomplete。
+type fetcher func(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error)
+
+// platform 是支持自动发现的平台。
+type platform struct {
+	host  string // 直播间地址的域名，也是 cookies 配置的键
+	fetch map[string]fetcher
+}
+
+// platforms 是按名称索引的平台。
+var platforms = map[string]platform{
+	configs.SourcePlatformBilibili: {
+		host: bilibiliHost,
+		fetch: map[string]fetcher{
+			configs.SourceTypeFollowing: bilibiliFollowing,
+			configs.SourceTypeCategory:  bilibiliCategory,
+		},
+	},
+	configs.SourcePlatformDouyu: {
+		host: douyuHost,
+		fetch: map[string]fetcher{
+			configs.SourceTypeFollowing: douyuFollowing,
+			configs.SourceTypeCategory:  douyuCategory,
+		},
+	},
+	configs.SourcePlatformTwitch: {
+		host: twitchHost,
+		fetch: map[string]fetcher{
+			configs.SourceTypeFollowing: twitchFollowing,
+			configs.SourceTypeCategory:  twitchCategory,
+		},
+	},
+}
+
+// Fetch 获取来源的直播间列表，超过 limit 的部分会被丢弃。
+// 列表不完整时返回已获取的直播间和 ErrIncomplete，其他错误不返回直播间。
+func Fetch(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	p, ok := platforms[source.Platform]
+	if !ok {
+		return nil, fmt.Errorf("不支持的平台：%s", source.Platform)
+	}
+	fetch, ok := p.fetch[source.Type]
+	if !ok {
+		return nil, fmt.Errorf("不支持的类型：%s", source.Type)
+	}
+	kvs := parseCookies(cookies[p.host])
+	if source.Type == configs.SourceTypeFollowing && len(kvs) == 0 {
+		return nil, fmt.Errorf("%w：%s", ErrNoCookies, p.host)
+	}
+	rooms, err := fetch(ctx, source, kvs)
+	if err != nil && !errors.Is(err, ErrIncomplete) {
+		return nil, err
+	}
+	if source.Limit > 0 && len(rooms) > source.Limit {
+		rooms = rooms[:source.Limit]
+	}
+	if err == nil && len(rooms) == 0 {
+		err = fmt.Errorf("%w：列表为空", ErrIncomplete)
+	}
+	return rooms, err
+}
+
+// do 发送可以通过 ctx 取消的请求。
+func do(ctx context.Context, method, apiUrl string, opts ...requests.RequestOption) (*requests.Response, error) {
+	req, err := requests.NewRequestWithContext(ctx, method, apiUrl, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return requests.DefaultSession.Do(req)
+}
+
+// errTruncated 返回超过最大页数时的错误。
+func errTruncated() error {
+	return fmt.Errorf("%w：超过 %d 页", ErrIncomplete, maxPages)
+}
+
+// errEmptyPage 返回中间的页为空时的错误。
+func errEmptyPage(page int) error {
+	return fmt.Errorf("%w：第 %d 页为空", ErrIncomplete, page)
+}
+
+// parseCookies 解析形如 k1=v1; k2=v2 的 cookies，与直播间使用的格式相同。
+func parseCookies(cookies string) map[string]string {
+	kvs := make(map[string]string)
+	for _, pairStr := range strings.Split(cookies, ";") {
+		pairs := strings.SplitN(pairStr, "=", 2)
+		if len(pairs) != 2 {
+			continue
+		}
+		kvs[strings.TrimSpace(pairs[0])] = strings.TrimSpace(pairs[1])
+	}
+	return kvs
+}
+
+// full 返回是否已获取到足够的直播间，limit 为0时不限制。
+func full(rooms []Room, limit int) bool {
+	return limit > 0 && len(rooms) >= limit
+}
+
+// Failure 是同步时应用失败的直播间。
+type Failure struct {
+	Url   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// Result 是一次同步的结果，只包含应用成功的修改。
+type Result struct {
+	Source  string    `json:"source"`
+	Time    time.Time `json:"time"`
+	Found   int       `json:"found"`   // 获取到的直播间数量
+	Added   []string  `json:"added"`   // 添加的直播间地址
+	Resumed []string  `json:"resumed"` // 重新出现在列表中、恢复监听的直播间地址
+	Stopped []string  `json:"stopped"` // 停止监听的直播间地址
+	Removed []string  `json:"removed"` // 移除的直播间地址
+	Failed  []Failure `json:"failed"`  // 应用失败的直播间
+	Error   string    `json:"error,omitempty"`
+}
+
+// newResult 创建来源的同步结果。
+func newResult(source string) *Result {
+	return &Result{
+		Source:  source,
+		Time:    time.Now(),
+		Added:   make([]string, 0),
+		Resumed: make([]string, 0),
+		Stopped: make([]string, 0),
+		Removed: make([]string, 0),
+		Failed:  make([]Failure, 0),
+	}
+}
+
+// Changed 返回同步是否修改了直播间列表。
+func (r *Result) Changed() bool {
+	return len(r.Added)+len(r.Resumed)+len(r.Stopped)+len(r.Removed) > 0
+}
+
+// liveId 返回直播间地址生成的直播 ID，地址无效时返回空字符串。
+func liveId(rawUrl string) live.ID {
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "https://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	return live.GenLiveId(u)
+}
+
+// Changes 是一次同步需要对直播间列表做的修改。
+type Changes struct {
+	Add    []configs.LiveRoom // 新添加的直播间
+	Resume []configs.LiveRoom // 恢复监听的直播间
+	Stop   []configs.LiveRoom // 停止监听的直播间
+	Remove []configs.LiveRoom // 移除的直播间
+}
+
+// Reconcile 根据来源获取的直播间计算需要对直播间列表做的修改，不修改 current。
+// 只有 source 为该来源名称的直播间会被停止监听或移除，手动添加的直播间即使出现在列表中也不会被标记为自动管理。
+// 只有被来源停止监听（SourceStopped）的直播间会被恢复监听，手动停止监听的直播间保持停止。
+// complete 为 false 时列表可能不完整，只添加和恢复直播间。
+// busy 返回直播间是否不能停止或移除（如正在录制），为空时都可以停止或移除。
+func Reconcile(source configs.Source, current []configs.LiveRoom, rooms []Room, complete bool, busy func(room configs.LiveRoom) bool) *Changes {
+	changes := &Changes{}
+	found := make(map[live.ID]struct{}, len(rooms))
+	for _, room := range rooms {
+		found[liveId(room.Url)] = struct{}{}
+	}
+
+	existing := make(map[live.ID]struct{}, len(current))
+	for _, room := range current {
+		id := liveId(room.Url)
+		existing[id] = struct{}{}
+		if room.LiveId != "" {
+			existing[room.LiveId] = struct{}{}
+		}
+		if room.Source != source.Name {
+			continue
+		}
+		_, ok := found[id]
+		switch {
+		case ok && !room.Listen && room.SourceStopped && source.GetRemovePolicy() == configs.SourceRemoveStop:
+			changes.Resume = append(changes.Resume, room)
+		case ok || !complete || (busy != nil && busy(room)):
+		case source.GetRemovePolicy() == configs.SourceRemoveStop && room.Listen:
+			changes.Stop = append(changes.Stop, room)
+		case source.GetRemovePolicy() == configs.SourceRemoveRemove:
+			changes.Remove = append(changes.Remove, room)
+		}
+	}
+
+	for _, room := range rooms {
+		id := liveId(room.Url)
+		if _, ok := existing[id]; ok {
+			continue
+		}
+		if source.GetAddPolicy() == configs.SourceAddLiving && !room.Living {
+			continue
+		}
+		existing[id] = struct{}{}
+		changes.Add = append(changes.Add, configs.LiveRoom{
+			Url:    room.Url,
+			Listen: true,
+			Record: !source.ListenOnly,
+			Group:  source.Group,
+			Tags:   source.Tags,
+			Source: source.Name,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,226 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+)
+
+func TestReconcile(t *testing.T) {
+	current := []configs.LiveRoom{
+		{Url: "https://live.bilibili.com/1", Listen: true, Record: true},
+		{Url: "https://live.bilibili.com/2", Listen: true, Record: true, Source: "follow"},
+		{Url: "https://live.bilibili.com/3", Listen: false, Record: true, Source: "follow", SourceStopped: true},
+		{Url: "https://live.bilibili.com/4", Listen: true, Record: true, Source: "other"},
+		{Url: "https://live.bilibili.com/7", Listen: false, Record: true, Source: "follow"},
+	}
+	rooms := []Room{
+		{Url: "https://live.bilibili.com/1", Living: true},
+		{Url: "https://live.bilibili.com/3"},
+		{Url: "https://live.bilibili.com/4"},
+		{Url: "https://live.bilibili.com/5", Living: true},
+		{Url: "https://live.bilibili.com/6"},
+		{Url: "https://live.bilibili.com/7"},
+	}
+
+	// 手动添加和其他来源的直播间保持不变，手动停止监听的直播间不恢复
+	source := configs.Source{Name: "follow", RemovePolicy: configs.SourceRemoveStop, ListenOnly: true, Tags: []string{"auto"}}
+	changes := Reconcile(source, current, rooms, true, nil)
+	assert.Equal(t, &Changes{
+		Add: []configs.LiveRoom{
+			{Url: "https://live.bilibili.com/5", Listen: true, Tags: []string{"auto"}, Source: "follow"},
+			{Url: "https://live.bilibili.com/6", Listen: true, Tags: []string{"auto"}, Source: "follow"},
+		},
+		Resume: []configs.LiveRoom{current[2]},
+		Stop:   []configs.LiveRoom{current[1]},
+	}, changes)
+
+	// 列表不完整时只添加和恢复
+	changes = Reconcile(source, current, rooms, false, nil)
+	assert.Len(t, changes.Add, 2)
+	assert.Len(t, changes.Resume, 1)
+	assert.Empty(t, changes.Stop)
+
+	// 正在录制的直播间不停止监听
+	busy := func(room configs.LiveRoom) bool { return room.Url == "https://live.bilibili.com/2" }
+	changes = Reconcile(source, current, rooms, true, busy)
+	assert.Empty(t, changes.Stop)
+
+	// 只添加正在直播的直播间，移除不在列表中的直播间
+	source = configs.Source{Name: "follow", AddPolicy: configs.SourceAddLiving, RemovePolicy: configs.SourceRemoveRemove}
+	changes = Reconcile(source, current, rooms, true, nil)
+	assert.Equal(t, []configs.LiveRoom{{Url: "https://live.bilibili.com/5", Listen: true, Record: true, Source: "follow"}}, changes.Add)
+	assert.Equal(t, []configs.LiveRoom{current[1]}, changes.Remove)
+	assert.Empty(t, changes.Resume)
+
+	// 正在录制的直播间不移除
+	changes = Reconcile(source, current, rooms, true, busy)
+	assert.Empty(t, changes.Remove)
+
+	// 列表不完整时不移除
+	changes = Reconcile(source, current, nil, false, nil)
+	assert.Empty(t, changes.Remove)
+
+	// 默认保留不在列表中的直播间
+	assert.Equal(t, &Changes{}, Reconcile(configs.Source{Name: "follow"}, current, nil, true, nil))
+}
+
+func TestFetchBilibili(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		switch r.URL.Path {
+		case "/following":
+			if _, err := r.Cookie("SESSDATA"); err != nil {
+				fmt.Fprint(w, `{"code":-101,"message":"账号未登录"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"code":0,"data":{"totalPage":2,"list":[{"roomid":%s1,"uname":"a","live_status":1},{"roomid":%s2,"uname":"b","live_status":0}]}}`, page, page)
+		case "/category":
+			assert.Equal(t, "9", r.URL.Query().Get("parent_area_id"))
+			assert.Equal(t, "371", r.URL.Query().Get("area_id"))
+			fmt.Fprintf(w, `{"code":0,"data":{"has_more":0,"list":[{"roomid":%s,"uname":"c"}]}}`, page)
+		}
+	}))
+	defer server.Close()
+	bilibiliFollowingUrl = server.URL + "/following"
+	bilibiliCategoryUrl = server.URL + "/category"
+
+	source := configs.Source{Platform: configs.SourcePlatformBilibili, Type: configs.SourceTypeFollowing}
+	_, err := Fetch(ctx, source, nil)
+	assert.ErrorIs(t, err, ErrNoCookies)
+	_, err = Fetch(ctx, source, map[string]string{bilibiliHost: "buvid3=1"})
+	assert.EqualError(t, err, "哔哩哔哩接口返回错误：-101 账号未登录")
+
+	cookies := map[string]string{bilibiliHost: "SESSDATA=1; bili_jct=2"}
+	rooms, err := Fetch(ctx, source, cookies)
+	assert.NoError(t, err)
+	assert.Equal(t, []Room{
+		{Url: "https://live.bilibili.com/11", HostName: "a", Living: true},
+		{Url: "https://live.bilibili.com/12", HostName: "b"},
+		{Url: "https://live.bilibili.com/21", HostName: "a", Living: true},
+		{Url: "https://live.bilibili.com/22", HostName: "b"},
+	}, rooms)
+
+	source.Limit = 3
+	rooms, err = Fetch(ctx, source, cookies)
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 3)
+
+	rooms, err = Fetch(ctx, configs.Source{Platform: configs.SourcePlatformBilibili, Type: configs.SourceTypeCategory, Category: "9/371"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Room{{Url: "https://live.bilibili.com/1", HostName: "c", Living: true}}, rooms)
+}
+
+func TestFetchIncomplete(t *testing.T) {
+	ctx := context.Background()
+	totalPage := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if totalPage == 0 || page == "2" && totalPage == 3 {
+			// 登录信息过期或中间的页为空
+			fmt.Fprintf(w, `{"code":0,"data":{"totalPage":%d,"list":[]}}`, totalPage)
+			return
+		}
+		fmt.Fprintf(w, `{"code":0,"data":{"totalPage":%d,"list":[{"roomid":%s,"live_status":1}]}}`, totalPage, page)
+	}))
+	defer server.Close()
+	bilibiliFollowingUrl = server.URL
+	source := configs.Source{Platform: configs.SourcePlatformBilibili, Type: configs.SourceTypeFollowing}
+	cookies := map[string]string{bilibiliHost: "SESSDATA=1"}
+
+	// 登录信息过期时接口返回空列表
+	rooms, err := Fetch(ctx, source, cookies)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.Empty(t, rooms)
+
+	totalPage = 3
+	rooms, err = Fetch(ctx, source, cookies)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.Len(t, rooms, 1)
+
+	totalPage = maxPages + 1
+	rooms, err = Fetch(ctx, source, cookies)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.Len(t, rooms, maxPages)
+
+	// 达到 limit 时不是不完整
+	source.Limit = 2
+	rooms, err = Fetch(ctx, source, cookies)
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 2)
+}
+
+func TestFetchDouyu(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/following":
+			fmt.Fprint(w, `{"error":0,"data":{"pageCount":1,"list":[{"room_id":1,"nickname":"a","show_status":1,"videoLoop":0},{"room_id":2,"nickname":"b","show_status":1,"videoLoop":1}]}}`)
+		case "/category/2_270/1":
+			fmt.Fprint(w, `{"code":0,"data":{"pgcnt":1,"rl":[{"rid":3,"nn":"c","type":1},{"rid":4,"nn":"d","type":2}]}}`)
+		default:
+			fmt.Fprint(w, `{"code":1,"msg":"error"}`)
+		}
+	}))
+	defer server.Close()
+	douyuFollowingUrl = server.URL + "/following"
+	douyuCategoryUrl = server.URL + "/category/2_%s/%d"
+
+	rooms, err := Fetch(ctx, configs.Source{Platform: configs.SourcePlatformDouyu, Type: configs.SourceTypeFollowing}, map[string]string{douyuHost: "acf_uid=1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Room{
+		{Url: "https://www.douyu.com/1", HostName: "a", Living: true},
+		{Url: "https://www.douyu.com/2", HostName: "b"},
+	}, rooms)
+
+	rooms, err = Fetch(ctx, configs.Source{Platform: configs.SourcePlatformDouyu, Type: configs.SourceTypeCategory, Category: "270"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Room{{Url: "https://www.douyu.com/3", HostName: "c", Living: true}}, rooms)
+
+	_, err = Fetch(ctx, configs.Source{Platform: configs.SourcePlatformDouyu, Type: configs.SourceTypeCategory, Category: "1"}, nil)
+	assert.Error(t, err)
+}
+
+func TestFetchTwitch(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if r.Header.Get("Authorization") != "OAuth token" {
+			fmt.Fprint(w, `{"data":{"currentUser":null}}`)
+			return
+		}
+		if req.Variables["cursor"] == nil {
+			fmt.Fprint(w, `{"data":{"currentUser":{"follows":{"edges":[{"cursor":"c1","node":{"login":"a","displayName":"A","stream":{"id":"1","type":"live"}}}],"pageInfo":{"hasNextPage":true}}}}}`)
+			return
+		}
+		assert.Equal(t, "c1", req.Variables["cursor"])
+		fmt.Fprint(w, `{"data":{"currentUser":{"follows":{"edges":[{"cursor":"c2","node":{"login":"b","displayName":"B","stream":null}}],"pageInfo":{"hasNextPage":false}}}}}`)
+	}))
+	defer server.Close()
+	twitchGqlUrl = server.URL
+
+	source := configs.Source{Platform: configs.SourcePlatformTwitch, Type: configs.SourceTypeFollowing}
+	_, err := Fetch(ctx, source, map[string]string{twitchHost: "unique_id=1"})
+	assert.ErrorIs(t, err, ErrNoCookies)
+
+	rooms, err := Fetch(ctx, source, map[string]string{twitchHost: "unique_id=1; auth-token=token"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Room{
+		{Url: "https://www.twitch.tv/a", HostName: "A", Living: true},
+		{Url: "https://www.twitch.tv/b", HostName: "B"},
+	}, rooms)
+
+	_, err = Fetch(ctx, configs.Source{Platform: configs.SourcePlatformTwitch, Type: configs.SourceTypeCategory, Category: "Minecraft"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/yuhaohwang/requests"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/live"
+)
+
+const (
+	twitchHost = "www.twitch.tv"
+
+	// twitchClientId 是 Twitch 网页使用的 Client-ID
+	twitchClientId = "kimne78kx3ncx6brgo4mv6wki5h1ko"
+
+	// twitchAuthCookie 是登录后保存 OAuth 令牌的 cookie
+	twitchAuthCookie = "auth-token"
+
+	// twitchPageSize 是每页获取的数量，Twitch 限制为100
+	twitchPageSize = 100
+
+	twitchFollowingQuery = `query FollowedChannels($first: Int!, $cursor: Cursor) {
+  currentUser {
+    follows(first: $first, after: $cursor) {
+      edges { cursor node { login displayName stream { id type } } }
+      pageInfo { hasNextPage }
+    }
+  }
+}`
+
+	twitchCategoryQuery = `query CategoryStreams($name: String!, $first: Int!, $cursor: Cursor) {
+  game(name: $name) {
+    streams(first: $first, after: $cursor) {
+      edges { cursor node { type broadcaster { login displayName } } }
+      pageInfo { hasNextPage }
+    }
+  }
+}`
+)
+
+// twitchGqlUrl 是 Twitch GQL 接口的地址，测试时替换为本地服务。
+var twitchGqlUrl = "https://gql.twitch.tv/gql"
+
+// twitchGql 发送 GQL 请求，返回 data 字段。
+func twitchGql(ctx context.Context, cookies map[string]string, query string, variables map[string]interface{}) (gjson.Result, error) {
+	opts := []requests.RequestOption{
+		live.CommonUserAgent,
+		requests.Header("Client-ID", twitchClientId),
+		requests.JSON(map[string]interface{}{"query": query, "variables": variables}),
+	}
+	if token := cookies[twitchAuthCookie]; token != "" {
+		opts = append(opts, requests.Header("Authorization", "OAuth "+token))
+	}
+	resp, err := do(ctx, http.MethodPost, twitchGqlUrl, opts...)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return gjson.Result{}, fmt.Errorf("twitch gql 请求失败：%d %s", resp.StatusCode, gjson.GetBytes(body, "message").String())
+	}
+	if errs := gjson.GetBytes(body, "errors"); errs.Exists() {
+		return gjson.Result{}, fmt.Errorf("twitch gql 请求失败：%s", errs.Get("0.message").String())
+	}
+	return gjson.GetBytes(body, "data"), nil
+}
+
+// twitchPages 按游标翻页获取列表，path 为 data 中连接（connection）的路径，
+// 返回 nil 的连接表示用户或游戏不存在。
+func twitchPages(ctx context.Context, source configs.Source, cookies map[string]string, query, path string, variables map[string]interface{}, toRoom func(node gjson.Result) Room) ([]Room, error) {
+	rooms := make([]Room, 0)
+	variables["first"] = twitchPageSize
+	for page := 1; !full(rooms, source.Limit); page++ {
+		if page > maxPages {
+			return rooms, errTruncated()
+		}
+		data, err := twitchGql(ctx, cookies, query, variables)
+		if err != nil {
+			return nil, err
+		}
+		connection := data.Get(path)
+		if !connection.IsObject() {
+			return nil, errors.New("twitch 用户未登录或游戏不存在")
+		}
+		edges := connection.Get("edges").Array()
+		for _, edge := range edges {
+			rooms = append(rooms, toRoom(edge.Get("node")))
+		}
+		if !connection.Get("pageInfo.hasNextPage").Bool() {
+			break
+		}
+		if len(edges) == 0 {
+			return rooms, errEmptyPage(page)
+		}
+		variables["cursor"] = edges[len(edges)-1].Get("cursor").String()
+	}
+	return rooms, nil
+}
+
+// twitchRoomUrl 返回 Twitch 频道的直播间地址。
+func twitchRoomUrl(login string) string {
+	return fmt.Sprintf("https://%s/%s", twitchHost, login)
+}
+
+// twitchFollowing 获取登录账号关注的频道，需要在 cookies 中设置 auth-token。
+func twitchFollowing(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	if cookies[twitchAuthCookie] == "" {
+		return nil, fmt.Errorf("%w：%s", ErrNoCookies, twitchAuthCookie)
+	}
+	return twitchPages(ctx, source, cookies, twitchFollowingQuery, "currentUser.follows", map[string]interface{}{}, func(node gjson.Result) Room {
+		return Room{
+			Url:      twitchRoomUrl(node.Get("login").String()),
+			HostName: node.Get("displayName").String(),
+			Living:   node.Get("stream.type").String() == "live",
+		}
+	})
+}
+
+// twitchCategory 获取游戏分类中正在直播的频道，分区为游戏名称。
+func twitchCategory(ctx context.Context, source configs.Source, cookies map[string]string) ([]Room, error) {
+	return twitchPages(ctx, source, cookies, twitchCategoryQuery, "game.streams", map[string]interface{}{"name": source.Category}, func(node gjson.Result) Room {
+		return Room{
+			Url:      twitchRoomUrl(node.Get("broadcaster.login").String()),
+			HostName: node.Get("broadcaster.displayName").String(),
+			Living:   node.Get("type").String() == "live",
+		}
+	})
+}
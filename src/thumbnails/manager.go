@@ -0,0 +1,152 @@
+// Package thumbnails 负责在录制完成后为录制文件生成缩略图和联系表。
+package thumbnails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/instance"
+	"github.com/yuhaohwang/bililive-go/src/interfaces"
+	"github.com/yuhaohwang/bililive-go/src/pkg/events"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+	"github.com/yuhaohwang/bililive-go/src/recorders"
+)
+
+// queueSize 是等待生成缩略图的文件队列长度。
+const queueSize = 64
+
+// ErrUnavailable 表示无法为文件生成缩略图，如未找到 FFmpeg 或生成失败。
+var ErrUnavailable = errors.New("无法生成缩略图")
+
+// Manager 定义缩略图管理器的接口。
+type Manager interface {
+	interfaces.Module
+	// Enqueue 将文件加入生成队列，队列已满或文件已在队列中时返回 false。
+	Enqueue(fileName string) bool
+	// Unavailable 返回文件无法生成缩略图的原因（包装 ErrUnavailable），可以生成或正在生成时返回 nil。
+	Unavailable(ctx context.Context, fileName string) error
+}
+
+// manager 是 Manager 的实现。
+type manager struct {
+	lock    sync.Mutex
+	pending map[string]struct{}
+	failed  map[string]error // 生成失败的文件，重新加入队列前不再生成
+	queue   chan string
+	stop    chan struct{}
+	once    sync.Once
+	cfg     *configs.Config
+	logger  *interfaces.Logger
+}
+
+// NewManager 创建一个新的缩略图管理器实例。
+func NewManager(ctx context.Context) Manager {
+	inst := instance.GetInstance(ctx)
+	m := &manager{
+		pending: make(map[string]struct{}),
+		failed:  make(map[string]error),
+		queue:   make(chan string, queueSize),
+		stop:    make(chan struct{}),
+		cfg:     inst.Config,
+		logger:  inst.Logger,
+	}
+	inst.ThumbnailManager = m
+	return m
+}
+
+// Start 启动后台生成任务，并在启用时监听录制完成事件。
+func (m *manager) Start(ctx context.Context) error {
+	inst := instance.GetInstance(ctx)
+	if m.cfg.Thumbnails.Enable {
+		inst.EventDispatcher.(events.Dispatcher).AddEventListener(recorders.RecordFileFinished, events.NewEventListener(func(event *events.Event) {
+			param := event.Object.(*recorders.FileFinishedParam)
+			m.lock.Lock()
+			delete(m.failed, param.FileName)
+			m.lock.Unlock()
+			m.Enqueue(param.FileName)
+		}))
+	}
+	go m.run(ctx)
+	return nil
+}
+
+// Close 停止后台生成任务，队列中未处理的文件会被丢弃。
+func (m *manager) Close(ctx context.Context) {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
+
+// Enqueue 将文件加入生成队列。
+func (m *manager) Enqueue(fileName string) bool {
+	if !IsVideoFile(fileName) {
+		return false
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.pending[fileName]; ok {
+		return false
+	}
+	if _, ok := m.failed[fileName]; ok {
+		return false
+	}
+	select {
+	case m.queue <- fileName:
+		m.pending[fileName] = struct{}{}
+		return true
+	default:
+		m.logger.Warnf("缩略图队列已满，跳过：%s", fileName)
+		return false
+	}
+}
+
+// Unavailable 返回文件无法生成缩略图的原因。
+func (m *manager) Unavailable(ctx context.Context, fileName string) error {
+	if _, err := utils.GetFFmpegPath(ctx); err != nil {
+		return fmt.Errorf("%w：未找到 FFmpeg", ErrUnavailable)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.failed[fileName]
+}
+
+// run 逐个处理队列中的文件。
+func (m *manager) run(ctx context.Context) {
+	for {
+		select {
+		case <-m.stop:
+			return
+		case fileName := <-m.queue:
+			m.process(ctx, fileName)
+			m.lock.Lock()
+			delete(m.pending, fileName)
+			m.lock.Unlock()
+		}
+	}
+}
+
+// process 为单个文件生成缩略图。
+func (m *manager) process(ctx context.Context, fileName string) {
+	if _, err := os.Stat(fileName); err != nil {
+		return
+	}
+	ffmpegPath, err := utils.GetFFmpegPath(ctx)
+	if err != nil {
+		// 未找到 FFmpeg 时由 Unavailable 返回错误，找到后可以重新生成
+		m.logger.WithError(err).Warnf("未找到 FFmpeg，无法生成缩略图：%s", fileName)
+		return
+	}
+	m.logger.Debugf("开始生成缩略图：%s", fileName)
+	if err := Generate(ctx, ffmpegPath, fileName, m.cfg.Thumbnails); err != nil {
+		m.logger.WithError(err).Warnf("生成缩略图失败：%s", fileName)
+		m.lock.Lock()
+		m.failed[fileName] = fmt.Errorf("%w：%v", ErrUnavailable, err)
+		m.lock.Unlock()
+		return
+	}
+	m.logger.Infof("已生成缩略图：%s", PosterFile(fileName))
+}
@@ -0,0 +1,187 @@
+package thumbnails
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuhaohwang/bililive-go/src/configs"
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
+	"github.com/yuhaohwang/bililive-go/src/pkg/utils"
+)
+
+const (
+	// PosterSuffix 是封面缩略图的文件后缀。
+	PosterSuffix = ".thumb.jpg"
+	// SheetSuffix 是联系表的文件后缀。
+	SheetSuffix = ".sheet.jpg"
+)
+
+var durationRegexp = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+
+// PosterFile 返回视频文件对应的封面缩略图路径。
+func PosterFile(fileName string) string {
+	return utils.SidecarFile(fileName, PosterSuffix)
+}
+
+// SheetFile 返回视频文件对应的联系表路径。
+func SheetFile(fileName string) string {
+	return utils.SidecarFile(fileName, SheetSuffix)
+}
+
+// IsVideoFile 判断文件是否是可以生成缩略图的录制文件。
+func IsVideoFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".flv", ".ts", ".mp4", ".mkv":
+		return true
+	}
+	return false
+}
+
+// probeTimestamps 返回均匀分布在视频中的 n 个截图时间点（秒）。
+// FLV 文件优先使用本地解析器扫描出的关键帧，使截图点落在关键帧上，截图仍需要 FFmpeg；
+// 其他格式则从 ffmpeg 输出中读取时长。
+func probeTimestamps(ctx context.Context, ffmpegPath, fileName string, n int) ([]float64, error) {
+	if strings.EqualFold(filepath.Ext(fileName), ".flv") {
+		if f, err := os.Open(fileName); err == nil {
+			index, err := flv.ReadIndex(f)
+			f.Close()
+			if err == nil && len(index.Keyframes) > 0 {
+				return pickKeyframes(index.Keyframes, index.FirstTimestamp, n), nil
+			}
+		}
+	}
+
+	duration, err := probeDuration(ctx, ffmpegPath, fileName)
+	if err != nil {
+		return nil, err
+	}
+	timestamps := make([]float64, n)
+	for i := range timestamps {
+		timestamps[i] = duration * (float64(i) + 0.5) / float64(n)
+	}
+	return timestamps, nil
+}
+
+// pickKeyframes 从关键帧列表中按时间均匀挑选 n 个关键帧的时间点。
+// FFmpeg 的 -ss 从文件的开始时间（第一个音视频标签的时间戳 start）算起，因此返回相对于 start 的时间点。
+func pickKeyframes(keyframes []flv.Keyframe, start uint32, n int) []float64 {
+	seconds := func(k flv.Keyframe) float64 {
+		if k.Timestamp < start {
+			return 0
+		}
+		return float64(k.Timestamp-start) / 1000
+	}
+	if len(keyframes) <= n {
+		timestamps := make([]float64, len(keyframes))
+		for i, k := range keyframes {
+			timestamps[i] = seconds(k)
+		}
+		return timestamps
+	}
+	first := float64(keyframes[0].Timestamp)
+	span := float64(keyframes[len(keyframes)-1].Timestamp) - first
+	timestamps := make([]float64, 0, n)
+	j := 0
+	for i := 0; i < n; i++ {
+		target := first + span*(float64(i)+0.5)/float64(n)
+		for j+1 < len(keyframes) && float64(keyframes[j+1].Timestamp) <= target {
+			j++
+		}
+		timestamps = append(timestamps, seconds(keyframes[j]))
+	}
+	return timestamps
+}
+
+// probeDuration 通过 ffmpeg 的输出获取视频时长（秒）。
+func probeDuration(ctx context.Context, ffmpegPath, fileName string) (float64, error) {
+	// 没有输出文件时 ffmpeg 会以非零状态退出，这里只关心其输出
+	out, _ := exec.CommandContext(ctx, ffmpegPath, "-hide_banner", "-i", fileName).CombinedOutput()
+	match := durationRegexp.FindSubmatch(out)
+	if match == nil {
+		return 0, fmt.Errorf("无法获取视频时长：%s", fileName)
+	}
+	h, _ := strconv.ParseFloat(string(match[1]), 64)
+	m, _ := strconv.ParseFloat(string(match[2]), 64)
+	s, _ := strconv.ParseFloat(string(match[3]), 64)
+	if duration := h*3600 + m*60 + s; duration > 0 {
+		return duration, nil
+	}
+	return 0, fmt.Errorf("视频时长为0：%s", fileName)
+}
+
+// extractFrame 截取 timestamp 处的一帧并缩放为指定宽度。
+func extractFrame(ctx context.Context, ffmpegPath, fileName string, timestamp float64, width int, output string) error {
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-ss", strconv.FormatFloat(timestamp, 'f', 3, 64),
+		"-i", fileName,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", width),
+		"-y", output,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("截取视频帧失败：%v，%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Generate 为录制文件生成封面缩略图和联系表。
+func Generate(ctx context.Context, ffmpegPath, fileName string, cfg configs.Thumbnails) error {
+	timestamps, err := probeTimestamps(ctx, ffmpegPath, fileName, cfg.Count)
+	if err != nil {
+		return err
+	}
+	if len(timestamps) == 0 {
+		return fmt.Errorf("视频中没有可用的帧：%s", fileName)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bililive-thumbnails-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	frames := 0
+	for _, ts := range timestamps {
+		output := filepath.Join(tmpDir, fmt.Sprintf("%03d.jpg", frames))
+		if err := extractFrame(ctx, ffmpegPath, fileName, ts, cfg.Width, output); err != nil {
+			continue
+		}
+		frames++
+	}
+	if frames == 0 {
+		return fmt.Errorf("无法从视频中截取任何帧：%s", fileName)
+	}
+
+	// 取中间一帧作为封面
+	poster, err := os.ReadFile(filepath.Join(tmpDir, fmt.Sprintf("%03d.jpg", frames/2)))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(PosterFile(fileName), poster, 0644); err != nil {
+		return err
+	}
+
+	columns := cfg.Columns
+	if columns > frames {
+		columns = frames
+	}
+	rows := (frames + columns - 1) / columns
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-i", filepath.Join(tmpDir, "%03d.jpg"),
+		"-vf", fmt.Sprintf("tile=%dx%d:padding=4:margin=4", columns, rows),
+		"-frames:v", "1",
+		"-y", SheetFile(fileName),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("生成联系表失败：%v，%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
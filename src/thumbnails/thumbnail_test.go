@@ -0,0 +1,28 @@
+package thumbnails
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuhaohwang/bililive-go/src/pkg/parser/native/flv"
+)
+
+func TestPickKeyframes(t *testing.T) {
+	keyframes := make([]flv.Keyframe, 0)
+	for i := 0; i <= 100; i++ {
+		keyframes = append(keyframes, flv.Keyframe{Timestamp: uint32(i * 2000)})
+	}
+	assert.Equal(t, []float64{50, 150}, pickKeyframes(keyframes, 0, 2))
+	assert.Equal(t, []float64{0, 2}, pickKeyframes(keyframes[:2], 0, 4))
+	// 时间点从第一个音视频标签开始计算
+	assert.Equal(t, []float64{48, 148}, pickKeyframes(keyframes, 2000, 2))
+	assert.Equal(t, []float64{0, 0.5}, pickKeyframes([]flv.Keyframe{{Timestamp: 1000}, {Timestamp: 1500}}, 1000, 4))
+}
+
+func TestSidecarFiles(t *testing.T) {
+	assert.Equal(t, "/a/[x][y].thumb.jpg", PosterFile("/a/[x][y].flv"))
+	assert.Equal(t, "/a/b.sheet.jpg", SheetFile("/a/b.ts"))
+	assert.True(t, IsVideoFile("a.FLV"))
+	assert.False(t, IsVideoFile("a.metadata.json"))
+}